@@ -6,9 +6,17 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"strings"
 )
 
-var ErrBuildFailed = errors.New("build failed") //nolint:revive
+var (
+	ErrArtifactNotSigned = errors.New("artifact not signed") //nolint:revive
+	ErrBuildFailed       = errors.New("build failed")
+	ErrSBOMNotAvailable  = errors.New("SBOM not available")
+	ErrUnknownArtifact   = errors.New("unknown artifact")
+	ErrUnknownDependency = errors.New("unknown dependency")
+)
 
 // Dependency defines a dependency and its semantic version constrains
 type Dependency struct {
@@ -16,6 +24,21 @@ type Dependency struct {
 	Name string `json:"name,omitempty"`
 	// Constraints specifies the semantic version constraints. E.g. >v0.2.0
 	Constraints string `json:"constraints,omitempty"`
+	// Clauses specifies the semantic version constraints in structured form, as an alternative
+	// to Constraints. A version must satisfy all the clauses.
+	Clauses []Constraint `json:"clauses,omitempty"`
+	// Replace is the module that replaces the dependency's module in the build (e.g. a fork)
+	// in the form path@version. E.g. github.com/example/xk6-kubernetes@v0.9.1
+	// The build service may reject replacements.
+	Replace string `json:"replace,omitempty"`
+}
+
+// Constraint defines a semantic version constraint in structured form. E.g. {">=", "v0.2.0"}
+type Constraint struct {
+	// Operator used for comparing the version. One of =, !=, >, <, >=, <=, ~ or ^. Defaults to =
+	Operator string `json:"operator,omitempty"`
+	// Version is the semantic version to compare. E.g. v0.2.0
+	Version string `json:"version,omitempty"`
 }
 
 // Artifact defines the metadata of binary that satisfies a set of dependencies
@@ -29,8 +52,32 @@ type Artifact struct {
 	Dependencies map[string]string `json:"dependencies,omitempty"`
 	// platform
 	Platform string `json:"platform,omitempty"`
-	// binary checksum (sha256)
+	// binary checksum: the hex digest for sha256 or <algorithm>:<hex digest> for other algorithms
 	Checksum string `json:"checksum,omitempty"`
+	// binary size in bytes. Can be 0 if the store doesn't report it
+	Size int64 `json:"size,omitempty"`
+	// Instrumentation flags the binary was built with (e.g. -race). Empty for regular builds.
+	BuildFlags []string `json:"build_flags,omitempty"`
+	// Environment the binary was built with. Can be nil for artifacts built by older versions
+	BuildInfo *BuildInfo `json:"build_info,omitempty"`
+	// URL to fetch the artifact's SBOM (software bill of materials). Only set if the build service generates them
+	SBOMURL string `json:"sbom_url,omitempty"`
+	// Base64 encoded ed25519 signature of the binary's checksum, in the form <algorithm>:<hex digest>.
+	// Only set if the build service signs the artifacts
+	Signature string `json:"signature,omitempty"`
+}
+
+// BuildInfo describes the environment used for building an artifact
+type BuildInfo struct {
+	// version of k6build that built the artifact
+	K6BuildVersion string `json:"k6build_version,omitempty"`
+	// version of k6foundry used for building the artifact
+	K6FoundryVersion string `json:"k6foundry_version,omitempty"`
+	// version of the go toolchain that compiled the artifact
+	GoVersion string `json:"go_version,omitempty"`
+	// versions of all the go modules linked in the artifact, including indirect dependencies.
+	// Only set if the build service is configured to keep them.
+	Modules map[string]string `json:"modules,omitempty"`
 }
 
 // String returns a text serialization of the Artifact
@@ -59,9 +106,30 @@ func (a Artifact) toString(details bool, sep string) string {
 		buffer.WriteString(fmt.Sprintf("%s:%q%s", dep, version, sep))
 	}
 	buffer.WriteString(fmt.Sprintf("checksum: %s%s", a.Checksum, sep))
+	if details && a.Size > 0 {
+		buffer.WriteString(fmt.Sprintf("size: %d%s", a.Size, sep))
+	}
+	if len(a.BuildFlags) > 0 {
+		buffer.WriteString(fmt.Sprintf("build flags: %s%s", strings.Join(a.BuildFlags, " "), sep))
+	}
+	if details && a.BuildInfo != nil {
+		buffer.WriteString(fmt.Sprintf(
+			"built with: k6build %s k6foundry %s %s%s",
+			a.BuildInfo.K6BuildVersion,
+			a.BuildInfo.K6FoundryVersion,
+			a.BuildInfo.GoVersion,
+			sep,
+		))
+	}
 	if details {
 		buffer.WriteString(fmt.Sprintf("url: %s%s", a.URL, sep))
 	}
+	if details && a.Signature != "" {
+		buffer.WriteString(fmt.Sprintf("signature: %s%s", a.Signature, sep))
+	}
+	if details && a.SBOMURL != "" {
+		buffer.WriteString(fmt.Sprintf("sbom: %s%s", a.SBOMURL, sep))
+	}
 	return buffer.String()
 }
 
@@ -74,3 +142,174 @@ type BuildService interface {
 	// cannot be satisfied
 	Resolve(ctx context.Context, k6Constrains string, deps []Dependency) (map[string]string, error)
 }
+
+// BuildPlan describes the artifact that satisfies a set of dependencies, without building it
+type BuildPlan struct {
+	// Id of the artifact that satisfies the dependencies
+	ID string `json:"id,omitempty"`
+	// platform
+	Platform string `json:"platform,omitempty"`
+	// Versions of the dependencies
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	// Go modules that implement the dependencies
+	Modules map[string]string `json:"modules,omitempty"`
+	// Indicates if the artifact is already built
+	Cached bool `json:"cached"`
+}
+
+// Planner defines the interface of build services that can plan a build without building it
+type Planner interface {
+	// Plan returns the BuildPlan for a set dependencies and version constrains.
+	Plan(ctx context.Context, platform string, k6Constrains string, deps []Dependency) (BuildPlan, error)
+}
+
+// DependencyResolution describes how a dependency was resolved
+type DependencyResolution struct {
+	// Name of the dependency
+	Name string `json:"name,omitempty"`
+	// Requested version constrains
+	Constraints string `json:"constraints,omitempty"`
+	// Resolved version
+	Version string `json:"version,omitempty"`
+	// Indicates if the resolved version is the latest available
+	Latest bool `json:"latest"`
+	// Sorted list of the available versions
+	Available []string `json:"available,omitempty"`
+}
+
+// DetailedResolver defines the interface of build services that can describe how dependencies are resolved
+type DetailedResolver interface {
+	// ResolveDetails returns how each dependency is resolved, starting with k6
+	ResolveDetails(ctx context.Context, k6Constrains string, deps []Dependency) ([]DependencyResolution, error)
+}
+
+// DependencyLister defines the interface of build services that can list the dependencies they support
+type DependencyLister interface {
+	// Dependencies returns the sorted list of the names of the supported dependencies
+	Dependencies(ctx context.Context) ([]string, error)
+	// Versions returns the sorted list of the supported versions of a dependency.
+	// Returns ErrUnknownDependency if the dependency is not supported.
+	Versions(ctx context.Context, name string) ([]string, error)
+}
+
+// PlatformLister defines the interface of build services that can list the platforms they support
+type PlatformLister interface {
+	// Platforms returns the list of the supported platforms (e.g. linux/amd64)
+	Platforms(ctx context.Context) ([]string, error)
+}
+
+// CatalogProvider defines the interface of build services that can return the catalog they use
+type CatalogProvider interface {
+	// Catalog returns the content of the catalog as a JSON document
+	Catalog(ctx context.Context) ([]byte, error)
+}
+
+// BuildInfoProvider defines the interface of build services that can return the build info of their artifacts
+type BuildInfoProvider interface {
+	// BuildInfo returns the build info of the artifact with the given id.
+	// Returns ErrUnknownArtifact if the artifact doesn't exist.
+	BuildInfo(ctx context.Context, id string) (BuildInfo, error)
+}
+
+// SBOMProvider defines the interface of build services that can return the SBOM of their artifacts
+type SBOMProvider interface {
+	// SBOM returns the SBOM (software bill of materials) of the artifact with the given id as a CycloneDX
+	// JSON document. Returns ErrUnknownArtifact if the artifact doesn't exist and ErrSBOMNotAvailable if
+	// its SBOM was not generated.
+	SBOM(ctx context.Context, id string) ([]byte, error)
+}
+
+// SignatureProvider defines the interface of build services that can return the signature of their artifacts
+type SignatureProvider interface {
+	// Signature returns the base64 encoded ed25519 signature of the artifact with the given id.
+	// Returns ErrUnknownArtifact if the artifact doesn't exist and ErrArtifactNotSigned if it was not signed.
+	Signature(ctx context.Context, id string) (string, error)
+}
+
+// ArtifactDownloader defines the interface of build services that can return the content of their artifacts
+type ArtifactDownloader interface {
+	// DownloadArtifact returns the content of the artifact with the given id.
+	// Returns ErrUnknownArtifact if the artifact doesn't exist.
+	DownloadArtifact(ctx context.Context, id string) (io.ReadCloser, error)
+}
+
+// BuildOptions defines optional settings for a build request.
+// They are passed to the BuildService in the context using WithBuildOptions.
+// The build service may reject options it does not allow.
+type BuildOptions struct {
+	// Pins maps go modules to the version that must be used in the build, including
+	// indirect dependencies (e.g. google.golang.org/grpc: v1.64.1)
+	Pins map[string]string `json:"pins,omitempty"`
+	// ExtraModules maps go modules that are not k6 extensions (e.g. a custom logger) to the version
+	// that must be added to the build. They are not resolved using the catalog.
+	ExtraModules map[string]string `json:"extra_modules,omitempty"`
+	// GoEnv maps go environment variables to the value used for this build, overriding the build
+	// service's defaults (e.g. GOPROXY: https://proxy.example.com,direct).
+	// The build service only allows overriding a restricted set of variables.
+	GoEnv map[string]string `json:"go_env,omitempty"`
+	// Race builds the binary with the race detector. Requires building for the build service's platform.
+	Race bool `json:"race,omitempty"`
+	// Cover builds the binary with coverage instrumentation
+	Cover bool `json:"cover,omitempty"`
+	// Force building the artifact even if a recent build of it failed
+	Force bool `json:"force,omitempty"`
+	// IncludePrereleases resolves the dependencies considering their prereleases (e.g. v0.3.0-rc1)
+	// even if their constraints don't include one
+	IncludePrereleases bool `json:"include_prereleases,omitempty"`
+}
+
+// BuildFlags returns the go build flags for the instrumentation enabled in the options
+func (o BuildOptions) BuildFlags() []string {
+	var flags []string
+	if o.Race {
+		flags = append(flags, "-race")
+	}
+	if o.Cover {
+		flags = append(flags, "-cover")
+	}
+
+	return flags
+}
+
+type buildOptionsKey struct{}
+
+// WithBuildOptions returns a context that carries the given build options
+func WithBuildOptions(ctx context.Context, opts BuildOptions) context.Context {
+	return context.WithValue(ctx, buildOptionsKey{}, opts)
+}
+
+// BuildOptionsFromContext returns the build options carried by the context, if any
+func BuildOptionsFromContext(ctx context.Context) BuildOptions {
+	opts, _ := ctx.Value(buildOptionsKey{}).(BuildOptions)
+	return opts
+}
+
+type buildSemversKey struct{}
+
+// WithBuildSemversAllowed returns a context that carries the decision of an authorization layer on whether
+// the request can build versions with build metadata and dependencies from a commit.
+// The build service only allows them if its configuration also allows them.
+func WithBuildSemversAllowed(ctx context.Context, allowed bool) context.Context {
+	return context.WithValue(ctx, buildSemversKey{}, allowed)
+}
+
+// BuildSemversAllowed returns the decision on building versions with build metadata carried by the
+// context and if the context carries a decision
+func BuildSemversAllowed(ctx context.Context) (bool, bool) {
+	allowed, found := ctx.Value(buildSemversKey{}).(bool)
+	return allowed, found
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context that carries the id of the request being processed,
+// for correlating the log records of the services that process it
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the id of the request carried by the context, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
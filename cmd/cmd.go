@@ -2,126 +2,102 @@
 package cmd
 
 import (
-	"fmt"
-	"runtime"
-	"runtime/debug"
-	"strings"
+	"encoding/json"
 
 	"github.com/spf13/cobra"
 
+	"github.com/grafana/k6build/cmd/doctor"
 	"github.com/grafana/k6build/cmd/local"
 	"github.com/grafana/k6build/cmd/remote"
 	"github.com/grafana/k6build/cmd/server"
 	"github.com/grafana/k6build/cmd/store"
+	"github.com/grafana/k6build/cmd/verify"
+	"github.com/grafana/k6build/cmd/warmup"
+	"github.com/grafana/k6build/internal/buildinfo"
 )
 
+const long = `
+The default values of the commands' flags can be set in a YAML config file, so they don't have to be
+repeated in each invocation. The config file is read from ` + "`" + DefaultConfigFile + "`" + `
+in the current directory, if it exists, or from the file given with --config.
+Flags given in the command line override the config.
+
+The "defaults" section sets the flags of all the commands that have them, and the section named after
+a command only the flags of that command. Lists and maps set the flag as if it was repeated:
+
+    defaults:
+      catalog: ./catalog.json
+      env:
+        GOPROXY: https://proxy.example.com
+    remote:
+      server: http://build.example.com:8000
+      platform: linux/amd64
+    server:
+      platforms: [linux/amd64, linux/arm64]
+`
+
 // New creates a new root command for k6build
 func New() *cobra.Command {
+	var configFile string
+
 	root := &cobra.Command{
 		Use:               "k6build",
 		Short:             "Build custom k6 binaries with extensions",
+		Long:              long,
 		SilenceUsage:      true,
 		SilenceErrors:     true,
 		DisableAutoGenTag: true,
 		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
-		Version:           fullVersion(),
+		Version:           buildinfo.String(),
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := loadConfig(configFile)
+			if err != nil {
+				return err
+			}
+
+			return cfg.apply(cmd)
+		},
 	}
 
+	root.PersistentFlags().StringVar(
+		&configFile,
+		"config",
+		"",
+		"config file with the default values of the flags (default "+DefaultConfigFile+" if it exists)",
+	)
+
 	root.AddCommand(store.New())
 	root.AddCommand(remote.New())
 	root.AddCommand(local.New())
 	root.AddCommand(server.New())
+	root.AddCommand(verify.New())
+	root.AddCommand(doctor.New())
+	root.AddCommand(warmup.New())
 	root.AddCommand(newVersionCommand())
 
 	return root
 }
 
 func newVersionCommand() *cobra.Command {
-	return &cobra.Command{
+	var jsonOut bool
+
+	cmd := &cobra.Command{
 		Use:           "version",
 		Short:         "k6build version",
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			if jsonOut {
+				return json.NewEncoder(cmd.OutOrStdout()).Encode(buildinfo.Version())
+			}
+
 			root := cmd.Root()
 			root.SetArgs([]string{"--version"})
 			_ = root.Execute()
 			return nil
 		},
 	}
-}
-
-const (
-	commitKey      = "commit"
-	commitDirtyKey = "commit_dirty"
-)
-
-// fullVersion returns the maximally full version and build information for
-// the currently running k6build executable.
-func fullVersion() string {
-	details := versionDetails()
-
-	goVersionArch := fmt.Sprintf("%s, %s/%s", details["go_version"], details["go_os"], details["go_arch"])
-
-	k6buildversion := fmt.Sprintf("%s", details["version"])
-	// for the fallback case when the version is not in the expected format
-	// cobra adds a "v" prefix to the version
-	k6buildversion = strings.TrimLeft(k6buildversion, "v")
-
-	commit, ok := details[commitKey].(string)
-	if !ok || commit == "" {
-		return fmt.Sprintf("%s (%s)", k6buildversion, goVersionArch)
-	}
-
-	isDirty, ok := details[commitDirtyKey].(bool)
-	if ok && isDirty {
-		commit += "-dirty"
-	}
-
-	return fmt.Sprintf("%s (commit/%s, %s)", k6buildversion, commit, goVersionArch)
-}
 
-// versionDetails returns the structured details about version
-func versionDetails() map[string]any {
-	v := "unreleased"
-
-	details := map[string]any{
-		"version":    v,
-		"go_version": runtime.Version(),
-		"go_os":      runtime.GOOS,
-		"go_arch":    runtime.GOARCH,
-	}
-
-	buildInfo, ok := debug.ReadBuildInfo()
-	if !ok {
-		return details
-	}
-	details["version"] = buildInfo.Main.Version
-
-	var (
-		commit string
-		dirty  bool
-	)
-	for _, s := range buildInfo.Settings {
-		switch s.Key {
-		case "vcs.revision":
-			commitLen := min(len(s.Value), 10)
-			commit = s.Value[:commitLen]
-		case "vcs.modified":
-			if s.Value == "true" {
-				dirty = true
-			}
-		default:
-		}
-	}
-
-	if commit == "" {
-		return details
-	}
-
-	details[commitKey] = commit
-	if dirty {
-		details[commitDirtyKey] = true
-	}
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "print the version information as JSON")
 
-	return details
+	return cmd
 }
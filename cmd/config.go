@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the config file used if it exists and no other file is given with --config
+const DefaultConfigFile = ".k6build.yaml"
+
+// defaultsSection is the section of the config file with the flags' values for all the commands
+const defaultsSection = "defaults"
+
+// ErrInvalidConfig is returned when the config file cannot be read or sets an unknown flag
+var ErrInvalidConfig = errors.New("invalid config")
+
+// config defines the values of the commands' flags by section. The "defaults" section applies to all the
+// commands that have the flag, and the section named after a command only to that command.
+type config map[string]map[string]any
+
+// loadConfig reads the config file. If the file is not given, the DefaultConfigFile is read if it exists.
+func loadConfig(file string) (config, error) {
+	explicit := file != ""
+	if !explicit {
+		file = DefaultConfigFile
+	}
+
+	content, err := os.ReadFile(file) //nolint:gosec
+	if !explicit && errors.Is(err, os.ErrNotExist) {
+		return config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+	}
+
+	cfg := config{}
+	if err = yaml.Unmarshal(content, &cfg); err != nil {
+		return nil, fmt.Errorf("%w: %s %w", ErrInvalidConfig, file, err)
+	}
+
+	return cfg, nil
+}
+
+// apply sets the flags of the command that were not given in the command line to the values in the config.
+// The values of the command's section take precedence over the defaults. Fails if the command's section sets
+// a flag the command doesn't have.
+func (c config) apply(cmd *cobra.Command) error {
+	values := map[string]any{}
+	for name, value := range c[defaultsSection] {
+		if cmd.Flags().Lookup(name) != nil {
+			values[name] = value
+		}
+	}
+
+	for name, value := range c[cmd.Name()] {
+		if cmd.Flags().Lookup(name) == nil {
+			return fmt.Errorf("%w: unknown flag %q for command %q", ErrInvalidConfig, name, cmd.Name())
+		}
+		values[name] = value
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		flag := cmd.Flags().Lookup(name)
+		if flag.Changed {
+			continue
+		}
+
+		if err := setFlag(cmd.Flags(), flag, values[name]); err != nil {
+			return fmt.Errorf("%w: flag %q %w", ErrInvalidConfig, name, err)
+		}
+	}
+
+	return nil
+}
+
+// setFlag sets the value of the flag. Lists set each element and maps each key=value pair,
+// as if the flag was repeated in the command line.
+func setFlag(flags *pflag.FlagSet, flag *pflag.Flag, value any) error {
+	switch v := value.(type) {
+	case []any:
+		for _, e := range v {
+			if err := flags.Set(flag.Name, fmt.Sprint(e)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]any:
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			if err := flags.Set(flag.Name, fmt.Sprintf("%s=%v", k, v[k])); err != nil {
+				return err
+			}
+		}
+		return nil
+	case nil:
+		return nil
+	default:
+		return flags.Set(flag.Name, fmt.Sprint(v))
+	}
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/cobra"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.yaml")
+	if err := os.WriteFile(valid, []byte("remote:\n  server: http://localhost:9000\n"), 0o600); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+	invalid := filepath.Join(dir, "invalid.yaml")
+	if err := os.WriteFile(invalid, []byte("remote: ["), 0o600); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		file      string
+		expect    config
+		expectErr error
+	}{
+		{
+			title:  "valid config",
+			file:   valid,
+			expect: config{"remote": {"server": "http://localhost:9000"}},
+		},
+		{
+			title:  "default config does not exist",
+			file:   "",
+			expect: config{},
+		},
+		{
+			title:     "config does not exist",
+			file:      filepath.Join(dir, "missing.yaml"),
+			expectErr: ErrInvalidConfig,
+		},
+		{
+			title:     "invalid config",
+			file:      invalid,
+			expectErr: ErrInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := loadConfig(tc.file)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if diff := cmp.Diff(tc.expect, cfg); tc.expectErr == nil && diff != "" {
+				t.Fatalf("config doesn't match: %s", diff)
+			}
+		})
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		cfg       config
+		args      []string
+		expect    map[string]string
+		expectEnv map[string]string
+		expectErr error
+	}{
+		{
+			title: "defaults",
+			cfg:   config{"defaults": {"catalog": "catalog.json", "other": "ignored"}},
+			expect: map[string]string{
+				"catalog": "catalog.json",
+				"server":  "http://localhost:8000",
+			},
+		},
+		{
+			title: "command overrides defaults",
+			cfg: config{
+				"defaults": {"catalog": "catalog.json", "server": "http://default:8000"},
+				"test":     {"server": "http://test:8000"},
+			},
+			expect: map[string]string{
+				"catalog": "catalog.json",
+				"server":  "http://test:8000",
+			},
+		},
+		{
+			title: "command line overrides config",
+			cfg:   config{"test": {"server": "http://test:8000"}},
+			args:  []string{"--server", "http://args:8000"},
+			expect: map[string]string{
+				"server": "http://args:8000",
+			},
+		},
+		{
+			title: "lists and maps",
+			cfg: config{"test": {
+				"platforms": []any{"linux/amd64", "linux/arm64"},
+				"env":       map[string]any{"GOPROXY": "direct", "CGO_ENABLED": 0},
+			}},
+			expect: map[string]string{
+				"platforms": "[linux/amd64,linux/arm64]",
+			},
+			// the text form of a map flag is not sorted
+			expectEnv: map[string]string{"GOPROXY": "direct", "CGO_ENABLED": "0"},
+		},
+		{
+			title: "other command",
+			cfg:   config{"other": {"unknown": "value"}},
+			expect: map[string]string{
+				"server": "http://localhost:8000",
+			},
+		},
+		{
+			title:     "unknown flag",
+			cfg:       config{"test": {"unknown": "value"}},
+			expectErr: ErrInvalidConfig,
+		},
+		{
+			title:     "invalid value",
+			cfg:       config{"test": {"verbose": "maybe"}},
+			expectErr: ErrInvalidConfig,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			cmd := &cobra.Command{Use: "test"}
+			cmd.Flags().String("server", "http://localhost:8000", "")
+			cmd.Flags().String("catalog", "", "")
+			cmd.Flags().Bool("verbose", false, "")
+			cmd.Flags().StringSlice("platforms", nil, "")
+			cmd.Flags().StringToString("env", nil, "")
+
+			if err := cmd.Flags().Parse(tc.args); err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+
+			err := tc.cfg.apply(cmd)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			for name, expect := range tc.expect {
+				if value := cmd.Flags().Lookup(name).Value.String(); value != expect {
+					t.Fatalf("expected %s=%q got %q", name, expect, value)
+				}
+			}
+
+			if tc.expectEnv != nil {
+				env, _ := cmd.Flags().GetStringToString("env")
+				if diff := cmp.Diff(tc.expectEnv, env); diff != "" {
+					t.Fatalf("env doesn't match: %s", diff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package doctor implements the doctor command
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/internal/buildinfo"
+	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/grafana/k6build/pkg/local"
+	"github.com/grafana/k6build/pkg/store"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrChecksFailed is returned when any of the checks failed
+var ErrChecksFailed = errors.New("checks failed")
+
+const (
+	long = `
+k6build doctor checks the environment used for building custom k6 binaries locally is correctly
+configured and prints a report with the result of each check:
+
+  go:      the go toolchain is installed and reports its version
+  catalog: the catalog can be loaded and resolves k6
+  store:   an object can be stored and read back from the store. The object is deleted afterwards
+           if the store supports it
+  build:   k6 without extensions builds for the host platform. The build uses a temporary store,
+           so it is not satisfied by an artifact already built
+
+The catalog and store are configured with the same flags used by the local command.
+Exits with a non-zero status if any check failed, so it can be used as a preflight step in CI.
+`
+
+	example = `
+# check the default configuration
+k6build doctor
+
+[PASS] go: go1.24.1
+[PASS] catalog: 42 dependencies, k6 v1.0.0
+[PASS] store: stored and read k6build-doctor-1718291234000000000
+[PASS] build: k6 v1.0.0 for linux/amd64 in 38s
+
+# check a shared store server without building
+k6build doctor --store-url http://store.example.com:9000 --skip-build
+`
+)
+
+// check is a named verification that returns a description of its result
+type check struct {
+	name string
+	run  func(ctx context.Context) (string, error)
+}
+
+// New creates new cobra command for the doctor command.
+func New() *cobra.Command {
+	var (
+		config    local.Config
+		k6        string
+		skipBuild bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "doctor",
+		Short:   "check the environment for building custom k6 binaries",
+		Long:    long,
+		Example: example,
+		// prevent the usage help to printed to stderr when an error is reported by a subcommand
+		SilenceUsage: true,
+		// this is needed to prevent cobra to print errors reported by subcommands in the stderr
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			config.Version = buildinfo.Version().Version
+
+			checks := []check{
+				{name: "go", run: goCheck(config.Env)},
+				{name: "catalog", run: catalogCheck(config.Catalog)},
+				{name: "store", run: storeCheck(config)},
+			}
+			if !skipBuild {
+				checks = append(checks, check{name: "build", run: buildCheck(config, k6)})
+			}
+
+			return runChecks(cmd.Context(), checks, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&config.Catalog, "catalog", "c", catalog.DefaultCatalogURL, "dependencies catalog")
+	cmd.Flags().StringVarP(&config.StoreDir, "store-dir", "f", "/tmp/k6build/store", "object store dir")
+	cmd.Flags().StringVar(
+		&config.StoreURL,
+		"store-url",
+		"",
+		"url of a store server for storing the artifacts instead of the --store-dir",
+	)
+	cmd.Flags().StringVar(
+		&config.StoreCacheDir,
+		"store-cache-dir",
+		"",
+		"dir for caching the artifacts obtained from the store server. Requires --store-url",
+	)
+	cmd.Flags().BoolVarP(&config.CopyGoEnv, "copy-go-env", "g", true, "copy go environment")
+	cmd.Flags().StringToStringVarP(&config.Opts.Env, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringVar(
+		&config.CacheDir,
+		"cache-dir",
+		"",
+		"directory for the go module and build caches. Caches are namespaced by go version.",
+	)
+	cmd.Flags().StringVarP(&k6, "k6", "k", "*", "k6 version constrains for the test build")
+	cmd.Flags().BoolVar(&skipBuild, "skip-build", false, "don't check building k6")
+
+	return cmd
+}
+
+// runChecks runs the checks in order, printing the result of each one.
+// Returns ErrChecksFailed if any check failed.
+func runChecks(ctx context.Context, checks []check, out io.Writer) error {
+	failed := []string{}
+	for _, c := range checks {
+		result, err := c.run(ctx)
+		if err != nil {
+			failed = append(failed, c.name)
+			fmt.Fprintf(out, "[FAIL] %s: %v\n", c.name, err)
+			continue
+		}
+		fmt.Fprintf(out, "[PASS] %s: %s\n", c.name, result)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", ErrChecksFailed, strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// goCheck checks the go toolchain is installed and returns its version
+func goCheck(env map[string]string) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		path, err := exec.LookPath("go")
+		if err != nil {
+			return "", fmt.Errorf("go toolchain not found %w", err)
+		}
+
+		cmd := exec.CommandContext(ctx, path, "env", "GOVERSION")
+		cmd.Env = os.Environ()
+		for k, v := range env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+
+		out, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("getting go version %w", err)
+		}
+
+		return strings.TrimSpace(string(out)), nil
+	}
+}
+
+// catalogCheck checks the catalog can be loaded and resolves k6
+func catalogCheck(location string) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		c, err := catalog.NewCatalog(ctx, location)
+		if err != nil {
+			return "", err
+		}
+
+		deps, err := c.Dependencies(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		k6, err := c.Resolve(ctx, catalog.Dependency{Name: "k6", Constrains: "*"})
+		if err != nil {
+			return "", fmt.Errorf("resolving k6 %w", err)
+		}
+
+		return fmt.Sprintf("%d dependencies, k6 %s", len(deps), k6.Version), nil
+	}
+}
+
+// storeCheck checks an object can be stored and read back from the store
+func storeCheck(config local.Config) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		objectStore, err := local.NewStore(config)
+		if err != nil {
+			return "", err
+		}
+
+		return checkStore(ctx, objectStore)
+	}
+}
+
+// checkStore stores a sentinel object, reads it back and deletes it if the store supports it
+func checkStore(ctx context.Context, objectStore store.ObjectStore) (string, error) {
+	id := fmt.Sprintf("k6build-doctor-%d", time.Now().UnixNano())
+	content := []byte("k6build doctor")
+
+	if _, err := objectStore.Put(ctx, id, bytes.NewReader(content)); err != nil {
+		return "", fmt.Errorf("storing object %w", err)
+	}
+
+	object, err := objectStore.Get(ctx, id)
+	if err != nil {
+		return "", fmt.Errorf("getting object %w", err)
+	}
+
+	if downloader, ok := objectStore.(store.ObjectDownloader); ok {
+		reader, err := downloader.Download(ctx, object)
+		if err != nil {
+			return "", fmt.Errorf("downloading object %w", err)
+		}
+		defer reader.Close() //nolint:errcheck
+
+		read, err := io.ReadAll(reader)
+		if err != nil {
+			return "", fmt.Errorf("downloading object %w", err)
+		}
+
+		if !bytes.Equal(read, content) {
+			return "", fmt.Errorf("object %s content doesn't match", id)
+		}
+	}
+
+	deleter, ok := objectStore.(store.ObjectDeleter)
+	if !ok {
+		return fmt.Sprintf("stored and read %s (not deleted, the store doesn't support it)", id), nil
+	}
+
+	if err = deleter.Delete(ctx, id); err != nil {
+		return "", fmt.Errorf("deleting object %w", err)
+	}
+
+	return "stored and read " + id, nil
+}
+
+// buildCheck checks k6 without extensions builds for the host platform using a temporary store
+func buildCheck(config local.Config, k6 string) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		storeDir, err := os.MkdirTemp("", "k6build-doctor-*")
+		if err != nil {
+			return "", fmt.Errorf("creating store dir %w", err)
+		}
+		defer os.RemoveAll(storeDir) //nolint:errcheck
+
+		config.StoreDir = storeDir
+		config.StoreURL = ""
+		config.StoreCacheDir = ""
+
+		srv, err := local.NewBuildService(ctx, config)
+		if err != nil {
+			return "", err
+		}
+
+		platform := runtime.GOOS + "/" + runtime.GOARCH
+		start := time.Now()
+		artifact, err := srv.Build(ctx, platform, k6, []k6build.Dependency{})
+		if err != nil {
+			return "", err
+		}
+
+		elapsed := time.Since(start).Round(time.Second)
+
+		return fmt.Sprintf("k6 %s for %s in %s", artifact.Dependencies["k6"], platform, elapsed), nil
+	}
+}
@@ -0,0 +1,101 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/store/file"
+	"github.com/grafana/k6build/pkg/store/memory"
+)
+
+func TestRunChecks(t *testing.T) {
+	t.Parallel()
+
+	pass := func(context.Context) (string, error) { return "ok", nil }
+	fail := func(context.Context) (string, error) { return "", errors.New("broken") }
+
+	testCases := []struct {
+		title     string
+		checks    []check
+		expect    string
+		expectErr error
+	}{
+		{
+			title:  "all pass",
+			checks: []check{{name: "a", run: pass}, {name: "b", run: pass}},
+			expect: "[PASS] a: ok\n[PASS] b: ok\n",
+		},
+		{
+			title:     "one fails",
+			checks:    []check{{name: "a", run: fail}, {name: "b", run: pass}},
+			expect:    "[FAIL] a: broken\n[PASS] b: ok\n",
+			expectErr: ErrChecksFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			out := &bytes.Buffer{}
+			err := runChecks(context.TODO(), tc.checks, out)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if out.String() != tc.expect {
+				t.Fatalf("expected report %q got %q", tc.expect, out.String())
+			}
+		})
+	}
+}
+
+func TestCheckStore(t *testing.T) {
+	t.Parallel()
+
+	fileStore, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	result, err := checkStore(context.TODO(), fileStore)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	// the sentinel object is deleted
+	objects, err := fileStore.(store.ObjectLister).List(context.TODO())
+	if err != nil {
+		t.Fatalf("listing objects %v", err)
+	}
+	if len(objects) != 0 {
+		t.Fatalf("expected sentinel object deleted, got %v (%s)", objects, result)
+	}
+
+	if _, err = checkStore(context.TODO(), memory.NewMemoryStore()); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+}
+
+func TestCatalogCheck(t *testing.T) {
+	t.Parallel()
+
+	result, err := catalogCheck(filepath.Join("..", "..", "pkg", "builder", "testdata", "catalog.json"))(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if !strings.HasPrefix(result, "3 dependencies") {
+		t.Fatalf("unexpected result %q", result)
+	}
+
+	_, err = catalogCheck(filepath.Join(t.TempDir(), "missing.json"))(context.TODO())
+	if err == nil {
+		t.Fatalf("expected error for missing catalog")
+	}
+}
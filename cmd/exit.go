@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/api"
+	"github.com/grafana/k6build/pkg/builder"
+	"github.com/grafana/k6build/pkg/catalog"
+)
+
+// Exit codes of the commands, so scripts can tell apart the cause of a failure
+const (
+	// ExitFailure signals the command failed
+	ExitFailure = 1
+	// ExitCannotSatisfy signals the dependencies are unknown or their constrains cannot be satisfied
+	ExitCannotSatisfy = 2
+	// ExitBuildFailed signals the binary failed to build
+	ExitBuildFailed = 3
+	// ExitRequestFailed signals a request to the build server failed
+	ExitRequestFailed = 4
+)
+
+// ExitCode returns the exit code for the error returned by a command
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	switch k6build.ErrorCodeOf(err) {
+	case k6build.ErrorCodeCannotSatisfy, k6build.ErrorCodeUnknownDependency:
+		return ExitCannotSatisfy
+	case k6build.ErrorCodeBuildFailed, k6build.ErrorCodeDownloadFailed:
+		return ExitBuildFailed
+	case k6build.ErrorCodeTooManyBuilds:
+		return ExitRequestFailed
+	}
+
+	switch {
+	case errors.Is(err, catalog.ErrCannotSatisfy), errors.Is(err, api.ErrCannotSatisfy),
+		errors.Is(err, k6build.ErrUnknownDependency):
+		return ExitCannotSatisfy
+	case errors.Is(err, builder.ErrBuildingArtifact), errors.Is(err, api.ErrBuildFailed):
+		return ExitBuildFailed
+	case errors.Is(err, api.ErrRequestFailed), errors.Is(err, api.ErrUnauthorized),
+		errors.Is(err, api.ErrTooManyBuilds):
+		return ExitRequestFailed
+	default:
+		return ExitFailure
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/api"
+	"github.com/grafana/k6build/pkg/builder"
+	"github.com/grafana/k6build/pkg/catalog"
+)
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		err    error
+		expect int
+	}{
+		{
+			title:  "no error",
+			err:    nil,
+			expect: 0,
+		},
+		{
+			title:  "other error",
+			err:    errors.New("other"),
+			expect: ExitFailure,
+		},
+		{
+			title: "unsatisfied constrains",
+			err: fmt.Errorf("building %w", k6build.NewWrappedError(
+				builder.ErrInvalidParameters,
+				fmt.Errorf("%w: k6/x/ext", catalog.ErrCannotSatisfy),
+			)),
+			expect: ExitCannotSatisfy,
+		},
+		{
+			title: "build failed with code",
+			err: k6build.NewWrappedError(
+				api.ErrRequestFailed,
+				k6build.NewCodedError(k6build.ErrorCodeBuildFailed, api.ErrBuildFailed, nil),
+			),
+			expect: ExitBuildFailed,
+		},
+		{
+			title:  "build failed",
+			err:    fmt.Errorf("building %w", k6build.NewWrappedError(builder.ErrBuildingArtifact, nil)),
+			expect: ExitBuildFailed,
+		},
+		{
+			title:  "request failed",
+			err:    fmt.Errorf("building %w", k6build.NewWrappedError(api.ErrRequestFailed, errors.New("refused"))),
+			expect: ExitRequestFailed,
+		},
+		{
+			title:  "unauthorized",
+			err:    k6build.NewWrappedError(api.ErrUnauthorized, errors.New("401 Unauthorized")),
+			expect: ExitRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if code := ExitCode(tc.err); code != tc.expect {
+				t.Fatalf("expected %d got %d", tc.expect, code)
+			}
+		})
+	}
+}
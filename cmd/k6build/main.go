@@ -13,7 +13,7 @@ func main() {
 
 	err := root.Execute()
 	if err != nil {
-		fmt.Printf("%s\n", err.Error())
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		os.Exit(cmd.ExitCode(err))
 	}
 }
@@ -2,6 +2,8 @@
 package local
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
@@ -9,8 +11,12 @@ import (
 	"strings"
 
 	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/internal/buildinfo"
+	"github.com/grafana/k6build/pkg/api"
 	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/grafana/k6build/pkg/client"
 	"github.com/grafana/k6build/pkg/local"
+	"github.com/grafana/k6build/pkg/util"
 
 	"github.com/spf13/cobra"
 )
@@ -19,6 +25,22 @@ const (
 	long = `
 k6build local builder creates a custom k6 binary artifacts that satisfies certain
 dependencies. Requires the golang toolchain and git.
+
+The binary is built for the host's platform unless --platform is given. Multiple platforms can be
+built by separating them with commas, or using "all" for the platforms in --all-platforms. When building
+multiple platforms, the binaries are named after the --output, suffixed with the platform (e.g. k6-linux-amd64).
+
+Using --resolve-only, the dependencies are resolved but the binary is not built. The versions
+that satisfy the dependencies are printed as JSON. This gives fast feedback when validating the
+constrains (e.g. in a pre-commit hook).
+
+Using --store-url, the artifacts are stored in a store server shared with other builders instead
+of the --store-dir, so an artifact built by any of them is not built again. Using --store-cache-dir,
+the artifacts obtained from the store server are cached locally, and following builds that resolve
+to the same artifact don't access the network. The copies are verified using the artifact's checksum.
+
+The exit code reflects the cause of a failure: 2 if the dependencies are unknown or their
+constrains cannot be satisfied, 3 if the build failed, and 1 for other errors.
 `
 
 	example = `
@@ -46,8 +68,21 @@ checksum: f4af178bb2e29862c0fc7d481076c9ba4468572903480fe9d6c999fea75f3793
 k6build local -k v0.50.0 -d k6/x/kubernetes \
     -c /path/to/catalog.json -q
 
+# resolve the versions of k6 v0.51.0 and k6/x/kubernetes without building the binary
+k6build local -k v0.51.0 -d k6/x/kubernetes:">v0.8.0" -p linux/amd64 --resolve-only
+
+{"dependencies":{"k6":"v0.51.0","k6/x/kubernetes":"v0.9.0"}}
+
+# build k6 v0.51.0 for linux/amd64 and darwin/arm64 as 'build/k6-linux-amd64' and 'build/k6-darwin-arm64'
+k6build local -k v0.51.0 -p linux/amd64,darwin/arm64 -o build/k6 -q
+
 # build k6 v0.50.0 using a custom GOPROXY
 k6build local -k v0.50.0 -e GOPROXY=http://localhost:80 -q
+
+# build k6 v0.51.0 using a shared store server, caching the artifacts locally
+k6build local -k v0.51.0 -d k6/x/kubernetes \
+    --store-url http://store.example.com:9000 \
+    --store-cache-dir ~/.cache/k6build/store -q
 `
 )
 
@@ -59,7 +94,14 @@ func New() *cobra.Command { //nolint:funlen
 		k6       string
 		output   string
 		platform string
+		all      []string
 		quiet    bool
+		pins     map[string]string
+		modules  map[string]string
+		race     bool
+		cover    bool
+		jsonOut  bool
+		resolve  bool
 	)
 
 	cmd := &cobra.Command{
@@ -72,6 +114,11 @@ func New() *cobra.Command { //nolint:funlen
 		// this is needed to prevent cobra to print errors reported by subcommands in the stderr
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, _ []string) error {
+			// pins and extra modules are allowed in local builds
+			config.AllowModulePins = true
+			config.AllowExtraModules = true
+
+			config.Version = buildinfo.Version().Version
 			srv, err := local.NewBuildService(cmd.Context(), config)
 			if err != nil {
 				return fmt.Errorf("configuring the build service %w", err)
@@ -86,35 +133,40 @@ func New() *cobra.Command { //nolint:funlen
 				buildDeps = append(buildDeps, k6build.Dependency{Name: name, Constraints: constrains})
 			}
 
-			artifact, err := srv.Build(cmd.Context(), platform, k6, buildDeps)
-			if err != nil {
-				return fmt.Errorf("building %w", err)
-			}
+			ctx := k6build.WithBuildOptions(cmd.Context(), k6build.BuildOptions{
+				Pins:         pins,
+				ExtraModules: modules,
+				Race:         race,
+				Cover:        cover,
+			})
 
-			if !quiet {
-				fmt.Println(artifact.PrintSummary())
+			if resolve {
+				return resolveDependencies(ctx, srv, k6, buildDeps)
 			}
 
-			binaryURL, err := url.Parse(artifact.URL)
-			if err != nil {
-				return fmt.Errorf("malformed URL %w", err)
-			}
-			artifactBinary, err := os.Open(binaryURL.Path)
-			if err != nil {
-				return fmt.Errorf("opening output file %w", err)
-			}
-			defer func() {
-				_ = artifactBinary.Close()
-			}()
+			platforms := client.ExpandPlatforms(cmd.Context(), srv, platform, all)
+			for _, p := range platforms {
+				artifact, err := srv.Build(ctx, p, k6, buildDeps)
+				if jsonOut {
+					_ = json.NewEncoder(os.Stdout).Encode(api.NewBuildResponse(artifact, err))
+				}
+				if err != nil {
+					return fmt.Errorf("building %w", err)
+				}
 
-			binary, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE, 0o755) //nolint:gosec
-			if err != nil {
-				return fmt.Errorf("opening output file %w", err)
-			}
+				if !quiet && !jsonOut {
+					fmt.Println(artifact.PrintSummary())
+				}
 
-			_, err = io.Copy(binary, artifactBinary)
-			if err != nil {
-				return fmt.Errorf("copying artifact %w", err)
+				// when building multiple platforms, the output is suffixed with the platform
+				outputPath := output
+				if len(platforms) > 1 {
+					outputPath = output + "-" + strings.ReplaceAll(p, "/", "-")
+				}
+
+				if err = copyArtifact(cmd.Context(), artifact, outputPath, quiet); err != nil {
+					return err
+				}
 			}
 
 			return nil
@@ -123,20 +175,139 @@ func New() *cobra.Command { //nolint:funlen
 
 	cmd.Flags().StringArrayVarP(&deps, "dependency", "d", nil, "list of dependencies in form package:constrains")
 	cmd.Flags().StringVarP(&k6, "k6", "k", "*", "k6 version constrains")
-	cmd.Flags().StringVarP(&platform, "platform", "p", "", "target platform (default GOOS/GOARCH)")
-	_ = cmd.MarkFlagRequired("platform")
+	cmd.Flags().StringVarP(
+		&platform,
+		"platform",
+		"p",
+		"",
+		"target platforms, separated by commas (default GOOS/GOARCH)."+
+			"\nUse \"all\" for building the platforms in --all-platforms.",
+	)
+	cmd.Flags().StringSliceVar(&all, "all-platforms", api.DefaultPlatforms, "platforms built for \"all\"")
 	cmd.Flags().StringVarP(&config.Catalog, "catalog", "c", catalog.DefaultCatalogURL, "dependencies catalog")
 	cmd.Flags().StringVarP(&config.StoreDir, "store-dir", "f", "/tmp/k6build/store", "object store dir")
+	cmd.Flags().StringVar(
+		&config.StoreURL,
+		"store-url",
+		"",
+		"url of a store server for storing the artifacts instead of the --store-dir",
+	)
+	cmd.Flags().StringVar(
+		&config.StoreCacheDir,
+		"store-cache-dir",
+		"",
+		"dir for caching the artifacts obtained from the store server. Requires --store-url",
+	)
 	cmd.Flags().BoolVarP(&config.Opts.Verbose, "verbose", "v", false, "print build process output")
 	cmd.Flags().BoolVarP(&config.CopyGoEnv, "copy-go-env", "g", true, "copy go environment")
 	cmd.Flags().StringToStringVarP(&config.Opts.Env, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringVar(
+		&config.CacheDir,
+		"cache-dir",
+		"",
+		"directory for the go module and build caches. Caches are namespaced by go version.",
+	)
 	cmd.Flags().StringVarP(&output, "output", "o", "k6", "path to put the binary as an executable.")
-	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "don't print artifact's details")
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "don't print artifact's details or copy progress")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "print the artifact's details, or the error if the build fails, as JSON")
+	cmd.Flags().BoolVar(
+		&resolve,
+		"resolve-only",
+		false,
+		"resolve the dependencies without building the binary and print their versions as JSON",
+	)
+	cmd.Flags().StringToStringVar(&pins, "pin", nil, "pin the version of a go module (e.g. google.golang.org/grpc=v1.64.1)")
+	cmd.Flags().StringToStringVar(
+		&modules,
+		"extra-module",
+		nil,
+		"add a go module that is not an extension to the build (e.g. github.com/example/logger=v0.1.0)",
+	)
+	cmd.Flags().BoolVar(&race, "race", false, "build with the race detector. Requires building for the native platform")
+	cmd.Flags().BoolVar(&cover, "cover", false, "build with coverage instrumentation")
+	cmd.Flags().BoolVar(
+		&config.IncludePrereleases,
+		"include-prereleases",
+		false,
+		"resolve the dependencies considering their prereleases (e.g. v0.3.0-rc1)",
+	)
 	cmd.Flags().BoolVar(
 		&config.AllowBuildSemvers,
 		"allow-build-semvers",
 		false,
-		"allow building versions with build metadata (e.g v0.0.0+build).",
+		"allow building versions with build metadata (e.g v0.0.0+build)"+
+			"\nand dependencies from a commit (e.g. k6/x/kubernetes:commit:0123abc).",
 	)
 	return cmd
 }
+
+// resolveDependencies prints the versions that satisfy the dependencies as JSON, or the error if they
+// cannot be resolved
+func resolveDependencies(ctx context.Context, srv k6build.BuildService, k6 string, deps []k6build.Dependency) error {
+	resolved, err := srv.Resolve(ctx, k6, deps)
+
+	resp := api.ResolveResponse{Dependencies: resolved}
+	if err != nil {
+		wrapped, ok := k6build.AsError(err)
+		if !ok {
+			wrapped = k6build.NewWrappedError(api.ErrResolveFailed, err)
+		}
+		resp.Error = wrapped
+	}
+
+	_ = json.NewEncoder(os.Stdout).Encode(resp)
+
+	if err != nil {
+		return fmt.Errorf("resolving %w", err)
+	}
+
+	return nil
+}
+
+// copyArtifact copies the artifact's binary to the output as an executable. Artifacts in a store server
+// that are not cached locally are downloaded.
+func copyArtifact(ctx context.Context, artifact k6build.Artifact, output string, quiet bool) error {
+	binaryURL, err := url.Parse(artifact.URL)
+	if err != nil {
+		return fmt.Errorf("malformed URL %w", err)
+	}
+
+	if binaryURL.Scheme == "http" || binaryURL.Scheme == "https" {
+		var progress util.ProgressFunc
+		if !quiet {
+			progress = util.ProgressPrinter(os.Stderr)
+		}
+
+		err = util.DownloadWithProgress(ctx, artifact.URL, output, artifact.Checksum, progress)
+		if err != nil {
+			return fmt.Errorf("downloading artifact %w", err)
+		}
+
+		return nil
+	}
+
+	artifactBinary, err := os.Open(binaryURL.Path)
+	if err != nil {
+		return fmt.Errorf("opening output file %w", err)
+	}
+	defer func() {
+		_ = artifactBinary.Close()
+	}()
+
+	info, err := artifactBinary.Stat()
+	if err != nil {
+		return fmt.Errorf("accessing artifact %w", err)
+	}
+
+	var content io.Reader = artifactBinary
+	if !quiet {
+		content = util.NewProgressReader(artifactBinary, info.Size(), util.ProgressPrinter(os.Stderr))
+	}
+
+	err = util.WriteExecutable(output, content, artifact.Checksum)
+	if err != nil {
+		return fmt.Errorf("copying artifact %w", err)
+	}
+
+	return nil
+}
@@ -2,11 +2,17 @@
 package remote
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/api"
 	"github.com/grafana/k6build/pkg/client"
+	"github.com/grafana/k6build/pkg/signing"
+	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/util"
 
 	"github.com/spf13/cobra"
@@ -16,6 +22,13 @@ const (
 	long = `
 Builds custom k6 binaries using a k6build server returning the details of the
 binary artifact and optionally download it.
+
+The downloaded binary is verified using the artifact's checksum. If the server signs the artifacts,
+--verify-signature also verifies the binary's signature using the server's public key.
+
+The exit code reflects the cause of a failure: 2 if the dependencies are unknown or their
+constrains cannot be satisfied, 3 if the build failed, 4 if the request to the build server
+failed, and 1 for other errors.
 `
 
 	example = `
@@ -26,13 +39,13 @@ k6build remote -s http://localhost:8000 \
     -d k6/x/kubernetes:v0.8.0 \
     -d k6/x/output-kafka:v0.7.0
 
-id: 62d08b13fdef171435e2c6874eaad0bb35f2f9c7
+id: c0d22131dd80c88bf1ca4c5688b04e70c13ad7aa6ceac01cef65f839c65ea721
 platform: linux/amd64
 k6: v0.51.0
 k6/x/kubernetes: v0.9.0
 k6/x/output-kafka": v0.7.0
 checksum: f4af178bb2e29862c0fc7d481076c9ba4468572903480fe9d6c999fea75f3793
-url: http://localhost:8000/store/62d08b13fdef171435e2c6874eaad0bb35f2f9c7/download
+url: http://localhost:8000/store/c0d22131dd80c88bf1ca4c5688b04e70c13ad7aa6ceac01cef65f839c65ea721/download
 
 
 # build k6 v0.51 with k6/x/output-kafka v0.7.0 and download as 'build/k6'
@@ -46,18 +59,41 @@ build/k6 version
 k6 v0.51.0 (go1.22.2, linux/amd64)
 Extensions:
   github.com/grafana/xk6-output-kafka v0.7.0, xk6-kafka [output]
+
+# build k6 v0.51 for all the platforms supported by the server and download them as 'build/k6-<os>-<arch>'
+k6build remote -s http://localhost:8000 \
+    -p all \
+    -k v0.51.0 \
+    -o build/k6 -q
+
+# build k6 v0.51 and download as 'build/k6' only if signed with the server's key
+k6build remote -s http://localhost:8000 \
+    -k v0.51.0 \
+    --verify-signature signing.pub \
+    -o build/k6 -q
 `
 )
 
 // New creates new cobra command for build client command.
 func New() *cobra.Command {
 	var (
-		config   client.BuildServiceClientConfig
-		deps     []string
-		k6       string
-		output   string
-		platform string
-		quiet    bool
+		config     client.BuildServiceClientConfig
+		deps       []string
+		k6         string
+		output     string
+		platform   string
+		quiet      bool
+		expiration time.Duration
+		pins       map[string]string
+		modules    map[string]string
+		goEnv      map[string]string
+		race       bool
+		verify     bool
+		verifyKey  string
+		cover      bool
+		force      bool
+		prerelease bool
+		jsonOut    bool
 	)
 
 	cmd := &cobra.Command{
@@ -70,7 +106,19 @@ func New() *cobra.Command {
 		// this is needed to prevent cobra to print errors reported by subcommands in the stderr
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			client, err := client.NewBuildServiceClient(config)
+			if verifyKey != "" {
+				pem, err := os.ReadFile(verifyKey) //nolint:gosec
+				if err != nil {
+					return fmt.Errorf("reading signature public key %w", err)
+				}
+
+				config.VerifyKey, err = signing.ParsePublicKey(pem)
+				if err != nil {
+					return fmt.Errorf("parsing signature public key %w", err)
+				}
+			}
+
+			srv, err := client.NewBuildServiceClient(config)
 			if err != nil {
 				return fmt.Errorf("configuring the client %w", err)
 			}
@@ -84,17 +132,54 @@ func New() *cobra.Command {
 				buildDeps = append(buildDeps, k6build.Dependency{Name: name, Constraints: constrains})
 			}
 
-			artifact, err := client.Build(cmd.Context(), platform, k6, buildDeps)
-			if err != nil {
-				return fmt.Errorf("building %w", err)
+			ctx := k6build.WithBuildOptions(cmd.Context(), k6build.BuildOptions{
+				Pins:               pins,
+				ExtraModules:       modules,
+				GoEnv:              goEnv,
+				Race:               race,
+				Cover:              cover,
+				Force:              force,
+				IncludePrereleases: prerelease,
+			})
+			if expiration > 0 {
+				ctx = store.WithURLExpiration(ctx, expiration)
 			}
 
-			if !quiet {
-				fmt.Println(artifact.Print())
-			}
+			platforms := client.ExpandPlatform(cmd.Context(), srv, platform)
+			for _, p := range platforms {
+				artifact, err := srv.Build(ctx, p, k6, buildDeps)
+				if jsonOut {
+					_ = json.NewEncoder(os.Stdout).Encode(api.NewBuildResponse(artifact, err))
+				}
+				if err != nil {
+					return fmt.Errorf("building %w", err)
+				}
+
+				if !quiet && !jsonOut {
+					fmt.Println(artifact.Print())
+				}
+
+				if output == "" {
+					continue
+				}
+
+				// when building multiple platforms, the output is suffixed with the platform
+				outputPath := output
+				if len(platforms) > 1 {
+					outputPath = output + "-" + strings.ReplaceAll(p, "/", "-")
+				}
+
+				var progress util.ProgressFunc
+				if !quiet {
+					progress = util.ProgressPrinter(os.Stderr)
+				}
+
+				// prevent a corrupted binary from being written
+				if !verify {
+					artifact.Checksum = ""
+				}
 
-			if output != "" {
-				err = util.Download(cmd.Context(), artifact.URL, output)
+				err = srv.Download(cmd.Context(), artifact, outputPath, progress)
 				if err != nil {
 					return fmt.Errorf("downloading artifact %w", err)
 				}
@@ -107,10 +192,71 @@ func New() *cobra.Command {
 	cmd.Flags().StringVarP(&config.URL, "server", "s", "http://localhost:8000", "url for build server")
 	cmd.Flags().StringArrayVarP(&deps, "dependency", "d", nil, "list of dependencies in form package:constrains")
 	cmd.Flags().StringVarP(&k6, "k6", "k", "*", "k6 version constrains")
-	cmd.Flags().StringVarP(&platform, "platform", "p", "", "target platform (default GOOS/GOARCH)")
+	cmd.Flags().StringVarP(
+		&platform,
+		"platform",
+		"p",
+		"",
+		"target platforms, separated by commas (default GOOS/GOARCH)."+
+			"\nUse \"all\" for building all the platforms supported by the server.",
+	)
 	cmd.Flags().StringVarP(&output, "output", "o", "", "path to download the custom binary as an executable."+
 		"\nIf not specified, the artifact is not downloaded.")
-	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "don't print artifact's details")
+	cmd.Flags().IntVar(
+		&config.ParallelDownloads,
+		"parallel-downloads",
+		1,
+		"number of byte ranges of the custom binary downloaded in parallel.",
+	)
+	cmd.Flags().BoolVarP(&quiet, "quiet", "q", false, "don't print artifact's details or download progress")
+	cmd.Flags().BoolVar(
+		&jsonOut,
+		"json",
+		false,
+		"print the artifact's details, or the error if the build fails, as JSON."+
+			"\nWhen building multiple platforms, a JSON object is printed for each platform.",
+	)
+	cmd.Flags().BoolVar(
+		&verify,
+		"verify",
+		true,
+		"verify the checksum of the downloaded binary. If it doesn't match, the binary is not written.",
+	)
+	cmd.Flags().StringVar(
+		&verifyKey,
+		"verify-signature",
+		"",
+		"path to a PEM encoded ed25519 public key for verifying the signature of the downloaded binary."+
+			"\nIf the binary is not signed or its signature doesn't match, the binary is not written.",
+	)
+	cmd.Flags().StringToStringVar(&pins, "pin", nil, "pin the version of a go module (e.g. google.golang.org/grpc=v1.64.1)")
+	cmd.Flags().StringToStringVar(
+		&modules,
+		"extra-module",
+		nil,
+		"add a go module that is not an extension to the build (e.g. github.com/example/logger=v0.1.0)",
+	)
+	cmd.Flags().StringToStringVar(
+		&goEnv,
+		"go-env",
+		nil,
+		"override a go environment variable for the build, if allowed by the server (e.g. GOPROXY=https://proxy.example.com)",
+	)
+	cmd.Flags().BoolVar(
+		&race,
+		"race",
+		false,
+		"build with the race detector. Requires building for the build server's platform",
+	)
+	cmd.Flags().BoolVar(&cover, "cover", false, "build with coverage instrumentation")
+	cmd.Flags().BoolVar(&force, "force", false, "rebuild even if the same build failed recently")
+	cmd.Flags().BoolVar(
+		&prerelease,
+		"include-prereleases",
+		false,
+		"resolve the dependencies considering their prereleases (e.g. v0.3.0-rc1)",
+	)
+	cmd.Flags().DurationVar(&expiration, "url-expiration", 0, "requested expiration for the artifact's download url")
 
 	return cmd
 }
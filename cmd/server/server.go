@@ -3,16 +3,24 @@ package server
 
 import (
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 
-	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/internal/buildinfo"
+	"github.com/grafana/k6build/pkg/api"
 	"github.com/grafana/k6build/pkg/builder"
 	"github.com/grafana/k6build/pkg/catalog"
 	"github.com/grafana/k6build/pkg/httpserver"
+	"github.com/grafana/k6build/pkg/lock"
 	"github.com/grafana/k6build/pkg/server"
+	"github.com/grafana/k6build/pkg/signing"
 	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/store/client"
 	"github.com/grafana/k6build/pkg/store/s3"
@@ -54,8 +62,8 @@ For example
 
 	{
 	  "artifact": {
-	  "id": "5a241ba6ff643075caadbd06d5a326e5e74f6f10",
-	  "url": "http://localhost:9000/store/5a241ba6ff643075caadbd06d5a326e5e74f6f10/download",
+	  "id": "d8680625b30b48ab8df6c5c8918bb8e9f868bc38b267ccbac09df6b29a15b0fb",
+	  "url": "http://localhost:9000/store/d8680625b30b48ab8df6c5c8918bb8e9f868bc38b267ccbac09df6b29a15b0fb/download",
 	  "dependencies": {
 	    "k6": "v0.50.0",
 	    "k6/x/kubernetes": "v0.10.0"
@@ -65,9 +73,185 @@ For example
 	  }
 	}
 
+Dependencies are resolved to the highest version in the catalog that satisfies their constraints.
+"*", "latest" or empty constraints select the highest version. ~v0.2.3 selects patch releases
+(>=v0.2.3, <v0.3.0) and ^v1.2.3 compatible versions (>=v1.2.3, <v2.0.0, or <v0.3.0 for ^v0.2.3).
+Prereleases (e.g. v0.3.0-rc1) are only selected if the constraints include a prerelease
+(e.g. ">=v0.3.0-rc1"). When constraints are combined, each one must include a prerelease
+(e.g. ">=v0.3.0-rc1, <v1.0.0-0").
+
+The constraints of a dependency can also be given in structured form using the "clauses" attribute,
+as a list of operator and version pairs that must all be satisfied
+(e.g. "clauses": [{"operator": ">=", "version": "v0.8.0"}, {"operator": "<", "version": "v0.10.0"}]).
+The operator is one of =, !=, >, <, >=, <=, ~ or ^ and defaults to =.
+
+If the server is started with --allow-build-semvers, a dependency can be built from an unreleased
+commit using "commit:<hash>" as its constraints (e.g. "constraints": "commit:0123abc"). The module's
+path is obtained from the catalog, but its versions and checksums are not used.
+
+Using --include-prereleases, or the "include_prereleases" attribute of the request, dependencies are
+resolved considering their prereleases even if their constraints don't include one. A prerelease
+satisfies the constraints if its release does (e.g. v0.3.0-rc1 satisfies >v0.2.0), so "latest" selects
+the highest prerelease if it is newer than the highest release. Versions with build metadata
+(e.g. v0.0.0+build) and commits are not resolved using the catalog and are not affected.
+
+Building versions with build metadata and from commits can be restricted to trusted requesters
+(e.g. a CI pipeline) using --build-semvers-tokens-file with a file that lists the allowed tokens,
+one per line. Only the requests with one of these tokens in the Authorization header
+(e.g. "Authorization: Bearer <token>") can build them. Other requests fail with "INVALID_REQUEST".
+
 Note: The build server disables CGO by default but enables it when a dependency requires it.
       use --enable-cgo=true to enable CGO support by default.
 
+Dependencies that require CGO can only be built for a platform other than the server's if a C toolchain
+is configured for it using --cgo-cc and optionally --cgo-cxx (e.g. --cgo-cc linux/arm64=aarch64-linux-gnu-gcc).
+Otherwise, the build fails with "INVALID_REQUEST".
+
+Each build keeps its temporary files in a dir under --temp-dir (the OS temp dir by default) that is
+removed when the build finishes, even if it fails. The working dir of the build tool is also created
+in --temp-dir, as the server sets its TMPDIR to it. Use --purge-temp-dir to remove at startup the
+temporary files left in --temp-dir by builds interrupted by a crash. Purging requires --temp-dir, and
+the temp dir must not be shared with other servers.
+
+If the server is started with --allow-module-pins, the request can pin the version of any go module
+used in the build, including indirect dependencies, using the "pins" attribute
+(e.g. "pins": {"google.golang.org/grpc": "v1.64.1"}). Pinned modules are part of the artifact's id.
+
+If the server is started with --allow-extra-modules, the request can add go modules that are not
+extensions (e.g. a custom logger) using the "extra_modules" attribute
+(e.g. "extra_modules": {"github.com/example/logger": "v0.1.0"}). Extra modules are not resolved
+using the catalog and are part of the artifact's id.
+
+If the server is started with --allow-replace, the request can replace the module of a dependency
+with another module, such as a fork, using the "replace" attribute of the dependency in the form
+path@version (e.g. "replace": "github.com/example/xk6-kubernetes@v0.9.1"). The version can also be
+a commit. Only modules in the hosts given with --replace-hosts are allowed (e.g. --replace-hosts github.com/grafana).
+Replacements are part of the artifact's id.
+
+The request can override go environment variables for its build using the "go_env" attribute
+(e.g. "go_env": {"GOPROXY": "https://proxy.example.com,https://proxy.golang.org"}). The overrides are
+merged over the server's build environment. Only the variables given with --allowed-go-env can be
+overridden (e.g. --allowed-go-env GOPROXY,GOPRIVATE). Overrides are part of the artifact's id.
+If GOFLAGS is allowed, requests can only set the flags -buildvcs, -mod, -modcacherw, -tags and -trimpath,
+as other flags (e.g. -toolexec) can execute arbitrary commands during the build.
+
+The dependencies that can be built can be restricted to a subset of the catalog using --allow-deps
+(e.g. --allow-deps k6/x/kubernetes,k6/x/output-kafka). Dependencies can also be excluded using
+--deny-deps. Requests for other dependencies fail with "INVALID_REQUEST".
+
+The request can build the binary with the race detector ("race": true) or with coverage instrumentation
+("cover": true). The race detector is only supported for the server's platform. The instrumentation
+flags are part of the artifact's id and are listed in its "build_flags" attribute.
+
+The artifact's "build_info" attribute reports the versions of k6build, k6foundry and the go toolchain
+used for building the binary (e.g. "build_info": {"k6build_version": "v0.5.0", "go_version": "go1.23.6"}).
+Artifacts built by versions of the server that did not record this information only report the go version.
+The version of the go toolchain is part of the artifact's id, so upgrading the toolchain rebuilds the artifacts.
+
+Using --full-build-info, the build info also reports the versions of all the go modules linked in the
+binary, including indirect dependencies ("modules" attribute). The build info of an artifact can be
+obtained from /artifact/<id>/info. If the artifact doesn't exist, the server returns 404.
+
+	curl http://localhost:8000/artifact/<id>/info | jq .
+
+Using --sbom, the server generates for each artifact a CycloneDX SBOM (software bill of materials)
+listing all the go modules linked in the binary, including indirect dependencies, and stores it along
+with the artifact. The artifact's "sbom_url" attribute has the URL for downloading it. The SBOM can also be
+obtained from /artifact/<id>/sbom. Artifacts built before enabling --sbom don't have one.
+
+	curl http://localhost:8000/artifact/<id>/sbom | jq .
+
+The artifact's id is the sha256 hash of the platform, the go toolchain version, the resolved versions
+of the dependencies and the build options. Ids generated by previous versions of the server (sha1) are
+not reused, so the artifacts are rebuilt once after upgrading and the old objects in the store can be removed.
+
+If the request is made with the "verbose=true" query parameter (e.g. /build?verbose=true), the
+response includes the details of the resolution of each dependency in the "resolution" attribute:
+the requested constrains, the resolved version, the available versions and if the resolved version
+is the latest available.
+
+The request can set the expiration of the download URL using the "url_expiration" attribute
+(e.g. "15m"). The expiration is limited by --max-url-expiration and is ignored by stores whose
+download URLs don't expire (e.g. the file-backed store server).
+
+The download URL is generated by the store (e.g. http://localhost:9000/store/<id>/download for the
+store server), which may not be reachable by the clients (e.g. when they access through a gateway).
+Using --public-url, the scheme and host of the download URLs are replaced by those of the public URL
+and its path, if any, is prepended to the URL's path (e.g. with --public-url https://k6build.example.com,
+the URL is https://k6build.example.com/store/<id>/download).
+
+The number of concurrent builds can be limited using --max-concurrent-builds. Requests exceeding
+the limit wait for a build to complete, and fail with status 503 if they are cancelled while waiting
+(e.g. the client disconnected). The --max-concurrent-builds-per-identity option limits
+the concurrent builds of each requester, identified by the token in the Authorization header,
+so a single requester cannot use all build slots. Requests exceeding it fail with status 429.
+
+A build request can be safely retried (e.g. after a timeout) by setting the Idempotency-Key header
+to a unique value (e.g. a UUID). Repeated requests with the same key return the outcome of the first
+request, waiting for it if it is in progress, instead of starting another build. Keys are scoped to
+the requester and are kept for --idempotency-key-ttl after the build completes. Keys of failed builds
+are not kept, so the request can be retried. Reusing a key for a different request fails with status 400.
+
+	curl http://localhost:8000/build -H "Idempotency-Key: 0b9c6d7e-3f1a-4d2b-9c8e-5a7f6e4d3c2b" -d \
+	'{"k6":"v0.50.0", "platform":"linux/amd64"}'
+
+Requests are validated before building: the k6 constraints cannot be empty, the platform must have
+the form os/arch and the dependencies must be extensions named k6/x/<name>. A dependency can be
+repeated only if it is requested with the same constraints and replacement each time.
+Invalid requests fail with status 400 and code "INVALID_REQUEST", reporting all the problems found.
+
+Errors have a "code" attribute that identifies their cause (e.g. "INVALID_PLATFORM", "CANNOT_SATISFY",
+"BUILD_FAILED"). The status of the response depends on the cause of the error: 400 for invalid requests
+or platforms, 422 for dependencies that are unknown or cannot be satisfied, 429 when too many builds
+are running, 502 when downloading the dependencies failed (e.g. the GOPROXY is not available) and
+500 for builds that failed for other reasons.
+
+Each request is identified by the id given in its X-Request-ID header or, if not given, by an id
+generated by the server. The id is returned in the X-Request-ID header of the response and in the
+"request_id" attribute of the build responses, and is included in the server's log records for the
+request, so the records of a failed build can be found using the id reported by the client.
+
+The JSON responses larger than 1KiB are compressed with gzip if the client accepts it
+(Accept-Encoding: gzip).
+
+Using --access-log, the server logs the method, path, status, duration and size of the response of
+each request served, including its request id.
+
+Browser-based tools served from other origins can call the API if their origins are given in
+--cors-origins ("*" allows any origin). The methods and headers allowed in their requests can be
+set with --cors-methods and --cors-headers. By default, cross-origin requests are not allowed.
+
+Build batch
+-----------
+
+Multiple independent build requests can be sent in one round trip to /build/batch as a list of
+build requests. The response is the list of build responses, in the same order as the requests.
+A failed request doesn't affect the others and its response reports the error. The status of the
+response is 200 unless the batch itself is invalid (e.g. it exceeds --max-batch-size).
+
+The requests of a batch are built concurrently, up to --batch-concurrency at a time, and are
+subject to the same limits of concurrent builds as individual requests.
+
+	curl http://localhost:8000/build/batch -d \
+	'[
+	  {"k6":"v0.50.0", "platform":"linux/amd64"},
+	  {"k6":"v0.50.0", "platform":"linux/arm64"}
+	]' | jq .
+
+Build download
+--------------
+
+A build request sent to /build/download returns the content of the binary instead of the artifact's
+details, skipping the download from the artifact's URL. The id and checksum of the artifact are returned
+in the X-Artifact-ID and X-Artifact-Checksum headers. The artifact is kept in the store as with any
+other build. If the build fails, the error is returned as in the build request.
+
+	curl http://localhost:8000/build/download -o k6 -d \
+	'{
+	  "k6":"v0.50.0",
+	  "platform":"linux/amd64"
+	}'
+
 Resolve
 =======
 
@@ -94,22 +278,239 @@ For example
 	  },
 	}
 
+If the constrains of a dependency cannot be satisfied, the error explains the versions available in the
+catalog and the closest match. For example:
+"cannot satisfy dependency : k6/x/kubernetes >v0.10.0: requested >v0.10.0 but only v0.9.0, v0.10.0 available
+(closest v0.10.0)"
+
+
+Plan
+====
+
+The plan endpoint (/plan) accepts the same request as the build endpoint, except the "url_expiration"
+attribute as no download URL is returned, and returns the resolved dependency versions, the go modules
+that implement them, the artifact's id and whether the artifact is already built ("cached"), without
+building it.
+
+Platforms
+---------
+
+The list of platforms supported by the server, set with --platforms, can be obtained from /platforms.
+Clients use it for building all the supported platforms (e.g. k6build remote --platform all).
+
+	curl http://localhost:8000/platforms | jq .
+
+	{
+	  "platforms": [
+	    "darwin/amd64",
+	    "darwin/arm64",
+	    "linux/amd64",
+	    "linux/arm64",
+	    "windows/amd64"
+	  ]
+	}
+
+Version
+-------
+
+The version of the server can be obtained from /version. Clients can use it for checking the
+server is compatible.
+
+	curl http://localhost:8000/version | jq .
+
+	{
+	  "version": "v0.1.0",
+	  "commit": "0123456789",
+	  "build_date": "2025-01-01T00:00:00Z",
+	  "go_version": "go1.23.6",
+	  "platform": "linux/amd64"
+	}
+
+The API is described by an OpenAPI 3 document, including the endpoints of the store server,
+that can be obtained from /openapi.json for generating clients in other languages.
+
+	curl http://localhost:8000/openapi.json | jq .
+
+Catalog
+-------
+
+The list of dependencies supported by the catalog can be obtained from /catalog/dependencies
+
+	curl http://localhost:8000/catalog/dependencies | jq .
+
+	{
+	  "dependencies": [
+	    "k6",
+	    "k6/x/kubernetes"
+	  ]
+	}
+
+The versions of a dependency supported by the catalog can be obtained from
+/catalog/dependencies/<name>/versions. The name must be escaped (e.g. k6%2Fx%2Fkubernetes).
+If the dependency is not supported, the server returns 404.
+
+	curl http://localhost:8000/catalog/dependencies/k6%2Fx%2Fkubernetes/versions | jq .
+
+	{
+	  "name": "k6/x/kubernetes",
+	  "versions": [
+	    "v0.8.0",
+	    "v0.9.0"
+	  ]
+	}
+
+The catalog used for resolving dependencies can be obtained from /catalog. If more than one catalog
+is used, the response contains the merged catalog.
+
+	curl http://localhost:8000/catalog | jq .
+
+	{
+	  "k6/x/kubernetes": {
+	    "module": "github.com/grafana/xk6-kubernetes",
+	    "versions": ["v0.8.0", "v0.9.0"]
+	  }
+	}
+
+The catalogs can be verified before they are loaded. Using --catalog-sha256, the catalog must match
+the given checksum. Using --catalog-pubkey, each catalog must have a detached ed25519 signature,
+base64 encoded, at the catalog's location with the ".sig" suffix (e.g. catalog.json.sig).
+Catalogs that don't match their checksum or signature are rejected.
+
+Catalog entries can pin the go.sum hash of the module's versions using the "sums" attribute
+(e.g. "sums": {"v0.9.0": "h1:..."}). Before building, the server downloads the pinned modules and
+fails the build if their hash doesn't match, preventing a compromised proxy from serving a tampered module.
+
+At startup, the catalogs are validated and the server fails reporting all the invalid dependencies
+(e.g. missing module path or invalid versions).
+
+The catalog and resolve responses can be cached by clients and edge caches (e.g. a CDN) for the time
+set with --cache-max-age. Responses to requests with an Authorization header can only be cached by
+the clients. The responses have an ETag that changes when the catalog changes. If the request's
+If-None-Match header matches the ETag, the server returns 304 (Not Modified).
+
+The resources used by each build can be limited. Using --build-timeout, builds exceeding the given
+duration are canceled. The --build-max-procs, --build-memory-limit and --build-parallelism flags
+limit the CPUs (GOMAXPROCS), the memory (GOMEMLIMIT) and the packages compiled in parallel
+(go build -p) of the go toolchain. These limits are hints to the go toolchain and don't replace
+the limits of the container or cgroup running the server.
+
+Some combinations of dependencies always fail to compile (e.g. incompatible extensions). Using
+--failed-builds-ttl, the server remembers the builds that failed compiling and returns the same
+failure for identical requests, without rebuilding, until the given time elapses. Requests with
+the "force" build option bypass the remembered failures.
+
+By default, the catalog is loaded for each request. Using --catalog-reload-interval, the catalog is
+loaded once and reloaded periodically or when the server receives a SIGHUP. If reloading fails, the
+last catalog loaded is used and the failure is logged and counted in the metrics.
+
+Downloading a catalog fails if it takes longer than --catalog-timeout, so an unresponsive catalog
+host doesn't block the server's startup or the requests indefinitely. Downloads that fail because the
+host cannot be reached or responds with a server error are retried up to --catalog-retries times,
+waiting --catalog-retry-delay before the first retry and doubling the delay for each following one,
+so a brief failure of the catalog host doesn't prevent the server from starting.
+
+Using --resolution-cache-ttl, the versions that satisfy the constrains of each dependency are cached
+for the given time, so requests for the same constrains are resolved without accessing the catalog.
+This speeds up the requests for artifacts already in the store, specially when the catalog is loaded
+for each request from a remote location. The cache is cleared when the catalog is reloaded. Resolutions
+served from the cache are counted in k6build_resolution_cache_hits_total.
+
+Object expiration
+-----------------
+
+When using a s3 bucket for storing binaries (--store-bucket), the objects can be tagged using
+--store-object-tags (e.g. --store-object-tags expire-after=7d) and expired by a bucket lifecycle
+rule that filters by the tag. For example, the following rule removes the objects tagged with
+expire-after=7d after 7 days:
+
+	{
+	  "Rules": [
+	    {
+	      "ID": "expire-k6build-binaries",
+	      "Status": "Enabled",
+	      "Filter": {"Tag": {"Key": "expire-after", "Value": "7d"}},
+	      "Expiration": {"Days": 7}
+	    }
+	  ]
+	}
+
+	aws s3api put-bucket-lifecycle-configuration --bucket k6build --lifecycle-configuration file://lifecycle.json
+
+Expired objects are rebuilt when requested again. Bucket lifecycle rules cannot exclude objects by tag,
+so objects pinned in the store (see the store command) have their expiration tag removed until they
+are unpinned.
+
+Alternatively, the server can delete the expired artifacts itself using --artifact-ttl. Every
+--artifact-sweep-interval, the server lists the objects in the bucket and deletes the artifacts
+stored before the given time, except those requested within that time by any server sharing the
+store, being built or pinned in the store (see the store command). The last request of an artifact
+is recorded in the store (in a bucket, in the object's k6build-accessed tag). Servers sharing a store
+should use a distributed lock (--s3-lock or --dynamodb-lock-table) so an artifact being built is not
+deleted. The objects and bytes deleted are counted in k6build_store_objects_swept_total and
+k6build_store_bytes_swept_total.
+
+Checksums
+---------
+
+The artifacts' checksums are the hex digest for sha256 (e.g. bfdf51...), as reported by previous
+versions, and have the form <algorithm>:<hex digest> for other algorithms (e.g. sha512:0a1b2c...).
+When using a s3 bucket, the algorithm can be changed using --store-checksum-algorithm (sha256 or sha512).
+Only sha256 checksums are verified by s3, other algorithms are kept in the object's metadata.
+
+Signatures
+----------
+
+Using --signing-key, the server signs the artifacts it builds with the given ed25519 private key
+(PEM encoded, PKCS #8). The signed message is the checksum of the binary stored, always in the form
+<algorithm>:<hex digest> (e.g. sha256:0123...), even if the artifact reports only the hex digest.
+The base64 encoded signature is reported in the artifact's "signature" attribute and can be obtained
+from /artifact/<id>/signature. Artifacts built before enabling the signing are signed when they
+are requested.
+
+	openssl genpkey -algorithm ed25519 -out signing.pem
+	openssl pkey -in signing.pem -pubout -out signing.pub
+
+	# verify a downloaded binary
+	curl -s http://localhost:8000/artifact/<id>/signature | jq -r .signature | base64 -d > k6.sig
+	printf 'sha256:%s' "$(sha256sum k6 | cut -d' ' -f1)" > k6.checksum
+	openssl pkeyutl -verify -pubin -inkey signing.pub -rawin -in k6.checksum -sigfile k6.sig
 
 Metrics
 --------
 
 The server exposes prometheus metrics at /metrics
 
+Besides the total of requests, builds and object store hits, the metrics include the duration of
+the requests served from the object store (k6build_store_hit_duration_seconds), separated from the
+duration of the builds (k6build_build_duration_seconds), the number of builds in progress
+(k6build_builds_in_flight) and the failed requests by reason (k6build_request_failures_total with
+reason invalid_parameters, compile_error, build_error or store_error).
+
+The requests and compilation failures of each dependency are counted in k6build_dependency_requests_total
+and k6build_dependency_compile_failures_total, labeled by dependency. Failures are attributed to the
+dependencies mentioned in the compilation errors.
+
+The space used by the temporary files of each build is recorded in k6build_build_scratch_bytes.
+
 Liveness Probe
 --------------
 
 The server exposes a liveness check at /alive
+
+Readiness Probe
+---------------
+
+The server exposes a readiness check at /readyz. It returns 503 (Service Unavailable) if the
+object store is not reachable (e.g. the store server is down or the S3 bucket is not accessible).
 `
 
 	example = `
 # start the build server using a custom local catalog
 k6build server -c /path/to/catalog.json
 
+# start the build server using the default catalog and an overlay with private extensions
+k6build server -c https://registry.k6.io/catalog.json -c /path/to/private-catalog.json
+
 # start the build server using a custom GOPROXY
 k6build server -e GOPROXY=http://localhost:80
 
@@ -118,29 +519,87 @@ k6build server -e GOPROXY=http://localhost:80
 export AWS_ACCESS_KEY_ID="test"
 export AWS_SECRET_ACCESS_KEY="test"
 k6build server --s3-endpoint http://localhost:4566 --store-bucket k6build
+
+# start the build server with a MinIO s3 storage backend using static credentials
+k6build server --s3-endpoint http://localhost:9000 --store-bucket k6build --s3-region us-east-1 \
+    --s3-path-style --s3-access-key-id minioadmin --s3-secret-access-key minioadmin
 `
 )
 
 type serverConfig struct {
 	allowBuildSemvers bool
-	catalogURL        string
+	semversTokens     string
+	allowModulePins   bool
+	allowExtraModules bool
+	allowReplace      bool
+	replaceHosts      []string
+	allowedGoEnv      []string
+	allowDeps         []string
+	denyDeps          []string
+	cacheDir          string
+	tempDir           string
+	purgeTempDir      bool
+	catalogURLs       []string
+	catalogReload     time.Duration
+	catalogTimeout    time.Duration
+	catalogRetries    int
+	catalogRetryDelay time.Duration
+	catalogSHA256     string
+	catalogPubKey     string
+	signingKey        string
+	dynamoLockTable   string
+	dynamoRegion      string
+	dynamoEndpoint    string
 	copyGoEnv         bool
 	enableCgo         bool
+	cgoCC             map[string]string
+	cgoCXX            map[string]string
 	goEnv             map[string]string
+	maxBuilds         int
+	maxIdentityBuilds int
+	maxBatchSize      int
+	batchConcurrency  int
+	idempotencyKeyTTL time.Duration
+	maxURLExpiration  time.Duration
+	platforms         []string
+	cacheMaxAge       time.Duration
 	port              int
 	s3Bucket          string
 	s3Endpoint        string
+	s3Lock            bool
+	lockLease         time.Duration
 	s3Region          string
-	storeURL          string
+	s3PathStyle       bool
+	s3AccessKeyID     string
+	s3SecretKey       string
+	s3SessionToken    string
+	s3DisableSSL      bool
+	storeTags         map[string]string
+	storeChecksum     string
+	storeURLs         []string
+	storeParallel     int
+	publicURL         string
 	verbose           bool
 	shutdownTimeout   time.Duration
+	slowBuild         time.Duration
+	failedBuildsTTL   time.Duration
+	resolutionTTL     time.Duration
+	artifactTTL       time.Duration
+	sweepInterval     time.Duration
+	cors              httpserver.CORSConfig
+	foundryLimits     builder.FoundryLimits
+	prereleases       bool
+	fullBuildInfo     bool
+	sbom              bool
 }
 
 // New creates new cobra command for the server command.
 func New() *cobra.Command { //nolint:funlen
 	var (
-		cfg      = serverConfig{}
-		logLevel string
+		cfg       = serverConfig{}
+		logLevel  string
+		logFormat string
+		accessLog bool
 	)
 
 	cmd := &cobra.Command{
@@ -153,7 +612,7 @@ func New() *cobra.Command { //nolint:funlen
 		// this is needed to prevent cobra to print errors reported by subcommands in the stderr
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			log, err := getLogger(logLevel)
+			log, err := util.NewLogger(os.Stderr, logLevel, logFormat)
 			if err != nil {
 				return err
 			}
@@ -162,15 +621,48 @@ func New() *cobra.Command { //nolint:funlen
 				log.Warn("CGO is enabled by default. Use --enable-cgo=false to disable it.")
 			}
 
-			buildSrv, err := cfg.getBuildService(cmd.Context())
+			// fail early with the list of invalid dependencies
+			err = cfg.validateCatalogs(cmd.Context())
 			if err != nil {
 				return err
 			}
 
+			buildSrv, err := cfg.getBuildService(cmd.Context(), log)
+			if err != nil {
+				return err
+			}
+
+			if cfg.catalogReload > 0 {
+				go reloadOnSignal(cmd.Context(), buildSrv, log)
+			}
+
 			apiConfig := server.APIServerConfig{
-				BuildService: buildSrv,
-				Log:          log,
+				BuildService:                   buildSrv,
+				Log:                            log,
+				MaxURLExpiration:               cfg.maxURLExpiration,
+				MaxConcurrentBuilds:            cfg.maxBuilds,
+				MaxConcurrentBuildsPerIdentity: cfg.maxIdentityBuilds,
+				Platforms:                      cfg.platforms,
+				CacheMaxAge:                    cfg.cacheMaxAge,
+				Version:                        buildinfo.Version(),
+				MaxBatchSize:                   cfg.maxBatchSize,
+				BatchConcurrency:               cfg.batchConcurrency,
+				IdempotencyKeyTTL:              cfg.idempotencyKeyTTL,
+				CORS:                           cfg.cors,
+			}
+
+			apiConfig.BuildSemversAuthorizer, err = cfg.getSemversAuthorizer()
+			if err != nil {
+				return err
 			}
+
+			if cfg.publicURL != "" {
+				apiConfig.DownloadURLRewriter, err = server.PublicURLRewriter(cfg.publicURL)
+				if err != nil {
+					return err
+				}
+			}
+
 			buildServer := server.NewAPIServer(apiConfig)
 
 			srvConfig := httpserver.ServerConfig{
@@ -178,7 +670,9 @@ func New() *cobra.Command { //nolint:funlen
 				Port:              cfg.port,
 				EnableMetrics:     true,
 				LivenessProbe:     true,
+				ReadinessCheck:    buildSrv.HealthCheck,
 				ReadHeaderTimeout: 5 * time.Second,
+				AccessLog:         accessLog,
 			}
 
 			srv := httpserver.NewServer(srvConfig)
@@ -193,33 +687,382 @@ func New() *cobra.Command { //nolint:funlen
 		},
 	}
 
-	cmd.Flags().StringVarP(
-		&cfg.catalogURL,
+	cmd.Flags().StringArrayVarP(
+		&cfg.catalogURLs,
 		"catalog",
 		"c",
-		catalog.DefaultCatalogURL,
-		"dependencies catalog. Can be path to a local file or an URL.",
+		[]string{catalog.DefaultCatalogURL},
+		"dependencies catalog. Can be path to a local file or an URL."+
+			"\nCan be repeated. Later catalogs override earlier ones for the same dependency.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.catalogReload,
+		"catalog-reload-interval",
+		0,
+		"time between reloads of the catalog. The catalog is also reloaded on SIGHUP."+
+			"\nIf 0, the catalog is loaded for each request.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.catalogTimeout,
+		"catalog-timeout",
+		30*time.Second,
+		"maximum time for downloading a catalog. If 0, there is no limit.",
+	)
+	cmd.Flags().IntVar(
+		&cfg.catalogRetries,
+		"catalog-retries",
+		3,
+		"number of times a failed download of a catalog is retried. If 0, downloads are not retried.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.catalogRetryDelay,
+		"catalog-retry-delay",
+		catalog.DefaultRetryDelay,
+		"time before the first retry of a catalog download. The delay is doubled for each following retry.",
 	)
 	cmd.Flags().StringVar(
-		&cfg.storeURL,
+		&cfg.catalogSHA256,
+		"catalog-sha256",
+		"",
+		"expected sha256 checksum of the catalog. Requires a single catalog.",
+	)
+	cmd.Flags().StringVar(
+		&cfg.catalogPubKey,
+		"catalog-pubkey",
+		"",
+		"path to a PEM encoded ed25519 public key for verifying the catalogs' signatures."+
+			"\nThe signature of each catalog is expected at the catalog's location with the \".sig\" suffix.",
+	)
+	cmd.Flags().StringVar(
+		&cfg.signingKey,
+		"signing-key",
+		"",
+		"path to a PEM encoded ed25519 private key for signing the artifacts.",
+	)
+	cmd.Flags().StringSliceVar(
+		&cfg.storeURLs,
 		"store-url",
-		"http://localhost:9000",
-		"store server url",
+		[]string{"http://localhost:9000"},
+		"store server url. If multiple urls are given, requests fail over among them.",
+	)
+	cmd.Flags().IntVar(
+		&cfg.storeParallel,
+		"store-parallel-downloads",
+		1,
+		"number of byte ranges of an artifact downloaded in parallel from the store server.",
+	)
+	cmd.Flags().StringVar(
+		&cfg.publicURL,
+		"public-url",
+		"",
+		"url the clients use for downloading the artifacts (e.g. through a gateway)."+
+			"\nIf not set, the download url generated by the store is returned.",
 	)
 	cmd.Flags().StringVar(&cfg.s3Bucket, "store-bucket", "", "s3 bucket for storing binaries")
 	cmd.Flags().StringVar(&cfg.s3Endpoint, "s3-endpoint", "", "s3 endpoint")
 	cmd.Flags().StringVar(&cfg.s3Region, "s3-region", "", "aws region")
+	cmd.Flags().BoolVar(
+		&cfg.s3PathStyle,
+		"s3-path-style",
+		false,
+		"use path-style addressing for the s3 bucket, required by some s3-compatible services (e.g. MinIO)",
+	)
+	cmd.Flags().StringVar(
+		&cfg.s3AccessKeyID,
+		"s3-access-key-id",
+		"",
+		"access key id for the s3 bucket. If not set, the default aws credentials are used",
+	)
+	cmd.Flags().StringVar(&cfg.s3SecretKey, "s3-secret-access-key", "", "secret access key for the s3 bucket")
+	cmd.Flags().StringVar(&cfg.s3SessionToken, "s3-session-token", "", "session token for the s3 bucket. Optional")
+	cmd.Flags().BoolVar(&cfg.s3DisableSSL, "s3-disable-ssl", false, "use http instead of https for accessing s3")
+	cmd.Flags().StringToStringVar(
+		&cfg.storeTags,
+		"store-object-tags",
+		nil,
+		"tags set on the objects stored in the s3 bucket (e.g. expire-after=7d). Requires --store-bucket",
+	)
+	cmd.Flags().StringVar(
+		&cfg.storeChecksum,
+		"store-checksum-algorithm",
+		util.DefaultChecksumAlgorithm,
+		"algorithm used for the checksum of the objects stored in the s3 bucket (sha256 or sha512)."+
+			"\nRequires --store-bucket",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.s3Lock,
+		"s3-lock",
+		false,
+		"use the s3 bucket for preventing concurrent builds of the same artifact by multiple servers."+
+			"\nRequires --store-bucket",
+	)
+	cmd.Flags().StringVar(
+		&cfg.dynamoLockTable,
+		"dynamodb-lock-table",
+		"",
+		"use a DynamoDB table for preventing concurrent builds of the same artifact by multiple servers."+
+			"\nThe table must have a string partition key named 'id'",
+	)
+	cmd.Flags().StringVar(
+		&cfg.dynamoRegion,
+		"dynamodb-region",
+		"",
+		"aws region of the dynamodb lock table. If not set, the region of the aws environment is used",
+	)
+	cmd.Flags().StringVar(&cfg.dynamoEndpoint, "dynamodb-endpoint", "", "dynamodb endpoint")
+	cmd.Flags().DurationVar(
+		&cfg.lockLease,
+		"lock-lease",
+		lock.DefaultLeaseDuration,
+		"time after which a s3 or dynamodb lock is considered expired. Must exceed the worst-case build time.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.lockLease,
+		"s3-lock-lease",
+		lock.DefaultLeaseDuration,
+		"time after which a s3 lock is considered expired",
+	)
+	_ = cmd.Flags().MarkDeprecated("s3-lock-lease", "use --lock-lease instead")
 	cmd.Flags().BoolVarP(&cfg.verbose, "verbose", "v", false, "print build process output")
 	cmd.Flags().BoolVarP(&cfg.copyGoEnv, "copy-go-env", "g", true, "copy go environment")
 	cmd.Flags().StringToStringVarP(&cfg.goEnv, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringVar(
+		&cfg.cacheDir,
+		"cache-dir",
+		"",
+		"directory for the go module and build caches shared by all builds."+
+			"\nCaches are namespaced by go version. If not set, the go environment's caches are used.",
+	)
+	cmd.Flags().StringVar(
+		&cfg.tempDir,
+		"temp-dir",
+		"",
+		"directory for the temporary files of the builds. If not set, the OS temp dir is used.",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.purgeTempDir,
+		"purge-temp-dir",
+		false,
+		"remove at startup the temporary files left in the temp dir by builds that didn't finish."+
+			"\nRequires --temp-dir",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.slowBuild,
+		"slow-build-threshold",
+		0,
+		"log a warning for builds taking longer than this threshold. If 0, slow builds are not logged.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.foundryLimits.Timeout,
+		"build-timeout",
+		0,
+		"maximum duration of a build. If 0, builds are not limited.",
+	)
+	cmd.Flags().IntVar(
+		&cfg.foundryLimits.MaxProcs,
+		"build-max-procs",
+		0,
+		"maximum number of CPUs used by the go toolchain in a build (GOMAXPROCS). If 0, not limited.",
+	)
+	cmd.Flags().StringVar(
+		&cfg.foundryLimits.MemoryLimit,
+		"build-memory-limit",
+		"",
+		"soft memory limit of the go toolchain in a build (GOMEMLIMIT) (e.g. 2GiB). If empty, not limited.",
+	)
+	cmd.Flags().IntVar(
+		&cfg.foundryLimits.Parallelism,
+		"build-parallelism",
+		0,
+		"maximum number of packages compiled in parallel in a build (go build -p). If 0, not limited.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.failedBuildsTTL,
+		"failed-builds-ttl",
+		0,
+		"time a build that failed compiling is remembered and its failure returned without rebuilding."+
+			"\nIf 0, failed builds are not remembered.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.resolutionTTL,
+		"resolution-cache-ttl",
+		0,
+		"time the resolution of a dependency's constrains is cached. The cache is cleared when the catalog is reloaded."+
+			"\nIf 0, resolutions are not cached.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.artifactTTL,
+		"artifact-ttl",
+		0,
+		"age after which the artifacts not requested recently are deleted from the store."+
+			"\nRequires a store that can list, delete and record the access of objects (--store-bucket)."+
+			"\nIf 0, artifacts are never deleted.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.sweepInterval,
+		"artifact-sweep-interval",
+		builder.DefaultArtifactSweepInterval,
+		"time between sweeps of the artifacts older than --artifact-ttl",
+	)
+	cmd.Flags().StringSliceVar(
+		&cfg.platforms,
+		"platforms",
+		api.DefaultPlatforms,
+		"platforms supported by the server, listed at /platforms",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.cacheMaxAge,
+		"cache-max-age",
+		0,
+		"time the catalog and resolve responses can be cached by clients and edge caches."+
+			"\nIf 0, caches must revalidate the responses before using them.",
+	)
 	cmd.Flags().IntVarP(&cfg.port, "port", "p", 8000, "port server will listen")
 	cmd.Flags().StringVarP(&logLevel, "log-level", "l", "INFO", "log level")
+	cmd.Flags().StringVar(&logFormat, "log-format", "text", "log format (text or json)")
+	cmd.Flags().BoolVar(&accessLog, "access-log", false, "log each request served")
+	cmd.Flags().StringSliceVar(
+		&cfg.cors.AllowedOrigins,
+		"cors-origins",
+		nil,
+		"origins allowed to make cross-origin requests (e.g. https://ui.example.com). \"*\" allows any origin.",
+	)
+	cmd.Flags().StringSliceVar(
+		&cfg.cors.AllowedMethods,
+		"cors-methods",
+		httpserver.DefaultCORSMethods,
+		"methods allowed in cross-origin requests",
+	)
+	cmd.Flags().StringSliceVar(
+		&cfg.cors.AllowedHeaders,
+		"cors-headers",
+		httpserver.DefaultCORSHeaders,
+		"headers allowed in cross-origin requests",
+	)
 	cmd.Flags().BoolVar(&cfg.enableCgo, "enable-cgo", false, "enable CGO for building binaries.")
+	cmd.Flags().StringToStringVar(
+		&cfg.cgoCC,
+		"cgo-cc",
+		nil,
+		"C compiler for cross-compiling with CGO, by platform (e.g. linux/arm64=aarch64-linux-gnu-gcc)",
+	)
+	cmd.Flags().StringToStringVar(
+		&cfg.cgoCXX,
+		"cgo-cxx",
+		nil,
+		"C++ compiler for cross-compiling with CGO, by platform (e.g. linux/arm64=aarch64-linux-gnu-g++)",
+	)
 	cmd.Flags().BoolVar(
 		&cfg.allowBuildSemvers,
 		"allow-build-semvers",
 		false,
-		"allow building versions with build metadata (e.g v0.0.0+build).",
+		"allow building versions with build metadata (e.g v0.0.0+build)"+
+			"\nand dependencies from a commit (e.g. k6/x/kubernetes:commit:0123abc).",
+	)
+	cmd.Flags().StringVar(
+		&cfg.semversTokens,
+		"build-semvers-tokens-file",
+		"",
+		"file with the auth tokens allowed to build versions with build metadata and from commits, one per line."+
+			"\nRequires --allow-build-semvers. If not set, any request can build them.",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.allowModulePins,
+		"allow-module-pins",
+		false,
+		"allow build requests to pin the version of go modules, including indirect dependencies.",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.allowExtraModules,
+		"allow-extra-modules",
+		false,
+		"allow build requests to add go modules that are not extensions, bypassing the catalog.",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.prereleases,
+		"include-prereleases",
+		false,
+		"resolve the dependencies considering their prereleases (e.g. v0.3.0-rc1).",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.fullBuildInfo,
+		"full-build-info",
+		false,
+		"keep in the artifacts' build info the versions of all the go modules linked in the binary.",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.sbom,
+		"sbom",
+		false,
+		"generate a CycloneDX SBOM for each artifact listing all the go modules linked in the binary.",
+	)
+	cmd.Flags().BoolVar(
+		&cfg.allowReplace,
+		"allow-replace",
+		false,
+		"allow build requests to replace the module of a dependency with a module in the --replace-hosts.",
+	)
+	cmd.Flags().StringSliceVar(
+		&cfg.replaceHosts,
+		"replace-hosts",
+		nil,
+		"hosts of the modules allowed as replacements. Can include a path prefix (e.g. github.com/grafana).",
+	)
+	cmd.Flags().StringSliceVar(
+		&cfg.allowDeps,
+		"allow-deps",
+		nil,
+		"dependencies build requests can use (e.g. k6/x/kubernetes). If not set, any dependency in the catalog.",
+	)
+	cmd.Flags().StringSliceVar(
+		&cfg.denyDeps,
+		"deny-deps",
+		nil,
+		"dependencies build requests cannot use, even if allowed by --allow-deps.",
+	)
+	cmd.Flags().StringSliceVar(
+		&cfg.allowedGoEnv,
+		"allowed-go-env",
+		nil,
+		"go environment variables build requests can override (e.g. GOPROXY,GOPRIVATE).",
+	)
+	cmd.Flags().IntVar(
+		&cfg.maxBuilds,
+		"max-concurrent-builds",
+		0,
+		"maximum number of concurrent builds. Requests exceeding the limit wait. 0 means no limit.",
+	)
+	cmd.Flags().IntVar(
+		&cfg.maxIdentityBuilds,
+		"max-concurrent-builds-per-identity",
+		0,
+		"maximum number of concurrent builds per requester, identified by its auth token."+
+			"\nRequests exceeding the limit are rejected. 0 means no limit.",
+	)
+	cmd.Flags().IntVar(
+		&cfg.maxBatchSize,
+		"max-batch-size",
+		server.DefaultMaxBatchSize,
+		"maximum number of requests in a batch build request",
+	)
+	cmd.Flags().IntVar(
+		&cfg.batchConcurrency,
+		"batch-concurrency",
+		server.DefaultBatchConcurrency,
+		"number of requests of a batch built concurrently",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.idempotencyKeyTTL,
+		"idempotency-key-ttl",
+		server.DefaultIdempotencyKeyTTL,
+		"time the outcome of a build request with an idempotency key is kept."+
+			"\nIf 0, idempotency keys are ignored.",
+	)
+	cmd.Flags().DurationVar(
+		&cfg.maxURLExpiration,
+		"max-url-expiration",
+		server.DefaultMaxURLExpiration,
+		"maximum expiration that a build request can set for the artifact's download URL",
 	)
 	cmd.Flags().DurationVar(
 		&cfg.shutdownTimeout,
@@ -231,23 +1074,22 @@ func New() *cobra.Command { //nolint:funlen
 	return cmd
 }
 
-func getLogger(logLevel string) (*slog.Logger, error) {
-	ll, err := util.ParseLogLevel(logLevel)
-	if err != nil {
-		return nil, fmt.Errorf("parsing log level %w", err)
+// getToolchains returns the toolchains for cross-compiling with cgo configured for each platform
+func (cfg serverConfig) getToolchains() map[string]builder.Toolchain {
+	toolchains := map[string]builder.Toolchain{}
+	for platform, cc := range cfg.cgoCC {
+		toolchains[platform] = builder.Toolchain{CC: cc, CXX: cfg.cgoCXX[platform]}
+	}
+	for platform, cxx := range cfg.cgoCXX {
+		if _, found := toolchains[platform]; !found {
+			toolchains[platform] = builder.Toolchain{CXX: cxx}
+		}
 	}
 
-	return slog.New(
-		slog.NewTextHandler(
-			os.Stderr,
-			&slog.HandlerOptions{
-				Level: ll,
-			},
-		),
-	), nil
+	return toolchains
 }
 
-func (cfg serverConfig) getBuildService(ctx context.Context) (k6build.BuildService, error) {
+func (cfg serverConfig) getBuildService(ctx context.Context, log *slog.Logger) (*builder.Builder, error) {
 	store, err := cfg.getStore() //nolint:contextcheck
 	if err != nil {
 		return nil, err
@@ -262,19 +1104,81 @@ func (cfg serverConfig) getBuildService(ctx context.Context) (k6build.BuildServi
 	}
 	cfg.goEnv["CGO_ENABLED"] = cgoEnabled
 
+	// the build tool creates its working dir in the process' temp dir
+	if cfg.tempDir != "" {
+		if err = os.MkdirAll(cfg.tempDir, 0o750); err != nil {
+			return nil, fmt.Errorf("creating temp dir %w", err)
+		}
+		if err = os.Setenv("TMPDIR", cfg.tempDir); err != nil {
+			return nil, fmt.Errorf("setting temp dir %w", err)
+		}
+	}
+
+	lock, err := cfg.getLock()
+	if err != nil {
+		return nil, err
+	}
+
+	verification, err := cfg.getCatalogVerification()
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey, err := cfg.getSigningKey()
+	if err != nil {
+		return nil, err
+	}
+
 	config := builder.Config{
 		Opts: builder.Opts{
 			GoOpts: builder.GoOpts{
 				Env:       cfg.goEnv,
 				CopyGoEnv: cfg.copyGoEnv,
 			},
-			Verbose:           cfg.verbose,
-			AllowBuildSemvers: cfg.allowBuildSemvers,
+			Verbose:             cfg.verbose,
+			AllowBuildSemvers:   cfg.allowBuildSemvers,
+			AllowModulePins:     cfg.allowModulePins,
+			AllowExtraModules:   cfg.allowExtraModules,
+			AllowReplace:        cfg.allowReplace,
+			ReplaceHosts:        cfg.replaceHosts,
+			AllowedGoEnv:        cfg.allowedGoEnv,
+			CacheDir:            cfg.cacheDir,
+			TempDir:             cfg.tempDir,
+			PurgeTempDir:        cfg.purgeTempDir,
+			SlowBuildThreshold:  cfg.slowBuild,
+			FailedBuildsTTL:     cfg.failedBuildsTTL,
+			ResolutionCacheTTL:  cfg.resolutionTTL,
+			IncludePrereleases:  cfg.prereleases,
+			FullBuildInfo:       cfg.fullBuildInfo,
+			GenerateSBOM:        cfg.sbom,
+			Toolchains:          cfg.getToolchains(),
+			DependencyAllowlist: cfg.allowDeps,
+			DependencyDenylist:  cfg.denyDeps,
 		},
-		Catalog:    cfg.catalogURL,
-		Store:      store,
-		Registerer: prometheus.DefaultRegisterer,
+		Catalog:               cfg.catalogURLs[0],
+		CatalogOverlays:       cfg.catalogURLs[1:],
+		CatalogReloadInterval: cfg.catalogReload,
+		CatalogVerification:   verification,
+		CatalogTimeout:        cfg.catalogTimeout,
+		CatalogRetries:        cfg.catalogRetries,
+		CatalogRetryDelay:     cfg.catalogRetryDelay,
+		ArtifactTTL:           cfg.artifactTTL,
+		ArtifactSweepInterval: cfg.sweepInterval,
+		Store:                 store,
+		Lock:                  lock,
+		Registerer:            prometheus.DefaultRegisterer,
+		Log:                   log,
+		Version:               buildinfo.Version().Version,
+		SigningKey:            signingKey,
+	}
+
+	if cfg.foundryLimits != (builder.FoundryLimits{}) {
+		config.Foundry, err = builder.NewLimitedFoundryFactory(nil, cfg.foundryLimits)
+		if err != nil {
+			return nil, fmt.Errorf("creating foundry %w", err)
+		}
 	}
+
 	builder, err := builder.New(ctx, config)
 	if err != nil {
 		return nil, fmt.Errorf("creating local build service  %w", err)
@@ -283,6 +1187,166 @@ func (cfg serverConfig) getBuildService(ctx context.Context) (k6build.BuildServi
 	return builder, nil
 }
 
+// validateCatalogs checks the catalogs can be loaded and are valid, reporting the problems found in all of them
+func (cfg serverConfig) validateCatalogs(ctx context.Context) error {
+	verification, err := cfg.getCatalogVerification()
+	if err != nil {
+		return err
+	}
+
+	problems := []error{}
+	for _, location := range cfg.catalogURLs {
+		ctlg, err := catalog.LoadCatalog(
+			ctx,
+			location,
+			catalog.LoadOptions{
+				Verification: verification,
+				Timeout:      cfg.catalogTimeout,
+				Retries:      cfg.catalogRetries,
+				RetryDelay:   cfg.catalogRetryDelay,
+			},
+		)
+		if err == nil {
+			err = catalog.Validate(ctx, ctlg)
+		}
+		if err != nil {
+			problems = append(problems, fmt.Errorf("catalog %s: %w", location, err))
+		}
+	}
+
+	return errors.Join(problems...)
+}
+
+func (cfg serverConfig) getCatalogVerification() (catalog.Verification, error) {
+	verification := catalog.Verification{SHA256: cfg.catalogSHA256}
+
+	if cfg.catalogSHA256 != "" && len(cfg.catalogURLs) > 1 {
+		return catalog.Verification{}, fmt.Errorf("--catalog-sha256 requires a single catalog")
+	}
+
+	if cfg.catalogPubKey != "" {
+		pem, err := os.ReadFile(cfg.catalogPubKey)
+		if err != nil {
+			return catalog.Verification{}, fmt.Errorf("reading catalog public key %w", err)
+		}
+
+		verification.PublicKey, err = signing.ParsePublicKey(pem)
+		if err != nil {
+			return catalog.Verification{}, fmt.Errorf("parsing catalog public key %w", err)
+		}
+	}
+
+	return verification, nil
+}
+
+// getSigningKey returns the key for signing the artifacts, if given
+func (cfg serverConfig) getSigningKey() (ed25519.PrivateKey, error) {
+	if cfg.signingKey == "" {
+		return nil, nil
+	}
+
+	pem, err := os.ReadFile(cfg.signingKey)
+	if err != nil {
+		return nil, fmt.Errorf("reading signing key %w", err)
+	}
+
+	key, err := signing.ParsePrivateKey(pem)
+	if err != nil {
+		return nil, fmt.Errorf("parsing signing key %w", err)
+	}
+
+	return key, nil
+}
+
+// getSemversAuthorizer returns the authorizer for building versions with build metadata, if the
+// tokens allowed to build them are given
+func (cfg serverConfig) getSemversAuthorizer() (server.BuildSemversAuthorizer, error) {
+	if cfg.semversTokens == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	content, err := os.ReadFile(cfg.semversTokens)
+	if err != nil {
+		return nil, fmt.Errorf("reading build semvers tokens %w", err)
+	}
+
+	tokens := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+
+	return server.TokenAuthorizer(tokens), nil
+}
+
+// reloadOnSignal reloads the builder's catalog when the process receives a SIGHUP
+func reloadOnSignal(ctx context.Context, b *builder.Builder, log *slog.Logger) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			// errors are logged by the builder
+			if err := b.ReloadCatalog(ctx); err == nil {
+				log.Info("catalog reloaded")
+			}
+		}
+	}
+}
+
+func (cfg serverConfig) getLock() (lock.Lock, error) {
+	if cfg.s3Lock && cfg.dynamoLockTable != "" {
+		return nil, fmt.Errorf("s3 lock and dynamodb lock are mutually exclusive")
+	}
+
+	if cfg.dynamoLockTable != "" {
+		dynamoLock, err := lock.NewDynamoLock(lock.DynamoConfig{
+			Table:         cfg.dynamoLockTable,
+			Endpoint:      cfg.dynamoEndpoint,
+			Region:        cfg.dynamoRegion,
+			LeaseDuration: cfg.lockLease,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("creating dynamodb lock %w", err)
+		}
+
+		return dynamoLock, nil
+	}
+
+	if !cfg.s3Lock {
+		return nil, nil //nolint:nilnil
+	}
+
+	if cfg.s3Bucket == "" {
+		return nil, fmt.Errorf("s3 lock requires a store bucket")
+	}
+
+	s3Lock, err := lock.NewS3Lock(lock.S3Config{
+		Bucket:          cfg.s3Bucket,
+		Endpoint:        cfg.s3Endpoint,
+		Region:          cfg.s3Region,
+		UsePathStyle:    cfg.s3PathStyle,
+		AccessKeyID:     cfg.s3AccessKeyID,
+		SecretAccessKey: cfg.s3SecretKey,
+		SessionToken:    cfg.s3SessionToken,
+		DisableSSL:      cfg.s3DisableSSL,
+		LeaseDuration:   cfg.lockLease,
+		Registerer:      prometheus.DefaultRegisterer,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating s3 lock %w", err)
+	}
+
+	return s3Lock, nil
+}
+
 func (cfg serverConfig) getStore() (store.ObjectStore, error) {
 	var (
 		err   error
@@ -291,16 +1355,32 @@ func (cfg serverConfig) getStore() (store.ObjectStore, error) {
 
 	if cfg.s3Bucket != "" {
 		store, err = s3.New(s3.Config{
-			Bucket:   cfg.s3Bucket,
-			Endpoint: cfg.s3Endpoint,
-			Region:   cfg.s3Region,
+			Bucket:            cfg.s3Bucket,
+			Endpoint:          cfg.s3Endpoint,
+			Region:            cfg.s3Region,
+			UsePathStyle:      cfg.s3PathStyle,
+			AccessKeyID:       cfg.s3AccessKeyID,
+			SecretAccessKey:   cfg.s3SecretKey,
+			SessionToken:      cfg.s3SessionToken,
+			DisableSSL:        cfg.s3DisableSSL,
+			Tags:              cfg.storeTags,
+			ChecksumAlgorithm: cfg.storeChecksum,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("creating s3 store %w", err)
 		}
 	} else {
+		if len(cfg.storeTags) > 0 {
+			return nil, fmt.Errorf("object tags require a store bucket")
+		}
+
+		if cfg.storeChecksum != util.DefaultChecksumAlgorithm {
+			return nil, fmt.Errorf("checksum algorithm requires a store bucket")
+		}
+
 		store, err = client.NewStoreClient(client.StoreClientConfig{
-			Server: cfg.storeURL,
+			Servers:           cfg.storeURLs,
+			ParallelDownloads: cfg.storeParallel,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("creating store %w", err)
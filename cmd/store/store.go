@@ -2,13 +2,15 @@
 package store
 
 import (
+	"context"
 	"fmt"
-	"log/slog"
 	"os"
 	"time"
 
 	"github.com/grafana/k6build/pkg/httpserver"
+	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/store/file"
+	"github.com/grafana/k6build/pkg/store/s3"
 	"github.com/grafana/k6build/pkg/store/server"
 	"github.com/grafana/k6build/pkg/util"
 
@@ -25,6 +27,28 @@ Objects can be retrieved by a download url returned when the object is stored.
 
 The --download-url specifies the base URL for downloading objects. This is necessary to allow
 downloading the objects from different machines.
+
+Using --s3-bucket, the objects are stored in a s3 bucket and the store directory keeps a local copy
+of the objects retrieved, so frequently requested objects are served without accessing the bucket.
+Objects are stored in both the bucket and the store directory.
+
+Using --access-log, the server logs the method, path, status, duration and size of the response of
+each request served.
+
+Browser-based tools served from other origins can download objects if their origins are given in
+--cors-origins ("*" allows any origin). By default, cross-origin requests are not allowed.
+
+The objects' checksums have the form <algorithm>:<hex digest>. The algorithm is set using
+--checksum-algorithm (sha256 or sha512).
+
+Objects can be pinned using POST /store/<id>/pin and unpinned using DELETE /store/<id>/pin.
+Pinned objects are not deleted when sweeping expired artifacts. In a s3 bucket, pinned objects have
+the tag k6build-pinned=true. As bucket lifecycle rules cannot exclude objects by tag, the expiration
+tag given with --s3-expiration-tag (expire-after by default) is removed from pinned objects, and
+restored when they are unpinned.
+
+The server exposes a liveness check at /alive and a readiness check at /readyz. The readiness check
+returns 503 (Service Unavailable) if the store directory is not writable or the bucket is not accessible.
 `
 
 	example = `
@@ -37,13 +61,16 @@ curl -x POST http://localhost:9000/store/objectID -d "object content" | jq .
 	"Error": "",
 	"Object": {
 	  "ID": "objectID",
-	  "Checksum": "17d3eb873fe4b1aac4f9d2505aefbb5b53b9a7f34a6aadd561be104c0e9d678b",
+	  "Checksum": "sha256:17d3eb873fe4b1aac4f9d2505aefbb5b53b9a7f34a6aadd561be104c0e9d678b",
 	  "URL": "http://external.url:9000/store/objectID/download"
 	}
       }
 
 # download object from another machine using the external url
 curl http://external.url:9000/store/objectID/download
+
+# pin object to keep it from expiring
+curl -X POST http://localhost:9000/store/objectID/pin
 `
 )
 
@@ -51,9 +78,14 @@ curl http://external.url:9000/store/objectID/download
 func New() *cobra.Command {
 	var (
 		storeDir        string
+		checksum        string
 		storeSrvURL     string
+		s3Config        s3.Config
 		port            int
 		logLevel        string
+		logFormat       string
+		accessLog       bool
+		cors            httpserver.CORSConfig
 		shutdownTimeout time.Duration
 	)
 
@@ -67,31 +99,36 @@ func New() *cobra.Command {
 		// this is needed to prevent cobra to print errors reported by subcommands in the stderr
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, _ []string) error {
-			// set log
-			ll, err := util.ParseLogLevel(logLevel)
+			log, err := util.NewLogger(os.Stderr, logLevel, logFormat)
 			if err != nil {
-				return fmt.Errorf("parsing log level %w", err)
+				return fmt.Errorf("creating logger %w", err)
 			}
 
-			log := slog.New(
-				slog.NewTextHandler(
-					os.Stderr,
-					&slog.HandlerOptions{
-						Level: ll,
-					},
-				),
-			)
-
-			store, err := file.NewFileStore(storeDir)
+			objectStore, err := file.New(file.Config{Dir: storeDir, ChecksumAlgorithm: checksum})
 			if err != nil {
 				return fmt.Errorf("creating object store %w", err)
 			}
 			log.Info("file store", "dir", storeDir)
 
+			if s3Config.Bucket != "" {
+				s3Config.ChecksumAlgorithm = checksum
+				remote, err := s3.New(s3Config)
+				if err != nil {
+					return fmt.Errorf("creating s3 store %w", err)
+				}
+
+				objectStore, err = store.NewTieredStore(objectStore, remote)
+				if err != nil {
+					return fmt.Errorf("creating object store %w", err)
+				}
+				log.Info("s3 store", "bucket", s3Config.Bucket)
+			}
+
 			config := server.StoreServerConfig{
 				BaseURL: storeSrvURL,
-				Store:   store,
+				Store:   objectStore,
 				Log:     log,
+				CORS:    cors,
 			}
 			storeSrv, err := server.NewStoreServer(config)
 			if err != nil {
@@ -102,7 +139,9 @@ func New() *cobra.Command {
 				Logger:            log,
 				Port:              port,
 				LivenessProbe:     true,
+				ReadinessCheck:    func(ctx context.Context) error { return store.HealthCheck(ctx, objectStore) },
 				ReadHeaderTimeout: 5 * time.Second,
+				AccessLog:         accessLog,
 			}
 
 			srv := httpserver.NewServer(srvConfig)
@@ -118,12 +157,48 @@ func New() *cobra.Command {
 	}
 
 	cmd.Flags().StringVarP(&storeDir, "store-dir", "c", "/tmp/k6build/store", "object store directory")
+	cmd.Flags().StringVar(
+		&checksum,
+		"checksum-algorithm",
+		util.DefaultChecksumAlgorithm,
+		"algorithm used for the checksum of the objects (sha256 or sha512)",
+	)
 	cmd.Flags().IntVarP(&port, "port", "p", 9000, "port server will listen")
 	cmd.Flags().StringVarP(&storeSrvURL,
 		"download-url", "d", "", "base url used for downloading objects."+
 			"\nIf not specified http://localhost:<port> is used",
 	)
+	cmd.Flags().StringVar(&s3Config.Bucket, "s3-bucket", "", "s3 bucket for storing the objects")
+	cmd.Flags().StringVar(&s3Config.Endpoint, "s3-endpoint", "", "s3 endpoint")
+	cmd.Flags().StringVar(&s3Config.Region, "s3-region", "", "aws region")
+	cmd.Flags().BoolVar(&s3Config.UsePathStyle, "s3-path-style", false, "use path-style addressing for the s3 bucket")
+	cmd.Flags().StringVar(
+		&s3Config.ExpirationTag,
+		"s3-expiration-tag",
+		s3.DefaultExpirationTag,
+		"key of the tag used by the bucket lifecycle rules for expiring objects. Removed from pinned objects",
+	)
 	cmd.Flags().StringVarP(&logLevel, "log-level", "l", "INFO", "log level")
+	cmd.Flags().StringVar(&logFormat, "log-format", "text", "log format (text or json)")
+	cmd.Flags().BoolVar(&accessLog, "access-log", false, "log each request served")
+	cmd.Flags().StringSliceVar(
+		&cors.AllowedOrigins,
+		"cors-origins",
+		nil,
+		"origins allowed to make cross-origin requests (e.g. https://ui.example.com). \"*\" allows any origin.",
+	)
+	cmd.Flags().StringSliceVar(
+		&cors.AllowedMethods,
+		"cors-methods",
+		httpserver.DefaultCORSMethods,
+		"methods allowed in cross-origin requests",
+	)
+	cmd.Flags().StringSliceVar(
+		&cors.AllowedHeaders,
+		"cors-headers",
+		httpserver.DefaultCORSHeaders,
+		"headers allowed in cross-origin requests",
+	)
 	cmd.Flags().DurationVar(
 		&shutdownTimeout,
 		"shutdown-timeout",
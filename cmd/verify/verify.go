@@ -0,0 +1,154 @@
+// Package verify implements the verify-reproducible command
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/builder"
+	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/grafana/k6build/pkg/store/file"
+
+	"github.com/spf13/cobra"
+)
+
+// ErrNotReproducible is returned when the builds produce different binaries
+var ErrNotReproducible = errors.New("build is not reproducible")
+
+const (
+	long = `
+k6build verify-reproducible builds the same custom k6 binary twice, bypassing any cached
+artifact, and reports if both builds produced the same binary. Each build uses its own empty
+go build cache, so the builds are independent. If the checksums differ, a summary of the
+differences between the builds (e.g. go version, module versions) is printed.
+
+Exits with a non-zero status if the builds are not reproducible.
+Requires the golang toolchain and git.
+`
+
+	example = `
+# verify k6 v0.51.0 with k6/x/kubernetes v0.9.0 builds reproducibly
+k6build verify-reproducible -k v0.51.0 -d k6/x/kubernetes:v0.9.0 -p linux/amd64
+
+platform: linux/amd64
+k6: v0.51.0
+k6/x/kubernetes: v0.9.0
+run 1 checksum: 7f06720503c80153816b4ef9f58571c2fce620e0447fba1bb092188ff87e322d
+run 2 checksum: 7f06720503c80153816b4ef9f58571c2fce620e0447fba1bb092188ff87e322d
+reproducible: true
+`
+)
+
+// New creates new cobra command for the verify-reproducible command.
+func New() *cobra.Command { //nolint:funlen
+	var (
+		opts     builder.Opts
+		catalogs string
+		deps     []string
+		k6       string
+		platform string
+		pins     map[string]string
+		modules  map[string]string
+		race     bool
+		cover    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "verify-reproducible",
+		Short:   "verify a custom k6 binary builds reproducibly",
+		Long:    long,
+		Example: example,
+		// prevent the usage help to printed to stderr when an error is reported by a subcommand
+		SilenceUsage: true,
+		// this is needed to prevent cobra to print errors reported by subcommands in the stderr
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// the builds bypass the store, but the builder requires one
+			storeDir, err := os.MkdirTemp("", "k6build-verify-*")
+			if err != nil {
+				return fmt.Errorf("creating store dir %w", err)
+			}
+			defer os.RemoveAll(storeDir) //nolint:errcheck
+
+			store, err := file.NewFileStore(storeDir)
+			if err != nil {
+				return fmt.Errorf("creating store %w", err)
+			}
+
+			// pins and extra modules are allowed in local builds
+			opts.AllowModulePins = true
+			opts.AllowExtraModules = true
+
+			b, err := builder.New(cmd.Context(), builder.Config{
+				Opts:    opts,
+				Catalog: catalogs,
+				Store:   store,
+			})
+			if err != nil {
+				return fmt.Errorf("configuring the builder %w", err)
+			}
+
+			buildDeps := []k6build.Dependency{}
+			for _, d := range deps {
+				name, constrains, _ := strings.Cut(d, ":")
+				if constrains == "" {
+					constrains = "*"
+				}
+				buildDeps = append(buildDeps, k6build.Dependency{Name: name, Constraints: constrains})
+			}
+
+			ctx := k6build.WithBuildOptions(cmd.Context(), k6build.BuildOptions{
+				Pins:         pins,
+				ExtraModules: modules,
+				Race:         race,
+				Cover:        cover,
+			})
+			if platform == "" {
+				platform = runtime.GOOS + "/" + runtime.GOARCH
+			}
+
+			report, err := b.VerifyReproducible(ctx, platform, k6, buildDeps)
+			if err != nil {
+				return fmt.Errorf("building %w", err)
+			}
+
+			fmt.Print(report.String())
+
+			if !report.Reproducible {
+				return ErrNotReproducible
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVarP(&deps, "dependency", "d", nil, "list of dependencies in form package:constrains")
+	cmd.Flags().StringVarP(&k6, "k6", "k", "*", "k6 version constrains")
+	cmd.Flags().StringVarP(&platform, "platform", "p", "", "target platform (default GOOS/GOARCH)")
+	cmd.Flags().StringVarP(&catalogs, "catalog", "c", catalog.DefaultCatalogURL, "dependencies catalog")
+	cmd.Flags().BoolVarP(&opts.Verbose, "verbose", "v", false, "print build process output")
+	cmd.Flags().BoolVarP(&opts.CopyGoEnv, "copy-go-env", "g", true, "copy go environment")
+	cmd.Flags().StringToStringVarP(&opts.Env, "env", "e", nil, "build environment variables")
+	cmd.Flags().StringToStringVar(&pins, "pin", nil, "pin the version of a go module (e.g. google.golang.org/grpc=v1.64.1)")
+	cmd.Flags().StringToStringVar(
+		&modules,
+		"extra-module",
+		nil,
+		"add a go module that is not an extension to the build (e.g. github.com/example/logger=v0.1.0)",
+	)
+	cmd.Flags().BoolVar(&race, "race", false, "build with the race detector. Requires building for the native platform")
+	cmd.Flags().BoolVar(&cover, "cover", false, "build with coverage instrumentation")
+	cmd.Flags().BoolVar(
+		&opts.AllowBuildSemvers,
+		"allow-build-semvers",
+		false,
+		"allow building versions with build metadata (e.g v0.0.0+build)"+
+			"\nand dependencies from a commit (e.g. k6/x/kubernetes:commit:0123abc).",
+	)
+
+	return cmd
+}
@@ -0,0 +1,337 @@
+// Package warmup implements the warmup command
+package warmup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/client"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrInvalidManifest = errors.New("invalid manifest") //nolint:revive
+	ErrWarmupFailed    = errors.New("warmup failed")
+)
+
+const (
+	long = `
+Pre-builds the custom k6 binaries listed in a manifest using a k6build server, so the first
+request for them is served from the server's cache (e.g. before announcing a release).
+
+The manifest is a YAML file with a list of builds. Each build is requested for every combination
+of its k6 versions and platforms. Use "all" as platform for building all the platforms supported
+by the server. Dependencies are in the form package:constrains.
+
+    builds:
+      - k6: [v0.51.0, v0.52.0]
+        platforms: [linux/amd64, darwin/arm64]
+        dependencies:
+          - k6/x/kubernetes:v0.9.0
+          - k6/x/output-kafka:v0.7.0
+      - k6: [v0.52.0]
+        platforms: [all]
+
+The builds are requested concurrently. The result of each build is reported when it completes
+as "cached" if the artifact was already built, "built" if it was built by this request, or
+"failed". A summary is printed at the end.
+
+By default, all the builds are requested even if some fail. Use --fail-fast for stopping at the
+first failure. Exits with a non-zero status if any build failed.
+`
+
+	example = `
+# pre-build the binaries listed in manifest.yaml, 8 at a time
+k6build warmup -s http://localhost:8000 -f manifest.yaml -c 8
+
+[1/6] cached k6:v0.51.0 linux/amd64 k6/x/kubernetes:v0.9.0 k6/x/output-kafka:v0.7.0
+[2/6] built k6:v0.52.0 linux/amd64 k6/x/kubernetes:v0.9.0 k6/x/output-kafka:v0.7.0
+...
+total: 6 cached: 2 built: 4 failed: 0
+`
+)
+
+// manifest defines the builds requested for warming up the cache
+type manifest struct {
+	Builds []manifestBuild `yaml:"builds"`
+}
+
+// manifestBuild defines a set of dependencies built for multiple k6 versions and platforms
+type manifestBuild struct {
+	K6           []string `yaml:"k6"`
+	Platforms    []string `yaml:"platforms"`
+	Dependencies []string `yaml:"dependencies"`
+}
+
+// buildRequest is a single build of the manifest
+type buildRequest struct {
+	k6       string
+	platform string
+	deps     []k6build.Dependency
+}
+
+func (r buildRequest) String() string {
+	desc := []string{"k6:" + r.k6, r.platform}
+	for _, d := range r.deps {
+		desc = append(desc, d.Name+":"+d.Constraints)
+	}
+
+	return strings.Join(desc, " ")
+}
+
+// build status
+const (
+	statusCached  = "cached"
+	statusBuilt   = "built"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// buildResult is the outcome of a build request
+type buildResult struct {
+	request buildRequest
+	status  string
+	err     error
+}
+
+// summary counts the results by status
+type summary struct {
+	total   int
+	cached  int
+	built   int
+	failed  int
+	skipped int
+}
+
+func (s summary) String() string {
+	str := fmt.Sprintf("total: %d cached: %d built: %d failed: %d", s.total, s.cached, s.built, s.failed)
+	if s.skipped > 0 {
+		str += fmt.Sprintf(" skipped: %d", s.skipped)
+	}
+
+	return str
+}
+
+// New creates new cobra command for the warmup command.
+func New() *cobra.Command {
+	var (
+		config      client.BuildServiceClientConfig
+		file        string
+		concurrency int
+		failFast    bool
+	)
+
+	cmd := &cobra.Command{
+		Use:     "warmup",
+		Short:   "pre-build the custom k6 binaries listed in a manifest using a remote build server",
+		Long:    long,
+		Example: example,
+		// prevent the usage help to printed to stderr when an error is reported by a subcommand
+		SilenceUsage: true,
+		// this is needed to prevent cobra to print errors reported by subcommands in the stderr
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			if concurrency < 1 {
+				return errors.New("concurrency must be at least 1")
+			}
+
+			srv, err := client.NewBuildServiceClient(config)
+			if err != nil {
+				return fmt.Errorf("configuring the client %w", err)
+			}
+
+			m, err := loadManifest(file)
+			if err != nil {
+				return err
+			}
+
+			requests, err := m.requests(cmd.Context(), srv)
+			if err != nil {
+				return err
+			}
+
+			return warmup(cmd.Context(), srv, requests, concurrency, failFast, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().StringVarP(&config.URL, "server", "s", "http://localhost:8000", "url for build server")
+	cmd.Flags().StringVarP(&file, "file", "f", "", "manifest with the builds (required)")
+	_ = cmd.MarkFlagRequired("file")
+	cmd.Flags().IntVarP(&concurrency, "concurrency", "c", 4, "maximum number of concurrent build requests")
+	cmd.Flags().BoolVar(&failFast, "fail-fast", false, "stop requesting builds after the first failure")
+
+	return cmd
+}
+
+// loadManifest reads the manifest from a file
+func loadManifest(file string) (manifest, error) {
+	content, err := os.ReadFile(file) //nolint:gosec
+	if err != nil {
+		return manifest{}, fmt.Errorf("reading manifest %w", err)
+	}
+
+	m := manifest{}
+	if err = yaml.Unmarshal(content, &m); err != nil {
+		return manifest{}, fmt.Errorf("%w: %w", ErrInvalidManifest, err)
+	}
+
+	return m, nil
+}
+
+// requests returns the build requests for every combination of k6 version and platform of each build.
+// The "all" platform is expanded to the platforms supported by the build service.
+func (m manifest) requests(ctx context.Context, srv k6build.BuildService) ([]buildRequest, error) {
+	if len(m.Builds) == 0 {
+		return nil, fmt.Errorf("%w: no builds", ErrInvalidManifest)
+	}
+
+	requests := []buildRequest{}
+	for i, b := range m.Builds {
+		if len(b.K6) == 0 || len(b.Platforms) == 0 {
+			return nil, fmt.Errorf("%w: build %d must have k6 versions and platforms", ErrInvalidManifest, i+1)
+		}
+
+		deps := []k6build.Dependency{}
+		for _, d := range b.Dependencies {
+			name, constrains, _ := strings.Cut(d, ":")
+			if name == "" {
+				return nil, fmt.Errorf("%w: build %d has an invalid dependency %q", ErrInvalidManifest, i+1, d)
+			}
+			if constrains == "" {
+				constrains = "*"
+			}
+			deps = append(deps, k6build.Dependency{Name: name, Constraints: constrains})
+		}
+
+		for _, k6 := range b.K6 {
+			for _, platform := range b.Platforms {
+				for _, p := range client.ExpandPlatform(ctx, srv, platform) {
+					requests = append(requests, buildRequest{k6: k6, platform: p, deps: deps})
+				}
+			}
+		}
+	}
+
+	return requests, nil
+}
+
+// warmup requests the builds using up to concurrency workers, reporting the result of each build as it completes
+// and a summary at the end. If failFast is set, the pending builds are skipped after the first failure.
+func warmup(
+	ctx context.Context,
+	srv k6build.BuildService,
+	requests []buildRequest,
+	concurrency int,
+	failFast bool,
+	out io.Writer,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan buildResult)
+	pending := make(chan buildRequest)
+	wg := sync.WaitGroup{}
+	for range min(concurrency, len(requests)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range pending {
+				result := warmupBuild(ctx, srv, r)
+				// cancel before reporting the failure, so no other build starts
+				if failFast && result.status == statusFailed {
+					cancel()
+				}
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		for _, r := range requests {
+			pending <- r
+		}
+		close(pending)
+		wg.Wait()
+		close(results)
+	}()
+
+	s := summary{total: len(requests)}
+	var firstErr error
+	done := 0
+	for r := range results {
+		if r.status == statusSkipped {
+			s.skipped++
+			continue
+		}
+
+		done++
+		switch r.status {
+		case statusCached:
+			s.cached++
+		case statusBuilt:
+			s.built++
+		default:
+			s.failed++
+			if firstErr == nil {
+				firstErr = fmt.Errorf("building %s: %w", r.request, r.err)
+			}
+		}
+
+		if r.err != nil {
+			fmt.Fprintf(out, "[%d/%d] %s %s: %v\n", done, s.total, r.status, r.request, r.err)
+		} else {
+			fmt.Fprintf(out, "[%d/%d] %s %s\n", done, s.total, r.status, r.request)
+		}
+	}
+
+	fmt.Fprintln(out, s)
+
+	if firstErr == nil {
+		return nil
+	}
+
+	if failFast {
+		return firstErr
+	}
+
+	return fmt.Errorf("%w: %d of %d builds failed", ErrWarmupFailed, s.failed, s.total)
+}
+
+// warmupBuild requests a build, checking first if the artifact is already built if the build service can plan builds
+func warmupBuild(ctx context.Context, srv k6build.BuildService, r buildRequest) buildResult {
+	if ctx.Err() != nil {
+		return buildResult{request: r, status: statusSkipped}
+	}
+
+	if planner, ok := srv.(k6build.Planner); ok {
+		plan, err := planner.Plan(ctx, r.platform, r.k6, r.deps)
+		if err != nil && ctx.Err() != nil {
+			return buildResult{request: r, status: statusSkipped}
+		}
+		if err != nil {
+			return buildResult{request: r, status: statusFailed, err: err}
+		}
+		if plan.Cached {
+			return buildResult{request: r, status: statusCached}
+		}
+	}
+
+	_, err := srv.Build(ctx, r.platform, r.k6, r.deps)
+	if err != nil && ctx.Err() != nil {
+		// canceled after a failure of another build
+		return buildResult{request: r, status: statusSkipped}
+	}
+	if err != nil {
+		return buildResult{request: r, status: statusFailed, err: err}
+	}
+
+	return buildResult{request: r, status: statusBuilt}
+}
@@ -0,0 +1,231 @@
+package warmup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/grafana/k6build"
+)
+
+var errBuildFailed = errors.New("build failed")
+
+// fakeService plans and builds artifacts, reporting as cached the k6 versions in cached and
+// failing the builds for the k6 versions in failed
+type fakeService struct {
+	cached []string
+	failed []string
+	mutex  sync.Mutex
+	builds int
+}
+
+func (f *fakeService) Build(
+	_ context.Context,
+	platform string,
+	k6 string,
+	_ []k6build.Dependency,
+) (k6build.Artifact, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.builds++
+
+	for _, v := range f.failed {
+		if v == k6 {
+			return k6build.Artifact{}, errBuildFailed
+		}
+	}
+
+	return k6build.Artifact{Platform: platform}, nil
+}
+
+func (f *fakeService) Resolve(context.Context, string, []k6build.Dependency) (map[string]string, error) {
+	return nil, nil
+}
+
+func (f *fakeService) Plan(
+	_ context.Context,
+	platform string,
+	k6 string,
+	_ []k6build.Dependency,
+) (k6build.BuildPlan, error) {
+	for _, v := range f.cached {
+		if v == k6 {
+			return k6build.BuildPlan{Platform: platform, Cached: true}, nil
+		}
+	}
+
+	return k6build.BuildPlan{Platform: platform}, nil
+}
+
+func (f *fakeService) Platforms(context.Context) ([]string, error) {
+	return []string{"linux/amd64", "linux/arm64", "windows/amd64"}, nil
+}
+
+func TestManifest(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		manifest  string
+		expect    []string
+		expectErr error
+	}{
+		{
+			title: "matrix",
+			manifest: `
+builds:
+  - k6: [v0.1.0, v0.2.0]
+    platforms: [linux/amd64, linux/arm64]
+    dependencies: ["k6/x/ext:v0.1.0", "k6/x/ext2"]
+`,
+			expect: []string{
+				"k6:v0.1.0 linux/amd64 k6/x/ext:v0.1.0 k6/x/ext2:*",
+				"k6:v0.1.0 linux/arm64 k6/x/ext:v0.1.0 k6/x/ext2:*",
+				"k6:v0.2.0 linux/amd64 k6/x/ext:v0.1.0 k6/x/ext2:*",
+				"k6:v0.2.0 linux/arm64 k6/x/ext:v0.1.0 k6/x/ext2:*",
+			},
+		},
+		{
+			title: "all platforms",
+			manifest: `
+builds:
+  - k6: [v0.1.0]
+    platforms: [all]
+`,
+			expect: []string{
+				"k6:v0.1.0 linux/amd64",
+				"k6:v0.1.0 linux/arm64",
+				"k6:v0.1.0 windows/amd64",
+			},
+		},
+		{
+			title:     "no builds",
+			manifest:  `builds: []`,
+			expectErr: ErrInvalidManifest,
+		},
+		{
+			title: "missing platforms",
+			manifest: `
+builds:
+  - k6: [v0.1.0]
+`,
+			expectErr: ErrInvalidManifest,
+		},
+		{
+			title:     "invalid yaml",
+			manifest:  `builds: {`,
+			expectErr: ErrInvalidManifest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			file := filepath.Join(t.TempDir(), "manifest.yaml")
+			if err := os.WriteFile(file, []byte(tc.manifest), 0o600); err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+
+			requests, err := loadManifestRequests(file)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			got := []string{}
+			for _, r := range requests {
+				got = append(got, r.String())
+			}
+
+			if strings.Join(got, "\n") != strings.Join(tc.expect, "\n") {
+				t.Fatalf("expected requests\n%s\ngot\n%s", strings.Join(tc.expect, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+// loadManifestRequests loads the manifest from the file and returns its build requests
+func loadManifestRequests(file string) ([]buildRequest, error) {
+	m, err := loadManifest(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.requests(context.TODO(), &fakeService{})
+}
+
+func TestWarmup(t *testing.T) {
+	t.Parallel()
+
+	requests := []buildRequest{
+		{k6: "v0.1.0", platform: "linux/amd64"},
+		{k6: "v0.2.0", platform: "linux/amd64"},
+		{k6: "v0.3.0", platform: "linux/amd64"},
+		{k6: "v0.4.0", platform: "linux/amd64"},
+	}
+
+	testCases := []struct {
+		title         string
+		failed        []string
+		concurrency   int
+		failFast      bool
+		expectSummary string
+		expectErr     error
+		expectBuilds  int
+	}{
+		{
+			title:         "cached and built",
+			concurrency:   2,
+			expectSummary: "total: 4 cached: 1 built: 3 failed: 0",
+			expectBuilds:  3,
+		},
+		{
+			title:         "continue on error",
+			failed:        []string{"v0.2.0"},
+			concurrency:   2,
+			expectSummary: "total: 4 cached: 1 built: 2 failed: 1",
+			expectErr:     ErrWarmupFailed,
+			expectBuilds:  3,
+		},
+		{
+			title:         "fail fast",
+			failed:        []string{"v0.2.0"},
+			concurrency:   1,
+			failFast:      true,
+			expectSummary: "total: 4 cached: 1 built: 0 failed: 1 skipped: 2",
+			expectErr:     errBuildFailed,
+			expectBuilds:  1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			srv := &fakeService{cached: []string{"v0.1.0"}, failed: tc.failed}
+			out := &bytes.Buffer{}
+
+			err := warmup(context.TODO(), srv, requests, tc.concurrency, tc.failFast, out)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if !strings.HasSuffix(out.String(), tc.expectSummary+"\n") {
+				t.Fatalf("expected summary %q got %q", tc.expectSummary, out.String())
+			}
+
+			if srv.builds != tc.expectBuilds {
+				t.Fatalf("expected %d builds got %d", tc.expectBuilds, srv.builds)
+			}
+		})
+	}
+}
@@ -9,6 +9,27 @@ import (
 // ErrReasonUnknown signals the reason for an WrappedError is unknown
 var ErrReasonUnknown = errors.New("reason unknown")
 
+// ErrorCode identifies the cause of an error in a way that is stable across the build service API.
+// Clients can use it instead of comparing error messages.
+type ErrorCode string
+
+const (
+	// ErrorCodeBuildFailed signals the artifact failed to build
+	ErrorCodeBuildFailed ErrorCode = "BUILD_FAILED"
+	// ErrorCodeCannotSatisfy signals the constrains of a dependency cannot be satisfied
+	ErrorCodeCannotSatisfy ErrorCode = "CANNOT_SATISFY"
+	// ErrorCodeDownloadFailed signals downloading the dependencies failed (e.g. the GOPROXY is not available)
+	ErrorCodeDownloadFailed ErrorCode = "DOWNLOAD_FAILED"
+	// ErrorCodeInvalidPlatform signals the target platform is not valid
+	ErrorCodeInvalidPlatform ErrorCode = "INVALID_PLATFORM"
+	// ErrorCodeInvalidRequest signals the request is not valid
+	ErrorCodeInvalidRequest ErrorCode = "INVALID_REQUEST"
+	// ErrorCodeTooManyBuilds signals the limit of concurrent builds was exceeded
+	ErrorCodeTooManyBuilds ErrorCode = "TOO_MANY_BUILDS"
+	// ErrorCodeUnknownDependency signals a dependency is not known
+	ErrorCodeUnknownDependency ErrorCode = "UNKNOWN_DEPENDENCY"
+)
+
 // WrappedError represents an error returned by the build service
 // This custom error type facilitates extracting the reason of an error
 // by using errors.Unwrap method.
@@ -18,8 +39,9 @@ var ErrReasonUnknown = errors.New("reason unknown")
 // - A nil WrappedError 'e' will not satisfy errors.Is(e, nil)
 // - Is method will not
 type WrappedError struct {
-	Err    error `json:"error,omitempty"`
-	Reason error `json:"reason,omitempty"`
+	Err    error     `json:"error,omitempty"`
+	Reason error     `json:"reason,omitempty"`
+	Code   ErrorCode `json:"code,omitempty"`
 }
 
 // Error returns the Error as a string
@@ -64,6 +86,7 @@ func (e *WrappedError) Unwrap() error {
 type jsonError struct {
 	Err    string     `json:"error,omitempty"`
 	Reason *jsonError `json:"reason,omitempty"`
+	Code   ErrorCode  `json:"code,omitempty"`
 }
 
 func wrap(e *jsonError) error {
@@ -71,11 +94,11 @@ func wrap(e *jsonError) error {
 		return nil
 	}
 	err := errors.New(e.Err)
-	if e.Reason == nil {
+	if e.Reason == nil && e.Code == "" {
 		return err
 	}
 
-	return NewWrappedError(err, wrap(e.Reason))
+	return NewCodedError(e.Code, err, wrap(e.Reason))
 }
 
 func unwrap(e error) *jsonError {
@@ -88,7 +111,7 @@ func unwrap(e error) *jsonError {
 		return &jsonError{Err: e.Error()}
 	}
 
-	return &jsonError{Err: err.Err.Error(), Reason: unwrap(errors.Unwrap(err))}
+	return &jsonError{Err: err.Err.Error(), Reason: unwrap(errors.Unwrap(err)), Code: err.Code}
 }
 
 // MarshalJSON implements the json.Marshaler interface for the WrappedError type
@@ -106,6 +129,7 @@ func (e *WrappedError) UnmarshalJSON(data []byte) error {
 
 	e.Err = errors.New(val.Err)
 	e.Reason = wrap(val.Reason)
+	e.Code = val.Code
 	return nil
 }
 
@@ -121,6 +145,28 @@ func NewWrappedError(err error, reason error) *WrappedError {
 	}
 }
 
+// NewCodedError creates an Error from an error and a reason, identified by the given code
+// If the reason is nil, ErrReasonUnknown is used
+func NewCodedError(code ErrorCode, err error, reason error) *WrappedError {
+	wrapped := NewWrappedError(err, reason)
+	wrapped.Code = code
+	return wrapped
+}
+
+// ErrorCodeOf returns the code set at the origin of the error, that is, the code of the innermost
+// WrappedError in the error's chain that has a code. Returns an empty code if no error has one.
+func ErrorCodeOf(err error) ErrorCode {
+	code := ErrorCode("")
+	for err != nil {
+		if e, ok := err.(*WrappedError); ok && e.Code != "" { //nolint:errorlint
+			code = e.Code
+		}
+		err = errors.Unwrap(err)
+	}
+
+	return code
+}
+
 // AsError returns an error as an Error, if possible
 func AsError(e error) (*WrappedError, bool) {
 	err := &WrappedError{}
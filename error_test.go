@@ -110,6 +110,18 @@ func Test_JsonSerialization(t *testing.T) {
 			err:    NewWrappedError(err, nil),
 			expect: []byte(`{"error":"error","reason":{"error":"reason unknown"}}`),
 		},
+		{
+			title:  "error with code",
+			err:    NewCodedError(ErrorCodeBuildFailed, err, reason),
+			expect: []byte(`{"error":"error","reason":{"error":"reason"},"code":"BUILD_FAILED"}`),
+		},
+		{
+			title: "error with code in cause",
+			err:   NewWrappedError(err, NewCodedError(ErrorCodeCannotSatisfy, reason, root)),
+			expect: []byte(
+				`{"error":"error","reason":{"error":"reason","reason":{"error":"root"},"code":"CANNOT_SATISFY"}}`,
+			),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -137,3 +149,59 @@ func Test_JsonSerialization(t *testing.T) {
 		})
 	}
 }
+
+func Test_ErrorCodeOf(t *testing.T) {
+	t.Parallel()
+
+	var (
+		err    = errors.New("error")
+		reason = errors.New("reason")
+	)
+
+	testCases := []struct {
+		title  string
+		err    error
+		expect ErrorCode
+	}{
+		{
+			title:  "error without code",
+			err:    NewWrappedError(err, reason),
+			expect: "",
+		},
+		{
+			title:  "error with code",
+			err:    NewCodedError(ErrorCodeInvalidPlatform, err, reason),
+			expect: ErrorCodeInvalidPlatform,
+		},
+		{
+			title:  "code in reason",
+			err:    NewWrappedError(err, NewCodedError(ErrorCodeCannotSatisfy, reason, nil)),
+			expect: ErrorCodeCannotSatisfy,
+		},
+		{
+			title:  "code of origin",
+			err:    NewCodedError(ErrorCodeBuildFailed, err, NewCodedError(ErrorCodeCannotSatisfy, reason, nil)),
+			expect: ErrorCodeCannotSatisfy,
+		},
+		{
+			title:  "wrapped coded error",
+			err:    fmt.Errorf("wrapped %w", NewCodedError(ErrorCodeInvalidRequest, err, reason)),
+			expect: ErrorCodeInvalidRequest,
+		},
+		{
+			title:  "other error",
+			err:    err,
+			expect: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if code := ErrorCodeOf(tc.err); code != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, code)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Package buildinfo provides the version information of the k6build binary
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"github.com/grafana/k6build/pkg/api"
+)
+
+// Build information injected at build time. For example
+//
+//	go build -ldflags "-X github.com/grafana/k6build/internal/buildinfo.version=v0.1.0 \
+//	  -X github.com/grafana/k6build/internal/buildinfo.commit=0123456789 \
+//	  -X github.com/grafana/k6build/internal/buildinfo.buildDate=2025-01-01T00:00:00Z"
+//
+// If not injected, the version and commit are obtained from the binary's build information.
+var (
+	version   = "" //nolint:gochecknoglobals
+	commit    = "" //nolint:gochecknoglobals
+	buildDate = "" //nolint:gochecknoglobals
+)
+
+// Version returns the version information of the running k6build binary
+func Version() api.VersionResponse {
+	info := api.VersionResponse{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		if info.Version == "" {
+			info.Version = "unreleased"
+		}
+		return info
+	}
+
+	if info.Version == "" {
+		info.Version = buildInfo.Main.Version
+	}
+
+	if info.Commit != "" {
+		return info
+	}
+
+	dirty := false
+	for _, s := range buildInfo.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Commit = s.Value[:min(len(s.Value), 10)]
+		case "vcs.modified":
+			dirty = s.Value == "true"
+		default:
+		}
+	}
+
+	if info.Commit != "" && dirty {
+		info.Commit += "-dirty"
+	}
+
+	return info
+}
+
+// String returns the version information in a human readable form
+func String() string {
+	info := Version()
+
+	details := []string{}
+	if info.Commit != "" {
+		details = append(details, "commit/"+info.Commit)
+	}
+	if info.BuildDate != "" {
+		details = append(details, "built "+info.BuildDate)
+	}
+	details = append(details, info.GoVersion, info.Platform)
+
+	// cobra adds a "v" prefix to the version
+	return fmt.Sprintf("%s (%s)", strings.TrimLeft(info.Version, "v"), strings.Join(details, ", "))
+}
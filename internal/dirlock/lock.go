@@ -0,0 +1,47 @@
+// Package dirlock implements an advisory lock on a directory that prevents concurrent access by
+// multiple processes in the same host. The lock is released by the operating system if the process exits.
+package dirlock
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	defaultBackoff = 1 * time.Second
+)
+
+var (
+	// ErrLocked is returned when the file is already locked
+	ErrLocked = errors.New("file locked")
+	// ErrLockFailed is returned when there's an error accessing the lock file
+	ErrLockFailed = errors.New("failed to lock file")
+	// ErrUnlockFailed is returned when there's an error unlocking the file
+	ErrUnlockFailed = errors.New("failed to unlock file")
+)
+
+// Lock places an advisory write lock on the directory.
+// returns ErrLocked immediately if the directory is locked and the timeout expires.
+// if timeout is 0, Lock will wait indefinitely.
+// If Lock returns nil, no other process will be able to place a lock until this process exits or unlocks it.
+//
+// This is an portable implementation that requires an operating system specific implementation or a non-blocking
+// TryLock.
+// Implementing the blocking lock functionality in an operating system specific way would be more complicated and
+// error prone.
+func (m *Lock) Lock(timeout time.Duration) error {
+	backoff := defaultBackoff
+	deadLine := time.Now().Add(timeout)
+	for {
+		err := m.TryLock()
+		if errors.Is(err, ErrLocked) {
+			if timeout != 0 && time.Now().After(deadLine) {
+				return ErrLocked
+			}
+			time.Sleep(backoff)
+			backoff *= 2
+			continue
+		}
+		return err
+	}
+}
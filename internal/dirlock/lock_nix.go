@@ -1,7 +1,7 @@
 //go:build !windows
 // +build !windows
 
-package file
+package dirlock
 
 import (
 	"errors"
@@ -11,27 +11,28 @@ import (
 	"syscall"
 )
 
-// A dir lock prevents concurrent access to a directory.
+// Lock prevents concurrent access to a directory.
 // This code is inspired on the golang's filelock package:
 // https://pkg.go.dev/cmd/go/internal/lockedfile/internal/filelock
-type dirLock struct {
+type Lock struct {
 	mutex    sync.Mutex
 	lockFile string
 	fd       int
 }
 
-func newDirLock(path string) *dirLock {
-	return &dirLock{
+// New returns a lock on the directory in the given path
+func New(path string) *Lock {
+	return &Lock{
 		lockFile: filepath.Join(path, ".lock"),
 		fd:       -1,
 	}
 }
 
-// tryLock places an advisory write lock on the directory
+// TryLock places an advisory write lock on the directory
 // If the directory is locked, returns ErrLocked immediately.
-// If tryLock returns nil, no other process will be able to place a lock until
+// If TryLock returns nil, no other process will be able to place a lock until
 // this process exits or unlocks it.
-func (m *dirLock) tryLock() error {
+func (m *Lock) TryLock() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -42,7 +43,7 @@ func (m *dirLock) tryLock() error {
 
 	fd, err := syscall.Open(m.lockFile, syscall.O_RDWR|syscall.O_CREAT, 0o600)
 	if err != nil {
-		return fmt.Errorf("%w %w", errLockFailed, err)
+		return fmt.Errorf("%w %w", ErrLockFailed, err)
 	}
 	err = syscall.Flock(fd, syscall.LOCK_EX|syscall.LOCK_NB)
 	if err == nil {
@@ -50,14 +51,18 @@ func (m *dirLock) tryLock() error {
 		return nil
 	}
 
+	// the lock is not held, so the file must be closed for not leaking it when retrying
+	_ = syscall.Close(fd)
+
 	if errors.Is(err, syscall.EWOULDBLOCK) {
-		return errLocked
+		return ErrLocked
 	}
 
-	return fmt.Errorf("%w %w", errLockFailed, err)
+	return fmt.Errorf("%w %w", ErrLockFailed, err)
 }
 
-func (m *dirLock) unlock() error {
+// Unlock releases the lock
+func (m *Lock) Unlock() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -73,7 +78,7 @@ func (m *dirLock) unlock() error {
 
 	err := syscall.Flock(m.fd, syscall.LOCK_UN)
 	if err != nil {
-		return fmt.Errorf("%w %w", errUnLockFailed, err)
+		return fmt.Errorf("%w %w", ErrUnlockFailed, err)
 	}
 	return nil
 }
@@ -1,4 +1,4 @@
-package file
+package dirlock
 
 import (
 	"context"
@@ -13,56 +13,56 @@ func Test_TryLock(t *testing.T) {
 	dir := t.TempDir()
 
 	// this is the original lock
-	firstLock := newDirLock(dir)
+	firstLock := New(dir)
 
 	// should lock dir without errors
-	if err := firstLock.tryLock(); err != nil {
+	if err := firstLock.TryLock(); err != nil {
 		t.Fatalf("unexpected %v", err)
 	}
 
 	//  locking again should return without errors
-	if err := firstLock.tryLock(); err != nil {
+	if err := firstLock.TryLock(); err != nil {
 		t.Fatalf("unexpected %v", err)
 	}
 
 	// another lock should return ErrLocked
-	if err := newDirLock(dir).tryLock(); !errors.Is(err, errLocked) {
+	if err := New(dir).TryLock(); !errors.Is(err, ErrLocked) {
 		t.Fatalf("unexpected %v", err)
 	}
 
 	// locking another directory return without errors
-	anotherLock := newDirLock(t.TempDir())
-	if err := anotherLock.tryLock(); err != nil {
+	anotherLock := New(t.TempDir())
+	if err := anotherLock.TryLock(); err != nil {
 		t.Fatalf("unexpected %v", err)
 	}
 	// must unlock or test can't clean up the tmp dir
-	defer anotherLock.unlock() //nolint:errcheck
+	defer anotherLock.Unlock() //nolint:errcheck
 
 	// unlock should work
-	if err := firstLock.unlock(); err != nil {
+	if err := firstLock.Unlock(); err != nil {
 		t.Fatalf("unexpected %v", err)
 	}
 
 	// unlocking again should return without errors
-	if err := firstLock.unlock(); err != nil {
+	if err := firstLock.Unlock(); err != nil {
 		t.Fatalf("unexpected %v", err)
 	}
 
 	// trying another lock again should work now
-	secondLock := newDirLock(dir)
-	if err := secondLock.tryLock(); err != nil {
+	secondLock := New(dir)
+	if err := secondLock.TryLock(); err != nil {
 		t.Fatalf("unexpected %v", err)
 	}
 	// must unlock or test can't clean up the tmp dir
-	defer secondLock.unlock() //nolint:errcheck
+	defer secondLock.Unlock() //nolint:errcheck
 
 	// retrying original lock should return ErrLocked
-	if err := firstLock.tryLock(); !errors.Is(err, errLocked) {
+	if err := firstLock.TryLock(); !errors.Is(err, ErrLocked) {
 		t.Fatalf("unexpected %v", err)
 	}
 
 	// trying to lock a non-existing dir should fails
-	if err := newDirLock("/path/to/non/existing/dir").tryLock(); !errors.Is(err, errLockFailed) {
+	if err := New("/path/to/non/existing/dir").TryLock(); !errors.Is(err, ErrLockFailed) {
 		t.Fatalf("unexpected %v", err)
 	}
 }
@@ -88,7 +88,7 @@ func Test_Lock(t *testing.T) {
 		{
 			name:    "timeout wating for unlock",
 			timeout: 1 * time.Second,
-			expect:  errLocked,
+			expect:  ErrLocked,
 		},
 	}
 
@@ -99,8 +99,8 @@ func Test_Lock(t *testing.T) {
 			dir := t.TempDir()
 
 			// get a lock on the tmp dir
-			lock := newDirLock(dir)
-			err := lock.tryLock()
+			lock := New(dir)
+			err := lock.TryLock()
 			if err != nil {
 				t.Fatalf("unexpected %v", err)
 			}
@@ -111,14 +111,14 @@ func Test_Lock(t *testing.T) {
 			t.Cleanup(cancel)
 			go func() {
 				<-ctx.Done()
-				_ = lock.unlock()
+				_ = lock.Unlock()
 			}()
 
 			// try to lock the tmp dir while still locked
-			secondLock := newDirLock(dir)
-			defer secondLock.unlock() //nolint:errcheck
+			secondLock := New(dir)
+			defer secondLock.Unlock() //nolint:errcheck
 
-			err = secondLock.lock(tc.timeout)
+			err = secondLock.Lock(tc.timeout)
 			if !errors.Is(err, tc.expect) {
 				t.Fatalf("expected %v got %v", tc.expect, err)
 			}
@@ -1,7 +1,7 @@
 //go:build windows
 // +build windows
 
-package file
+package dirlock
 
 import (
 	"fmt"
@@ -22,27 +22,28 @@ var (
 	procUnlockFile = modkernel32.NewProc("UnlockFile")
 )
 
-// A dirLock prevents concurrent access to a directory.
+// Lock prevents concurrent access to a directory.
 // This code is inspired on the golang's fslock package:
 // https://github.com/juju/fslock/blob/master/fslock_windows.go
-type dirLock struct {
+type Lock struct {
 	mutex    sync.Mutex
 	lockFile string
 	handle   syscall.Handle
 }
 
-func newDirLock(path string) *dirLock {
-	return &dirLock{
+// New returns a lock on the directory in the given path
+func New(path string) *Lock {
+	return &Lock{
 		lockFile: filepath.Join(path, "k6provider.lock"),
 		handle:   syscall.InvalidHandle,
 	}
 }
 
-// tryLock places an advisory write lock on the directory
+// TryLock places an advisory write lock on the directory
 // If the directory is locked, returns ErrLocked immediately.
-// If tryLock returns nil, no other process will be able to place a lock until
+// If TryLock returns nil, no other process will be able to place a lock until
 // this process exits or unlocks it.
-func (m *dirLock) tryLock() error {
+func (m *Lock) TryLock() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -67,7 +68,7 @@ func (m *dirLock) tryLock() error {
 		0,
 	)
 	if err != nil {
-		return fmt.Errorf("%w %w", errLockFailed, err)
+		return fmt.Errorf("%w %w", ErrLockFailed, err)
 	}
 
 	r1, _, e1 := syscall.SyscallN(
@@ -82,21 +83,22 @@ func (m *dirLock) tryLock() error {
 		_ = syscall.Close(handle)
 
 		if syscall.Errno(e1) == errnoLocked {
-			return errLocked
+			return ErrLocked
 		}
 
 		if e1 == 0 { // error code is unknown
 			err = syscall.EINVAL
 		}
 
-		return fmt.Errorf("%w (errno %d) %s", errLockFailed, e1, error(e1).Error())
+		return fmt.Errorf("%w (errno %d) %s", ErrLockFailed, e1, error(e1).Error())
 	}
 
 	m.handle = handle
 	return nil
 }
 
-func (m *dirLock) unlock() error {
+// Unlock releases the lock
+func (m *Lock) Unlock() error {
 	m.mutex.Lock()
 	defer m.mutex.Unlock()
 
@@ -122,7 +124,7 @@ func (m *dirLock) unlock() error {
 		if e1 == 0 { // e1 is the error code, if it's not 0, there was an error
 			e1 = syscall.EINVAL
 		}
-		return fmt.Errorf("%w %s", errUnLockFailed, error(e1).Error())
+		return fmt.Errorf("%w %s", ErrUnlockFailed, error(e1).Error())
 	}
 
 	return nil
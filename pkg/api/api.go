@@ -5,7 +5,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
+	"strings"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/grafana/k6build"
 )
 
@@ -19,15 +23,140 @@ var (
 	ErrInvalidRequest = errors.New("invalid request")
 	// ErrRequestFailed signals the request failed, probably due to a network error
 	ErrRequestFailed = errors.New("request failed")
+	// ErrPlanFailed signals the plan request failed
+	ErrPlanFailed = errors.New("plan failed")
 	// ErrResolveFailed signals the resolve request failed
 	ErrResolveFailed = errors.New("resolve failed")
+	// ErrUnknownDependency signals the dependency is not supported by the build service
+	ErrUnknownDependency = errors.New("unknown dependency")
+	// ErrUnknownArtifact signals the artifact doesn't exist in the build service
+	ErrUnknownArtifact = errors.New("unknown artifact")
+	// ErrTooManyBuilds signals the requester exceeded its limit of concurrent builds
+	ErrTooManyBuilds = errors.New("too many concurrent builds")
+	// ErrUnauthorized signals the request was rejected due to missing or invalid credentials
+	ErrUnauthorized = errors.New("unauthorized")
 )
 
+// IdempotencyKeyHeader is the request header with a key that identifies a build request, so it can be
+// retried without building again
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// RequestIDHeader is the header with the id of a request, for correlating the log records of its processing.
+// If the request doesn't have one, the server generates it. The response always has it.
+const RequestIDHeader = "X-Request-ID"
+
+// ArtifactIDHeader is the header with the id of the artifact returned by the build download request
+const ArtifactIDHeader = "X-Artifact-ID"
+
+// ArtifactChecksumHeader is the header with the checksum of the artifact returned by the build download request,
+// in the form <algorithm>:<hex digest>
+const ArtifactChecksumHeader = "X-Artifact-Checksum"
+
+// DefaultPlatforms is the default set of platforms supported by the build service
+var DefaultPlatforms = []string{ //nolint:gochecknoglobals
+	"darwin/amd64",
+	"darwin/arm64",
+	"linux/amd64",
+	"linux/arm64",
+	"windows/amd64",
+}
+
+// operators supported in the structured constraints
+var operators = []string{"", "=", "!=", ">", "<", ">=", "<=", "~", "^"} //nolint:gochecknoglobals
+
+var (
+	// platforms have the form os/arch (e.g. linux/amd64)
+	platformRe = regexp.MustCompile(`^[a-z0-9]+/[a-z0-9]+$`)
+	// dependencies are k6 extensions (e.g. k6/x/kubernetes)
+	dependencyRe = regexp.MustCompile(`^k6/x/[a-zA-Z0-9._-]+(/[a-zA-Z0-9._-]+)*$`)
+)
+
+// ConvertConstraints returns the dependencies with the constraints given in structured form converted
+// to the string form. Fails if a dependency has both forms or if a clause is not valid.
+func ConvertConstraints(deps []k6build.Dependency) ([]k6build.Dependency, error) {
+	converted := make([]k6build.Dependency, 0, len(deps))
+	for _, dep := range deps {
+		if len(dep.Clauses) == 0 {
+			converted = append(converted, dep)
+			continue
+		}
+
+		if dep.Constraints != "" {
+			return nil, fmt.Errorf("%s: constraints and clauses are mutually exclusive", dep.Name)
+		}
+
+		clauses := []string{}
+		for _, clause := range dep.Clauses {
+			if !slices.Contains(operators, clause.Operator) {
+				return nil, fmt.Errorf("%s: invalid operator %q", dep.Name, clause.Operator)
+			}
+
+			if _, err := semver.StrictNewVersion(strings.TrimPrefix(clause.Version, "v")); err != nil {
+				return nil, fmt.Errorf("%s: invalid version %q", dep.Name, clause.Version)
+			}
+
+			clauses = append(clauses, clause.Operator+clause.Version)
+		}
+
+		converted = append(converted, k6build.Dependency{
+			Name:        dep.Name,
+			Constraints: strings.Join(clauses, ", "),
+			Replace:     dep.Replace,
+		})
+	}
+
+	return converted, nil
+}
+
 // BuildRequest defines a request to the build service
 type BuildRequest struct {
 	K6Constrains string               `json:"k6,omitempty"`
 	Dependencies []k6build.Dependency `json:"dependencies,omitempty"`
 	Platform     string               `json:"platform,omitempty"`
+	// URLExpiration is the requested expiration of the artifact's download URL as a duration (e.g. "15m").
+	// The server may limit it to a maximum. Ignored by stores whose URLs don't expire.
+	URLExpiration string `json:"url_expiration,omitempty"`
+	// Optional build settings. The build service may reject options it does not allow.
+	k6build.BuildOptions
+}
+
+// Validate checks the request is well-formed before it is sent to the build service.
+// All the problems found are reported in a single error that wraps ErrInvalidRequest.
+func (r BuildRequest) Validate() error {
+	errs := []error{}
+
+	if r.K6Constrains == "" {
+		errs = append(errs, errors.New("k6 constraints cannot be empty"))
+	}
+
+	if !platformRe.MatchString(r.Platform) {
+		errs = append(errs, fmt.Errorf("invalid platform %q, expected os/arch", r.Platform))
+	}
+
+	// a dependency can be repeated only if it is requested the same way each time
+	requested := map[string]k6build.Dependency{}
+	for _, dep := range r.Dependencies {
+		if !dependencyRe.MatchString(dep.Name) {
+			errs = append(errs, fmt.Errorf("invalid dependency name %q, expected k6/x/<name>", dep.Name))
+		}
+
+		previous, found := requested[dep.Name]
+		if found && !sameDependency(previous, dep) {
+			errs = append(errs, fmt.Errorf("conflicting duplicate dependency %q", dep.Name))
+		}
+		requested[dep.Name] = dep
+	}
+
+	if len(errs) > 0 {
+		return k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, ErrInvalidRequest, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// sameDependency returns if both dependencies are requested the same way
+func sameDependency(a, b k6build.Dependency) bool {
+	return a.Constraints == b.Constraints && a.Replace == b.Replace && slices.Equal(a.Clauses, b.Clauses)
 }
 
 // BuildResponse defines the response for a BuildRequest
@@ -38,6 +167,25 @@ type BuildResponse struct {
 	Error *k6build.WrappedError `json:"error,omitempty"`
 	// Artifact metadata. If an error occurred, content is undefined
 	Artifact k6build.Artifact `json:"artifact,omitempty"`
+	// Details of the resolution of the dependencies. Only returned for verbose requests
+	Resolution []k6build.DependencyResolution `json:"resolution,omitempty"`
+	// Id of the request that obtained the response
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// NewBuildResponse returns the BuildResponse for the outcome of a build.
+// If the error is not a k6build.WrappedError, it is reported as the reason of ErrBuildFailed.
+func NewBuildResponse(artifact k6build.Artifact, err error) BuildResponse {
+	if err == nil {
+		return BuildResponse{Artifact: artifact}
+	}
+
+	wrapped, ok := k6build.AsError(err)
+	if !ok {
+		wrapped = k6build.NewWrappedError(ErrBuildFailed, err)
+	}
+
+	return BuildResponse{Error: wrapped}
 }
 
 // String returns a text serialization of the BuildRequest
@@ -48,6 +196,21 @@ func (r BuildRequest) String() string {
 	for _, d := range r.Dependencies {
 		buffer.WriteString(fmt.Sprintf("%s:%q", d.Name, d.Constraints))
 	}
+	for m, v := range r.Pins {
+		buffer.WriteString(fmt.Sprintf("pin %s:%q", m, v))
+	}
+	for m, v := range r.ExtraModules {
+		buffer.WriteString(fmt.Sprintf("module %s:%q", m, v))
+	}
+	for _, f := range r.BuildFlags() {
+		buffer.WriteString(fmt.Sprintf("flag %s", f))
+	}
+	for e, v := range r.GoEnv {
+		buffer.WriteString(fmt.Sprintf("env %s:%q", e, v))
+	}
+	if r.Force {
+		buffer.WriteString("force")
+	}
 	return buffer.String()
 }
 
@@ -58,11 +221,131 @@ func (r BuildResponse) String() string {
 	return buffer.String()
 }
 
+// PlanRequest defines a request to the build service for planning a build without building it
+type PlanRequest struct {
+	K6Constrains string               `json:"k6,omitempty"`
+	Dependencies []k6build.Dependency `json:"dependencies,omitempty"`
+	Platform     string               `json:"platform,omitempty"`
+	// Optional build settings. The build service may reject options it does not allow.
+	k6build.BuildOptions
+}
+
+// PlanResponse defines the response for a PlanRequest
+type PlanResponse struct {
+	// If not empty an error occurred processing the request
+	// This Error can be compared to the errors defined in this package using errors.Is
+	// to know the type of error, and use Unwrap to obtain its cause if available.
+	Error *k6build.WrappedError `json:"error,omitempty"`
+	// Build plan. If an error occurred, content is undefined
+	Plan k6build.BuildPlan `json:"plan,omitempty"`
+}
+
+// Validate checks the request is well-formed, as a request for building the same artifact.
+// All the problems found are reported in a single error that wraps ErrInvalidRequest.
+func (r PlanRequest) Validate() error {
+	return BuildRequest{
+		K6Constrains: r.K6Constrains,
+		Dependencies: r.Dependencies,
+		Platform:     r.Platform,
+		BuildOptions: r.BuildOptions,
+	}.Validate()
+}
+
+// String returns a text serialization of the PlanRequest
+func (r PlanRequest) String() string {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(fmt.Sprintf("platform: %s", r.Platform))
+	buffer.WriteString(fmt.Sprintf("k6: %s", r.K6Constrains))
+	for _, d := range r.Dependencies {
+		buffer.WriteString(fmt.Sprintf("%s:%q", d.Name, d.Constraints))
+	}
+	return buffer.String()
+}
+
+// String returns a text serialization of the PlanResponse
+func (r PlanResponse) String() string {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(fmt.Sprintf("id: %s cached: %t", r.Plan.ID, r.Plan.Cached))
+	return buffer.String()
+}
+
+// DependenciesResponse defines the response to a request for the list of supported dependencies
+type DependenciesResponse struct {
+	// If not empty an error occurred processing the request
+	// This Error can be compared to the errors defined in this package using errors.Is
+	// to know the type of error, and use Unwrap to obtain its cause if available.
+	Error *k6build.WrappedError `json:"error,omitempty"`
+	// Sorted list of the names of the supported dependencies
+	Dependencies []string `json:"dependencies,omitempty"`
+}
+
+// PlatformsResponse defines the response to a request for the list of supported platforms
+type PlatformsResponse struct {
+	// If not empty an error occurred processing the request
+	// This Error can be compared to the errors defined in this package using errors.Is
+	// to know the type of error, and use Unwrap to obtain its cause if available.
+	Error *k6build.WrappedError `json:"error,omitempty"`
+	// List of the supported platforms (e.g. linux/amd64)
+	Platforms []string `json:"platforms,omitempty"`
+}
+
+// VersionsResponse defines the response to a request for the list of supported versions of a dependency
+type VersionsResponse struct {
+	// If not empty an error occurred processing the request
+	// This Error can be compared to the errors defined in this package using errors.Is
+	// to know the type of error, and use Unwrap to obtain its cause if available.
+	Error *k6build.WrappedError `json:"error,omitempty"`
+	// Name of the dependency
+	Name string `json:"name,omitempty"`
+	// Sorted list of the supported versions of the dependency
+	Versions []string `json:"versions,omitempty"`
+}
+
+// BuildInfoResponse defines the response to a request for the build info of an artifact
+type BuildInfoResponse struct {
+	// If not empty an error occurred processing the request
+	// This Error can be compared to the errors defined in this package using errors.Is
+	// to know the type of error, and use Unwrap to obtain its cause if available.
+	Error *k6build.WrappedError `json:"error,omitempty"`
+	// ID of the artifact
+	ID string `json:"id,omitempty"`
+	// Environment the artifact was built with
+	BuildInfo *k6build.BuildInfo `json:"build_info,omitempty"`
+}
+
+// SignatureResponse defines the response to a request for the signature of an artifact
+type SignatureResponse struct {
+	// If not empty an error occurred processing the request
+	// This Error can be compared to the errors defined in this package using errors.Is
+	// to know the type of error, and use Unwrap to obtain its cause if available.
+	Error *k6build.WrappedError `json:"error,omitempty"`
+	// ID of the artifact
+	ID string `json:"id,omitempty"`
+	// Base64 encoded ed25519 signature of the checksum of the artifact's binary
+	Signature string `json:"signature,omitempty"`
+}
+
+// VersionResponse defines the response to a request for the version of the build service
+type VersionResponse struct {
+	// Version of the build service (e.g. v0.1.0)
+	Version string `json:"version,omitempty"`
+	// Git commit the build service was built from
+	Commit string `json:"commit,omitempty"`
+	// Date the build service was built
+	BuildDate string `json:"build_date,omitempty"`
+	// Version of the go toolchain used for building the build service
+	GoVersion string `json:"go_version,omitempty"`
+	// Platform the build service runs on (e.g. linux/amd64)
+	Platform string `json:"platform,omitempty"`
+}
+
 // ResolveRequest defines a request to the build service for validating if the dependency
 // constrains can be satisfied
 type ResolveRequest struct {
 	K6Constrains string               `json:"k6,omitempty"`
 	Dependencies []k6build.Dependency `json:"dependencies,omitempty"`
+	// Resolve the dependencies considering their prereleases even if their constraints don't include one
+	IncludePrereleases bool `json:"include_prereleases,omitempty"`
 }
 
 // ResolveResponse defines the response for a ResolveRequest
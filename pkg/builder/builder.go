@@ -4,22 +4,32 @@ package builder
 import (
 	"bytes"
 	"context"
-	"crypto/sha1" //nolint:gosec
+	"crypto/ed25519"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"maps"
+	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
+	"runtime"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/grafana/k6build"
 	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/grafana/k6build/pkg/lock"
+	"github.com/grafana/k6build/pkg/manifest"
 	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/util"
 	"github.com/grafana/k6foundry"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -27,20 +37,42 @@ const (
 	k6DependencyName = "k6"
 	k6Path           = "go.k6.io/k6"
 
-	opRe    = `(?<operator>[=|~|>|<|\^|>=|<=|!=]){0,1}(?:\s*)`
+	// prefix of the constrains that reference a commit
+	commitPrefix = "commit:"
+
+	// manifestKey, followed by the platforms, is used instead of the platform for generating the id of a
+	// multi-platform manifest
+	manifestKey = "manifest"
+
+	// two-character operators must be tried first, so >= is not taken as >.
+	// Build metadata follows a "+". A "-" starts a prerelease (e.g. v0.51.0-rc1), resolved by the catalog
+	opRe    = `(?<operator>>=|<=|!=|=|~|>|<|\^)?(?:\s*)`
 	verRe   = `(?P<version>[v|V](?:0|[1-9]\d*)\.(?:0|[1-9]\d*)\.(?:0|[1-9]\d*))`
-	buildRe = `(?:[+|-|])(?P<build>(?:[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))`
+	buildRe = `\+(?P<build>(?:[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))`
 )
 
 var (
-	ErrAccessingArtifact     = errors.New("accessing artifact") //nolint:revive
-	ErrBuildingArtifact      = errors.New("building artifact")
-	ErrBuildSemverNotAllowed = errors.New("semvers with build metadata not allowed")
-	ErrInitializingBuilder   = errors.New("initializing builder")
-	ErrInvalidParameters     = errors.New("invalid build parameters")
-	ErrResolvingDependencies = errors.New("resolving dependencies")
+	ErrAccessingArtifact      = errors.New("accessing artifact") //nolint:revive
+	ErrBuildingArtifact       = errors.New("building artifact")
+	ErrBuildSemverNotAllowed  = errors.New("semvers with build metadata not allowed")
+	ErrBuildTimeout           = errors.New("build timed out")
+	ErrCommitNotAllowed       = errors.New("building dependencies from commits not allowed")
+	ErrDependencyNotAllowed   = errors.New("dependency not allowed")
+	ErrExtraModulesNotAllowed = errors.New("extra modules not allowed")
+	ErrGoEnvNotAllowed        = errors.New("go environment variable not allowed")
+	ErrInitializingBuilder    = errors.New("initializing builder")
+	ErrInvalidParameters      = errors.New("invalid build parameters")
+	ErrModulePinsNotAllowed   = errors.New("module pins not allowed")
+	ErrModuleSumMismatch      = errors.New("module checksum mismatch")
+	ErrNoCgoToolchain         = errors.New("no cgo toolchain configured for platform")
+	ErrRaceNotSupported       = errors.New("race detector not supported for platform")
+	ErrReplaceNotAllowed      = errors.New("module replacement not allowed")
+	ErrResolvingDependencies  = errors.New("resolving dependencies")
+	ErrSigningArtifact        = errors.New("signing artifact")
 
 	constrainRe = regexp.MustCompile(opRe + verRe + buildRe)
+	commitRe    = regexp.MustCompile(`^[0-9a-f]{7,40}$`)
+	modPathRe   = regexp.MustCompile(`^[a-zA-Z0-9.-]+(/[a-zA-Z0-9._~-]+)+$`)
 )
 
 // GoOpts defines the options for the go build environment
@@ -64,35 +96,165 @@ func (f FoundryFactoryFunction) NewFoundry(
 
 // Opts defines the options for configuring the builder
 type Opts struct {
-	// Allow semvers with build metadata
+	// Allow semvers with build metadata and dependencies built from a commit (commit:<hash>)
 	AllowBuildSemvers bool
+	// Allow requests to pin the version of go modules, including indirect dependencies.
+	// Pinned versions can break the build or introduce vulnerable modules. Use with care.
+	AllowModulePins bool
+	// Allow requests to add go modules that are not extensions, bypassing the catalog.
+	// Extra modules are not vetted by the catalog. Use with care.
+	AllowExtraModules bool
+	// Allow requests to replace the module of a dependency with another module (e.g. a fork)
+	// hosted in one of the ReplaceHosts. Replacements are not vetted by the catalog. Use with care.
+	AllowReplace bool
+	// Hosts of the modules allowed as replacements (e.g. github.com).
+	// Can include a path prefix for restricting the owners (e.g. github.com/grafana).
+	ReplaceHosts []string
+	// Go environment variables requests can override for their build (e.g. GOPROXY, GOPRIVATE).
+	// If empty, requests cannot override the go environment. If GOFLAGS is allowed, requests can
+	// only set the flags in goFlagsAllowed, as other flags can execute arbitrary commands.
+	AllowedGoEnv []string
 	// Generate build output
 	Verbose bool
+	// Directory for the go module and build caches shared by all builds.
+	// The caches are namespaced by go version to prevent builds with different toolchains
+	// from clobbering each other's caches. If not set, the caches from the go environment are used.
+	CacheDir string
+	// Builds taking longer than this threshold are logged as a warning. If 0, slow builds are not logged.
+	SlowBuildThreshold time.Duration
+	// Time a build that failed compiling is remembered. Requests for the same artifact during this time
+	// return the same failure without building it, unless the build is forced. If 0, failures are not remembered.
+	FailedBuildsTTL time.Duration
+	// Time the resolution of a dependency's constrains is cached, so requests resolving the same constrains
+	// don't access the catalog. The cache is cleared when the catalog is reloaded. If 0, resolutions are not cached.
+	ResolutionCacheTTL time.Duration
+	// Resolve the dependencies considering their prereleases (e.g. v0.3.0-rc1) even if their
+	// constrains don't include one. Requests can also include them using the build options.
+	IncludePrereleases bool
+	// Keep in the artifacts' build info the versions of all the go modules linked in the binary
+	FullBuildInfo bool
+	// Generate and store for each artifact a CycloneDX SBOM (software bill of materials) listing all
+	// the go modules linked in the binary. The artifact's SBOMURL references it.
+	GenerateSBOM bool
+	// C toolchains for cross-compiling with cgo, by platform (e.g. linux/arm64). Dependencies that
+	// require cgo can only be built for the host's platform or a platform with a toolchain.
+	Toolchains map[string]Toolchain
+	// Dir where the builds create their temporary files. Each build uses a dir that is removed when
+	// it finishes. If not set, the OS temp dir is used. The default foundry creates its working dir
+	// in the process' temp dir, so the process' TMPDIR should also be set to this dir.
+	TempDir string
+	// Remove at startup the temporary files left in TempDir by builds that didn't finish (e.g. the process
+	// crashed). Requires TempDir, which must not be shared with other running builders.
+	PurgeTempDir bool
+	// Dependencies requests can build (e.g. k6/x/kubernetes). If empty, any dependency in the catalog is allowed
+	DependencyAllowlist []string
+	// Dependencies requests cannot build, even if they are in the DependencyAllowlist
+	DependencyDenylist []string
 	// Build environment options
 	GoOpts
 }
 
+// Toolchain defines the C toolchain used for building with cgo for a platform other than the host's
+type Toolchain struct {
+	// C compiler (e.g. aarch64-linux-gnu-gcc)
+	CC string
+	// C++ compiler (e.g. aarch64-linux-gnu-g++). Optional
+	CXX string
+	// Additional environment variables for the build (e.g. PKG_CONFIG_PATH). Optional
+	Env map[string]string
+}
+
+// env returns the environment variables for building with the toolchain
+func (t Toolchain) env() map[string]string {
+	env := maps.Clone(t.Env)
+	if env == nil {
+		env = map[string]string{}
+	}
+	if t.CC != "" {
+		env["CC"] = t.CC
+	}
+	if t.CXX != "" {
+		env["CXX"] = t.CXX
+	}
+
+	return env
+}
+
 // Config defines the configuration for a Builder
 type Config struct {
-	Opts       Opts
-	Catalog    string
-	Store      store.ObjectStore
-	Foundry    FoundryFactory
-	Registerer prometheus.Registerer
+	Opts    Opts
+	Catalog string
+	// Locations of additional catalogs merged with Catalog.
+	// Later catalogs override earlier ones for the same dependency.
+	CatalogOverlays []string
+	// Time between reloads of the catalogs. If 0, the catalogs are loaded for each request.
+	CatalogReloadInterval time.Duration
+	// Verification of the catalogs when they are loaded. Optional
+	CatalogVerification catalog.Verification
+	Store               store.ObjectStore
+	Foundry             FoundryFactory
+	Registerer          prometheus.Registerer
+	// Lock used for preventing concurrent builds of the same artifact across multiple builders.
+	// Optional. If not set, concurrent builds are only prevented within this builder.
+	Lock lock.Lock
+	Log  *slog.Logger
+	// Version of k6build reported in the build info of the artifacts. Optional
+	Version string
+	// Client used for downloading the catalogs. Optional. Defaults to http.DefaultClient
+	CatalogClient *http.Client
+	// Maximum time for downloading a catalog. If 0, downloads are only limited by the request's context
+	CatalogTimeout time.Duration
+	// Number of times a failed download of a catalog is retried. If 0, downloads are not retried
+	CatalogRetries int
+	// Time before the first retry of a catalog download. Defaults to catalog.DefaultRetryDelay
+	CatalogRetryDelay time.Duration
+	// Age after which the artifacts are deleted from the store, unless they were requested recently.
+	// Requires a store that can list, delete and touch objects. If 0, artifacts are never deleted.
+	ArtifactTTL time.Duration
+	// Time between sweeps of the expired artifacts. Defaults to DefaultArtifactSweepInterval
+	ArtifactSweepInterval time.Duration
+	// Key for signing the artifacts. The signature is stored with the artifact and reported in the
+	// artifact's Signature. Optional. If not set, artifacts are not signed.
+	SigningKey ed25519.PrivateKey
 }
 
 // Builder implements the BuildService interface
 type Builder struct {
-	opts    Opts
-	catalog string
-	store   store.ObjectStore
-	mutexes sync.Map
-	foundry FoundryFactory
-	metrics *metrics
+	opts Opts
+	// catalog locations in merge order
+	catalogs []string
+	// options for loading the catalogs
+	catalogOpts catalog.LoadOptions
+	// periodically reloaded catalog. Nil if the catalog is loaded for each request
+	reloading *catalog.ReloadingCatalog
+	log       *slog.Logger
+	store     store.ObjectStore
+	mutexes   sync.Map
+	lock      lock.Lock
+	foundry   FoundryFactory
+	metrics   *metrics
+	// version of the go toolchain, obtained on first use. Part of the artifacts' id and used for
+	// namespacing the shared caches
+	goVersionMutex sync.Mutex
+	goVersion      string
+	// returns the hash of the modules pinned in the catalog
+	moduleSum moduleSumFunc
+	// builds that failed compiling recently
+	failedBuilds *failedBuilds
+	// cached resolutions of the dependencies. Nil if resolutions are not cached
+	resolutions *resolutionCache
+	// version of k6build reported in the build info
+	version string
+	// age after which the artifacts are deleted. If 0, artifacts are never deleted
+	artifactTTL time.Duration
+	// last time each artifact was touched in the store by this builder, for limiting the updates to the store
+	touched sync.Map
+	// key for signing the artifacts. Nil if artifacts are not signed
+	signingKey ed25519.PrivateKey
 }
 
 // New returns a new instance of Builder given a BuilderConfig
-func New(_ context.Context, config Config) (*Builder, error) {
+func New(ctx context.Context, config Config) (*Builder, error) {
 	if config.Catalog == "" {
 		return nil, k6build.NewWrappedError(ErrInitializingBuilder, errors.New("catalog cannot be nil"))
 	}
@@ -114,13 +276,102 @@ func New(_ context.Context, config Config) (*Builder, error) {
 		}
 	}
 
-	return &Builder{
-		catalog: config.Catalog,
-		opts:    config.Opts,
-		store:   config.Store,
-		foundry: foundry,
-		metrics: metrics,
-	}, nil
+	log := config.Log
+	if log == nil {
+		log = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{}))
+	}
+
+	catalogs := append([]string{config.Catalog}, config.CatalogOverlays...)
+	catalogOpts := catalog.LoadOptions{
+		Verification: config.CatalogVerification,
+		Client:       config.CatalogClient,
+		Timeout:      config.CatalogTimeout,
+		Retries:      config.CatalogRetries,
+		RetryDelay:   config.CatalogRetryDelay,
+	}
+
+	var resolutions *resolutionCache
+	if config.Opts.ResolutionCacheTTL > 0 {
+		resolutions = newResolutionCache(config.Opts.ResolutionCacheTTL)
+	}
+
+	var reloading *catalog.ReloadingCatalog
+	if config.CatalogReloadInterval > 0 {
+		var err error
+		reloading, err = catalog.NewReloadingCatalog(ctx, catalog.ReloadingCatalogConfig{
+			Sources:      catalogs,
+			Interval:     config.CatalogReloadInterval,
+			Verification: config.CatalogVerification,
+			Client:       config.CatalogClient,
+			Timeout:      config.CatalogTimeout,
+			Retries:      config.CatalogRetries,
+			RetryDelay:   config.CatalogRetryDelay,
+			OnReload: func(err error) {
+				if err != nil {
+					metrics.catalogReloadsFailed.Inc()
+					log.Error("reloading catalog, using last loaded catalog", "error", err.Error())
+					return
+				}
+				// resolutions from the previous catalog may no longer be valid
+				if resolutions != nil {
+					resolutions.clear()
+				}
+			},
+		})
+		if err != nil {
+			return nil, k6build.NewWrappedError(ErrInitializingBuilder, err)
+		}
+	}
+
+	if config.ArtifactTTL > 0 && !canSweep(config.Store) {
+		return nil, k6build.NewWrappedError(
+			ErrInitializingBuilder,
+			errors.New("expiring artifacts requires a store that can list, delete and touch objects"),
+		)
+	}
+
+	// purging the OS temp dir would remove the files of other processes
+	if config.Opts.PurgeTempDir && config.Opts.TempDir == "" {
+		return nil, k6build.NewWrappedError(ErrInitializingBuilder, errors.New("purging requires a temp dir"))
+	}
+
+	if config.Opts.TempDir != "" {
+		if err := os.MkdirAll(config.Opts.TempDir, 0o750); err != nil {
+			return nil, k6build.NewWrappedError(ErrInitializingBuilder, err)
+		}
+	}
+
+	builder := &Builder{
+		catalogs:     catalogs,
+		catalogOpts:  catalogOpts,
+		reloading:    reloading,
+		log:          log,
+		opts:         config.Opts,
+		store:        config.Store,
+		lock:         config.Lock,
+		foundry:      foundry,
+		metrics:      metrics,
+		moduleSum:    goModuleSum,
+		failedBuilds: newFailedBuilds(config.Opts.FailedBuildsTTL),
+		resolutions:  resolutions,
+		version:      config.Version,
+		artifactTTL:  config.ArtifactTTL,
+		signingKey:   config.SigningKey,
+	}
+
+	if config.ArtifactTTL > 0 {
+		interval := config.ArtifactSweepInterval
+		if interval == 0 {
+			interval = DefaultArtifactSweepInterval
+		}
+		go builder.sweepLoop(ctx, interval)
+	}
+
+	if config.Opts.PurgeTempDir {
+		purgeTempDir(config.Opts.TempDir, log)
+	}
+
+	return builder, nil
 }
 
 // Build builds a custom k6 binary with dependencies
@@ -129,84 +380,349 @@ func (b *Builder) Build(
 	platform string,
 	k6Constrains string,
 	deps []k6build.Dependency,
-) (artifact k6build.Artifact, buildErr error) {
+) (k6build.Artifact, error) {
+	return b.observeBuild(func() (k6build.Artifact, bool, error) {
+		// check if the platform is valid early to avoid unnecessary work
+		_, err := k6foundry.ParsePlatform(platform)
+		if err != nil {
+			return k6build.Artifact{}, false, k6build.NewCodedError(
+				k6build.ErrorCodeInvalidPlatform,
+				ErrInvalidParameters,
+				err,
+			)
+		}
+
+		resolved, err := b.resolveDependencies(ctx, k6Constrains, deps)
+		if err != nil {
+			return k6build.Artifact{}, false, k6build.NewCodedError(resolveErrorCode(err), ErrInvalidParameters, err)
+		}
+
+		return b.buildResolved(ctx, platform, resolved)
+	})
+}
+
+// observeBuild updates the request metrics with the outcome of the build.
+// The build returns the artifact and whether it was found in the store.
+func (b *Builder) observeBuild(build func() (k6build.Artifact, bool, error)) (k6build.Artifact, error) {
 	b.metrics.requestCounter.Inc()
 
 	requestTimer := prometheus.NewTimer(b.metrics.requestTimeHistogram)
-	defer func() {
-		if buildErr == nil {
-			requestTimer.ObserveDuration()
+	artifact, storeHit, buildErr := build()
+	if buildErr == nil {
+		requestTime := requestTimer.ObserveDuration()
+		if storeHit {
+			b.metrics.storeHitTimeHistogram.Observe(requestTime.Seconds())
 		}
+	} else {
+		b.metrics.failuresCounter.WithLabelValues(failureReason(buildErr)).Inc()
+	}
 
-		// FIXME: this is a temporary solution because the logic has many paths that return
-		// an invalid parameters error and we need to increment the metrics in all of them
-		if errors.Is(buildErr, ErrInvalidParameters) {
-			b.metrics.buildsInvalidCounter.Inc()
-		}
-	}()
+	// FIXME: this is a temporary solution because the logic has many paths that return
+	// an invalid parameters error and we need to increment the metrics in all of them
+	if errors.Is(buildErr, ErrInvalidParameters) {
+		b.metrics.buildsInvalidCounter.Inc()
+	}
 
-	// check if the platform is valid early to avoid unnecessary work
-	_, err := k6foundry.ParsePlatform(platform)
+	return artifact, buildErr
+}
+
+// buildResolved builds the artifact for the resolved dependencies, if it is not already in the store.
+// Returns the artifact and whether it was found in the store.
+func (b *Builder) buildResolved(
+	ctx context.Context,
+	platform string,
+	resolved map[string]catalog.Module,
+) (k6build.Artifact, bool, error) {
+	buildOpts := k6build.BuildOptionsFromContext(ctx)
+	err := b.checkBuildOptions(buildOpts, platform, resolved)
 	if err != nil {
-		return k6build.Artifact{}, k6build.NewWrappedError(ErrInvalidParameters, err)
+		return k6build.Artifact{}, false, k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, ErrInvalidParameters, err)
 	}
 
-	resolved, err := b.resolveDependencies(ctx, k6Constrains, deps)
+	for dep := range resolved {
+		b.metrics.dependencyRequests.WithLabelValues(dep).Inc()
+	}
+
+	goVersion, err := b.toolchainVersion(ctx)
 	if err != nil {
-		return k6build.Artifact{}, k6build.NewWrappedError(ErrInvalidParameters, err)
+		return k6build.Artifact{}, false, k6build.NewWrappedError(ErrBuildingArtifact, err)
 	}
 
-	id := generateArtifactID(platform, resolved)
+	id := ArtifactID(platform, goVersion, resolved, buildOpts)
 
 	unlock := b.lockArtifact(id)
 	defer unlock()
 
 	artifactObject, err := b.store.Get(ctx, id)
 	if err == nil {
-		b.metrics.storeHitsCounter.Inc()
-
-		return k6build.Artifact{
-			ID:           id,
-			Checksum:     artifactObject.Checksum,
-			URL:          artifactObject.URL,
-			Dependencies: resolvedVersions(resolved),
-			Platform:     platform,
-		}, nil
+		return b.storedArtifact(ctx, id, artifactObject, platform, resolved, buildOpts), true, nil
 	}
 
 	if !errors.Is(err, store.ErrObjectNotFound) {
-		return k6build.Artifact{}, k6build.NewWrappedError(ErrAccessingArtifact, err)
+		return k6build.Artifact{}, false, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	// don't retry builds that are known to fail, unless forced
+	if failure := b.failedBuilds.get(id); failure != nil && !buildOpts.Force {
+		b.metrics.failedBuildsHits.Inc()
+		b.logger(ctx).Debug("build failed recently, not retrying", "id", id)
+		return k6build.Artifact{}, false, k6build.NewCodedError(k6build.ErrorCodeBuildFailed, ErrBuildingArtifact, failure)
+	}
+
+	if b.lock != nil {
+		release, err := b.lock.Lock(ctx, id)
+		if err != nil {
+			return k6build.Artifact{}, false, k6build.NewWrappedError(ErrAccessingArtifact, err)
+		}
+		defer release()
+
+		// check again as the artifact could have been built by another builder while waiting for the lock
+		artifactObject, err = b.store.Get(ctx, id)
+		if err == nil {
+			return b.storedArtifact(ctx, id, artifactObject, platform, resolved, buildOpts), true, nil
+		}
+
+		if !errors.Is(err, store.ErrObjectNotFound) {
+			return k6build.Artifact{}, false, k6build.NewWrappedError(ErrAccessingArtifact, err)
+		}
 	}
 
 	b.metrics.buildCounter.Inc()
+	buildInfo := b.newBuildInfo(goVersion)
 	buildTimer := prometheus.NewTimer(b.metrics.buildTimeHistogram)
 
 	artifactBuffer := &bytes.Buffer{}
 
-	err = b.buildArtifact(ctx, platform, resolved, artifactBuffer)
+	b.logger(ctx).Debug("building artifact", "id", id, "platform", platform)
+
+	b.metrics.buildsInFlight.Inc()
+	foundryInfo, err := b.buildArtifact(ctx, platform, resolved, buildOpts, false, artifactBuffer)
+	b.metrics.buildsInFlight.Dec()
 	if err != nil {
-		return k6build.Artifact{}, k6build.NewWrappedError(ErrBuildingArtifact, err)
+		b.logger(ctx).Debug("build failed", "id", id, "error", err.Error())
+		// only compilation errors are remembered, as other errors can be transient
+		if errors.Is(err, k6foundry.ErrCompiling) && ctx.Err() == nil {
+			b.failedBuilds.add(id, err)
+			for _, dep := range failedDependencies(err, resolved) {
+				b.metrics.dependencyFailures.WithLabelValues(dep).Inc()
+			}
+		}
+		return k6build.Artifact{}, false, k6build.NewCodedError(buildErrorCode(err), ErrBuildingArtifact, err)
 	}
-	buildTimer.ObserveDuration()
+	b.failedBuilds.remove(id)
+	buildTime := buildTimer.ObserveDuration()
 
-	artifactObject, err = b.store.Put(ctx, id, artifactBuffer)
+	if b.opts.FullBuildInfo && foundryInfo != nil {
+		buildInfo.Modules = foundryInfo.ModVersions
+	}
+
+	if b.opts.SlowBuildThreshold > 0 && buildTime > b.opts.SlowBuildThreshold {
+		b.metrics.slowBuildsCounter.Inc()
+		b.logger(ctx).Warn(
+			"slow build",
+			"id", id,
+			"platform", platform,
+			"dependencies", resolvedVersions(resolved),
+			"duration", buildTime.String(),
+		)
+	}
+
+	binary := artifactBuffer.Bytes()
+	artifactObject, err = b.store.Put(ctx, id, bytes.NewReader(binary))
 
 	// if there was a conflict creating the object, get returns the object
 	if errors.Is(err, store.ErrDuplicateObject) || (err != nil && strings.Contains(err.Error(), "duplicate object")) {
+		// the object stored by another builder can be different from the one built
+		binary = nil
 		artifactObject, err = b.store.Get(ctx, id)
 	}
 
 	if err != nil {
-		return k6build.Artifact{}, k6build.NewWrappedError(ErrAccessingArtifact, err)
+		return k6build.Artifact{}, false, k6build.NewWrappedError(ErrAccessingArtifact, err)
 	}
 
+	sbomURL := ""
+	if b.opts.GenerateSBOM {
+		sbomURL = b.storeSBOM(ctx, artifactObject, platform, binary)
+	}
+
+	// the signature is over the checksum of the stored object, which can be the one stored
+	// by another builder if both builders stored the artifact at the same time
+	signature := ""
+	if b.signingKey != nil {
+		signature, err = b.signArtifact(ctx, id, artifactObject.Checksum)
+		if err != nil {
+			return k6build.Artifact{}, false, k6build.NewWrappedError(ErrSigningArtifact, err)
+		}
+	}
+
+	b.storeBuildInfo(ctx, id, buildInfo)
+
 	return k6build.Artifact{
 		ID:           id,
-		Checksum:     artifactObject.Checksum,
+		Checksum:     util.CompactChecksum(artifactObject.Checksum),
+		Size:         artifactObject.Size,
 		URL:          artifactObject.URL,
 		Dependencies: resolvedVersions(resolved),
 		Platform:     platform,
-	}, nil
+		BuildFlags:   buildOpts.BuildFlags(),
+		BuildInfo:    &buildInfo,
+		SBOMURL:      sbomURL,
+		Signature:    signature,
+	}, false, nil
+}
+
+// storedArtifact returns the artifact for an object found in the store, recording the store hit
+func (b *Builder) storedArtifact(
+	ctx context.Context,
+	id string,
+	artifactObject store.Object,
+	platform string,
+	resolved map[string]catalog.Module,
+	buildOpts k6build.BuildOptions,
+) k6build.Artifact {
+	b.metrics.storeHitsCounter.Inc()
+	b.touchArtifact(ctx, id)
+
+	return k6build.Artifact{
+		ID:           id,
+		Checksum:     util.CompactChecksum(artifactObject.Checksum),
+		Size:         artifactObject.Size,
+		URL:          artifactObject.URL,
+		Dependencies: resolvedVersions(resolved),
+		Platform:     platform,
+		BuildFlags:   buildOpts.BuildFlags(),
+		BuildInfo:    b.fetchBuildInfo(ctx, id),
+		SBOMURL:      b.fetchSBOMURL(ctx, id),
+		Signature:    b.fetchSignature(ctx, id, artifactObject.Checksum),
+	}
+}
+
+// BuildManifest builds an artifact for each of the given platforms and stores a manifest
+// listing them under a platform-independent id.
+func (b *Builder) BuildManifest(
+	ctx context.Context,
+	platforms []string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (manifest.Manifest, error) {
+	if len(platforms) == 0 {
+		return manifest.Manifest{}, k6build.NewCodedError(
+			k6build.ErrorCodeInvalidPlatform,
+			ErrInvalidParameters,
+			errors.New("platforms cannot be empty"),
+		)
+	}
+
+	for _, platform := range platforms {
+		_, err := k6foundry.ParsePlatform(platform)
+		if err != nil {
+			return manifest.Manifest{}, k6build.NewCodedError(k6build.ErrorCodeInvalidPlatform, ErrInvalidParameters, err)
+		}
+	}
+
+	resolved, err := b.resolveDependencies(ctx, k6Constrains, deps)
+	if err != nil {
+		return manifest.Manifest{}, k6build.NewCodedError(resolveErrorCode(err), ErrInvalidParameters, err)
+	}
+
+	artifacts := []k6build.Artifact{}
+	for _, platform := range platforms {
+		artifact, err := b.observeBuild(func() (k6build.Artifact, bool, error) {
+			return b.buildResolved(ctx, platform, resolved)
+		})
+		if err != nil {
+			return manifest.Manifest{}, err
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	goVersion, err := b.toolchainVersion(ctx)
+	if err != nil {
+		return manifest.Manifest{}, k6build.NewWrappedError(ErrBuildingArtifact, err)
+	}
+
+	// the platforms are part of the id, so manifests for different platforms don't collide in the store
+	manifestPlatforms := slices.Compact(slices.Sorted(slices.Values(platforms)))
+	manifestID := ArtifactID(
+		manifestKey+":"+strings.Join(manifestPlatforms, ","),
+		goVersion,
+		resolved,
+		k6build.BuildOptionsFromContext(ctx),
+	)
+
+	m, err := manifest.New(manifestID, artifacts)
+	if err != nil {
+		return manifest.Manifest{}, k6build.NewWrappedError(ErrBuildingArtifact, err)
+	}
+
+	m, err = manifest.Store(ctx, b.store, m)
+	if err != nil {
+		return manifest.Manifest{}, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	return m, nil
+}
+
+// Plan returns the plan for building an artifact that satisfies the dependencies without building it
+func (b *Builder) Plan(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.BuildPlan, error) {
+	_, err := k6foundry.ParsePlatform(platform)
+	if err != nil {
+		return k6build.BuildPlan{}, k6build.NewCodedError(k6build.ErrorCodeInvalidPlatform, ErrInvalidParameters, err)
+	}
+
+	resolved, err := b.resolveDependencies(ctx, k6Constrains, deps)
+	if err != nil {
+		return k6build.BuildPlan{}, k6build.NewCodedError(resolveErrorCode(err), ErrInvalidParameters, err)
+	}
+
+	buildOpts := k6build.BuildOptionsFromContext(ctx)
+	err = b.checkBuildOptions(buildOpts, platform, resolved)
+	if err != nil {
+		return k6build.BuildPlan{}, k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, ErrInvalidParameters, err)
+	}
+
+	modules := map[string]string{}
+	for dep, mod := range resolved {
+		modules[dep] = mod.Path
+	}
+
+	goVersion, err := b.toolchainVersion(ctx)
+	if err != nil {
+		return k6build.BuildPlan{}, k6build.NewWrappedError(ErrBuildingArtifact, err)
+	}
+
+	plan := k6build.BuildPlan{
+		ID:           ArtifactID(platform, goVersion, resolved, buildOpts),
+		Platform:     platform,
+		Dependencies: resolvedVersions(resolved),
+		Modules:      modules,
+	}
+
+	_, err = b.store.Get(ctx, plan.ID)
+	switch {
+	case err == nil:
+		plan.Cached = true
+	case !errors.Is(err, store.ErrObjectNotFound):
+		return k6build.BuildPlan{}, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	return plan, nil
+}
+
+// ReloadCatalog reloads the catalogs if they are periodically reloaded.
+// If reloading fails, the last catalog loaded is used.
+func (b *Builder) ReloadCatalog(ctx context.Context) error {
+	if b.reloading == nil {
+		return nil
+	}
+
+	return b.reloading.Reload(ctx)
 }
 
 // Resolve returns the version that resolve the given dependencies
@@ -217,22 +733,183 @@ func (b *Builder) Resolve(
 ) (map[string]string, error) {
 	resolved, err := b.resolveDependencies(ctx, k6Constrains, deps)
 	if err != nil {
-		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+		return nil, k6build.NewCodedError(resolveErrorCode(err), ErrResolvingDependencies, err)
 	}
 
 	return resolvedVersions(resolved), nil
 }
 
+// ResolveDetails returns how each dependency is resolved, starting with k6
+func (b *Builder) ResolveDetails(
+	ctx context.Context,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) ([]k6build.DependencyResolution, error) {
+	ctlg, err := b.getCatalog(ctx)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+	}
+
+	resolved, err := b.resolveFromCatalog(ctx, ctlg, k6Constrains, deps)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+	}
+
+	requested := append([]k6build.Dependency{{Name: k6DependencyName, Constraints: k6Constrains}}, deps...)
+
+	details := []k6build.DependencyResolution{}
+	for _, dep := range requested {
+		version := resolved[dep.Name].Version
+
+		available, err := ctlg.Versions(ctx, dep.Name)
+		if err != nil {
+			return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+		}
+
+		details = append(details, k6build.DependencyResolution{
+			Name:        dep.Name,
+			Constraints: dep.Constraints,
+			Version:     version,
+			Latest:      version != "" && version == latestVersion(available, b.includePrereleases(ctx)),
+			Available:   available,
+		})
+	}
+
+	return details, nil
+}
+
+// latestVersion returns the highest release in the sorted list of versions, or the highest version
+// if prereleases are included or all the versions are prereleases
+func latestVersion(versions []string, prereleases bool) string {
+	if len(versions) == 0 {
+		return ""
+	}
+
+	if !prereleases {
+		for _, v := range slices.Backward(versions) {
+			version, err := semver.NewVersion(v)
+			if err == nil && version.Prerelease() == "" {
+				return v
+			}
+		}
+	}
+
+	return versions[len(versions)-1]
+}
+
+// HealthCheck checks the object store used by the builder is available
+func (b *Builder) HealthCheck(ctx context.Context) error {
+	return store.HealthCheck(ctx, b.store)
+}
+
+// Catalog returns the content of the catalog used for resolving dependencies
+func (b *Builder) Catalog(ctx context.Context) ([]byte, error) {
+	ctlg, err := b.getCatalog(ctx)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+	}
+
+	raw, err := catalog.Raw(ctlg)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+	}
+
+	return raw, nil
+}
+
+// Dependencies returns the sorted list of the dependencies supported by the catalog
+func (b *Builder) Dependencies(ctx context.Context) ([]string, error) {
+	ctlg, err := b.getCatalog(ctx)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+	}
+
+	deps, err := ctlg.Dependencies(ctx)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+	}
+
+	return deps, nil
+}
+
+// Versions returns the sorted list of the versions of a dependency supported by the catalog
+func (b *Builder) Versions(ctx context.Context, name string) ([]string, error) {
+	ctlg, err := b.getCatalog(ctx)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+	}
+
+	versions, err := ctlg.Versions(ctx, name)
+	if errors.Is(err, catalog.ErrUnknownDependency) {
+		return nil, k6build.NewCodedError(k6build.ErrorCodeUnknownDependency, k6build.ErrUnknownDependency, err)
+	}
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrResolvingDependencies, err)
+	}
+
+	return versions, nil
+}
+
+// getCatalog returns the periodically reloaded catalog, if any, or loads the catalog
+func (b *Builder) getCatalog(ctx context.Context) (catalog.Catalog, error) {
+	if b.reloading != nil {
+		return b.reloading, nil
+	}
+
+	return catalog.LoadMergedCatalog(ctx, b.catalogOpts, b.catalogs...)
+}
+
 func (b *Builder) resolveDependencies(
 	ctx context.Context,
 	k6Constrains string,
 	deps []k6build.Dependency,
 ) (map[string]catalog.Module, error) {
-	ctlg, err := catalog.NewCatalog(ctx, b.catalog)
+	// with cached resolutions, the catalog is only obtained if a resolution is not cached
+	if b.resolutions != nil {
+		ctlg := &cachedCatalog{cache: b.resolutions, hits: b.metrics.resolutionCacheHits, load: b.getCatalog}
+		return b.resolveFromCatalog(ctx, ctlg, k6Constrains, deps)
+	}
+
+	ctlg, err := b.getCatalog(ctx)
 	if err != nil {
 		return nil, err
 	}
 
+	return b.resolveFromCatalog(ctx, ctlg, k6Constrains, deps)
+}
+
+// resolveErrorCode returns the error code for a failure resolving the dependencies.
+// Failures accessing the catalog have no code.
+func resolveErrorCode(err error) k6build.ErrorCode {
+	switch {
+	case errors.Is(err, catalog.ErrUnknownDependency):
+		return k6build.ErrorCodeUnknownDependency
+	case errors.Is(err, catalog.ErrCannotSatisfy), errors.Is(err, catalog.ErrInvalidConstrain):
+		return k6build.ErrorCodeCannotSatisfy
+	case errors.Is(err, ErrBuildSemverNotAllowed), errors.Is(err, ErrCommitNotAllowed),
+		errors.Is(err, ErrReplaceNotAllowed), errors.Is(err, ErrInvalidParameters):
+		return k6build.ErrorCodeInvalidRequest
+	default:
+		return ""
+	}
+}
+
+// buildErrorCode returns the error code for a failed build.
+// Failures downloading the dependencies are distinguished as they are usually transient.
+func buildErrorCode(err error) k6build.ErrorCode {
+	if errors.Is(err, k6foundry.ErrResolvingDependency) && !errors.Is(err, k6foundry.ErrCompiling) {
+		return k6build.ErrorCodeDownloadFailed
+	}
+
+	return k6build.ErrorCodeBuildFailed
+}
+
+func (b *Builder) resolveFromCatalog(
+	ctx context.Context,
+	ctlg catalog.Catalog,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (map[string]catalog.Module, error) {
 	resolved := map[string]catalog.Module{}
 
 	// check if it is a semver of the form v0.0.0+<build>
@@ -244,30 +921,136 @@ func (b *Builder) resolveDependencies(
 		return nil, err
 	}
 	if buildMetadata != "" {
-		if !b.opts.AllowBuildSemvers {
+		if !b.buildSemversAllowed(ctx) {
 			return nil, ErrBuildSemverNotAllowed
 		}
 		// use a semantic version for the build metadata
 		k6Mod = catalog.Module{Path: k6Path, Version: "v0.0.0+" + buildMetadata}
 	} else {
-		k6Mod, err = ctlg.Resolve(ctx, catalog.Dependency{Name: k6DependencyName, Constrains: k6Constrains})
+		k6Mod, err = ctlg.Resolve(ctx, catalog.Dependency{
+			Name:               k6DependencyName,
+			Constrains:         k6Constrains,
+			IncludePrereleases: b.includePrereleases(ctx),
+		})
 		if err != nil {
 			return nil, err
 		}
 	}
 	resolved[k6DependencyName] = k6Mod
 
+	// a nil and an empty list of dependencies are equivalent: only k6 is resolved,
+	// so both yield the same artifact id
+	if len(deps) == 0 {
+		return resolved, nil
+	}
+
+	requested := map[string]k6build.Dependency{}
 	for _, d := range deps {
-		m, err := ctlg.Resolve(ctx, catalog.Dependency{Name: d.Name, Constrains: d.Constraints})
+		// a dependency requested more than once must be requested the same way each time
+		if previous, found := requested[d.Name]; found {
+			if previous.Constraints != d.Constraints || previous.Replace != d.Replace {
+				return nil, k6build.NewWrappedError(
+					ErrInvalidParameters,
+					fmt.Errorf("conflicting requests for dependency %s: %q and %q",
+						d.Name, requestedAs(previous), requestedAs(d)),
+				)
+			}
+			continue
+		}
+		requested[d.Name] = d
+
+		m, err := b.resolveDependency(ctx, ctlg, d)
 		if err != nil {
 			return nil, err
 		}
+
+		if d.Replace != "" {
+			err = b.checkReplace(d.Replace)
+			if err != nil {
+				return nil, err
+			}
+			// the checksum pinned in the catalog is not for the replacement
+			m.Replace, m.Sum = strings.TrimPrefix(d.Replace, "https://"), ""
+		}
+
 		resolved[d.Name] = m
 	}
 
 	return resolved, nil
 }
 
+// logger returns the logger for the request being processed, which identifies the request if its id is known
+func (b *Builder) logger(ctx context.Context) *slog.Logger {
+	if id := k6build.RequestID(ctx); id != "" {
+		return b.log.With("request_id", id)
+	}
+
+	return b.log
+}
+
+// buildSemversAllowed returns if the request can build versions with build metadata and dependencies
+// from a commit. If the request carries an authorization decision, it must also allow them.
+func (b *Builder) buildSemversAllowed(ctx context.Context) bool {
+	if !b.opts.AllowBuildSemvers {
+		return false
+	}
+
+	allowed, decided := k6build.BuildSemversAllowed(ctx)
+	return !decided || allowed
+}
+
+// includePrereleases returns if the dependencies are resolved considering their prereleases,
+// either because the builder or the request's build options include them
+func (b *Builder) includePrereleases(ctx context.Context) bool {
+	return b.opts.IncludePrereleases || k6build.BuildOptionsFromContext(ctx).IncludePrereleases
+}
+
+// requestedAs returns how a dependency was requested, including its replacement if any
+func requestedAs(dep k6build.Dependency) string {
+	if dep.Replace == "" {
+		return dep.Constraints
+	}
+
+	return dep.Constraints + " => " + dep.Replace
+}
+
+// resolveDependency returns the module that satisfies the dependency's constrains.
+// If the constrains are a commit (commit:<hash>), the module is built at that commit,
+// bypassing the versions in the catalog, which only provides the module's path.
+func (b *Builder) resolveDependency(
+	ctx context.Context,
+	ctlg catalog.Catalog,
+	dep k6build.Dependency,
+) (catalog.Module, error) {
+	commit, found := strings.CutPrefix(dep.Constraints, commitPrefix)
+	if !found {
+		return ctlg.Resolve(ctx, catalog.Dependency{
+			Name:               dep.Name,
+			Constrains:         dep.Constraints,
+			IncludePrereleases: b.includePrereleases(ctx),
+		})
+	}
+
+	if !b.buildSemversAllowed(ctx) {
+		return catalog.Module{}, ErrCommitNotAllowed
+	}
+
+	if !commitRe.MatchString(commit) {
+		return catalog.Module{}, k6build.NewWrappedError(
+			ErrInvalidParameters,
+			fmt.Errorf("invalid commit %q for %s", commit, dep.Name),
+		)
+	}
+
+	mod, err := ctlg.Resolve(ctx, catalog.Dependency{Name: dep.Name, Constrains: "*"})
+	if err != nil {
+		return catalog.Module{}, err
+	}
+
+	// the checksum pinned in the catalog is for a released version, not for the commit
+	return catalog.Module{Path: mod.Path, Version: commit, Cgo: mod.Cgo}, nil
+}
+
 // lockArtifact obtains a mutex used to prevent concurrent builds of the same artifact and
 // returns a function that will unlock the mutex associated to the given id in the object store.
 // The lock is also removed from the map. Subsequent calls will get another lock on the same
@@ -317,23 +1100,244 @@ func hasBuildMetadata(constrain string) (string, error) {
 	return build, nil
 }
 
-// generateArtifactID generates a unique identifier for a build
-func generateArtifactID(platform string, deps map[string]catalog.Module) string {
-	hashData := bytes.Buffer{}
-	hashData.WriteString(platform)
+// checkBuildOptions checks if the build options are allowed for the platform and don't conflict with the
+// resolved dependencies
+func (b *Builder) checkBuildOptions(opts k6build.BuildOptions, platform string, deps map[string]catalog.Module) error {
+	err := b.checkDependencies(deps)
+	if err != nil {
+		return err
+	}
 
-	// add k6 as the first dependency
-	hashData.WriteString(fmt.Sprintf(":%s%s", k6DependencyName, deps[k6DependencyName].Version))
+	err = b.checkPins(opts.Pins, deps)
+	if err != nil {
+		return err
+	}
+
+	err = b.checkExtraModules(opts.ExtraModules, deps)
+	if err != nil {
+		return err
+	}
+
+	err = b.checkGoEnv(opts.GoEnv)
+	if err != nil {
+		return err
+	}
+
+	// the race detector requires cgo, so the binary must be built for the native platform
+	native := hostPlatform()
+	if opts.Race && platform != native {
+		return fmt.Errorf("%w: race detector requires building for %s", ErrRaceNotSupported, native)
+	}
+
+	// cross-compiling dependencies that require cgo needs a toolchain for the platform
+	if platform != native && requiresCgo(deps) {
+		if _, found := b.opts.Toolchains[platform]; !found {
+			return fmt.Errorf("%w %s: dependencies require cgo", ErrNoCgoToolchain, platform)
+		}
+	}
+
+	return nil
+}
+
+// hostPlatform returns the platform the builder runs on
+func hostPlatform() string {
+	return runtime.GOOS + "/" + runtime.GOARCH
+}
+
+// requiresCgo returns true if any of the dependencies requires cgo
+func requiresCgo(deps map[string]catalog.Module) bool {
+	for _, m := range deps {
+		if m.Cgo {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkDependencies checks the dependencies are allowed by the allowlist and not in the denylist
+func (b *Builder) checkDependencies(deps map[string]catalog.Module) error {
+	for _, name := range slices.Sorted(maps.Keys(deps)) {
+		if name == k6DependencyName {
+			continue
+		}
+
+		allowed := len(b.opts.DependencyAllowlist) == 0 || slices.Contains(b.opts.DependencyAllowlist, name)
+		if !allowed || slices.Contains(b.opts.DependencyDenylist, name) {
+			return fmt.Errorf("%w: %s", ErrDependencyNotAllowed, name)
+		}
+	}
+
+	return nil
+}
+
+// checkReplace checks if the replacement of a dependency's module is allowed.
+// The replacement must be a module in one of the allowed hosts with an explicit version.
+func (b *Builder) checkReplace(replace string) error {
+	if !b.opts.AllowReplace {
+		return ErrReplaceNotAllowed
+	}
+
+	path, version, found := strings.Cut(strings.TrimPrefix(replace, "https://"), "@")
+	if !found || version == "" {
+		return k6build.NewWrappedError(
+			ErrInvalidParameters,
+			fmt.Errorf("replacement %q must have a version (path@version)", replace),
+		)
+	}
+
+	if !modPathRe.MatchString(path) {
+		return k6build.NewWrappedError(ErrInvalidParameters, fmt.Errorf("invalid replacement module %q", path))
+	}
+
+	if _, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v")); err != nil && !commitRe.MatchString(version) {
+		return k6build.NewWrappedError(ErrInvalidParameters, fmt.Errorf("invalid replacement version %q", version))
+	}
+
+	for _, host := range b.opts.ReplaceHosts {
+		if path == host || strings.HasPrefix(path, strings.TrimSuffix(host, "/")+"/") {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %q is not in an allowed host", ErrReplaceNotAllowed, path)
+}
+
+// checkPins checks if the module pins are allowed and don't conflict with the resolved dependencies
+func (b *Builder) checkPins(pins map[string]string, deps map[string]catalog.Module) error {
+	if len(pins) == 0 {
+		return nil
+	}
+
+	if !b.opts.AllowModulePins {
+		return ErrModulePinsNotAllowed
+	}
+
+	for path, version := range pins {
+		if path == "" || version == "" {
+			return fmt.Errorf("invalid module pin %q: %q", path, version)
+		}
+
+		if _, err := semver.StrictNewVersion(strings.TrimPrefix(version, "v")); err != nil {
+			return fmt.Errorf("invalid version for module pin %q: %w", path, err)
+		}
+
+		for _, m := range deps {
+			if m.Path == path {
+				return fmt.Errorf("module pin %q conflicts with dependency", path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkExtraModules checks if the extra modules are allowed and don't conflict with the resolved dependencies
+func (b *Builder) checkExtraModules(mods map[string]string, deps map[string]catalog.Module) error {
+	if len(mods) == 0 {
+		return nil
+	}
+
+	if !b.opts.AllowExtraModules {
+		return ErrExtraModulesNotAllowed
+	}
+
+	for path, version := range mods {
+		if path == "" || version == "" {
+			return fmt.Errorf("invalid extra module %q: %q", path, version)
+		}
+
+		if _, err := semver.NewVersion(version); err != nil {
+			return fmt.Errorf("invalid version for extra module %q: %w", path, err)
+		}
+
+		for _, m := range deps {
+			if m.Path == path {
+				return fmt.Errorf("extra module %q conflicts with dependency", path)
+			}
+		}
+	}
+
+	return nil
+}
+
+// goFlagsAllowed are the flags requests can set in GOFLAGS. Other flags can execute arbitrary
+// commands (e.g. -toolexec, or -ldflags with -extld) or change the sources of the build (e.g. -overlay, -modfile).
+var goFlagsAllowed = []string{"-buildvcs", "-mod", "-modcacherw", "-tags", "-trimpath"}
+
+// checkGoEnv checks if the go environment variables can be overridden
+func (b *Builder) checkGoEnv(env map[string]string) error {
+	for name, value := range env {
+		if !slices.Contains(b.opts.AllowedGoEnv, name) {
+			return fmt.Errorf("%w: %q", ErrGoEnvNotAllowed, name)
+		}
+
+		if name != "GOFLAGS" {
+			continue
+		}
+
+		// GOFLAGS is a space-separated list of -flag=value settings. Flags can have one or two dashes.
+		for _, flag := range strings.Fields(value) {
+			flagName, _, _ := strings.Cut(flag, "=")
+			if !slices.Contains(goFlagsAllowed, "-"+strings.TrimLeft(flagName, "-")) {
+				return fmt.Errorf("%w: GOFLAGS flag %q", ErrGoEnvNotAllowed, flagName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ArtifactID returns the unique identifier of the artifact built for a platform with the given
+// go toolchain version, resolved dependencies and build options.
+// Builds with different toolchains produce different binaries, so they have different ids.
+// Tools that need the id a build service assigns, without building the artifact, can obtain it
+// from the service's Plan (POST /plan), which resolves the dependencies using the service's catalog.
+//
+// The id is the hex encoded sha256 hash of a sequence of length-prefixed fields ("<length>:<value>"),
+// so different inputs cannot produce the same sequence. The fields are the platform, the go version
+// and the k6 version, followed by a record for each dependency, pinned module, extra module,
+// instrumentation flag and go environment override, in sorted order. Each record starts with its kind
+// (dep, pin, mod, flag, env) followed by the name and, except for flags, the version or value.
+// Dependencies whose module is replaced are followed by a replacement record (rep) with the replacing module.
+func ArtifactID(platform string, goVersion string, deps map[string]catalog.Module, opts k6build.BuildOptions) string {
+	hashData := &bytes.Buffer{}
+	writeFields(hashData, platform, goVersion, deps[k6DependencyName].Version)
 
-	// add the other dependencies
 	for _, d := range slices.Sorted(maps.Keys(deps)) {
 		if d == k6DependencyName {
 			continue
 		}
-		hashData.WriteString(fmt.Sprintf(":%s%s", d, deps[d].Version))
+		writeFields(hashData, "dep", d, deps[d].Version)
+		if deps[d].Replace != "" {
+			writeFields(hashData, "rep", deps[d].Replace)
+		}
+	}
+
+	for _, p := range slices.Sorted(maps.Keys(opts.Pins)) {
+		writeFields(hashData, "pin", p, opts.Pins[p])
+	}
+
+	for _, m := range slices.Sorted(maps.Keys(opts.ExtraModules)) {
+		writeFields(hashData, "mod", m, opts.ExtraModules[m])
+	}
+
+	for _, f := range opts.BuildFlags() {
+		writeFields(hashData, "flag", f)
 	}
 
-	return fmt.Sprintf("%x", sha1.Sum(hashData.Bytes())) //nolint:gosec
+	for _, e := range slices.Sorted(maps.Keys(opts.GoEnv)) {
+		writeFields(hashData, "env", e, opts.GoEnv[e])
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(hashData.Bytes()))
+}
+
+// writeFields writes each field prefixed by its length
+func writeFields(buffer *bytes.Buffer, fields ...string) {
+	for _, f := range fields {
+		fmt.Fprintf(buffer, "%d:%s", len(f), f)
+	}
 }
 
 func resolvedVersions(deps map[string]catalog.Module) map[string]string {
@@ -350,8 +1354,10 @@ func (b *Builder) buildArtifact(
 	ctx context.Context,
 	platform string,
 	deps map[string]catalog.Module,
+	opts k6build.BuildOptions,
+	isolatedCache bool,
 	artifactBuffer io.Writer,
-) error {
+) (*k6foundry.BuildInfo, error) {
 	// already checked the platform is valid, should be safe to ignore the error
 	buildPlatform, _ := k6foundry.ParsePlatform(platform)
 
@@ -363,19 +1369,65 @@ func (b *Builder) buildArtifact(
 		if k == k6DependencyName {
 			continue
 		}
-		mods = append(mods, k6foundry.Module{Path: m.Path, Version: m.Version})
 		cgoEnabled = cgoEnabled || m.Cgo
-	}
 
-	// set CGO_ENABLED if any of the dependencies require it
-	env := b.opts.Env
-	if cgoEnabled {
-		if env == nil {
-			env = map[string]string{}
+		if m.Replace != "" {
+			// the replacement applies to any version of the module required by the build
+			path, version, _ := strings.Cut(m.Replace, "@")
+			mods = append(mods, k6foundry.Module{Path: m.Path, ReplacePath: path, ReplaceVersion: version})
+			continue
 		}
+
+		mods = append(mods, k6foundry.Module{Path: m.Path, Version: m.Version})
+	}
+
+	// add the extra modules, which are not resolved by the catalog
+	for _, p := range slices.Sorted(maps.Keys(opts.ExtraModules)) {
+		mods = append(mods, k6foundry.Module{Path: p, Version: opts.ExtraModules[p]})
+	}
+
+	env := maps.Clone(b.opts.Env)
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	// the request's overrides were checked against the allowed variables
+	maps.Copy(env, opts.GoEnv)
+
+	// set CGO_ENABLED if any of the dependencies or the race detector require it
+	if cgoEnabled || opts.Race {
 		env["CGO_ENABLED"] = "1"
 	}
 
+	// cross-compiling with cgo uses the platform's toolchain, if one is configured
+	toolchain, found := b.opts.Toolchains[platform]
+	if found && env["CGO_ENABLED"] == "1" && platform != hostPlatform() {
+		maps.Copy(env, toolchain.env())
+	}
+
+	if b.opts.CacheDir != "" {
+		goVersion, err := b.toolchainVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(env, cacheEnv(b.opts.CacheDir, goVersion, buildPlatform))
+	}
+
+	// the temporary files of the go commands are kept in a dir that is removed after the build
+	scratchDir, err := b.newScratchDir()
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrInitializingBuilder, err)
+	}
+	defer b.removeScratchDir(ctx, scratchDir)
+
+	env["GOTMPDIR"] = scratchDir
+	env["TMPDIR"] = scratchDir
+
+	// an empty build cache, removed with the scratch dir, ensures nothing is reused from previous builds
+	if isolatedCache {
+		env["GOCACHE"] = filepath.Join(scratchDir, "gocache")
+	}
+
 	builderOpts := k6foundry.NativeFoundryOpts{
 		GoOpts: k6foundry.GoOpts{
 			Env:       env,
@@ -387,9 +1439,15 @@ func (b *Builder) buildArtifact(
 		builderOpts.Stderr = os.Stderr
 	}
 
+	// verify the modules before building, as the build uses the modules downloaded for the verification
+	err = b.verifyModuleSums(ctx, env, deps)
+	if err != nil {
+		return nil, err
+	}
+
 	builder, err := b.foundry.NewFoundry(ctx, builderOpts)
 	if err != nil {
-		return k6build.NewWrappedError(ErrInitializingBuilder, err)
+		return nil, k6build.NewWrappedError(ErrInitializingBuilder, err)
 	}
 
 	// if the version is a build version, we need the build metadata and ignore the version
@@ -399,12 +1457,18 @@ func (b *Builder) buildArtifact(
 		k6Version = build
 	}
 
-	_, err = builder.Build(ctx, buildPlatform, k6Version, mods, nil, []string{}, artifactBuffer)
+	// pin modules by replacing them with the pinned version
+	replacements := []k6foundry.Module{}
+	for _, p := range slices.Sorted(maps.Keys(opts.Pins)) {
+		replacements = append(replacements, k6foundry.Module{Path: p, ReplacePath: p, ReplaceVersion: opts.Pins[p]})
+	}
+
+	buildInfo, err := builder.Build(ctx, buildPlatform, k6Version, mods, replacements, opts.BuildFlags(), artifactBuffer)
 	if err != nil {
 		b.metrics.buildsFailedCounter.Inc()
-		return k6build.NewWrappedError(ErrAccessingArtifact, err)
+		return nil, k6build.NewWrappedError(ErrAccessingArtifact, err)
 	}
 
 	// TODO: complete artifact info
-	return nil
+	return buildInfo, nil
 }
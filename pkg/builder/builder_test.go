@@ -1,19 +1,34 @@
 package builder
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/api"
 	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/grafana/k6build/pkg/lock"
+	"github.com/grafana/k6build/pkg/signing"
+	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/store/file"
+	"github.com/grafana/k6build/pkg/store/memory"
 	"github.com/grafana/k6foundry"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/testutil"
@@ -44,6 +59,7 @@ func (m *mockFoundry) Build(
 	for _, mod := range mods {
 		modVersions[mod.Path] = mod.Version
 	}
+	_, _ = out.Write([]byte("k6 binary"))
 	return &k6foundry.BuildInfo{
 		Platform:    platform.String(),
 		ModVersions: modVersions,
@@ -79,11 +95,12 @@ func TestBuild(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		title     string
-		k6        string
-		deps      []k6build.Dependency
-		expectErr error
-		expect    k6build.Artifact
+		title      string
+		k6         string
+		deps       []k6build.Dependency
+		expectErr  error
+		expectCode k6build.ErrorCode
+		expect     k6build.Artifact
 	}{
 		{
 			title:     "build k6 v0.1.0 ",
@@ -106,10 +123,18 @@ func TestBuild(t *testing.T) {
 			},
 		},
 		{
-			title:     "build unsatisfied constrain (>v0.2.0)",
-			k6:        ">v0.2.0",
-			deps:      []k6build.Dependency{},
-			expectErr: ErrInvalidParameters,
+			title:      "build unsatisfied constrain (>v0.2.0)",
+			k6:         ">v0.2.0",
+			deps:       []k6build.Dependency{},
+			expectErr:  ErrInvalidParameters,
+			expectCode: k6build.ErrorCodeCannotSatisfy,
+		},
+		{
+			title:      "build unknown dependency",
+			k6:         "v0.1.0",
+			deps:       []k6build.Dependency{{Name: "k6/x/unknown", Constraints: "*"}},
+			expectErr:  ErrInvalidParameters,
+			expectCode: k6build.ErrorCodeUnknownDependency,
 		},
 	}
 
@@ -134,6 +159,10 @@ func TestBuild(t *testing.T) {
 				t.Fatalf("unexpected error wanted %v got %v", tc.expectErr, err)
 			}
 
+			if code := k6build.ErrorCodeOf(err); code != tc.expectCode {
+				t.Fatalf("unexpected error code wanted %q got %q", tc.expectCode, code)
+			}
+
 			// don't check artifact if error is expected
 			if tc.expectErr != nil {
 				return
@@ -144,6 +173,15 @@ func TestBuild(t *testing.T) {
 			if diff != "" {
 				t.Fatalf("dependencies don't match: %s\n", diff)
 			}
+
+			object, err := buildsrv.store.Get(context.TODO(), artifact.ID)
+			if err != nil {
+				t.Fatalf("retrieving artifact %v", err)
+			}
+
+			if artifact.Size == 0 || artifact.Size != object.Size {
+				t.Fatalf("expected size %d got %d", object.Size, artifact.Size)
+			}
 		})
 	}
 }
@@ -557,3 +595,3068 @@ func TestMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildManifest(t *testing.T) {
+	t.Parallel()
+
+	buildsrv, err := SetupTestBuilder(t)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	platforms := []string{"linux/amd64", "darwin/arm64"}
+	deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+	m, err := buildsrv.BuildManifest(context.TODO(), platforms, "v0.1.0", deps)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if m.URL == "" {
+		t.Fatalf("manifest URL is empty")
+	}
+
+	for _, p := range platforms {
+		artifact, err := buildsrv.Build(context.TODO(), p, "v0.1.0", deps)
+		if err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+
+		entry, err := m.Resolve(p)
+		if err != nil {
+			t.Fatalf("resolving %s %v", p, err)
+		}
+
+		if entry.ID != artifact.ID || entry.Checksum != artifact.Checksum {
+			t.Fatalf("manifest entry for %s doesn't match artifact", p)
+		}
+	}
+
+	// the order of the platforms doesn't change the manifest
+	same, err := buildsrv.BuildManifest(context.TODO(), []string{"darwin/arm64", "linux/amd64"}, "v0.1.0", deps)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if same.ID != m.ID {
+		t.Fatalf("expected same manifest id")
+	}
+
+	// a different set of platforms is a different manifest
+	other, err := buildsrv.BuildManifest(context.TODO(), []string{"linux/arm64"}, "v0.1.0", deps)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if other.ID == m.ID {
+		t.Fatalf("expected different manifest id")
+	}
+
+	if _, err := other.Resolve("linux/arm64"); err != nil {
+		t.Fatalf("resolving linux/arm64 %v", err)
+	}
+
+	if _, err := other.Resolve("linux/amd64"); err == nil {
+		t.Fatalf("expected linux/amd64 not in manifest")
+	}
+}
+
+// mockLock simulates a distributed lock. If peer is set, it is called before the lock is granted
+// to simulate another builder completing the build while waiting for the lock.
+type mockLock struct {
+	mutex    sync.Mutex
+	locked   int
+	released int
+	peer     func(id string)
+}
+
+func (l *mockLock) Lock(_ context.Context, id string) (func(), error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.locked++
+	if l.peer != nil {
+		l.peer(id)
+	}
+
+	return func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+		l.released++
+	}, nil
+}
+
+func TestDistributedLock(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		peerBuild   bool
+		expectBuild float64
+	}{
+		{
+			title:       "build artifact",
+			peerBuild:   false,
+			expectBuild: 1,
+		},
+		{
+			title:       "artifact built by peer while waiting for lock",
+			peerBuild:   true,
+			expectBuild: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			lock := &mockLock{}
+			if tc.peerBuild {
+				lock.peer = func(id string) {
+					_, _ = store.Put(context.TODO(), id, strings.NewReader("peer"))
+				}
+			}
+
+			builder, err := New(context.Background(), Config{
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(MockFoundryFactory),
+				Lock:    lock,
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			artifact, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", []k6build.Dependency{})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if lock.locked != 1 || lock.released != 1 {
+				t.Fatalf("expected lock acquired and released once got %d/%d", lock.locked, lock.released)
+			}
+
+			if builds := testutil.ToFloat64(builder.metrics.buildCounter); builds != tc.expectBuild {
+				t.Fatalf("expected %f builds got %f", tc.expectBuild, builds)
+			}
+
+			// once built, the lock should not be requested again
+			rebuild, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", []k6build.Dependency{})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if rebuild.ID != artifact.ID || lock.locked != 1 {
+				t.Fatalf("expected artifact returned from store")
+			}
+		})
+	}
+}
+
+// recordingFoundry records the modules, replacements and build flags requested to the foundry
+type recordingFoundry struct {
+	mockFoundry
+	mutex sync.Mutex
+	mods  []k6foundry.Module
+	reps  []k6foundry.Module
+	flags []string
+}
+
+func (r *recordingFoundry) Build(
+	ctx context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	reps []k6foundry.Module,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	r.mutex.Lock()
+	r.mods = append(r.mods, mods...)
+	r.reps = append(r.reps, reps...)
+	r.flags = append(r.flags, buildOpts...)
+	r.mutex.Unlock()
+
+	return r.mockFoundry.Build(ctx, platform, k6Version, mods, reps, buildOpts, out)
+}
+
+func TestModulePins(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		allow     bool
+		pins      map[string]string
+		expectErr error
+	}{
+		{
+			title: "pin indirect module",
+			allow: true,
+			pins:  map[string]string{"google.golang.org/grpc": "v1.64.1"},
+		},
+		{
+			title:     "pins not allowed",
+			allow:     false,
+			pins:      map[string]string{"google.golang.org/grpc": "v1.64.1"},
+			expectErr: ErrInvalidParameters,
+		},
+		{
+			title:     "pin conflicts with dependency",
+			allow:     true,
+			pins:      map[string]string{"go.k6.io/k6ext": "v0.2.0"},
+			expectErr: ErrInvalidParameters,
+		},
+		{
+			title:     "invalid pin version",
+			allow:     true,
+			pins:      map[string]string{"google.golang.org/grpc": "latest"},
+			expectErr: ErrInvalidParameters,
+		},
+		{
+			title:     "incomplete pin version",
+			allow:     true,
+			pins:      map[string]string{"google.golang.org/grpc": "v1.64"},
+			expectErr: ErrInvalidParameters,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			foundry := &recordingFoundry{}
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{AllowModulePins: tc.allow},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, _ k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+			unpinned, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			ctx := k6build.WithBuildOptions(context.TODO(), k6build.BuildOptions{Pins: tc.pins})
+			pinned, err := builder.Build(ctx, "linux/amd64", "v0.1.0", deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if pinned.ID == unpinned.ID {
+				t.Fatalf("expected pinned artifact to have a different id")
+			}
+
+			for path, version := range tc.pins {
+				found := false
+				for _, rep := range foundry.reps {
+					if rep.Path == path && rep.ReplaceVersion == version {
+						found = true
+					}
+				}
+				if !found {
+					t.Fatalf("pin %s=%s not passed to foundry", path, version)
+				}
+			}
+		})
+	}
+}
+
+func TestCommitDependencies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title      string
+		allow      bool
+		denied     bool
+		dep        k6build.Dependency
+		expectErr  error
+		expectCode k6build.ErrorCode
+	}{
+		{
+			title: "build from commit",
+			allow: true,
+			dep:   k6build.Dependency{Name: "k6/x/ext", Constraints: "commit:0123abc"},
+		},
+		{
+			title:      "commits not allowed",
+			allow:      false,
+			dep:        k6build.Dependency{Name: "k6/x/ext", Constraints: "commit:0123abc"},
+			expectErr:  ErrCommitNotAllowed,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+		{
+			title:      "commits not authorized",
+			allow:      true,
+			denied:     true,
+			dep:        k6build.Dependency{Name: "k6/x/ext", Constraints: "commit:0123abc"},
+			expectErr:  ErrCommitNotAllowed,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+		{
+			title:      "invalid commit",
+			allow:      true,
+			dep:        k6build.Dependency{Name: "k6/x/ext", Constraints: "commit:main"},
+			expectErr:  ErrInvalidParameters,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+		{
+			title:      "unknown dependency",
+			allow:      true,
+			dep:        k6build.Dependency{Name: "k6/x/unknown", Constraints: "commit:0123abc"},
+			expectErr:  ErrInvalidParameters,
+			expectCode: k6build.ErrorCodeUnknownDependency,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			foundry := &recordingFoundry{}
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{AllowBuildSemvers: tc.allow},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, _ k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			ctx := context.TODO()
+			if tc.denied {
+				ctx = k6build.WithBuildSemversAllowed(ctx, false)
+			}
+
+			artifact, err := builder.Build(ctx, "linux/amd64", "v0.1.0", []k6build.Dependency{tc.dep})
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if code := k6build.ErrorCodeOf(err); code != tc.expectCode {
+				t.Fatalf("expected code %q got %q", tc.expectCode, code)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			commit := strings.TrimPrefix(tc.dep.Constraints, "commit:")
+			if version := artifact.Dependencies[tc.dep.Name]; version != commit {
+				t.Fatalf("expected version %q got %q", commit, version)
+			}
+
+			expected := []k6foundry.Module{{Path: "go.k6.io/k6ext", Version: commit}}
+			if diff := cmp.Diff(expected, foundry.mods); diff != "" {
+				t.Fatalf("unexpected modules (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDuplicateDependencies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title      string
+		deps       []k6build.Dependency
+		expectErr  error
+		expectCode k6build.ErrorCode
+	}{
+		{
+			title: "identical duplicates",
+			deps: []k6build.Dependency{
+				{Name: "k6/x/ext", Constraints: "v0.1.0"},
+				{Name: "k6/x/ext", Constraints: "v0.1.0"},
+			},
+		},
+		{
+			title: "conflicting constraints",
+			deps: []k6build.Dependency{
+				{Name: "k6/x/ext", Constraints: "v0.1.0"},
+				{Name: "k6/x/ext", Constraints: ">v0.2.0"},
+			},
+			expectErr:  ErrInvalidParameters,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+		{
+			title: "conflicting replacements",
+			deps: []k6build.Dependency{
+				{Name: "k6/x/ext", Constraints: "v0.1.0"},
+				{Name: "k6/x/ext", Constraints: "v0.1.0", Replace: "github.com/example/k6ext@v0.1.1"},
+			},
+			expectErr:  ErrInvalidParameters,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			builder, err := SetupTestBuilder(t)
+			if err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+
+			artifact, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", tc.deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if code := k6build.ErrorCodeOf(err); code != tc.expectCode {
+				t.Fatalf("expected code %q got %q", tc.expectCode, code)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			// duplicates are ignored, so the artifact is the same as requesting the dependency once
+			single, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", tc.deps[:1])
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if artifact.ID != single.ID {
+				t.Fatalf("expected artifact %s got %s", single.ID, artifact.ID)
+			}
+		})
+	}
+}
+
+func TestReplaceDependencies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title      string
+		allow      bool
+		replace    string
+		expectErr  error
+		expectCode k6build.ErrorCode
+		expect     k6foundry.Module
+	}{
+		{
+			title:   "replace with fork",
+			allow:   true,
+			replace: "github.com/example/k6ext@v0.1.1",
+			expect: k6foundry.Module{
+				Path:           "go.k6.io/k6ext",
+				ReplacePath:    "github.com/example/k6ext",
+				ReplaceVersion: "v0.1.1",
+			},
+		},
+		{
+			title:   "replace with url and commit",
+			allow:   true,
+			replace: "https://github.com/example/k6ext@0123abc",
+			expect: k6foundry.Module{
+				Path:           "go.k6.io/k6ext",
+				ReplacePath:    "github.com/example/k6ext",
+				ReplaceVersion: "0123abc",
+			},
+		},
+		{
+			title:      "replace not allowed",
+			allow:      false,
+			replace:    "github.com/example/k6ext@v0.1.1",
+			expectErr:  ErrReplaceNotAllowed,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+		{
+			title:      "host not allowed",
+			allow:      true,
+			replace:    "gitlab.com/example/k6ext@v0.1.1",
+			expectErr:  ErrReplaceNotAllowed,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+		{
+			title:      "path prefix not allowed",
+			allow:      true,
+			replace:    "github.com/examples/k6ext@v0.1.1",
+			expectErr:  ErrReplaceNotAllowed,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+		{
+			title:      "missing version",
+			allow:      true,
+			replace:    "github.com/example/k6ext",
+			expectErr:  ErrInvalidParameters,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+		{
+			title:      "local path",
+			allow:      true,
+			replace:    "../k6ext@v0.1.1",
+			expectErr:  ErrInvalidParameters,
+			expectCode: k6build.ErrorCodeInvalidRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			foundry := &recordingFoundry{}
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{AllowReplace: tc.allow, ReplaceHosts: []string{"github.com/example"}},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, _ k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			dep := k6build.Dependency{Name: "k6/x/ext", Constraints: "v0.1.0"}
+			original, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", []k6build.Dependency{dep})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			dep.Replace = tc.replace
+			replaced, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", []k6build.Dependency{dep})
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if code := k6build.ErrorCodeOf(err); code != tc.expectCode {
+				t.Fatalf("expected code %q got %q", tc.expectCode, code)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if replaced.ID == original.ID {
+				t.Fatalf("expected replaced artifact to have a different id")
+			}
+
+			if diff := cmp.Diff(tc.expect, foundry.mods[len(foundry.mods)-1]); diff != "" {
+				t.Fatalf("unexpected module (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestExtraModules(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		allow     bool
+		modules   map[string]string
+		expectErr error
+	}{
+		{
+			title:   "add extra module",
+			allow:   true,
+			modules: map[string]string{"github.com/example/logger": "v0.1.0"},
+		},
+		{
+			title:     "extra modules not allowed",
+			allow:     false,
+			modules:   map[string]string{"github.com/example/logger": "v0.1.0"},
+			expectErr: ErrInvalidParameters,
+		},
+		{
+			title:     "extra module conflicts with dependency",
+			allow:     true,
+			modules:   map[string]string{"go.k6.io/k6ext": "v0.2.0"},
+			expectErr: ErrInvalidParameters,
+		},
+		{
+			title:     "invalid extra module version",
+			allow:     true,
+			modules:   map[string]string{"github.com/example/logger": "latest"},
+			expectErr: ErrInvalidParameters,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			foundry := &recordingFoundry{}
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{AllowExtraModules: tc.allow},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, _ k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+			plain, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			ctx := k6build.WithBuildOptions(context.TODO(), k6build.BuildOptions{ExtraModules: tc.modules})
+			extended, err := builder.Build(ctx, "linux/amd64", "v0.1.0", deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if extended.ID == plain.ID {
+				t.Fatalf("expected artifact with extra modules to have a different id")
+			}
+
+			for path, version := range tc.modules {
+				found := false
+				for _, mod := range foundry.mods {
+					if mod.Path == path && mod.Version == version {
+						found = true
+					}
+				}
+				if !found {
+					t.Fatalf("extra module %s@%s not passed to foundry", path, version)
+				}
+			}
+		})
+	}
+}
+
+func TestGoEnv(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		allowed   []string
+		goEnv     map[string]string
+		expectErr error
+	}{
+		{
+			title:   "override allowed variables",
+			allowed: []string{"GOPROXY", "GOFLAGS"},
+			goEnv:   map[string]string{"GOPROXY": "https://proxy.example.com,direct", "GOFLAGS": "-mod=mod --trimpath"},
+		},
+		{
+			title:     "GOFLAGS flag not allowed",
+			allowed:   []string{"GOFLAGS"},
+			goEnv:     map[string]string{"GOFLAGS": "-trimpath -toolexec=/bin/sh"},
+			expectErr: ErrGoEnvNotAllowed,
+		},
+		{
+			title:     "GOFLAGS linker flags not allowed",
+			allowed:   []string{"GOFLAGS"},
+			goEnv:     map[string]string{"GOFLAGS": "-ldflags=-extld=/bin/sh"},
+			expectErr: ErrGoEnvNotAllowed,
+		},
+		{
+			title:     "variable not allowed",
+			allowed:   []string{"GOPROXY"},
+			goEnv:     map[string]string{"GOPRIVATE": "*"},
+			expectErr: ErrGoEnvNotAllowed,
+		},
+		{
+			title:     "no variables allowed",
+			goEnv:     map[string]string{"GOPROXY": "https://proxy.example.com"},
+			expectErr: ErrGoEnvNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			var env map[string]string
+			builder, err := New(context.Background(), Config{
+				Opts: Opts{
+					AllowedGoEnv: tc.allowed,
+					GoOpts:       GoOpts{Env: map[string]string{"GOPROXY": "https://proxy.golang.org"}},
+				},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, opts k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						env = opts.Env
+						return &mockFoundry{}, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+			plain, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			ctx := k6build.WithBuildOptions(context.TODO(), k6build.BuildOptions{GoEnv: tc.goEnv})
+			overridden, err := builder.Build(ctx, "linux/amd64", "v0.1.0", deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if overridden.ID == plain.ID {
+				t.Fatalf("expected artifact with go env overrides to have a different id")
+			}
+
+			for name, value := range tc.goEnv {
+				if env[name] != value {
+					t.Fatalf("expected %s=%q got %q", name, value, env[name])
+				}
+			}
+		})
+	}
+}
+
+func TestCacheNamespace(t *testing.T) {
+	t.Parallel()
+
+	cacheDir := t.TempDir()
+
+	testCases := []struct {
+		title     string
+		goVersion string
+		platform  string
+	}{
+		{
+			title:     "go1.23 linux/amd64",
+			goVersion: "go1.23.4",
+			platform:  "linux/amd64",
+		},
+		{
+			title:     "go1.24 linux/amd64",
+			goVersion: "go1.24.1",
+			platform:  "linux/amd64",
+		},
+	}
+
+	envs := make([]map[string]string, len(testCases))
+	for i, tc := range testCases {
+		store, err := file.NewFileStore(t.TempDir())
+		if err != nil {
+			t.Fatalf("creating temporary object store %v", err)
+		}
+
+		var foundryOpts k6foundry.NativeFoundryOpts
+		builder, err := New(context.Background(), Config{
+			Opts:    Opts{CacheDir: cacheDir},
+			Catalog: filepath.Join("testdata", "catalog.json"),
+			Store:   store,
+			Foundry: FoundryFactoryFunction(
+				func(ctx context.Context, opts k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+					foundryOpts = opts
+					return MockFoundryFactory(ctx, opts)
+				},
+			),
+		})
+		if err != nil {
+			t.Fatalf("%s: creating builder %v", tc.title, err)
+		}
+
+		// simulate the toolchain used by the builder
+		builder.goVersion = tc.goVersion
+
+		_, err = builder.Build(context.TODO(), tc.platform, "v0.1.0", []k6build.Dependency{})
+		if err != nil {
+			t.Fatalf("%s: unexpected %v", tc.title, err)
+		}
+
+		for _, v := range []string{"GOMODCACHE", "GOCACHE"} {
+			dir := foundryOpts.Env[v]
+			if !strings.HasPrefix(dir, filepath.Join(cacheDir, tc.goVersion)+string(filepath.Separator)) {
+				t.Fatalf("%s: expected %s under %s got %q", tc.title, v, tc.goVersion, dir)
+			}
+		}
+
+		envs[i] = foundryOpts.Env
+	}
+
+	for _, v := range []string{"GOMODCACHE", "GOCACHE"} {
+		if envs[0][v] == envs[1][v] {
+			t.Fatalf("expected separate %s for each toolchain got %q", v, envs[0][v])
+		}
+	}
+}
+
+func TestToolchainArtifactID(t *testing.T) {
+	t.Parallel()
+
+	store, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating temporary object store %v", err)
+	}
+
+	deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+	ids := map[string]string{}
+	for _, version := range []string{"go1.23.4", "go1.24.1"} {
+		builder, err := New(context.Background(), Config{
+			Catalog: filepath.Join("testdata", "catalog.json"),
+			Store:   store,
+			Foundry: FoundryFactoryFunction(MockFoundryFactory),
+		})
+		if err != nil {
+			t.Fatalf("creating builder %v", err)
+		}
+
+		// simulate the toolchain used by the builder
+		builder.goVersion = version
+
+		artifact, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+		if err != nil {
+			t.Fatalf("%s: unexpected %v", version, err)
+		}
+
+		if artifact.BuildInfo == nil || artifact.BuildInfo.GoVersion != version {
+			t.Fatalf("%s: unexpected build info %v", version, artifact.BuildInfo)
+		}
+
+		plan, err := builder.Plan(context.TODO(), "linux/amd64", "v0.1.0", deps)
+		if err != nil {
+			t.Fatalf("%s: unexpected %v", version, err)
+		}
+
+		if plan.ID != artifact.ID {
+			t.Fatalf("%s: plan id %s does not match artifact id %s", version, plan.ID, artifact.ID)
+		}
+
+		ids[artifact.ID] = version
+	}
+
+	if len(ids) != 2 {
+		t.Fatalf("expected a different id for each toolchain got %v", ids)
+	}
+}
+
+// sequenceFoundry returns the outputs in sequence for successive builds
+type sequenceFoundry struct {
+	mutex   sync.Mutex
+	outputs []string
+	mods    []map[string]string
+	builds  int
+}
+
+func (s *sequenceFoundry) Build(
+	_ context.Context,
+	platform k6foundry.Platform,
+	_ string,
+	_ []k6foundry.Module,
+	_ []k6foundry.Module,
+	_ []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	build := s.builds
+	s.builds++
+
+	_, err := out.Write([]byte(s.outputs[build]))
+	if err != nil {
+		return nil, err
+	}
+
+	return &k6foundry.BuildInfo{Platform: platform.String(), ModVersions: s.mods[build]}, nil
+}
+
+func TestVerifyReproducible(t *testing.T) {
+	t.Parallel()
+
+	mods := map[string]string{"go.k6.io/k6": "v0.1.0"}
+
+	testCases := []struct {
+		title        string
+		outputs      []string
+		mods         []map[string]string
+		reproducible bool
+		expectDiff   []string
+	}{
+		{
+			title:        "identical outputs",
+			outputs:      []string{"binary", "binary"},
+			mods:         []map[string]string{mods, mods},
+			reproducible: true,
+			expectDiff:   []string{},
+		},
+		{
+			title:        "different outputs",
+			outputs:      []string{"binary", "other binary"},
+			mods:         []map[string]string{mods, mods},
+			reproducible: false,
+			expectDiff:   []string{"checksum"},
+		},
+		{
+			title:   "different module versions",
+			outputs: []string{"binary", "other binary"},
+			mods: []map[string]string{
+				mods,
+				{"go.k6.io/k6": "v0.1.0", "golang.org/x/net": "v0.1.0"},
+			},
+			reproducible: false,
+			expectDiff:   []string{"checksum", "module golang.org/x/net"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			foundry := &sequenceFoundry{outputs: tc.outputs, mods: tc.mods}
+			cacheDir := t.TempDir()
+			buildCaches := []string{}
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{CacheDir: cacheDir},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, opts k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						buildCaches = append(buildCaches, opts.Env["GOCACHE"])
+						return foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			report, err := builder.VerifyReproducible(context.TODO(), "linux/amd64", "v0.1.0", []k6build.Dependency{})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if foundry.builds != 2 {
+				t.Fatalf("expected 2 builds got %d", foundry.builds)
+			}
+
+			// each build uses its own build cache instead of the shared one
+			if buildCaches[0] == buildCaches[1] {
+				t.Fatalf("expected different build caches got %v", buildCaches)
+			}
+			for _, buildCache := range buildCaches {
+				if buildCache == "" || strings.HasPrefix(buildCache, cacheDir) {
+					t.Fatalf("expected isolated build cache got %q", buildCache)
+				}
+			}
+
+			if report.Reproducible != tc.reproducible {
+				t.Fatalf("expected reproducible %t got %t", tc.reproducible, report.Reproducible)
+			}
+
+			if len(report.Differences) != len(tc.expectDiff) {
+				t.Fatalf("expected differences %v got %v", tc.expectDiff, report.Differences)
+			}
+
+			for i, diff := range tc.expectDiff {
+				if !strings.HasPrefix(report.Differences[i], diff) {
+					t.Fatalf("expected difference %q got %q", diff, report.Differences[i])
+				}
+			}
+		})
+	}
+}
+
+func TestCatalogReload(t *testing.T) {
+	t.Parallel()
+
+	catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+	content, err := os.ReadFile(filepath.Join("testdata", "catalog.json"))
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+	if err = os.WriteFile(catalogFile, content, 0o644); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	store, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating temporary object store %v", err)
+	}
+
+	builder, err := New(context.Background(), Config{
+		Catalog:               catalogFile,
+		CatalogReloadInterval: time.Hour,
+		Store:                 store,
+		Foundry:               FoundryFactoryFunction(MockFoundryFactory),
+	})
+	if err != nil {
+		t.Fatalf("creating builder %v", err)
+	}
+
+	// the catalog is broken, the last loaded catalog must be used
+	if err = os.WriteFile(catalogFile, []byte("invalid"), 0o644); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	if err = builder.ReloadCatalog(context.TODO()); err == nil {
+		t.Fatalf("expected reload to fail")
+	}
+
+	if failed := testutil.ToFloat64(builder.metrics.catalogReloadsFailed); failed != 1 {
+		t.Fatalf("expected 1 failed reload got %f", failed)
+	}
+
+	_, err = builder.Build(context.TODO(), "linux/amd64", "v0.1.0", []k6build.Dependency{})
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	builder, err := SetupTestBuilder(t)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+	plan, err := builder.Plan(context.TODO(), "linux/amd64", "v0.1.0", deps)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if plan.Cached {
+		t.Fatalf("expected artifact not cached")
+	}
+
+	expectModules := map[string]string{"k6": "go.k6.io/k6", "k6/x/ext": "go.k6.io/k6ext"}
+	if diff := cmp.Diff(expectModules, plan.Modules); diff != "" {
+		t.Fatalf("modules mismatch (-want +got):\n%s", diff)
+	}
+
+	artifact, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if artifact.ID != plan.ID {
+		t.Fatalf("expected plan id %s got artifact id %s", plan.ID, artifact.ID)
+	}
+
+	plan, err = builder.Plan(context.TODO(), "linux/amd64", "v0.1.0", deps)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if !plan.Cached {
+		t.Fatalf("expected artifact cached")
+	}
+
+	if builds := testutil.ToFloat64(builder.metrics.buildCounter); builds != 1 {
+		t.Fatalf("expected plans not to build got %f builds", builds)
+	}
+
+	_, err = builder.Plan(context.TODO(), "linux/amd64", "v0.1.0", []k6build.Dependency{{Name: "unknown"}})
+	if !errors.Is(err, ErrInvalidParameters) {
+		t.Fatalf("expected %v got %v", ErrInvalidParameters, err)
+	}
+}
+
+func TestEmptyDependencies(t *testing.T) {
+	t.Parallel()
+
+	builder, err := SetupTestBuilder(t)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	expected, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", nil)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	testCases := []struct {
+		title   string
+		request string
+	}{
+		{
+			title:   "omitted dependencies",
+			request: `{"k6": "v0.1.0", "platform": "linux/amd64"}`,
+		},
+		{
+			title:   "null dependencies",
+			request: `{"k6": "v0.1.0", "platform": "linux/amd64", "dependencies": null}`,
+		},
+		{
+			title:   "empty dependencies",
+			request: `{"k6": "v0.1.0", "platform": "linux/amd64", "dependencies": []}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			req := api.BuildRequest{}
+			err := json.Unmarshal([]byte(tc.request), &req)
+			if err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+
+			artifact, err := builder.Build(context.TODO(), req.Platform, req.K6Constrains, req.Dependencies)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if artifact.ID != expected.ID {
+				t.Fatalf("expected id %s got %s", expected.ID, artifact.ID)
+			}
+
+			plan, err := builder.Plan(context.TODO(), req.Platform, req.K6Constrains, req.Dependencies)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if plan.ID != expected.ID {
+				t.Fatalf("expected plan id %s got %s", expected.ID, plan.ID)
+			}
+		})
+	}
+}
+
+func TestResolveDetails(t *testing.T) {
+	t.Parallel()
+
+	builder, err := SetupTestBuilder(t)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		k6        string
+		deps      []k6build.Dependency
+		expect    []k6build.DependencyResolution
+		expectErr error
+	}{
+		{
+			title: "latest versions",
+			k6:    "*",
+			deps:  []k6build.Dependency{{Name: "k6/x/ext2", Constraints: "*"}},
+			expect: []k6build.DependencyResolution{
+				{Name: "k6", Constraints: "*", Version: "v0.2.0", Latest: true, Available: []string{"v0.1.0", "v0.2.0"}},
+				{Name: "k6/x/ext2", Constraints: "*", Version: "v0.1.0", Latest: true, Available: []string{"v0.1.0"}},
+			},
+		},
+		{
+			title: "pinned versions",
+			k6:    "v0.1.0",
+			deps:  []k6build.Dependency{{Name: "k6/x/ext", Constraints: "<v0.2.0"}},
+			expect: []k6build.DependencyResolution{
+				{Name: "k6", Constraints: "v0.1.0", Version: "v0.1.0", Latest: false, Available: []string{"v0.1.0", "v0.2.0"}},
+				{
+					Name:        "k6/x/ext",
+					Constraints: "<v0.2.0",
+					Version:     "v0.1.0",
+					Latest:      false,
+					Available:   []string{"v0.1.0", "v0.2.0"},
+				},
+			},
+		},
+		{
+			title:     "unsatisfied constrain",
+			k6:        "v0.3.0",
+			expectErr: ErrResolvingDependencies,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			details, err := builder.ResolveDetails(context.TODO(), tc.k6, tc.deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if diff := cmp.Diff(tc.expect, details); tc.expectErr == nil && diff != "" {
+				t.Fatalf("resolution mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestConstrainResolution(t *testing.T) {
+	t.Parallel()
+
+	catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+	err := os.WriteFile(catalogFile, []byte(`{
+"k6": {"module": "go.k6.io/k6", "versions": ["v0.1.0", "v0.2.0", "v0.3.0-rc1"]},
+"k6/x/ext": {"module": "go.k6.io/k6ext", "versions": ["v0.1.0", "v0.1.1", "v0.2.0"]}
+}`), 0o600)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	// builders that include prereleases or not
+	builders := map[bool]*Builder{}
+	for _, prereleases := range []bool{false, true} {
+		builders[prereleases], err = New(context.Background(), Config{
+			Opts:    Opts{AllowBuildSemvers: true, IncludePrereleases: prereleases},
+			Catalog: catalogFile,
+			Store:   memory.NewMemoryStore(),
+			Foundry: FoundryFactoryFunction(MockFoundryFactory),
+		})
+		if err != nil {
+			t.Fatalf("test setup %v", err)
+		}
+	}
+
+	testCases := []struct {
+		title        string
+		k6           string
+		ext          string
+		prereleases  bool
+		opts         k6build.BuildOptions
+		expectK6     string
+		expectExt    string
+		expectLatest bool
+		expectErr    error
+	}{
+		{title: "latest", k6: "latest", ext: "latest", expectK6: "v0.2.0", expectExt: "v0.2.0", expectLatest: true},
+		{title: "any version", k6: "*", ext: "", expectK6: "v0.2.0", expectExt: "v0.2.0", expectLatest: true},
+		{title: "prerelease", k6: "v0.3.0-rc1", ext: "*", expectK6: "v0.3.0-rc1", expectExt: "v0.2.0"},
+		{title: "prerelease range", k6: ">=v0.3.0-rc1", ext: "*", expectK6: "v0.3.0-rc1", expectExt: "v0.2.0"},
+		{
+			title:        "minimum version",
+			k6:           ">=v0.1.0",
+			ext:          ">=v0.1.0",
+			expectK6:     "v0.2.0",
+			expectExt:    "v0.2.0",
+			expectLatest: true,
+		},
+		{title: "tilde range", k6: "~v0.1.0", ext: "~v0.1.0", expectK6: "v0.1.0", expectExt: "v0.1.1"},
+		{title: "caret range", k6: "^v0.1.0", ext: "^v0.1.0", expectK6: "v0.1.0", expectExt: "v0.1.1"},
+		{title: "build metadata", k6: "v0.0.0+abc", ext: "*", expectK6: "v0.0.0+abc", expectExt: "v0.2.0"},
+		{title: "build metadata range", k6: ">=v0.0.0+abc", ext: "*", expectErr: ErrInvalidParameters},
+		{
+			title:        "builder includes prereleases",
+			k6:           "latest",
+			ext:          "latest",
+			prereleases:  true,
+			expectK6:     "v0.3.0-rc1",
+			expectExt:    "v0.2.0",
+			expectLatest: true,
+		},
+		{
+			title:        "request includes prereleases",
+			k6:           ">v0.1.0",
+			ext:          "*",
+			opts:         k6build.BuildOptions{IncludePrereleases: true},
+			expectK6:     "v0.3.0-rc1",
+			expectExt:    "v0.2.0",
+			expectLatest: true,
+		},
+		{
+			title:       "build metadata including prereleases",
+			k6:          "v0.0.0+abc",
+			ext:         "~v0.1.0",
+			prereleases: true,
+			expectK6:    "v0.0.0+abc",
+			expectExt:   "v0.1.1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			details, err := builders[tc.prereleases].ResolveDetails(
+				k6build.WithBuildOptions(context.TODO(), tc.opts),
+				tc.k6,
+				[]k6build.Dependency{{Name: "k6/x/ext", Constraints: tc.ext}},
+			)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if details[0].Version != tc.expectK6 || details[1].Version != tc.expectExt {
+				t.Fatalf("expected k6 %s ext %s got %s %s", tc.expectK6, tc.expectExt, details[0].Version, details[1].Version)
+			}
+
+			if details[0].Latest != tc.expectLatest {
+				t.Fatalf("expected latest %t got %t", tc.expectLatest, details[0].Latest)
+			}
+		})
+	}
+}
+
+// slowFoundry takes the given delay to build, unless the context is canceled
+type slowFoundry struct {
+	mockFoundry
+	delay time.Duration
+}
+
+func (s *slowFoundry) Build(
+	ctx context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	reps []k6foundry.Module,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(s.delay):
+		return s.mockFoundry.Build(ctx, platform, k6Version, mods, reps, buildOpts, out)
+	}
+}
+
+func TestSlowBuilds(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		threshold time.Duration
+		delay     time.Duration
+		expect    float64
+	}{
+		{
+			title:     "build exceeds threshold",
+			threshold: 10 * time.Millisecond,
+			delay:     50 * time.Millisecond,
+			expect:    1,
+		},
+		{
+			title:     "build within threshold",
+			threshold: time.Minute,
+			delay:     0,
+			expect:    0,
+		},
+		{
+			title:     "threshold disabled",
+			threshold: 0,
+			delay:     50 * time.Millisecond,
+			expect:    0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			logBuffer := &bytes.Buffer{}
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{SlowBuildThreshold: tc.threshold},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, _ k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return &slowFoundry{delay: tc.delay}, nil
+					},
+				),
+				Log: slog.New(slog.NewTextHandler(logBuffer, &slog.HandlerOptions{})),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			_, err = builder.Build(context.TODO(), "linux/amd64", "v0.1.0", []k6build.Dependency{})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if slow := testutil.ToFloat64(builder.metrics.slowBuildsCounter); slow != tc.expect {
+				t.Fatalf("expected %f slow builds got %f", tc.expect, slow)
+			}
+
+			logged := strings.Contains(logBuffer.String(), "slow build")
+			if logged != (tc.expect > 0) {
+				t.Fatalf("expected slow build logged %t got log %q", tc.expect > 0, logBuffer.String())
+			}
+		})
+	}
+}
+
+func TestBuildFlags(t *testing.T) {
+	t.Parallel()
+
+	native := runtime.GOOS + "/" + runtime.GOARCH
+	other := "linux/amd64"
+	if native == other {
+		other = "linux/arm64"
+	}
+
+	testCases := []struct {
+		title     string
+		platform  string
+		opts      k6build.BuildOptions
+		expect    []string
+		expectErr error
+	}{
+		{
+			title:    "race detector",
+			platform: native,
+			opts:     k6build.BuildOptions{Race: true},
+			expect:   []string{"-race"},
+		},
+		{
+			title:    "coverage",
+			platform: other,
+			opts:     k6build.BuildOptions{Cover: true},
+			expect:   []string{"-cover"},
+		},
+		{
+			title:    "race detector and coverage",
+			platform: native,
+			opts:     k6build.BuildOptions{Race: true, Cover: true},
+			expect:   []string{"-race", "-cover"},
+		},
+		{
+			title:     "race detector for other platform",
+			platform:  other,
+			opts:      k6build.BuildOptions{Race: true},
+			expectErr: ErrInvalidParameters,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			foundry := &recordingFoundry{}
+			builder, err := New(context.Background(), Config{
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, _ k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+			plain, err := builder.Build(context.TODO(), tc.platform, "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			ctx := k6build.WithBuildOptions(context.TODO(), tc.opts)
+			instrumented, err := builder.Build(ctx, tc.platform, "v0.1.0", deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if instrumented.ID == plain.ID {
+				t.Fatalf("expected instrumented artifact to have a different id")
+			}
+
+			if !cmp.Equal(instrumented.BuildFlags, tc.expect) {
+				t.Fatalf("artifact build flags %s", cmp.Diff(tc.expect, instrumented.BuildFlags))
+			}
+
+			if !cmp.Equal(foundry.flags, tc.expect) {
+				t.Fatalf("foundry build flags %s", cmp.Diff(tc.expect, foundry.flags))
+			}
+		})
+	}
+}
+
+func TestToolchains(t *testing.T) {
+	t.Parallel()
+
+	catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+	catalogContent := `{
+	"k6": {"module": "go.k6.io/k6", "versions": ["v0.1.0"]},
+	"k6/x/ext": {"module": "go.k6.io/k6ext", "versions": ["v0.1.0"]},
+	"k6/x/cgo": {"module": "go.k6.io/k6cgo", "cgo": true, "versions": ["v0.1.0"]}
+}`
+	if err := os.WriteFile(catalogFile, []byte(catalogContent), 0o600); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	native := runtime.GOOS + "/" + runtime.GOARCH
+	other := "linux/amd64"
+	if native == other {
+		other = "linux/arm64"
+	}
+
+	toolchains := map[string]Toolchain{
+		other: {CC: "cross-gcc", CXX: "cross-g++", Env: map[string]string{"PKG_CONFIG_PATH": "/cross/lib"}},
+	}
+
+	testCases := []struct {
+		title      string
+		platform   string
+		dep        string
+		toolchains map[string]Toolchain
+		expectEnv  map[string]string
+		expectErr  error
+	}{
+		{
+			title:      "cgo cross-build with toolchain",
+			platform:   other,
+			dep:        "k6/x/cgo",
+			toolchains: toolchains,
+			expectEnv:  map[string]string{"CC": "cross-gcc", "CXX": "cross-g++", "PKG_CONFIG_PATH": "/cross/lib"},
+		},
+		{
+			title:     "cgo cross-build without toolchain",
+			platform:  other,
+			dep:       "k6/x/cgo",
+			expectErr: ErrNoCgoToolchain,
+		},
+		{
+			title:      "cgo native build",
+			platform:   native,
+			dep:        "k6/x/cgo",
+			toolchains: toolchains,
+			expectEnv:  map[string]string{"CC": "", "CXX": ""},
+		},
+		{
+			title:      "cross-build without cgo",
+			platform:   other,
+			dep:        "k6/x/ext",
+			toolchains: toolchains,
+			expectEnv:  map[string]string{"CC": "", "CXX": ""},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			var env map[string]string
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{Toolchains: tc.toolchains},
+				Catalog: catalogFile,
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, opts k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						env = opts.Env
+						return &mockFoundry{}, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: tc.dep, Constraints: "v0.1.0"}}
+			_, err = builder.Build(context.TODO(), tc.platform, "v0.1.0", deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			for name, value := range tc.expectEnv {
+				if env[name] != value {
+					t.Fatalf("expected %s=%q got %q", name, value, env[name])
+				}
+			}
+		})
+	}
+}
+
+func TestModuleSums(t *testing.T) {
+	t.Parallel()
+
+	catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+	catalogContent := `{
+	"k6": {"module": "go.k6.io/k6", "versions": ["v0.1.0"]},
+	"k6/x/ext": {
+		"module": "go.k6.io/k6ext",
+		"versions": ["v0.1.0", "v0.2.0"],
+		"sums": {"v0.1.0": "h1:pinned"}
+	}
+}`
+	if err := os.WriteFile(catalogFile, []byte(catalogContent), 0o600); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	testCases := []struct {
+		title      string
+		constrains string
+		sum        string
+		expectErr  error
+	}{
+		{
+			title:      "pinned module matches",
+			constrains: "v0.1.0",
+			sum:        "h1:pinned",
+		},
+		{
+			title:      "pinned module mismatch",
+			constrains: "v0.1.0",
+			sum:        "h1:tampered",
+			expectErr:  ErrModuleSumMismatch,
+		},
+		{
+			title:      "module not pinned",
+			constrains: "v0.2.0",
+			sum:        "h1:tampered",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			builder, err := New(context.Background(), Config{
+				Catalog: catalogFile,
+				Store:   store,
+				Foundry: FoundryFactoryFunction(MockFoundryFactory),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			// simulate the hash of the module served by the proxy
+			builder.moduleSum = func(_ context.Context, _ map[string]string, path string, _ string) (string, error) {
+				if path != "go.k6.io/k6ext" {
+					return "", fmt.Errorf("unexpected verification of %s", path)
+				}
+				return tc.sum, nil
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: tc.constrains}}
+			_, err = builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+// failingFoundry fails all builds with the given error, counting the builds
+type failingFoundry struct {
+	mockFoundry
+	err    error
+	builds atomic.Int32
+}
+
+func (f *failingFoundry) Build(
+	_ context.Context,
+	_ k6foundry.Platform,
+	_ string,
+	_ []k6foundry.Module,
+	_ []k6foundry.Module,
+	_ []string,
+	_ io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	f.builds.Add(1)
+	return nil, f.err
+}
+
+func TestFailedBuilds(t *testing.T) {
+	t.Parallel()
+
+	compileErr := fmt.Errorf("%w: undefined: ext.Foo", k6foundry.ErrCompiling)
+
+	testCases := []struct {
+		title      string
+		ttl        time.Duration
+		err        error
+		force      bool
+		expect     int32
+		expectCode k6build.ErrorCode
+	}{
+		{
+			title:      "failure remembered",
+			ttl:        time.Hour,
+			err:        compileErr,
+			expect:     1,
+			expectCode: k6build.ErrorCodeBuildFailed,
+		},
+		{
+			title:  "forced build",
+			ttl:    time.Hour,
+			err:    compileErr,
+			force:  true,
+			expect: 2,
+		},
+		{
+			title:  "failures not remembered",
+			ttl:    0,
+			err:    compileErr,
+			expect: 2,
+		},
+		{
+			title:      "transient failure",
+			ttl:        time.Hour,
+			err:        fmt.Errorf("%w: connection reset", k6foundry.ErrResolvingDependency),
+			expect:     2,
+			expectCode: k6build.ErrorCodeDownloadFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			foundry := &failingFoundry{err: tc.err}
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{FailedBuildsTTL: tc.ttl},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, _ k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+			_, err = builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if !errors.Is(err, ErrBuildingArtifact) {
+				t.Fatalf("expected %v got %v", ErrBuildingArtifact, err)
+			}
+
+			ctx := k6build.WithBuildOptions(context.TODO(), k6build.BuildOptions{Force: tc.force})
+			_, err = builder.Build(ctx, "linux/amd64", "v0.1.0", deps)
+			if !errors.Is(err, ErrBuildingArtifact) || !errors.Is(err, tc.err) {
+				t.Fatalf("expected %v got %v", tc.err, err)
+			}
+
+			if tc.expectCode != "" && k6build.ErrorCodeOf(err) != tc.expectCode {
+				t.Fatalf("expected code %q got %q", tc.expectCode, k6build.ErrorCodeOf(err))
+			}
+
+			if builds := foundry.builds.Load(); builds != tc.expect {
+				t.Fatalf("expected %d builds got %d", tc.expect, builds)
+			}
+		})
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	t.Parallel()
+
+	store, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating temporary object store %v", err)
+	}
+
+	config := Config{
+		Catalog: filepath.Join("testdata", "catalog.json"),
+		Store:   store,
+		Foundry: FoundryFactoryFunction(MockFoundryFactory),
+		Version: "v0.1.0",
+	}
+
+	builder, err := New(context.Background(), config)
+	if err != nil {
+		t.Fatalf("creating builder %v", err)
+	}
+
+	deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+
+	built, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+	if err != nil {
+		t.Fatalf("building %v", err)
+	}
+
+	if built.BuildInfo == nil {
+		t.Fatalf("build info not returned")
+	}
+
+	if built.BuildInfo.K6BuildVersion != config.Version {
+		t.Fatalf("expected k6build version %q got %q", config.Version, built.BuildInfo.K6BuildVersion)
+	}
+
+	if built.BuildInfo.GoVersion == "" {
+		t.Fatalf("go version not returned")
+	}
+
+	// the build info is returned for the stored artifact, also by other builders using the store
+	config.Version = "v0.2.0"
+	other, err := New(context.Background(), config)
+	if err != nil {
+		t.Fatalf("creating builder %v", err)
+	}
+
+	stored, err := other.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+	if err != nil {
+		t.Fatalf("building %v", err)
+	}
+
+	if diff := cmp.Diff(built.BuildInfo, stored.BuildInfo); diff != "" {
+		t.Fatalf("build info mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestFullBuildInfo(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		fullBuildInfo bool
+		expectModules bool
+	}{
+		{
+			title:         "full build info",
+			fullBuildInfo: true,
+			expectModules: true,
+		},
+		{
+			title:         "default build info",
+			fullBuildInfo: false,
+			expectModules: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{FullBuildInfo: tc.fullBuildInfo},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(MockFoundryFactory),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+			built, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("building %v", err)
+			}
+
+			if hasModules := len(built.BuildInfo.Modules) > 0; hasModules != tc.expectModules {
+				t.Fatalf("expected modules %t got %v", tc.expectModules, built.BuildInfo.Modules)
+			}
+
+			// the build info can be obtained for the artifact's id
+			info, err := builder.BuildInfo(context.TODO(), built.ID)
+			if err != nil {
+				t.Fatalf("getting build info %v", err)
+			}
+
+			if diff := cmp.Diff(*built.BuildInfo, info); diff != "" {
+				t.Fatalf("build info mismatch (-want +got):\n%s", diff)
+			}
+
+			_, err = builder.BuildInfo(context.TODO(), "unknown")
+			if !errors.Is(err, k6build.ErrUnknownArtifact) {
+				t.Fatalf("expected %v got %v", k6build.ErrUnknownArtifact, err)
+			}
+		})
+	}
+}
+
+func TestSBOMOfArtifactStoredByPeer(t *testing.T) {
+	t.Parallel()
+
+	objectStore, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating temporary object store %v", err)
+	}
+
+	builder, err := New(context.Background(), Config{
+		Opts:    Opts{GenerateSBOM: true},
+		Catalog: filepath.Join("testdata", "catalog.json"),
+		Store:   objectStore,
+		Foundry: FoundryFactoryFunction(MockFoundryFactory),
+	})
+	if err != nil {
+		t.Fatalf("creating builder %v", err)
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+	binary, err := os.ReadFile(executable) //nolint:gosec
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	// the artifact was stored by another builder, so the SBOM is generated from the stored object
+	artifact, err := objectStore.Put(context.TODO(), "artifact", bytes.NewReader(binary))
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	sbomURL := builder.storeSBOM(context.TODO(), artifact, "linux/amd64", nil)
+	if sbomURL == "" {
+		t.Fatalf("expected sbom")
+	}
+
+	// the SBOM stored is reused
+	if again := builder.storeSBOM(context.TODO(), artifact, "linux/amd64", nil); again != sbomURL {
+		t.Fatalf("expected sbom url %q got %q", sbomURL, again)
+	}
+
+	if _, err = builder.SBOM(context.TODO(), "artifact"); err != nil {
+		t.Fatalf("getting sbom %v", err)
+	}
+}
+
+// goBinaryFoundry returns the test's executable as the binary, as it has the go build info
+// used for generating the SBOM
+type goBinaryFoundry struct {
+	mockFoundry
+}
+
+func (g *goBinaryFoundry) Build(
+	ctx context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	reps []k6foundry.Module,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	binary, err := os.ReadFile(executable) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := g.mockFoundry.Build(ctx, platform, k6Version, mods, reps, buildOpts, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = out.Write(binary)
+
+	return info, err
+}
+
+func TestSBOM(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		generateSBOM bool
+		foundry      k6foundry.Foundry
+		expectSBOM   bool
+	}{
+		{
+			title:        "generate sbom",
+			generateSBOM: true,
+			foundry:      &goBinaryFoundry{},
+			expectSBOM:   true,
+		},
+		{
+			title:        "sbom not enabled",
+			generateSBOM: false,
+			foundry:      &goBinaryFoundry{},
+			expectSBOM:   false,
+		},
+		{
+			title:        "binary without build info",
+			generateSBOM: true,
+			foundry:      &mockFoundry{},
+			expectSBOM:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{GenerateSBOM: tc.generateSBOM},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(context.Context, k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return tc.foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+			built, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("building %v", err)
+			}
+
+			if hasSBOM := built.SBOMURL != ""; hasSBOM != tc.expectSBOM {
+				t.Fatalf("expected sbom %t got %q", tc.expectSBOM, built.SBOMURL)
+			}
+
+			// the sbom is also returned for the stored artifact
+			stored, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("building %v", err)
+			}
+			if stored.SBOMURL != built.SBOMURL {
+				t.Fatalf("expected sbom url %q got %q", built.SBOMURL, stored.SBOMURL)
+			}
+
+			content, err := builder.SBOM(context.TODO(), built.ID)
+			if !tc.expectSBOM {
+				if !errors.Is(err, k6build.ErrSBOMNotAvailable) {
+					t.Fatalf("expected %v got %v", k6build.ErrSBOMNotAvailable, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("getting sbom %v", err)
+			}
+
+			sbom := cycloneDX{}
+			if err = json.Unmarshal(content, &sbom); err != nil {
+				t.Fatalf("decoding sbom %v", err)
+			}
+
+			if sbom.BOMFormat != cycloneDXFormat {
+				t.Fatalf("expected format %q got %q", cycloneDXFormat, sbom.BOMFormat)
+			}
+
+			// the modules linked in the test's executable, including indirect dependencies, are listed
+			for _, module := range []string{foundryModule, "github.com/google/go-cmp"} {
+				if !slices.ContainsFunc(sbom.Components, func(c cycloneDXComp) bool { return c.Name == module }) {
+					t.Fatalf("module %s not in sbom", module)
+				}
+			}
+
+			_, err = builder.SBOM(context.TODO(), "unknown")
+			if !errors.Is(err, k6build.ErrUnknownArtifact) {
+				t.Fatalf("expected %v got %v", k6build.ErrUnknownArtifact, err)
+			}
+		})
+	}
+}
+
+func TestSignArtifact(t *testing.T) {
+	t.Parallel()
+
+	publicKey, signingKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key %v", err)
+	}
+
+	testCases := []struct {
+		title      string
+		signingKey ed25519.PrivateKey
+		expectErr  error
+	}{
+		{
+			title:      "signed artifact",
+			signingKey: signingKey,
+		},
+		{
+			title:     "signing not configured",
+			expectErr: k6build.ErrArtifactNotSigned,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			objectStore, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			builder, err := New(context.Background(), Config{
+				Catalog:    filepath.Join("testdata", "catalog.json"),
+				Store:      objectStore,
+				Foundry:    FoundryFactoryFunction(MockFoundryFactory),
+				SigningKey: tc.signingKey,
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "v0.1.0"}}
+			built, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("building %v", err)
+			}
+
+			signature, err := builder.Signature(context.TODO(), built.ID)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if signature != built.Signature {
+				t.Fatalf("expected signature %q got %q", built.Signature, signature)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			decoded, err := base64.StdEncoding.DecodeString(signature)
+			if err != nil {
+				t.Fatalf("decoding signature %v", err)
+			}
+
+			// sha256 checksums are reported as the hex digest, but signed with the algorithm
+			if strings.Contains(built.Checksum, ":") {
+				t.Fatalf("expected sha256 hex digest got %q", built.Checksum)
+			}
+
+			// the signature is over the checksum of the stored artifact
+			message, err := signing.Message(built.Checksum)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+			if !ed25519.Verify(publicKey, message, decoded) {
+				t.Fatalf("invalid signature")
+			}
+
+			// the signature is also returned for the stored artifact
+			stored, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("building %v", err)
+			}
+			if stored.Signature != signature {
+				t.Fatalf("expected signature %q got %q", signature, stored.Signature)
+			}
+
+			// an artifact stored without signature is signed when requested
+			deleter, _ := objectStore.(store.ObjectDeleter)
+			if err = deleter.Delete(context.TODO(), built.ID+signatureSuffix); err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+			resigned, err := builder.Build(context.TODO(), "linux/amd64", "v0.1.0", deps)
+			if err != nil {
+				t.Fatalf("building %v", err)
+			}
+			if resigned.Signature != signature {
+				t.Fatalf("expected signature %q got %q", signature, resigned.Signature)
+			}
+
+			_, err = builder.Signature(context.TODO(), "unknown")
+			if !errors.Is(err, k6build.ErrUnknownArtifact) {
+				t.Fatalf("expected %v got %v", k6build.ErrUnknownArtifact, err)
+			}
+		})
+	}
+}
+
+func TestArtifactID(t *testing.T) {
+	t.Parallel()
+
+	deps := map[string]catalog.Module{
+		"k6":        {Path: "go.k6.io/k6", Version: "v0.1.0"},
+		"k6/x/ext2": {Path: "github.com/grafana/k6-ext2", Version: "v0.2.0"},
+		"k6/x/ext":  {Path: "github.com/grafana/k6-ext", Version: "v0.1.0"},
+	}
+	opts := k6build.BuildOptions{
+		Pins:         map[string]string{"google.golang.org/grpc": "v1.64.1"},
+		ExtraModules: map[string]string{"github.com/example/logger": "v0.1.0"},
+		Race:         true,
+	}
+
+	input := "11:linux/amd648:go1.24.16:v0.1.0" +
+		"3:dep8:k6/x/ext6:v0.1.03:dep9:k6/x/ext26:v0.2.0" +
+		"3:pin22:google.golang.org/grpc7:v1.64.1" +
+		"3:mod25:github.com/example/logger6:v0.1.0" +
+		"4:flag5:-race"
+	expected := fmt.Sprintf("%x", sha256.Sum256([]byte(input)))
+
+	if id := ArtifactID("linux/amd64", "go1.24.1", deps, opts); id != expected {
+		t.Fatalf("expected %s got %s", expected, id)
+	}
+}
+
+func TestArtifactIDAliasing(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title  string
+		first  map[string]catalog.Module
+		second map[string]catalog.Module
+	}{
+		{
+			title:  "dependency name and version boundary",
+			first:  map[string]catalog.Module{"k6": {Version: "v0.1.0"}, "k6/x/a": {Version: "bc"}},
+			second: map[string]catalog.Module{"k6": {Version: "v0.1.0"}, "k6/x/ab": {Version: "c"}},
+		},
+		{
+			title:  "dependency boundary",
+			first:  map[string]catalog.Module{"k6": {Version: "v0.1.0"}, "k6/x/a": {Version: "v0.1.0:k6/x/b"}},
+			second: map[string]catalog.Module{"k6": {Version: "v0.1.0"}, "k6/x/a": {Version: "v0.1.0"}, "k6/x/b": {}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			first := ArtifactID("linux/amd64", "go1.24.1", tc.first, k6build.BuildOptions{})
+			second := ArtifactID("linux/amd64", "go1.24.1", tc.second, k6build.BuildOptions{})
+			if first == second {
+				t.Fatalf("different dependencies have the same id %s", first)
+			}
+		})
+	}
+}
+
+func TestRequestMetrics(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title          string
+		requests       []string
+		foundryErr     error
+		expectHits     uint64
+		expectFailures map[string]float64
+		expectRequests float64
+	}{
+		{
+			title:          "store hit",
+			requests:       []string{"v0.2.0", "v0.2.0"},
+			expectHits:     1,
+			expectRequests: 2,
+		},
+		{
+			title:          "invalid parameters",
+			requests:       []string{"v0.3.0"},
+			expectFailures: map[string]float64{failureInvalidParameters: 1},
+		},
+		{
+			title:          "compile error",
+			requests:       []string{"v0.2.0"},
+			foundryErr:     fmt.Errorf("%w: undefined: ext.Foo", k6foundry.ErrCompiling),
+			expectFailures: map[string]float64{failureCompileError: 1},
+			expectRequests: 1,
+		},
+		{
+			title:          "build error",
+			requests:       []string{"v0.2.0"},
+			foundryErr:     fmt.Errorf("%w: connection reset", k6foundry.ErrResolvingDependency),
+			expectFailures: map[string]float64{failureBuildError: 1},
+			expectRequests: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			register := prometheus.NewPedanticRegistry()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			foundry := FoundryFactory(FoundryFactoryFunction(MockFoundryFactory))
+			if tc.foundryErr != nil {
+				foundry = FoundryFactoryFunction(
+					func(_ context.Context, _ k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						return &failingFoundry{err: tc.foundryErr}, nil
+					},
+				)
+			}
+
+			builder, err := New(context.Background(), Config{
+				Catalog:    filepath.Join("testdata", "catalog.json"),
+				Store:      store,
+				Foundry:    foundry,
+				Registerer: register,
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			for _, k6 := range tc.requests {
+				_, _ = builder.Build(context.TODO(), "linux/amd64", k6, []k6build.Dependency{})
+			}
+
+			families, err := register.Gather()
+			if err != nil {
+				t.Fatalf("gathering metrics %v", err)
+			}
+
+			hits := uint64(0)
+			for _, family := range families {
+				if family.GetName() == "k6build_store_hit_duration_seconds" {
+					hits = family.GetMetric()[0].GetHistogram().GetSampleCount()
+				}
+			}
+			if hits != tc.expectHits {
+				t.Fatalf("expected %d store hits got %d", tc.expectHits, hits)
+			}
+
+			for _, reason := range []string{
+				failureBuildError,
+				failureCompileError,
+				failureInvalidParameters,
+				failureStoreError,
+			} {
+				failures := testutil.ToFloat64(builder.metrics.failuresCounter.WithLabelValues(reason))
+				if failures != tc.expectFailures[reason] {
+					t.Fatalf("expected %v %s failures got %v", tc.expectFailures[reason], reason, failures)
+				}
+			}
+
+			if inFlight := testutil.ToFloat64(builder.metrics.buildsInFlight); inFlight != 0 {
+				t.Fatalf("expected no builds in flight got %v", inFlight)
+			}
+
+			requests := testutil.ToFloat64(builder.metrics.dependencyRequests.WithLabelValues(k6DependencyName))
+			if requests != tc.expectRequests {
+				t.Fatalf("expected %v k6 requests got %v", tc.expectRequests, requests)
+			}
+		})
+	}
+}
+
+func TestFailedDependencies(t *testing.T) {
+	t.Parallel()
+
+	deps := map[string]catalog.Module{
+		"k6":          {Path: "go.k6.io/k6", Version: "v0.50.0"},
+		"k6/x/sql":    {Path: "github.com/grafana/xk6-sql", Version: "v0.1.0"},
+		"k6/x/sql/my": {Path: "github.com/grafana/xk6-sql-driver-mysql", Version: "v0.1.0"},
+	}
+
+	testCases := []struct {
+		title  string
+		err    string
+		expect []string
+	}{
+		{
+			title:  "extension package",
+			err:    "# github.com/grafana/xk6-sql/sql\nsql.go:10:2: undefined: Foo",
+			expect: []string{"k6/x/sql"},
+		},
+		{
+			title:  "extension with prefix",
+			err:    "../go/pkg/mod/github.com/grafana/xk6-sql-driver-mysql@v0.1.0/driver.go:5:1: undefined: Bar",
+			expect: []string{"k6/x/sql/my"},
+		},
+		{
+			title:  "extension using k6 packages",
+			err:    "# github.com/grafana/xk6-sql\ncannot use m (type go.k6.io/k6/js/modules.Module)",
+			expect: []string{"k6/x/sql"},
+		},
+		{
+			title:  "k6",
+			err:    "# go.k6.io/k6/cmd\nmain.go:3:1: undefined: Baz",
+			expect: []string{"k6"},
+		},
+		{
+			title:  "not attributable",
+			err:    "signal: killed",
+			expect: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := fmt.Errorf("%w: %s", k6foundry.ErrCompiling, tc.err)
+			if diff := cmp.Diff(tc.expect, failedDependencies(err, deps)); diff != "" {
+				t.Fatalf("unexpected dependencies (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestResolutionCache(t *testing.T) {
+	t.Parallel()
+
+	const (
+		original = `{
+		  "k6": {"module": "go.k6.io/k6", "versions": ["v0.1.0"]},
+		  "k6/x/ext": {"module": "go.k6.io/k6ext", "versions": ["v0.1.0"]}
+		}`
+		updated = `{
+		  "k6": {"module": "go.k6.io/k6", "versions": ["v0.1.0"]},
+		  "k6/x/ext": {"module": "go.k6.io/k6ext", "versions": ["v0.1.0", "v0.2.0"]}
+		}`
+	)
+
+	testCases := []struct {
+		title         string
+		ttl           time.Duration
+		reload        bool
+		expectVersion string
+		expectHits    float64
+	}{
+		{
+			title:         "cached resolution",
+			ttl:           time.Minute,
+			expectVersion: "v0.1.0",
+			expectHits:    2,
+		},
+		{
+			title:         "cache disabled",
+			ttl:           0,
+			expectVersion: "v0.2.0",
+			expectHits:    0,
+		},
+		{
+			title:         "expired resolution",
+			ttl:           time.Nanosecond,
+			expectVersion: "v0.2.0",
+			expectHits:    0,
+		},
+		{
+			title:         "cache cleared on reload",
+			ttl:           time.Minute,
+			reload:        true,
+			expectVersion: "v0.2.0",
+			expectHits:    0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+			if err := os.WriteFile(catalogFile, []byte(original), 0o600); err != nil {
+				t.Fatalf("writing catalog %v", err)
+			}
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			config := Config{
+				Opts:    Opts{ResolutionCacheTTL: tc.ttl},
+				Catalog: catalogFile,
+				Store:   store,
+				Foundry: FoundryFactoryFunction(MockFoundryFactory),
+			}
+			if tc.reload {
+				// reload only on request
+				config.CatalogReloadInterval = time.Hour
+			}
+
+			builder, err := New(context.Background(), config)
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{{Name: "k6/x/ext", Constraints: "*"}}
+
+			_, err = builder.Resolve(context.TODO(), "*", deps)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if err = os.WriteFile(catalogFile, []byte(updated), 0o600); err != nil {
+				t.Fatalf("updating catalog %v", err)
+			}
+
+			if tc.reload {
+				if err = builder.ReloadCatalog(context.TODO()); err != nil {
+					t.Fatalf("reloading catalog %v", err)
+				}
+			}
+
+			resolved, err := builder.Resolve(context.TODO(), "*", deps)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if resolved["k6/x/ext"] != tc.expectVersion {
+				t.Fatalf("expected %s got %s", tc.expectVersion, resolved["k6/x/ext"])
+			}
+
+			if hits := testutil.ToFloat64(builder.metrics.resolutionCacheHits); hits != tc.expectHits {
+				t.Fatalf("expected %v cache hits got %v", tc.expectHits, hits)
+			}
+		})
+	}
+}
+
+func TestArtifactSweep(t *testing.T) {
+	t.Parallel()
+
+	const ttl = 50 * time.Millisecond
+
+	objectStore := memory.NewMemoryStore()
+	builder, err := New(context.Background(), Config{
+		Catalog:     filepath.Join("testdata", "catalog.json"),
+		Store:       objectStore,
+		Foundry:     FoundryFactoryFunction(MockFoundryFactory),
+		ArtifactTTL: ttl,
+		Lock:        busyLock{busy: []string{"peer-locked"}},
+	})
+	if err != nil {
+		t.Fatalf("creating builder %v", err)
+	}
+
+	ids := []string{
+		"expired", "expired" + buildInfoSuffix, "expired" + sbomSuffix,
+		"accessed", "accessed" + buildInfoSuffix, "touched", "locked", "peer-locked",
+		"pinned", "pinned" + buildInfoSuffix, "pinned" + sbomSuffix,
+	}
+	for _, id := range ids {
+		if _, err = objectStore.Put(context.TODO(), id, strings.NewReader("content")); err != nil {
+			t.Fatalf("test setup %v", err)
+		}
+	}
+
+	if err = objectStore.Pin(context.TODO(), "pinned"); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	time.Sleep(2 * ttl)
+
+	if _, err = objectStore.Put(context.TODO(), "recent", strings.NewReader("content")); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	// accessed by another builder sharing the store
+	if err = objectStore.Touch(context.TODO(), "accessed"); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+	builder.touchArtifact(context.TODO(), "touched")
+	unlock := builder.lockArtifact("locked")
+	defer unlock()
+
+	builder.sweep(context.TODO())
+
+	objects, err := objectStore.List(context.TODO())
+	if err != nil {
+		t.Fatalf("listing objects %v", err)
+	}
+
+	remaining := []string{}
+	for _, object := range objects {
+		remaining = append(remaining, object.ID)
+	}
+	slices.Sort(remaining)
+
+	expected := []string{
+		"accessed", "accessed" + buildInfoSuffix, "locked", "peer-locked",
+		"pinned", "pinned" + buildInfoSuffix, "pinned" + sbomSuffix, "recent", "touched",
+	}
+	if diff := cmp.Diff(expected, remaining); diff != "" {
+		t.Fatalf("unexpected objects (-want +got):\n%s", diff)
+	}
+
+	if swept := testutil.ToFloat64(builder.metrics.sweptObjects); swept != 3 {
+		t.Fatalf("expected 3 objects swept got %f", swept)
+	}
+
+	if swept := testutil.ToFloat64(builder.metrics.sweptBytes); swept != float64(3*len("content")) {
+		t.Fatalf("expected %d bytes swept got %f", 3*len("content"), swept)
+	}
+}
+
+// busyLock is a distributed lock held by another builder for the busy ids
+type busyLock struct {
+	busy []string
+}
+
+func (l busyLock) Lock(_ context.Context, id string) (func(), error) {
+	if slices.Contains(l.busy, id) {
+		return nil, lock.ErrLocking
+	}
+
+	return func() {}, nil
+}
+
+func TestArtifactSweepUnsupportedStore(t *testing.T) {
+	t.Parallel()
+
+	_, err := New(context.Background(), Config{
+		Catalog:     filepath.Join("testdata", "catalog.json"),
+		Store:       struct{ store.ObjectStore }{memory.NewMemoryStore()},
+		Foundry:     FoundryFactoryFunction(MockFoundryFactory),
+		ArtifactTTL: time.Hour,
+	})
+	if !errors.Is(err, ErrInitializingBuilder) {
+		t.Fatalf("expected %v got %v", ErrInitializingBuilder, err)
+	}
+}
+
+func TestLimitedFoundry(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		limits    FoundryLimits
+		env       map[string]string
+		delay     time.Duration
+		expectEnv map[string]string
+		expectErr error
+	}{
+		{
+			title:     "no limits",
+			env:       map[string]string{"GOFLAGS": "-mod=mod"},
+			expectEnv: map[string]string{"GOFLAGS": "-mod=mod"},
+		},
+		{
+			title:  "resource limits",
+			limits: FoundryLimits{MaxProcs: 2, MemoryLimit: "2GiB", Parallelism: 4},
+			env:    map[string]string{"GOFLAGS": "-mod=mod"},
+			expectEnv: map[string]string{
+				"GOFLAGS":    "-mod=mod -p=4",
+				"GOMAXPROCS": "2",
+				"GOMEMLIMIT": "2GiB",
+			},
+		},
+		{
+			title:     "build within timeout",
+			limits:    FoundryLimits{Timeout: time.Second},
+			expectEnv: map[string]string{},
+		},
+		{
+			title:     "build exceeds timeout",
+			limits:    FoundryLimits{Timeout: 10 * time.Millisecond},
+			delay:     time.Second,
+			expectEnv: map[string]string{},
+			expectErr: ErrBuildTimeout,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			var opts k6foundry.NativeFoundryOpts
+			factory := FoundryFactoryFunction(
+				func(_ context.Context, o k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+					opts = o
+					return &slowFoundry{delay: tc.delay}, nil
+				},
+			)
+
+			limited, err := NewLimitedFoundryFactory(factory, tc.limits)
+			if err != nil {
+				t.Fatalf("creating factory %v", err)
+			}
+
+			foundry, err := limited.NewFoundry(
+				context.TODO(),
+				k6foundry.NativeFoundryOpts{GoOpts: GoOpts{Env: tc.env}},
+			)
+			if err != nil {
+				t.Fatalf("creating foundry %v", err)
+			}
+
+			if diff := cmp.Diff(tc.expectEnv, opts.Env); diff != "" {
+				t.Fatalf("unexpected env (-want +got):\n%s", diff)
+			}
+
+			platform, _ := k6foundry.ParsePlatform("linux/amd64")
+			_, err = foundry.Build(context.TODO(), platform, "v0.1.0", nil, nil, nil, &bytes.Buffer{})
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestDependencyLists(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		allowlist []string
+		denylist  []string
+		deps      []string
+		expectErr error
+	}{
+		{
+			title: "no lists",
+			deps:  []string{"k6/x/ext", "k6/x/ext2"},
+		},
+		{
+			title:     "allowed dependency",
+			allowlist: []string{"k6/x/ext"},
+			deps:      []string{"k6/x/ext"},
+		},
+		{
+			title:     "dependency not in allowlist",
+			allowlist: []string{"k6/x/ext"},
+			deps:      []string{"k6/x/ext", "k6/x/ext2"},
+			expectErr: ErrDependencyNotAllowed,
+		},
+		{
+			title:     "denied dependency",
+			denylist:  []string{"k6/x/ext2"},
+			deps:      []string{"k6/x/ext2"},
+			expectErr: ErrDependencyNotAllowed,
+		},
+		{
+			title:     "denied dependency in allowlist",
+			allowlist: []string{"k6/x/ext", "k6/x/ext2"},
+			denylist:  []string{"k6/x/ext2"},
+			deps:      []string{"k6/x/ext2"},
+			expectErr: ErrDependencyNotAllowed,
+		},
+		{
+			title:    "k6 without dependencies",
+			denylist: []string{"k6/x/ext"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{DependencyAllowlist: tc.allowlist, DependencyDenylist: tc.denylist},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(MockFoundryFactory),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			deps := []k6build.Dependency{}
+			for _, d := range tc.deps {
+				deps = append(deps, k6build.Dependency{Name: d, Constraints: "v0.1.0"})
+			}
+
+			_, err = builder.Build(context.TODO(), platform(), "v0.1.0", deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil && !errors.Is(err, ErrInvalidParameters) {
+				t.Fatalf("expected %v got %v", ErrInvalidParameters, err)
+			}
+
+			_, err = builder.Plan(context.TODO(), platform(), "v0.1.0", deps)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("plan: expected %v got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestDownloadArtifact(t *testing.T) {
+	t.Parallel()
+
+	builder, err := SetupTestBuilder(t)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	artifact, err := builder.Build(context.TODO(), platform(), "v0.1.0", []k6build.Dependency{})
+	if err != nil {
+		t.Fatalf("building artifact %v", err)
+	}
+
+	content, err := builder.DownloadArtifact(context.TODO(), artifact.ID)
+	if err != nil {
+		t.Fatalf("downloading artifact %v", err)
+	}
+	defer content.Close() //nolint:errcheck
+
+	binary, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatalf("reading artifact %v", err)
+	}
+
+	if string(binary) != "k6 binary" {
+		t.Fatalf("unexpected content %q", binary)
+	}
+
+	_, err = builder.DownloadArtifact(context.TODO(), "unknown")
+	if !errors.Is(err, k6build.ErrUnknownArtifact) {
+		t.Fatalf("expected %v got %v", k6build.ErrUnknownArtifact, err)
+	}
+}
+
+// scratchFoundry checks the build's temporary dir exists during the build and fails if requested
+type scratchFoundry struct {
+	mockFoundry
+	fail       bool
+	scratchDir string
+}
+
+func (s *scratchFoundry) Build(
+	ctx context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	reps []k6foundry.Module,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	s.scratchDir = s.opts.Env["GOTMPDIR"]
+	if _, err := os.Stat(s.scratchDir); err != nil {
+		return nil, fmt.Errorf("scratch dir %w", err)
+	}
+
+	if s.fail {
+		return nil, errors.New("build failed")
+	}
+
+	return s.mockFoundry.Build(ctx, platform, k6Version, mods, reps, buildOpts, out)
+}
+
+func TestScratchDir(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title string
+		fail  bool
+	}{
+		{
+			title: "build succeeds",
+		},
+		{
+			title: "build fails",
+			fail:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			store, err := file.NewFileStore(t.TempDir())
+			if err != nil {
+				t.Fatalf("creating temporary object store %v", err)
+			}
+
+			tempDir := filepath.Join(t.TempDir(), "scratch")
+			foundry := &scratchFoundry{fail: tc.fail}
+			builder, err := New(context.Background(), Config{
+				Opts:    Opts{TempDir: tempDir},
+				Catalog: filepath.Join("testdata", "catalog.json"),
+				Store:   store,
+				Foundry: FoundryFactoryFunction(
+					func(_ context.Context, opts k6foundry.NativeFoundryOpts) (k6foundry.Foundry, error) {
+						foundry.opts = opts
+						return foundry, nil
+					},
+				),
+			})
+			if err != nil {
+				t.Fatalf("creating builder %v", err)
+			}
+
+			_, err = builder.Build(context.TODO(), platform(), "v0.1.0", []k6build.Dependency{})
+			if tc.fail != (err != nil) {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if filepath.Dir(foundry.scratchDir) != tempDir {
+				t.Fatalf("expected scratch dir in %s got %q", tempDir, foundry.scratchDir)
+			}
+
+			if _, err = os.Stat(foundry.scratchDir); !errors.Is(err, os.ErrNotExist) {
+				t.Fatalf("expected scratch dir removed got %v", err)
+			}
+		})
+	}
+}
+
+func TestPurgeTempDir(t *testing.T) {
+	t.Parallel()
+
+	tempDir := t.TempDir()
+	leftovers := []string{"k6build-scratch-123", "k6foundry456"}
+	others := []string{"other"}
+	for _, dir := range append(leftovers, others...) {
+		if err := os.MkdirAll(filepath.Join(tempDir, dir, "sub"), 0o750); err != nil {
+			t.Fatalf("test setup %v", err)
+		}
+	}
+
+	store, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating temporary object store %v", err)
+	}
+
+	_, err = New(context.Background(), Config{
+		Opts:    Opts{TempDir: tempDir, PurgeTempDir: true},
+		Catalog: filepath.Join("testdata", "catalog.json"),
+		Store:   store,
+		Foundry: FoundryFactoryFunction(MockFoundryFactory),
+	})
+	if err != nil {
+		t.Fatalf("creating builder %v", err)
+	}
+
+	for _, dir := range leftovers {
+		if _, err = os.Stat(filepath.Join(tempDir, dir)); !errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected %s purged got %v", dir, err)
+		}
+	}
+
+	for _, dir := range others {
+		if _, err = os.Stat(filepath.Join(tempDir, dir)); err != nil {
+			t.Fatalf("expected %s kept got %v", dir, err)
+		}
+	}
+}
+
+func TestPurgeTempDirRequiresTempDir(t *testing.T) {
+	t.Parallel()
+
+	store, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating temporary object store %v", err)
+	}
+
+	_, err = New(context.Background(), Config{
+		Opts:    Opts{PurgeTempDir: true},
+		Catalog: filepath.Join("testdata", "catalog.json"),
+		Store:   store,
+		Foundry: FoundryFactoryFunction(MockFoundryFactory),
+	})
+	if !errors.Is(err, ErrInitializingBuilder) {
+		t.Fatalf("expected %v got %v", ErrInitializingBuilder, err)
+	}
+}
+
+func TestLazyGoVersion(t *testing.T) {
+	t.Parallel()
+
+	store, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating temporary object store %v", err)
+	}
+
+	// the go toolchain fails with an invalid toolchain, but it's not used until the first build
+	builder, err := New(context.Background(), Config{
+		Opts:    Opts{GoOpts: GoOpts{Env: map[string]string{"GOTOOLCHAIN": "invalid"}}},
+		Catalog: filepath.Join("testdata", "catalog.json"),
+		Store:   store,
+		Foundry: FoundryFactoryFunction(MockFoundryFactory),
+	})
+	if err != nil {
+		t.Fatalf("creating builder %v", err)
+	}
+
+	_, err = builder.Build(context.TODO(), "linux/amd64", "v0.1.0", nil)
+	if !errors.Is(err, ErrBuildingArtifact) {
+		t.Fatalf("expected %v got %v", ErrBuildingArtifact, err)
+	}
+}
@@ -0,0 +1,125 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/store/downloader"
+)
+
+const (
+	// suffix of the id of the object that stores the build info of an artifact
+	buildInfoSuffix = "-buildinfo"
+
+	foundryModule = "github.com/grafana/k6foundry"
+)
+
+// foundryVersion returns the version of the k6foundry module linked in the binary
+func foundryVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != foundryModule {
+			continue
+		}
+		if dep.Replace != nil {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+
+	return ""
+}
+
+// newBuildInfo returns the build info for an artifact built by this builder
+func (b *Builder) newBuildInfo(goVersion string) k6build.BuildInfo {
+	return k6build.BuildInfo{
+		K6BuildVersion:   b.version,
+		K6FoundryVersion: foundryVersion(),
+		GoVersion:        goVersion,
+	}
+}
+
+// storeBuildInfo stores the build info of the artifact, so it can be returned when the artifact
+// is retrieved from the store. Failures are logged but don't fail the build.
+func (b *Builder) storeBuildInfo(ctx context.Context, id string, info k6build.BuildInfo) {
+	content, err := json.Marshal(info)
+	if err != nil {
+		b.logger(ctx).Warn("encoding build info", "id", id, "error", err.Error())
+		return
+	}
+
+	_, err = b.store.Put(ctx, id+buildInfoSuffix, bytes.NewReader(content))
+	if err != nil && !errors.Is(err, store.ErrDuplicateObject) {
+		b.logger(ctx).Warn("storing build info", "id", id, "error", err.Error())
+	}
+}
+
+// fetchBuildInfo returns the build info of an artifact in the store.
+// If it was not stored, only the go version, which is part of the artifact's id, is known.
+// Returns nil if it cannot be retrieved.
+func (b *Builder) fetchBuildInfo(ctx context.Context, id string) *k6build.BuildInfo {
+	object, err := b.store.Get(ctx, id+buildInfoSuffix)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		goVersion, err := b.toolchainVersion(ctx)
+		if err != nil {
+			b.logger(ctx).Warn("accessing go version", "id", id, "error", err.Error())
+			return nil
+		}
+		return &k6build.BuildInfo{GoVersion: goVersion}
+	}
+	if err != nil {
+		b.logger(ctx).Warn("accessing build info", "id", id, "error", err.Error())
+		return nil
+	}
+
+	var content io.ReadCloser
+	if objectDownloader, ok := b.store.(store.ObjectDownloader); ok {
+		content, err = objectDownloader.Download(ctx, object)
+	} else {
+		content, err = downloader.Download(ctx, http.DefaultClient, object)
+	}
+	if err != nil {
+		b.logger(ctx).Warn("downloading build info", "id", id, "error", err.Error())
+		return nil
+	}
+	defer content.Close() //nolint:errcheck
+
+	info := k6build.BuildInfo{}
+	if err = json.NewDecoder(content).Decode(&info); err != nil {
+		b.logger(ctx).Warn("decoding build info", "id", id, "error", err.Error())
+		return nil
+	}
+
+	return &info
+}
+
+// BuildInfo returns the build info of the artifact with the given id.
+// Returns k6build.ErrUnknownArtifact if the artifact is not in the store.
+func (b *Builder) BuildInfo(ctx context.Context, id string) (k6build.BuildInfo, error) {
+	_, err := b.store.Get(ctx, id)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		return k6build.BuildInfo{}, k6build.NewWrappedError(k6build.ErrUnknownArtifact, err)
+	}
+	if err != nil {
+		return k6build.BuildInfo{}, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	info := b.fetchBuildInfo(ctx, id)
+	if info == nil {
+		return k6build.BuildInfo{}, fmt.Errorf("%w: build info not available for %q", ErrAccessingArtifact, id)
+	}
+
+	return *info, nil
+}
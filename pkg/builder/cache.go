@@ -0,0 +1,61 @@
+package builder
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/k6foundry"
+)
+
+// goVersion returns the version of the go toolchain used for building with the given environment
+func goVersion(ctx context.Context, env map[string]string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "env", "GOVERSION")
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	// the version can include additional information (e.g. experiments) separated by spaces
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), " ")
+
+	return version, nil
+}
+
+// toolchainVersion returns the version of the go toolchain used for building. The version is obtained
+// on first use, so creating a builder doesn't require the toolchain. Failures are not cached.
+func (b *Builder) toolchainVersion(ctx context.Context) (string, error) {
+	b.goVersionMutex.Lock()
+	defer b.goVersionMutex.Unlock()
+
+	if b.goVersion != "" {
+		return b.goVersion, nil
+	}
+
+	version, err := goVersion(ctx, b.opts.Env)
+	if err != nil {
+		return "", err
+	}
+	b.goVersion = version
+
+	return version, nil
+}
+
+// cacheEnv returns the environment variables for using the shared caches in cacheDir.
+// The module cache is namespaced by go version and the build cache also by target platform,
+// so builds with different toolchains don't share cached content.
+func cacheEnv(cacheDir string, goVersion string, platform k6foundry.Platform) map[string]string {
+	versionDir := filepath.Join(cacheDir, goVersion)
+
+	return map[string]string{
+		"GOMODCACHE": filepath.Join(versionDir, "mod"),
+		"GOCACHE":    filepath.Join(versionDir, "build", platform.OS+"_"+platform.Arch),
+	}
+}
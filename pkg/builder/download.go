@@ -0,0 +1,53 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/store/downloader"
+)
+
+// DownloadArtifact returns the content of the artifact with the given id.
+// Returns k6build.ErrUnknownArtifact if the artifact is not in the store.
+func (b *Builder) DownloadArtifact(ctx context.Context, id string) (io.ReadCloser, error) {
+	object, err := b.store.Get(ctx, id)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		return nil, k6build.NewWrappedError(k6build.ErrUnknownArtifact, err)
+	}
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	b.touchArtifact(ctx, id)
+
+	content, err := b.downloadObject(ctx, object)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	return content, nil
+}
+
+// downloadObject returns the content of an object in the store
+func (b *Builder) downloadObject(ctx context.Context, object store.Object) (io.ReadCloser, error) {
+	if objectDownloader, ok := b.store.(store.ObjectDownloader); ok {
+		return objectDownloader.Download(ctx, object)
+	}
+
+	return downloader.Download(ctx, http.DefaultClient, object)
+}
+
+// readObject returns the content of an object in the store
+func (b *Builder) readObject(ctx context.Context, object store.Object) ([]byte, error) {
+	content, err := b.downloadObject(ctx, object)
+	if err != nil {
+		return nil, err
+	}
+	defer content.Close() //nolint:errcheck
+
+	return io.ReadAll(content)
+}
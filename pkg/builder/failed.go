@@ -0,0 +1,68 @@
+package builder
+
+import (
+	"sync"
+	"time"
+)
+
+type failedBuild struct {
+	err     error
+	expires time.Time
+}
+
+// failedBuilds remembers the artifacts that failed to compile, so the builds are not retried
+// until the failure expires
+type failedBuilds struct {
+	mutex  sync.Mutex
+	ttl    time.Duration
+	builds map[string]failedBuild
+}
+
+func newFailedBuilds(ttl time.Duration) *failedBuilds {
+	return &failedBuilds{
+		ttl:    ttl,
+		builds: map[string]failedBuild{},
+	}
+}
+
+// get returns the failure of the artifact's build or nil if it didn't fail or the failure expired
+func (f *failedBuilds) get(id string) error {
+	if f.ttl == 0 {
+		return nil
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	build, found := f.builds[id]
+	if !found {
+		return nil
+	}
+
+	if time.Now().After(build.expires) {
+		delete(f.builds, id)
+		return nil
+	}
+
+	return build.err
+}
+
+// add records the failure of the artifact's build
+func (f *failedBuilds) add(id string, err error) {
+	if f.ttl == 0 {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.builds[id] = failedBuild{err: err, expires: time.Now().Add(f.ttl)}
+}
+
+// remove forgets the failure of the artifact's build
+func (f *failedBuilds) remove(id string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.builds, id)
+}
@@ -0,0 +1,114 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6foundry"
+)
+
+// FoundryLimits defines the limits enforced on the builds of a foundry.
+// The resource limits are hints passed to the go toolchain in its environment.
+type FoundryLimits struct {
+	// Maximum duration of a build. 0 means no limit
+	Timeout time.Duration
+	// Maximum number of CPUs the go toolchain executes simultaneously (GOMAXPROCS). 0 means no limit
+	MaxProcs int
+	// Soft memory limit of the go toolchain (GOMEMLIMIT) (e.g. 2GiB). Empty means no limit
+	MemoryLimit string
+	// Maximum number of packages compiled in parallel (go build -p). 0 means no limit
+	Parallelism int
+}
+
+// LimitedFoundryFactory is a FoundryFactory that enforces limits on the builds of the foundries
+// created by another FoundryFactory
+type LimitedFoundryFactory struct {
+	factory FoundryFactory
+	limits  FoundryLimits
+}
+
+// NewLimitedFoundryFactory returns a FoundryFactory that enforces the limits on the foundries created
+// by the given factory. If the factory is nil, the native foundry is used.
+func NewLimitedFoundryFactory(factory FoundryFactory, limits FoundryLimits) (*LimitedFoundryFactory, error) {
+	if limits.Timeout < 0 || limits.MaxProcs < 0 || limits.Parallelism < 0 {
+		return nil, fmt.Errorf("%w: foundry limits cannot be negative", ErrInitializingBuilder)
+	}
+
+	if factory == nil {
+		factory = FoundryFactoryFunction(k6foundry.NewNativeFoundry)
+	}
+
+	return &LimitedFoundryFactory{
+		factory: factory,
+		limits:  limits,
+	}, nil
+}
+
+// NewFoundry returns a foundry created by the wrapped factory that enforces the limits
+func (f *LimitedFoundryFactory) NewFoundry(
+	ctx context.Context,
+	opts k6foundry.NativeFoundryOpts,
+) (k6foundry.Foundry, error) {
+	env := maps.Clone(opts.Env)
+	if env == nil {
+		env = map[string]string{}
+	}
+
+	if f.limits.MaxProcs > 0 {
+		env["GOMAXPROCS"] = strconv.Itoa(f.limits.MaxProcs)
+	}
+
+	if f.limits.MemoryLimit != "" {
+		env["GOMEMLIMIT"] = f.limits.MemoryLimit
+	}
+
+	if f.limits.Parallelism > 0 {
+		env["GOFLAGS"] = strings.TrimSpace(env["GOFLAGS"] + " -p=" + strconv.Itoa(f.limits.Parallelism))
+	}
+
+	opts.Env = env
+
+	foundry, err := f.factory.NewFoundry(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if f.limits.Timeout == 0 {
+		return foundry, nil
+	}
+
+	return &limitedFoundry{foundry: foundry, timeout: f.limits.Timeout}, nil
+}
+
+// limitedFoundry cancels the builds that exceed the timeout
+type limitedFoundry struct {
+	foundry k6foundry.Foundry
+	timeout time.Duration
+}
+
+func (f *limitedFoundry) Build(
+	ctx context.Context,
+	platform k6foundry.Platform,
+	k6Version string,
+	mods []k6foundry.Module,
+	replacements []k6foundry.Module,
+	buildOpts []string,
+	out io.Writer,
+) (*k6foundry.BuildInfo, error) {
+	buildCtx, cancel := context.WithTimeout(ctx, f.timeout)
+	defer cancel()
+
+	buildInfo, err := f.foundry.Build(buildCtx, platform, k6Version, mods, replacements, buildOpts, out)
+	// report the timeout only if the build was not canceled by the caller
+	if err != nil && ctx.Err() == nil && errors.Is(buildCtx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("%w: build exceeded %s", ErrBuildTimeout, f.timeout)
+	}
+
+	return buildInfo, err
+}
@@ -1,19 +1,46 @@
 package builder
 
 import (
+	"errors"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/grafana/k6foundry"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 const metricsNamespace = "k6build"
 
+// reasons of the failed build requests
+const (
+	failureBuildError        = "build_error"
+	failureCompileError      = "compile_error"
+	failureInvalidParameters = "invalid_parameters"
+	failureStoreError        = "store_error"
+)
+
 type metrics struct {
-	requestCounter       prometheus.Counter
-	requestTimeHistogram prometheus.Histogram
-	buildCounter         prometheus.Counter
-	storeHitsCounter     prometheus.Counter
-	buildsFailedCounter  prometheus.Counter
-	buildsInvalidCounter prometheus.Counter
-	buildTimeHistogram   prometheus.Histogram
+	requestCounter        prometheus.Counter
+	requestTimeHistogram  prometheus.Histogram
+	buildCounter          prometheus.Counter
+	storeHitsCounter      prometheus.Counter
+	buildsFailedCounter   prometheus.Counter
+	buildsInvalidCounter  prometheus.Counter
+	buildTimeHistogram    prometheus.Histogram
+	catalogReloadsFailed  prometheus.Counter
+	slowBuildsCounter     prometheus.Counter
+	failedBuildsHits      prometheus.Counter
+	storeHitTimeHistogram prometheus.Histogram
+	buildsInFlight        prometheus.Gauge
+	failuresCounter       *prometheus.CounterVec
+	dependencyRequests    *prometheus.CounterVec
+	dependencyFailures    *prometheus.CounterVec
+	resolutionCacheHits   prometheus.Counter
+	sweptObjects          prometheus.Counter
+	sweptBytes            prometheus.Counter
+	scratchBytes          prometheus.Histogram
 }
 
 func newMetrics() *metrics {
@@ -61,14 +88,100 @@ func newMetrics() *metrics {
 		Buckets:   []float64{1, 2.5, 5, 10, 20, 30, 60, 120, 300},
 	})
 
+	catalogReloadsFailed := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "catalog_reloads_failed_total",
+		Help:      "The total number of failed catalog reloads",
+	})
+
+	slowBuildsCounter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "builds_slow_total",
+		Help:      "The total number of builds exceeding the slow build threshold",
+	})
+
+	failedBuildsHits := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "failed_builds_hits_total",
+		Help:      "The total number of builds not retried because the artifact failed to compile recently",
+	})
+
+	storeHitTimeHistogram := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "store_hit_duration_seconds",
+		Help:      "The duration of the build requests served from the object store in seconds",
+		Buckets:   []float64{0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	})
+
+	buildsInFlight := prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "builds_in_flight",
+		Help:      "The number of builds in progress",
+	})
+
+	failuresCounter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "request_failures_total",
+		Help:      "The total number of failed build requests by reason",
+	}, []string{"reason"})
+
+	dependencyRequests := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dependency_requests_total",
+		Help:      "The total number of valid build requests including the dependency",
+	}, []string{"dependency"})
+
+	dependencyFailures := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "dependency_compile_failures_total",
+		Help:      "The total number of builds that failed compiling the dependency",
+	}, []string{"dependency"})
+
+	resolutionCacheHits := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "resolution_cache_hits_total",
+		Help:      "The total number of dependencies resolved from the resolution cache",
+	})
+
+	sweptObjects := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "store_objects_swept_total",
+		Help:      "The total number of expired objects deleted from the object store",
+	})
+
+	sweptBytes := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "store_bytes_swept_total",
+		Help:      "The total size in bytes of the expired objects deleted from the object store",
+	})
+
+	scratchBytes := prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "build_scratch_bytes",
+		Help:      "The size in bytes of the temporary files of the builds, measured when they are removed",
+		Buckets:   prometheus.ExponentialBuckets(1<<20, 4, 8),
+	})
+
 	return &metrics{
-		requestCounter:       requestCounter,
-		requestTimeHistogram: requestDuration,
-		buildCounter:         buildCounter,
-		buildsFailedCounter:  buildsFailedCounter,
-		buildsInvalidCounter: buildsInvalidCounter,
-		storeHitsCounter:     storeHitsCounter,
-		buildTimeHistogram:   buildTimeHistogram,
+		requestCounter:        requestCounter,
+		requestTimeHistogram:  requestDuration,
+		buildCounter:          buildCounter,
+		buildsFailedCounter:   buildsFailedCounter,
+		buildsInvalidCounter:  buildsInvalidCounter,
+		storeHitsCounter:      storeHitsCounter,
+		buildTimeHistogram:    buildTimeHistogram,
+		catalogReloadsFailed:  catalogReloadsFailed,
+		slowBuildsCounter:     slowBuildsCounter,
+		failedBuildsHits:      failedBuildsHits,
+		storeHitTimeHistogram: storeHitTimeHistogram,
+		buildsInFlight:        buildsInFlight,
+		failuresCounter:       failuresCounter,
+		dependencyRequests:    dependencyRequests,
+		dependencyFailures:    dependencyFailures,
+		resolutionCacheHits:   resolutionCacheHits,
+		sweptObjects:          sweptObjects,
+		sweptBytes:            sweptBytes,
+		scratchBytes:          scratchBytes,
 	}
 }
 
@@ -101,5 +214,98 @@ func (m *metrics) register(registerer prometheus.Registerer) error {
 		return err
 	}
 
+	if err := registerer.Register(m.catalogReloadsFailed); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.slowBuildsCounter); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.failedBuildsHits); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.storeHitTimeHistogram); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.buildsInFlight); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.failuresCounter); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.dependencyRequests); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.dependencyFailures); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.resolutionCacheHits); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.sweptObjects); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.sweptBytes); err != nil {
+		return err
+	}
+
+	if err := registerer.Register(m.scratchBytes); err != nil {
+		return err
+	}
+
 	return nil
 }
+
+// failureReason returns the reason of a failed build request reported in the metrics
+func failureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidParameters):
+		return failureInvalidParameters
+	case errors.Is(err, ErrBuildingArtifact) && errors.Is(err, k6foundry.ErrCompiling):
+		return failureCompileError
+	case errors.Is(err, ErrBuildingArtifact):
+		return failureBuildError
+	default:
+		return failureStoreError
+	}
+}
+
+// failedDependencies returns the sorted dependencies whose module is mentioned in a compilation error.
+// The go compiler reports the errors using the path of the package that failed to compile.
+// As extensions use k6's packages, k6 is only considered if no extension is mentioned.
+func failedDependencies(err error, deps map[string]catalog.Module) []string {
+	failed := []string{}
+	for _, dep := range slices.Sorted(maps.Keys(deps)) {
+		if dep == k6DependencyName {
+			continue
+		}
+		if mentionsModule(err.Error(), deps[dep].Path) {
+			failed = append(failed, dep)
+		}
+	}
+
+	if len(failed) == 0 && mentionsModule(err.Error(), deps[k6DependencyName].Path) {
+		failed = append(failed, k6DependencyName)
+	}
+
+	return failed
+}
+
+// mentionsModule returns true if the text mentions the module path or any of its packages.
+// Modules whose path has the module as prefix (e.g. xk6-sql and xk6-sql-driver) are not matched.
+func mentionsModule(text string, path string) bool {
+	if path == "" {
+		return false
+	}
+
+	return regexp.MustCompile(regexp.QuoteMeta(path) + `([^\w.-]|$)`).MatchString(text)
+}
@@ -0,0 +1,140 @@
+package builder
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type cachedResolution struct {
+	mod     catalog.Module
+	expires time.Time
+}
+
+// resolutionCache remembers the module that satisfies a dependency's constrains, so the catalog
+// is not accessed for resolving it again until the resolution expires or the catalog is reloaded
+type resolutionCache struct {
+	mutex       sync.Mutex
+	ttl         time.Duration
+	resolutions map[catalog.Dependency]cachedResolution
+	// incremented when the cache is cleared, so resolutions from a previous catalog are not added
+	generation uint64
+}
+
+func newResolutionCache(ttl time.Duration) *resolutionCache {
+	return &resolutionCache{
+		ttl:         ttl,
+		resolutions: map[catalog.Dependency]cachedResolution{},
+	}
+}
+
+// get returns the module that resolves the dependency, if its resolution is cached and didn't expire.
+// It also returns the current generation of the cache, which must be used for adding the resolution.
+func (c *resolutionCache) get(dep catalog.Dependency) (catalog.Module, bool, uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	resolution, found := c.resolutions[dep]
+	if !found {
+		return catalog.Module{}, false, c.generation
+	}
+
+	if time.Now().After(resolution.expires) {
+		delete(c.resolutions, dep)
+		return catalog.Module{}, false, c.generation
+	}
+
+	return resolution.mod, true, c.generation
+}
+
+// add records the module that resolves the dependency, unless the cache was cleared since the
+// given generation was obtained
+func (c *resolutionCache) add(dep catalog.Dependency, mod catalog.Module, generation uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	c.resolutions[dep] = cachedResolution{mod: mod, expires: time.Now().Add(c.ttl)}
+}
+
+// clear forgets all the resolutions
+func (c *resolutionCache) clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.resolutions = map[catalog.Dependency]cachedResolution{}
+	c.generation++
+}
+
+// cachedCatalog is a catalog that resolves dependencies using the resolution cache.
+// The catalog is only obtained when a resolution is not cached.
+type cachedCatalog struct {
+	cache   *resolutionCache
+	hits    prometheus.Counter
+	load    func(context.Context) (catalog.Catalog, error)
+	catalog catalog.Catalog
+}
+
+// getCatalog returns the catalog, loading it the first time it is needed
+func (c *cachedCatalog) getCatalog(ctx context.Context) (catalog.Catalog, error) {
+	if c.catalog != nil {
+		return c.catalog, nil
+	}
+
+	ctlg, err := c.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.catalog = ctlg
+
+	return ctlg, nil
+}
+
+// Resolve returns the module that satisfies the dependency, using the cached resolution if any
+func (c *cachedCatalog) Resolve(ctx context.Context, dep catalog.Dependency) (catalog.Module, error) {
+	mod, found, generation := c.cache.get(dep)
+	if found {
+		c.hits.Inc()
+		return mod, nil
+	}
+
+	ctlg, err := c.getCatalog(ctx)
+	if err != nil {
+		return catalog.Module{}, err
+	}
+
+	mod, err = ctlg.Resolve(ctx, dep)
+	if err != nil {
+		return catalog.Module{}, err
+	}
+
+	c.cache.add(dep, mod, generation)
+
+	return mod, nil
+}
+
+// Dependencies returns the sorted list of the dependencies in the catalog
+func (c *cachedCatalog) Dependencies(ctx context.Context) ([]string, error) {
+	ctlg, err := c.getCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctlg.Dependencies(ctx)
+}
+
+// Versions returns the sorted list of the versions of a dependency in the catalog
+func (c *cachedCatalog) Versions(ctx context.Context, name string) ([]string, error) {
+	ctlg, err := c.getCatalog(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return ctlg.Versions(ctx, name)
+}
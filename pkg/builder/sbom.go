@@ -0,0 +1,212 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"debug/buildinfo"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/store"
+)
+
+const (
+	// suffix of the id of the object that stores the SBOM of an artifact
+	sbomSuffix = "-sbom"
+
+	cycloneDXFormat  = "CycloneDX"
+	cycloneDXVersion = "1.5"
+)
+
+// cycloneDX is the subset of the CycloneDX JSON document used for describing the go modules linked in a binary
+type cycloneDX struct {
+	BOMFormat   string            `json:"bomFormat"`
+	SpecVersion string            `json:"specVersion"`
+	Version     int               `json:"version"`
+	Metadata    cycloneDXMetadata `json:"metadata"`
+	Components  []cycloneDXComp   `json:"components"`
+}
+
+type cycloneDXMetadata struct {
+	Timestamp string                     `json:"timestamp"`
+	Tools     map[string][]cycloneDXComp `json:"tools,omitempty"`
+	Component cycloneDXComp              `json:"component"`
+}
+
+type cycloneDXComp struct {
+	Type       string              `json:"type"`
+	BOMRef     string              `json:"bom-ref,omitempty"`
+	Name       string              `json:"name"`
+	Version    string              `json:"version,omitempty"`
+	PURL       string              `json:"purl,omitempty"`
+	Properties []cycloneDXProperty `json:"properties,omitempty"`
+}
+
+type cycloneDXProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// moduleComponent returns the component that describes a go module.
+// Replaced modules are described by their replacement, as it is the code linked in the binary.
+func moduleComponent(mod *debug.Module) cycloneDXComp {
+	if mod.Replace != nil {
+		comp := moduleComponent(mod.Replace)
+		comp.Properties = append(comp.Properties, cycloneDXProperty{Name: "k6build:replaces", Value: mod.Path})
+		return comp
+	}
+
+	purl := "pkg:golang/" + mod.Path
+	if mod.Version != "" {
+		purl += "@" + mod.Version
+	}
+
+	return cycloneDXComp{
+		Type:    "library",
+		BOMRef:  purl,
+		Name:    mod.Path,
+		Version: mod.Version,
+		PURL:    purl,
+	}
+}
+
+// newSBOM returns a CycloneDX SBOM listing all the go modules linked in the binary, including indirect
+// dependencies, from the build information embedded in the binary by the go toolchain.
+func newSBOM(binary io.ReaderAt, platform string, version string) ([]byte, error) {
+	info, err := buildinfo.Read(binary)
+	if err != nil {
+		return nil, fmt.Errorf("reading binary's build info %w", err)
+	}
+
+	k6 := cycloneDXComp{Type: "application", Name: "k6"}
+	components := []cycloneDXComp{}
+	for _, dep := range info.Deps {
+		comp := moduleComponent(dep)
+		if dep.Path == k6Path {
+			k6.Version = comp.Version
+		}
+		components = append(components, comp)
+	}
+
+	k6.Properties = []cycloneDXProperty{
+		{Name: "k6build:platform", Value: platform},
+		{Name: "k6build:go_version", Value: info.GoVersion},
+	}
+
+	sbom := cycloneDX{
+		BOMFormat:   cycloneDXFormat,
+		SpecVersion: cycloneDXVersion,
+		Version:     1,
+		Metadata: cycloneDXMetadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: map[string][]cycloneDXComp{
+				"components": {{Type: "application", Name: "k6build", Version: version}},
+			},
+			Component: k6,
+		},
+		Components: components,
+	}
+
+	return json.MarshalIndent(sbom, "", "  ")
+}
+
+// storeSBOM generates and stores the SBOM of the stored artifact. Returns the URL of the SBOM object.
+// The binary is the content of the stored artifact. If nil, the artifact was stored by another builder,
+// and its SBOM is generated from the stored artifact, if that builder has not stored it yet.
+// Failures are logged but don't fail the build.
+func (b *Builder) storeSBOM(ctx context.Context, artifact store.Object, platform string, binary []byte) string {
+	id := artifact.ID
+	if binary == nil {
+		if sbomURL := b.fetchSBOMURL(ctx, id); sbomURL != "" {
+			return sbomURL
+		}
+
+		var err error
+		binary, err = b.readObject(ctx, artifact)
+		if err != nil {
+			b.logger(ctx).Warn("generating SBOM", "id", id, "error", err.Error())
+			return ""
+		}
+	}
+
+	content, err := newSBOM(bytes.NewReader(binary), platform, b.version)
+	if err != nil {
+		b.logger(ctx).Warn("generating SBOM", "id", id, "error", err.Error())
+		return ""
+	}
+
+	object, err := b.store.Put(ctx, id+sbomSuffix, bytes.NewReader(content))
+	if errors.Is(err, store.ErrDuplicateObject) {
+		object, err = b.store.Get(ctx, id+sbomSuffix)
+	}
+	if err != nil {
+		b.logger(ctx).Warn("storing SBOM", "id", id, "error", err.Error())
+		return ""
+	}
+
+	return object.URL
+}
+
+// fetchSBOMURL returns the URL of the SBOM of an artifact in the store.
+// Returns an empty string if SBOMs are not generated or the artifact doesn't have one.
+func (b *Builder) fetchSBOMURL(ctx context.Context, id string) string {
+	if !b.opts.GenerateSBOM {
+		return ""
+	}
+
+	object, err := b.store.Get(ctx, id+sbomSuffix)
+	if err != nil {
+		if !errors.Is(err, store.ErrObjectNotFound) {
+			b.logger(ctx).Warn("accessing SBOM", "id", id, "error", err.Error())
+		}
+		return ""
+	}
+
+	return object.URL
+}
+
+// SBOM returns the SBOM of the artifact with the given id.
+// Returns k6build.ErrUnknownArtifact if the artifact is not in the store and
+// k6build.ErrSBOMNotAvailable if its SBOM was not generated.
+func (b *Builder) SBOM(ctx context.Context, id string) ([]byte, error) {
+	_, err := b.store.Get(ctx, id)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		return nil, k6build.NewWrappedError(k6build.ErrUnknownArtifact, err)
+	}
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	object, err := b.store.Get(ctx, id+sbomSuffix)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		return nil, k6build.NewWrappedError(k6build.ErrSBOMNotAvailable, err)
+	}
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	sbom, err := b.readObject(ctx, object)
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	return sbom, nil
+}
+
+// artifactOfObject returns the id of the artifact an object in the store belongs to.
+// Artifacts' build info, SBOM and signature are stored in objects with the artifact's id and a suffix.
+func artifactOfObject(objectID string) string {
+	for _, suffix := range []string{buildInfoSuffix, sbomSuffix, signatureSuffix} {
+		if id, found := strings.CutSuffix(objectID, suffix); found {
+			return id
+		}
+	}
+
+	return objectID
+}
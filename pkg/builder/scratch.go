@@ -0,0 +1,74 @@
+package builder
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// pattern of the dirs with the temporary files of a build
+	scratchDirPattern = "k6build-scratch-*"
+	// pattern of the working dirs created by the default foundry in the process' temp dir
+	foundryWorkDirPattern = "k6foundry*"
+)
+
+// tempDir returns the dir where the builds' scratch dirs are created
+func (b *Builder) tempDir() string {
+	if b.opts.TempDir != "" {
+		return b.opts.TempDir
+	}
+
+	return os.TempDir()
+}
+
+// newScratchDir creates a dir for the temporary files of a build
+func (b *Builder) newScratchDir() (string, error) {
+	return os.MkdirTemp(b.tempDir(), scratchDirPattern)
+}
+
+// removeScratchDir removes the scratch dir of a build, recording the space used by its files
+func (b *Builder) removeScratchDir(ctx context.Context, dir string) {
+	size := dirSize(dir)
+	b.metrics.scratchBytes.Observe(float64(size))
+	b.logger(ctx).Debug("removing build scratch dir", "dir", dir, "bytes", size)
+
+	if err := os.RemoveAll(dir); err != nil {
+		b.logger(ctx).Warn("removing build scratch dir", "dir", dir, "error", err.Error())
+	}
+}
+
+// dirSize returns the size of the files in the dir. Files that cannot be accessed are ignored.
+func dirSize(dir string) int64 {
+	size := int64(0)
+	_ = filepath.WalkDir(dir, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return nil //nolint:nilerr
+		}
+		if info, err := entry.Info(); err == nil {
+			size += info.Size()
+		}
+		return nil
+	})
+
+	return size
+}
+
+// purgeTempDir removes the scratch dirs and foundry working dirs left in the configured temp dir by
+// builds that didn't finish (e.g. the process crashed). It must never be used on the OS temp dir,
+// as it is shared with other processes.
+func purgeTempDir(dir string, log *slog.Logger) {
+	for _, pattern := range []string{scratchDirPattern, foundryWorkDirPattern} {
+		leftovers, _ := filepath.Glob(filepath.Join(dir, pattern))
+		for _, leftover := range leftovers {
+			size := dirSize(leftover)
+			if err := os.RemoveAll(leftover); err != nil {
+				log.Warn("purging temp dir", "dir", leftover, "error", err.Error())
+				continue
+			}
+			log.Info("purged temp dir", "dir", leftover, "bytes", size)
+		}
+	}
+}
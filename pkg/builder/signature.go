@@ -0,0 +1,95 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/signing"
+	"github.com/grafana/k6build/pkg/store"
+)
+
+// suffix of the id of the object that stores the signature of an artifact
+const signatureSuffix = "-signature"
+
+// signArtifact signs the checksum of the stored artifact and stores the signature, base64 encoded.
+// Returns the encoded signature. The artifact is signed after it is stored, so the signature is always
+// over the object in the store, even if another builder stored it. Signing the checksum, and not the
+// binary, allows signing artifacts without downloading them.
+func (b *Builder) signArtifact(ctx context.Context, id string, checksum string) (string, error) {
+	signature, err := signing.SignChecksum(b.signingKey, checksum)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = b.store.Put(ctx, id+signatureSuffix, strings.NewReader(signature))
+	if err != nil && !errors.Is(err, store.ErrDuplicateObject) {
+		return "", err
+	}
+
+	// another builder stored the artifact's signature
+	if err != nil {
+		return b.readSignature(ctx, id)
+	}
+
+	return signature, nil
+}
+
+// readSignature returns the signature of an artifact in the store
+func (b *Builder) readSignature(ctx context.Context, id string) (string, error) {
+	object, err := b.store.Get(ctx, id+signatureSuffix)
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := b.readObject(ctx, object)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(signature)), nil
+}
+
+// fetchSignature returns the signature of an artifact in the store.
+// Returns an empty string if artifacts are not signed. Artifacts stored without a signature, for example
+// because signing failed after storing them, are signed. Failures are logged but don't fail the request.
+func (b *Builder) fetchSignature(ctx context.Context, id string, checksum string) string {
+	if b.signingKey == nil {
+		return ""
+	}
+
+	signature, err := b.readSignature(ctx, id)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		signature, err = b.signArtifact(ctx, id, checksum)
+	}
+	if err != nil {
+		b.logger(ctx).Warn("accessing signature", "id", id, "error", err.Error())
+		return ""
+	}
+
+	return signature
+}
+
+// Signature returns the base64 encoded ed25519 signature of the checksum of the artifact with the given id.
+// Returns k6build.ErrUnknownArtifact if the artifact is not in the store and
+// k6build.ErrArtifactNotSigned if it was not signed.
+func (b *Builder) Signature(ctx context.Context, id string) (string, error) {
+	_, err := b.store.Get(ctx, id)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		return "", k6build.NewWrappedError(k6build.ErrUnknownArtifact, err)
+	}
+	if err != nil {
+		return "", k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	signature, err := b.readSignature(ctx, id)
+	if errors.Is(err, store.ErrObjectNotFound) {
+		return "", k6build.NewWrappedError(k6build.ErrArtifactNotSigned, err)
+	}
+	if err != nil {
+		return "", k6build.NewWrappedError(ErrAccessingArtifact, err)
+	}
+
+	return signature, nil
+}
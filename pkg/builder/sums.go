@@ -0,0 +1,70 @@
+package builder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/grafana/k6build/pkg/catalog"
+)
+
+// moduleSumFunc returns the go.sum hash of a module version downloaded using the given environment
+type moduleSumFunc func(ctx context.Context, env map[string]string, path string, version string) (string, error)
+
+// goModuleSum downloads the module using the go toolchain and returns its go.sum hash.
+// The module is downloaded to the module cache, so the build uses the verified content.
+func goModuleSum(ctx context.Context, env map[string]string, path string, version string) (string, error) {
+	cmd := exec.CommandContext(ctx, "go", "mod", "download", "-json", path+"@"+version) //nolint:gosec
+	cmd.Env = os.Environ()
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("downloading module %s@%s: %w", path, version, err)
+	}
+
+	info := struct {
+		Sum string
+	}{}
+	err = json.Unmarshal(out, &info)
+	if err != nil {
+		return "", fmt.Errorf("parsing module %s@%s info: %w", path, version, err)
+	}
+
+	return info.Sum, nil
+}
+
+// verifyModuleSums checks the modules whose hash is pinned in the catalog match it.
+// A compromised module proxy serving a tampered module fails the verification.
+func (b *Builder) verifyModuleSums(ctx context.Context, env map[string]string, deps map[string]catalog.Module) error {
+	for _, dep := range slices.Sorted(maps.Keys(deps)) {
+		mod := deps[dep]
+		if mod.Sum == "" {
+			continue
+		}
+
+		sum, err := b.moduleSum(ctx, env, mod.Path, mod.Version)
+		if err != nil {
+			return err
+		}
+
+		if sum != mod.Sum {
+			return fmt.Errorf(
+				"%w: %s@%s expected %s got %s",
+				ErrModuleSumMismatch,
+				mod.Path,
+				mod.Version,
+				mod.Sum,
+				sum,
+			)
+		}
+	}
+
+	return nil
+}
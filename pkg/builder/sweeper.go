@@ -0,0 +1,187 @@
+package builder
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6build/pkg/store"
+)
+
+const (
+	// DefaultArtifactSweepInterval is the default time between sweeps of the expired artifacts
+	DefaultArtifactSweepInterval = time.Hour
+
+	// maxTouchInterval is the maximum time between updates of the last access of an artifact in the store
+	maxTouchInterval = time.Hour
+
+	// sweepLockTimeout is the maximum time the sweeper waits for the distributed lock of an artifact.
+	// If it cannot be acquired, the artifact is being built or swept by another builder and is skipped.
+	sweepLockTimeout = 5 * time.Second
+)
+
+// canSweep returns if the expired artifacts can be deleted from the store
+func canSweep(s store.ObjectStore) bool {
+	_, canList := s.(store.ObjectLister)
+	_, canDelete := s.(store.ObjectDeleter)
+	_, canTouch := s.(store.ObjectToucher)
+	return canList && canDelete && canTouch
+}
+
+// touchInterval returns the time between updates of the last access of an artifact in the store.
+// Artifacts are touched several times within the TTL, so an artifact in use is never considered expired.
+func (b *Builder) touchInterval() time.Duration {
+	if b.artifactTTL > 0 {
+		return min(b.artifactTTL/10, maxTouchInterval)
+	}
+
+	return maxTouchInterval
+}
+
+// touchArtifact records in the store that the artifact was accessed, so it is not deleted by any
+// builder sharing the store. For limiting the updates to the store, each artifact is touched at
+// most once per touch interval. Failures are logged but don't fail the request.
+func (b *Builder) touchArtifact(ctx context.Context, id string) {
+	toucher, ok := b.store.(store.ObjectToucher)
+	if !ok {
+		return
+	}
+
+	if touched, found := b.touched.Load(id); found {
+		if t, _ := touched.(time.Time); time.Since(t) < b.touchInterval() {
+			return
+		}
+	}
+
+	if err := toucher.Touch(ctx, id); err != nil {
+		b.logger(ctx).Warn("recording artifact access", "id", id, "error", err.Error())
+		return
+	}
+
+	b.touched.Store(id, time.Now())
+}
+
+func (b *Builder) sweepLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.sweep(ctx)
+		}
+	}
+}
+
+// sweep deletes the artifacts, and their build info, SBOM and signature, stored before the artifact TTL.
+// Artifacts pinned, accessed within the TTL or being built or retrieved by any builder are kept.
+func (b *Builder) sweep(ctx context.Context) {
+	lister, _ := b.store.(store.ObjectLister)
+
+	objects, err := lister.List(ctx)
+	if err != nil {
+		b.log.Warn("listing artifacts for sweeping", "error", err.Error())
+		return
+	}
+
+	expiration := time.Now().Add(-b.artifactTTL)
+
+	// forget the artifacts that were not touched recently
+	b.touched.Range(func(id, touched any) bool {
+		if t, _ := touched.(time.Time); time.Since(t) > b.touchInterval() {
+			b.touched.Delete(id)
+		}
+		return true
+	})
+
+	// the objects of an artifact are swept together
+	expired := map[string][]store.Object{}
+	for _, object := range objects {
+		if object.Created.IsZero() || object.Created.After(expiration) {
+			continue
+		}
+
+		id := artifactOfObject(object.ID)
+		expired[id] = append(expired[id], object)
+	}
+
+	for id, artifactObjects := range expired {
+		b.sweepArtifact(ctx, id, artifactObjects, expiration)
+	}
+}
+
+// sweepArtifact deletes the expired objects of the artifact, unless the artifact is pinned, in use or
+// was accessed after the expiration. The artifact is locked while it is checked and its objects deleted.
+func (b *Builder) sweepArtifact(ctx context.Context, id string, objects []store.Object, expiration time.Time) {
+	unlock, locked := b.tryLockArtifact(id)
+	if !locked {
+		return
+	}
+	defer unlock()
+
+	if b.lock != nil {
+		lockCtx, cancel := context.WithTimeout(ctx, sweepLockTimeout)
+		release, err := b.lock.Lock(lockCtx, id)
+		cancel()
+		if err != nil {
+			b.log.Debug("artifact locked, not sweeping", "id", id, "error", err.Error())
+			return
+		}
+		defer release()
+	}
+
+	// the build info, SBOM and signature of a pinned artifact are also kept
+	pinned, err := store.IsPinned(ctx, b.store, id)
+	if err != nil && !errors.Is(err, store.ErrObjectNotFound) {
+		b.log.Warn("checking if expired artifact is pinned", "id", id, "error", err.Error())
+		return
+	}
+	if pinned {
+		return
+	}
+
+	// the last access is recorded in the artifact, and is shared by all the builders using the store
+	toucher, _ := b.store.(store.ObjectToucher)
+	accessed, err := toucher.Accessed(ctx, id)
+	if err != nil && !errors.Is(err, store.ErrObjectNotFound) {
+		b.log.Warn("checking last access of expired artifact", "id", id, "error", err.Error())
+		return
+	}
+	if accessed.After(expiration) {
+		return
+	}
+
+	deleter, _ := b.store.(store.ObjectDeleter)
+	for _, object := range objects {
+		err = deleter.Delete(ctx, object.ID)
+		if errors.Is(err, store.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			b.log.Warn("deleting expired artifact", "id", object.ID, "error", err.Error())
+			continue
+		}
+
+		b.log.Debug("deleted expired artifact", "id", object.ID, "created", object.Created)
+		b.metrics.sweptObjects.Inc()
+		b.metrics.sweptBytes.Add(float64(object.Size))
+	}
+}
+
+// tryLockArtifact locks the artifact if it is not locked.
+// Returns a function for releasing the lock and false if the artifact was already locked.
+func (b *Builder) tryLockArtifact(id string) (func(), bool) {
+	mtx := &sync.Mutex{}
+	mtx.Lock()
+	if _, locked := b.mutexes.LoadOrStore(id, mtx); locked {
+		return nil, false
+	}
+
+	return func() {
+		b.mutexes.Delete(id)
+		mtx.Unlock()
+	}, true
+}
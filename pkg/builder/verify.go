@@ -0,0 +1,141 @@
+package builder
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6foundry"
+)
+
+// BuildRun describes the outcome of one of the builds in a reproducibility check
+type BuildRun struct {
+	// binary checksum (sha256)
+	Checksum string `json:"checksum,omitempty"`
+	// version of the go toolchain used for the build
+	GoVersion string `json:"go_version,omitempty"`
+	// versions of the go modules included in the binary
+	ModVersions map[string]string `json:"mod_versions,omitempty"`
+}
+
+// ReproducibilityReport reports if building the same request twice produced the same binary
+type ReproducibilityReport struct {
+	Platform     string            `json:"platform,omitempty"`
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	Runs         []BuildRun        `json:"runs,omitempty"`
+	Reproducible bool              `json:"reproducible"`
+	// Summary of the differences between the runs
+	Differences []string `json:"differences,omitempty"`
+}
+
+// VerifyReproducible builds the artifact twice, bypassing the store and the shared build cache,
+// and reports if both builds produced the same binary. If not, the report summarizes what differed between the builds.
+func (b *Builder) VerifyReproducible(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (ReproducibilityReport, error) {
+	_, err := k6foundry.ParsePlatform(platform)
+	if err != nil {
+		return ReproducibilityReport{}, k6build.NewCodedError(k6build.ErrorCodeInvalidPlatform, ErrInvalidParameters, err)
+	}
+
+	resolved, err := b.resolveDependencies(ctx, k6Constrains, deps)
+	if err != nil {
+		return ReproducibilityReport{}, k6build.NewCodedError(resolveErrorCode(err), ErrInvalidParameters, err)
+	}
+
+	buildOpts := k6build.BuildOptionsFromContext(ctx)
+	err = b.checkBuildOptions(buildOpts, platform, resolved)
+	if err != nil {
+		return ReproducibilityReport{}, k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, ErrInvalidParameters, err)
+	}
+
+	report := ReproducibilityReport{
+		Platform:     platform,
+		Dependencies: resolvedVersions(resolved),
+	}
+
+	version, err := b.toolchainVersion(ctx)
+	if err != nil {
+		return ReproducibilityReport{}, k6build.NewWrappedError(ErrBuildingArtifact, err)
+	}
+
+	// each build uses its own empty build cache, so the second build is not served from the
+	// results of the first one
+	for range 2 {
+		binary := &bytes.Buffer{}
+		buildInfo, err := b.buildArtifact(ctx, platform, resolved, buildOpts, true, binary)
+		if err != nil {
+			return ReproducibilityReport{}, k6build.NewCodedError(buildErrorCode(err), ErrBuildingArtifact, err)
+		}
+
+		run := BuildRun{
+			Checksum:  fmt.Sprintf("%x", sha256.Sum256(binary.Bytes())),
+			GoVersion: version,
+		}
+		if buildInfo != nil {
+			run.ModVersions = buildInfo.ModVersions
+		}
+
+		report.Runs = append(report.Runs, run)
+	}
+
+	report.Differences = diffRuns(report.Runs[0], report.Runs[1])
+	report.Reproducible = report.Runs[0].Checksum == report.Runs[1].Checksum
+
+	return report, nil
+}
+
+// diffRuns returns a summary of the differences between two build runs
+func diffRuns(first, second BuildRun) []string {
+	diff := []string{}
+
+	if first.Checksum != second.Checksum {
+		diff = append(diff, fmt.Sprintf("checksum: %s != %s", first.Checksum, second.Checksum))
+	}
+
+	if first.GoVersion != second.GoVersion {
+		diff = append(diff, fmt.Sprintf("go version: %s != %s", first.GoVersion, second.GoVersion))
+	}
+
+	mods := slices.Sorted(maps.Keys(first.ModVersions))
+	for mod := range maps.Keys(second.ModVersions) {
+		if _, found := first.ModVersions[mod]; !found {
+			mods = append(mods, mod)
+		}
+	}
+	slices.Sort(mods)
+
+	for _, mod := range mods {
+		firstVersion, secondVersion := first.ModVersions[mod], second.ModVersions[mod]
+		if firstVersion != secondVersion {
+			diff = append(diff, fmt.Sprintf("module %s: %q != %q", mod, firstVersion, secondVersion))
+		}
+	}
+
+	return diff
+}
+
+// String returns a text serialization of the ReproducibilityReport
+func (r ReproducibilityReport) String() string {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(fmt.Sprintf("platform: %s\n", r.Platform))
+	for _, dep := range slices.Sorted(maps.Keys(r.Dependencies)) {
+		buffer.WriteString(fmt.Sprintf("%s: %s\n", dep, r.Dependencies[dep]))
+	}
+	for i, run := range r.Runs {
+		buffer.WriteString(fmt.Sprintf("run %d checksum: %s\n", i+1, run.Checksum))
+	}
+	buffer.WriteString(fmt.Sprintf("reproducible: %t\n", r.Reproducible))
+	for _, d := range r.Differences {
+		buffer.WriteString(fmt.Sprintf("  %s\n", d))
+	}
+
+	return buffer.String()
+}
@@ -11,7 +11,8 @@
 //		     "<dependency>": {
 //	              "module": "<module path>",
 //	              "versions": ["<version>", "<version>", ... "<version>"],
-//	              "cgo": <bool>
+//	              "cgo": <bool>,
+//	              "sums": {"<version>": "<hash>", ...}
 //		     },
 //		}
 //
@@ -20,6 +21,8 @@
 // module: is the path to the go module that implements the dependency
 // versions: is the list of supported versions
 // cgo: is a boolean that indicates if the module requires cgo
+// sums: optional go.sum hashes (e.g. h1:...) of the module's versions. The builder verifies the
+// module downloaded for a version matches its hash.
 //
 // Example:
 //
@@ -29,6 +32,22 @@
 //	     "k6/x/output-kafka": {"module": "github.com/grafana/xk6-output-kafka", "versions": ["v0.7.0"]},
 //	     "k6/x/xk6-sql-driver-sqlite3": {"module": "github.com/grafana/xk6-sql", "cgo": true, "versions": ["v0.1.0"]}
 //	}
+//
+// Dependencies are resolved to the highest version in the catalog that satisfies their constrains:
+//
+//   - "*", "latest" or empty: any version
+//   - "v0.2.0" or "=v0.2.0": exactly the given version
+//   - ">v0.2.0", ">=v0.2.0", "<v0.2.0", "<=v0.2.0", "!=v0.2.0": compared with the given version
+//   - "~v0.2.3": patch releases of the given version (>=v0.2.3, <v0.3.0)
+//   - "^v1.2.3": versions compatible with the given version (>=v1.2.3, <v2.0.0).
+//     For v0 versions, only patch releases (^v0.2.3 is >=v0.2.3, <v0.3.0)
+//
+// Multiple constrains can be combined with "," (and) or "||" (or) (e.g. ">=v0.2.0, <v0.4.0").
+//
+// Prereleases (e.g. v0.3.0-rc1) are only considered if the constrains include a prerelease
+// (e.g. ">=v0.3.0-rc1"), so "latest" is the highest release. When combining constrains, each one
+// must include a prerelease (e.g. ">=v0.3.0-rc1, <v1.0.0-0"). Dependencies can include prereleases
+// regardless of their constrains using Dependency.IncludePrereleases.
 package catalog
 
 import (
@@ -38,10 +57,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
+	"regexp"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver/v3"
 )
@@ -49,15 +72,27 @@ import (
 const (
 	DefaultCatalogFile = "catalog.json"                        //nolint:revive
 	DefaultCatalogURL  = "https://registry.k6.io/catalog.json" //nolint:revive
+	// LatestConstrain resolves a dependency to its highest release, as "*"
+	LatestConstrain = "latest"
+	// DefaultRetryDelay is the time before retrying a failed catalog download for the first time
+	DefaultRetryDelay = time.Second
 )
 
 var (
 	ErrCannotSatisfy     = errors.New("cannot satisfy dependency") //nolint:revive
+	ErrConnection        = errors.New("connecting to catalog host")
 	ErrDownload          = errors.New("downloading catalog")
 	ErrInvalidConstrain  = errors.New("invalid constrain")
 	ErrInvalidCatalog    = fmt.Errorf("invalid catalog")
+	ErrInvalidVersion    = errors.New("invalid version")
 	ErrOpening           = errors.New("opening catalog")
+	ErrUnexpectedStatus  = errors.New("unexpected response status")
 	ErrUnknownDependency = errors.New("unknown dependency")
+	ErrUnsupported       = errors.New("operation not supported")
+	ErrUntrustedCatalog  = errors.New("untrusted catalog")
+
+	// a version with a prerelease (e.g. v0.3.0-rc1)
+	prereleaseRe = regexp.MustCompile(`\d+\.\d+\.\d+-`)
 )
 
 // Dependency defines a Dependency with a version constrain
@@ -67,6 +102,9 @@ var (
 type Dependency struct {
 	Name       string `json:"name,omitempty"`
 	Constrains string `json:"constrains,omitempty"`
+	// Consider prereleases even if the constrains don't include one.
+	// A prerelease satisfies the constrains if its release does (e.g. v0.3.0-rc1 satisfies >v0.2.0)
+	IncludePrereleases bool `json:"include_prereleases,omitempty"`
 }
 
 // Module defines a go module that resolves a Dependency
@@ -74,19 +112,34 @@ type Module struct {
 	Path    string `json:"path,omitempty"`
 	Version string `json:"version,omitempty"`
 	Cgo     bool   `json:"cgo,omitempty"`
+	// Expected go.sum hash of the module (e.g. h1:...). Empty if the catalog doesn't pin it.
+	Sum string `json:"sum,omitempty"`
+	// Module that replaces this module in the build, in the form path@version. Not set by the catalog.
+	Replace string `json:"replace,omitempty"`
 }
 
 // Catalog defines the interface of the extension catalog service
 type Catalog interface {
 	// Resolve returns a Module that satisfies a Dependency
 	Resolve(ctx context.Context, dep Dependency) (Module, error)
+	// Dependencies returns the sorted list of the names of the dependencies in the catalog
+	Dependencies(ctx context.Context) ([]string, error)
+	// Versions returns the sorted list of the versions of a dependency in the catalog
+	Versions(ctx context.Context, name string) ([]string, error)
+}
+
+// RawCatalog defines the interface of the catalogs that can return their content
+type RawCatalog interface {
+	// Raw returns the content of the catalog as a JSON document
+	Raw() ([]byte, error)
 }
 
 // entry defines a catalog entry
 type entry struct {
-	Module   string   `json:"module,omitempty"`
-	Versions []string `json:"versions,omitempty"`
-	Cgo      bool     `json:"cgo,omitempty"`
+	Module   string            `json:"module,omitempty"`
+	Versions []string          `json:"versions,omitempty"`
+	Cgo      bool              `json:"cgo,omitempty"`
+	Sums     map[string]string `json:"sums,omitempty"`
 }
 
 type catalog struct {
@@ -103,6 +156,41 @@ func (c catalog) getVersions(_ context.Context, mod string) (entry, error) {
 	return e, nil
 }
 
+// Dependencies returns the sorted list of the names of the dependencies in the catalog
+func (c catalog) Dependencies(_ context.Context) ([]string, error) {
+	return slices.Sorted(maps.Keys(c.dependencies)), nil
+}
+
+// Raw returns the content of the catalog as a JSON document
+func (c catalog) Raw() ([]byte, error) {
+	return json.Marshal(c.dependencies)
+}
+
+// Versions returns the list of the versions of a dependency in the catalog, sorted from lower to higher
+func (c catalog) Versions(ctx context.Context, name string) ([]string, error) {
+	entry, err := c.getVersions(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []*semver.Version{}
+	for _, v := range entry.Versions {
+		version, err := semver.NewVersion(v)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q", ErrInvalidVersion, v)
+		}
+		versions = append(versions, version)
+	}
+	sort.Sort(semver.Collection(versions))
+
+	sorted := []string{}
+	for _, v := range versions {
+		sorted = append(sorted, v.Original())
+	}
+
+	return sorted, nil
+}
+
 // NewCatalogFromJSON creates a Catalog from a json file that follows the [schema](./schema.json):
 func NewCatalogFromJSON(stream io.Reader) (Catalog, error) {
 	buff := &bytes.Buffer{}
@@ -134,9 +222,9 @@ func NewCatalog(ctx context.Context, location string) (Catalog, error) {
 
 // NewCatalogFromFile creates a Catalog from a json file
 func NewCatalogFromFile(catalogFile string) (Catalog, error) {
-	json, err := os.ReadFile(catalogFile) //nolint:gosec
+	json, err := readFile(catalogFile)
 	if err != nil {
-		return nil, fmt.Errorf("%w: %w", ErrOpening, err)
+		return nil, err
 	}
 
 	buff := bytes.NewBuffer(json)
@@ -145,27 +233,265 @@ func NewCatalogFromFile(catalogFile string) (Catalog, error) {
 
 // NewCatalogFromURL creates a Catalog from a URL
 func NewCatalogFromURL(ctx context.Context, catalogURL string) (Catalog, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, catalogURL, nil)
+	return LoadOptions{}.fromURL(ctx, catalogURL)
+}
+
+// LoadOptions defines how catalogs are loaded
+type LoadOptions struct {
+	// Verification applied to the catalogs. Optional
+	Verification Verification
+	// Client used for downloading the catalogs. Defaults to http.DefaultClient
+	Client *http.Client
+	// Maximum time for downloading a catalog. If 0, downloads are only limited by the context
+	Timeout time.Duration
+	// Number of times a download is retried if the host cannot be reached or responds with a server error
+	// or too many requests. If 0, downloads are not retried
+	Retries int
+	// Time before the first retry, doubled for each following retry. Defaults to DefaultRetryDelay
+	RetryDelay time.Duration
+}
+
+// LoadCatalog returns a catalog loaded from a location, which can be a local path or an URL.
+// Failures downloading the catalog return ErrDownload, and also ErrConnection if the host could
+// not be reached or ErrUnexpectedStatus if the response was not successful.
+// Catalogs that cannot be parsed return ErrInvalidCatalog.
+func LoadCatalog(ctx context.Context, location string, opts LoadOptions) (Catalog, error) {
+	if opts.Verification.SHA256 == "" && opts.Verification.PublicKey == nil {
+		if strings.HasPrefix(location, "http") {
+			return opts.fromURL(ctx, location)
+		}
+		return NewCatalogFromFile(location)
+	}
+
+	content, err := opts.read(ctx, location)
 	if err != nil {
-		return nil, fmt.Errorf("%w %w", ErrDownload, err)
+		return nil, err
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	err = opts.verify(ctx, location, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCatalogFromJSON(bytes.NewBuffer(content))
+}
+
+func (o LoadOptions) fromURL(ctx context.Context, catalogURL string) (Catalog, error) {
+	json, err := o.download(ctx, catalogURL)
+	if err != nil {
+		return nil, err
+	}
+
+	catalog, err := NewCatalogFromJSON(bytes.NewBuffer(json))
 	if err != nil {
 		return nil, fmt.Errorf("%w %w", ErrDownload, err)
 	}
+
+	return catalog, nil
+}
+
+// read returns the content of a location, which can be a local path or an URL
+func (o LoadOptions) read(ctx context.Context, location string) ([]byte, error) {
+	if strings.HasPrefix(location, "http") {
+		return o.download(ctx, location)
+	}
+
+	return readFile(location)
+}
+
+func readFile(file string) ([]byte, error) {
+	content, err := os.ReadFile(file) //nolint:gosec
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrOpening, err)
+	}
+
+	return content, nil
+}
+
+// download downloads the content of the url, retrying the failures that may be transient.
+// After exhausting the retries, the last error is returned.
+func (o LoadOptions) download(ctx context.Context, url string) ([]byte, error) {
+	delay := o.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	for attempt := 0; ; attempt++ {
+		content, retry, err := o.downloadOnce(ctx, url)
+		if err == nil || !retry || attempt >= o.Retries || ctx.Err() != nil {
+			return content, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// downloadOnce downloads the content of the url. If it fails, returns if the failure may be transient.
+func (o LoadOptions) downloadOnce(ctx context.Context, url string) ([]byte, bool, error) {
+	if o.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.Timeout)
+		defer cancel()
+	}
+
+	client := o.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w %w", ErrDownload, err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("%w: %w %w", ErrDownload, ErrConnection, err)
+	}
 	defer resp.Body.Close() //nolint:errcheck
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w %s", ErrDownload, resp.Status)
+		retry := resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return nil, retry, fmt.Errorf("%w: %w %s", ErrDownload, ErrUnexpectedStatus, resp.Status)
 	}
 
-	catalog, err := NewCatalogFromJSON(resp.Body)
+	content, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("%w %w", ErrDownload, err)
+		return nil, true, fmt.Errorf("%w: %w %w", ErrDownload, ErrConnection, err)
 	}
 
-	return catalog, nil
+	return content, false, nil
+}
+
+// NewMergedCatalog returns a catalog that merges the catalogs loaded from the given locations.
+// Later locations override earlier ones for the same dependency.
+func NewMergedCatalog(ctx context.Context, locations ...string) (Catalog, error) {
+	return NewVerifiedMergedCatalog(ctx, Verification{}, locations...)
+}
+
+// NewVerifiedMergedCatalog returns a catalog that merges the catalogs loaded from the given locations
+// after verifying each of them. Later locations override earlier ones for the same dependency.
+func NewVerifiedMergedCatalog(ctx context.Context, verification Verification, locations ...string) (Catalog, error) {
+	return LoadMergedCatalog(ctx, LoadOptions{Verification: verification}, locations...)
+}
+
+// LoadMergedCatalog returns a catalog that merges the catalogs loaded from the given locations
+// using the given options. Later locations override earlier ones for the same dependency.
+func LoadMergedCatalog(ctx context.Context, opts LoadOptions, locations ...string) (Catalog, error) {
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("%w: no catalog locations", ErrOpening)
+	}
+
+	catalogs := []Catalog{}
+	for _, location := range locations {
+		catalog, err := LoadCatalog(ctx, location, opts)
+		if err != nil {
+			return nil, err
+		}
+		catalogs = append(catalogs, catalog)
+	}
+
+	return Merge(catalogs...), nil
+}
+
+// mergedCatalog resolves dependencies from multiple catalogs in priority order
+type mergedCatalog struct {
+	// catalogs in priority order (highest first)
+	catalogs []Catalog
+}
+
+// Merge returns a catalog that layers the given catalogs. Later catalogs override earlier ones
+// for the same dependency, and the union of the dependencies of all catalogs can be resolved.
+func Merge(catalogs ...Catalog) Catalog {
+	if len(catalogs) == 1 {
+		return catalogs[0]
+	}
+
+	merged := mergedCatalog{}
+	for i := len(catalogs) - 1; i >= 0; i-- {
+		merged.catalogs = append(merged.catalogs, catalogs[i])
+	}
+
+	return merged
+}
+
+// Resolve resolves the dependency using the first catalog, in priority order, that defines it
+func (m mergedCatalog) Resolve(ctx context.Context, dep Dependency) (Module, error) {
+	for _, c := range m.catalogs {
+		mod, err := c.Resolve(ctx, dep)
+		if errors.Is(err, ErrUnknownDependency) {
+			continue
+		}
+		return mod, err
+	}
+
+	return Module{}, fmt.Errorf("%w : %s", ErrUnknownDependency, dep.Name)
+}
+
+// Dependencies returns the sorted union of the dependencies of the merged catalogs
+func (m mergedCatalog) Dependencies(ctx context.Context) ([]string, error) {
+	names := map[string]struct{}{}
+	for _, c := range m.catalogs {
+		deps, err := c.Dependencies(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range deps {
+			names[d] = struct{}{}
+		}
+	}
+
+	return slices.Sorted(maps.Keys(names)), nil
+}
+
+// Versions returns the versions of the dependency in the first catalog, in priority order, that defines it
+func (m mergedCatalog) Versions(ctx context.Context, name string) ([]string, error) {
+	for _, c := range m.catalogs {
+		versions, err := c.Versions(ctx, name)
+		if errors.Is(err, ErrUnknownDependency) {
+			continue
+		}
+		return versions, err
+	}
+
+	return nil, fmt.Errorf("%w : %s", ErrUnknownDependency, name)
+}
+
+// Raw returns the content of the merged catalogs. If a dependency is defined in more than one catalog,
+// the entry from the catalog with the highest priority is used.
+func (m mergedCatalog) Raw() ([]byte, error) {
+	merged := map[string]json.RawMessage{}
+	// add the catalogs from the lowest to the highest priority so the later ones override the entries
+	for _, c := range slices.Backward(m.catalogs) {
+		raw, err := Raw(c)
+		if err != nil {
+			return nil, err
+		}
+
+		entries := map[string]json.RawMessage{}
+		if err = json.Unmarshal(raw, &entries); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+		}
+		maps.Copy(merged, entries)
+	}
+
+	return json.Marshal(merged)
+}
+
+// Raw returns the content of the catalog as a JSON document.
+// Fails with ErrUnsupported if the catalog doesn't implement RawCatalog.
+func Raw(c Catalog) ([]byte, error) {
+	raw, ok := c.(RawCatalog)
+	if !ok {
+		return nil, fmt.Errorf("%w: catalog content not available", ErrUnsupported)
+	}
+
+	return raw.Raw()
 }
 
 // DefaultCatalog creates a Catalog from the default catalog URL
@@ -179,11 +505,14 @@ func (c catalog) Resolve(ctx context.Context, dep Dependency) (Module, error) {
 		return Module{}, err
 	}
 
-	constrain, err := semver.NewConstraint(dep.Constrains)
+	constrain, err := semver.NewConstraint(normalizeConstrains(dep.Constrains))
 	if err != nil {
 		return Module{}, fmt.Errorf("%w : %s", ErrInvalidConstrain, dep.Constrains)
 	}
 
+	// some operators (e.g. !=) accept prereleases even if the constrains don't include one
+	prereleases := prereleaseRe.MatchString(dep.Constrains)
+
 	versions := []*semver.Version{}
 	for _, v := range entry.Versions {
 		version, err := semver.NewVersion(v)
@@ -197,11 +526,91 @@ func (c catalog) Resolve(ctx context.Context, dep Dependency) (Module, error) {
 		// try to find the higher version that satisfies the condition
 		sort.Sort(sort.Reverse(semver.Collection(versions)))
 		for _, v := range versions {
-			if constrain.Check(v) {
-				return Module{Path: entry.Module, Version: v.Original(), Cgo: entry.Cgo}, nil
+			if v.Prerelease() != "" && !prereleases && !dep.IncludePrereleases {
+				continue
+			}
+			if constrain.Check(v) || (dep.IncludePrereleases && releaseSatisfies(constrain, v)) {
+				return Module{
+					Path:    entry.Module,
+					Version: v.Original(),
+					Cgo:     entry.Cgo,
+					Sum:     entry.Sums[v.Original()],
+				}, nil
 			}
 		}
 	}
 
-	return Module{}, fmt.Errorf("%w : %s %s", ErrCannotSatisfy, dep.Name, dep.Constrains)
+	return Module{}, fmt.Errorf(
+		"%w : %s %s: %s", ErrCannotSatisfy, dep.Name, dep.Constrains, explainUnsatisfied(dep, constrain, versions),
+	)
+}
+
+// maxExplainedVersions is the maximum number of available versions listed when a dependency cannot be satisfied
+const maxExplainedVersions = 10
+
+// explainUnsatisfied describes why no version satisfies the dependency's constrains: the versions
+// available and the closest match. The versions are sorted from higher to lower.
+func explainUnsatisfied(dep Dependency, constrain *semver.Constraints, versions []*semver.Version) string {
+	if len(versions) == 0 {
+		return fmt.Sprintf("requested %s but no versions are available", dep.Constrains)
+	}
+
+	available := []string{}
+	for _, v := range slices.Backward(versions[:min(len(versions), maxExplainedVersions)]) {
+		available = append(available, v.Original())
+	}
+	list := strings.Join(available, ", ")
+	if older := len(versions) - len(available); older > 0 {
+		list += fmt.Sprintf(" (and %d older)", older)
+	}
+
+	// prereleases that would satisfy the constrains are the closest match
+	for _, v := range versions {
+		if v.Prerelease() != "" && (constrain.Check(v) || releaseSatisfies(constrain, v)) {
+			return fmt.Sprintf(
+				"requested %s but only %s available. %s satisfies it if prereleases are included",
+				dep.Constrains, list, v.Original(),
+			)
+		}
+	}
+
+	return fmt.Sprintf(
+		"requested %s but only %s available (closest %s)",
+		dep.Constrains, list, closestVersion(constrain, versions).Original(),
+	)
+}
+
+// closestVersion returns the version closest to the range of the constrains: the lowest version if the
+// range is below all the versions and the highest version otherwise. The versions are sorted from higher to lower.
+func closestVersion(constrain *semver.Constraints, versions []*semver.Version) *semver.Version {
+	lowest := versions[len(versions)-1]
+	if constrain.Check(semver.New(0, 0, 0, "", "")) && !constrain.Check(lowest) {
+		return lowest
+	}
+
+	return versions[0]
+}
+
+// releaseSatisfies returns true if the version is a prerelease and its release satisfies the constrains
+func releaseSatisfies(constrain *semver.Constraints, version *semver.Version) bool {
+	if version.Prerelease() == "" {
+		return false
+	}
+
+	release, err := version.SetPrerelease("")
+	if err != nil {
+		return false
+	}
+
+	return constrain.Check(&release)
+}
+
+// normalizeConstrains returns the constrains that match any version for the empty and "latest" constrains
+func normalizeConstrains(constrains string) string {
+	constrains = strings.TrimSpace(constrains)
+	if constrains == "" || constrains == LatestConstrain {
+		return "*"
+	}
+
+	return constrains
 }
@@ -3,16 +3,22 @@ package catalog
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 const testCatalog = `{
-"dep": {"Module": "github.com/dep", "Versions": ["v0.1.0", "v0.2.0"]},
+"dep": {"Module": "github.com/dep", "Versions": ["v0.1.0", "v0.2.0"], "Sums": {"v0.2.0": "h1:sum"}},
 "dep2": {"Module": "github.com/dep2", "Versions": ["v0.1.0"], "Cgo": true}
 }`
 
@@ -33,12 +39,12 @@ func TestResolve(t *testing.T) {
 		{
 			title:  "resolve > constrain",
 			dep:    Dependency{Name: "dep", Constrains: ">v0.1.0"},
-			expect: Module{Path: "github.com/dep", Version: "v0.2.0", Cgo: false},
+			expect: Module{Path: "github.com/dep", Version: "v0.2.0", Cgo: false, Sum: "h1:sum"},
 		},
 		{
 			title:  "resolve latest version",
 			dep:    Dependency{Name: "dep", Constrains: "*"},
-			expect: Module{Path: "github.com/dep", Version: "v0.2.0", Cgo: false},
+			expect: Module{Path: "github.com/dep", Version: "v0.2.0", Cgo: false, Sum: "h1:sum"},
 		},
 		{
 			title:  "resolve cgo dependency",
@@ -75,6 +81,140 @@ func TestResolve(t *testing.T) {
 	}
 }
 
+// TestConstrainSemantics checks the versions selected for each form of constrains
+func TestConstrainSemantics(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := NewCatalogFromJSON(bytes.NewBufferString(`{
+"dep": {
+  "Module": "github.com/dep",
+  "Versions": ["v0.1.0", "v0.2.0", "v0.2.5", "v0.3.0-rc1", "v1.0.0", "v1.2.0", "v2.0.0-beta.1"]
+}
+}`))
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	testCases := []struct {
+		constrains  string
+		prereleases bool
+		expect      string
+		expectErr   error
+	}{
+		{constrains: "*", expect: "v1.2.0"},
+		{constrains: "latest", expect: "v1.2.0"},
+		{constrains: "", expect: "v1.2.0"},
+		{constrains: "v0.2.0", expect: "v0.2.0"},
+		{constrains: "=v0.2.0", expect: "v0.2.0"},
+		{constrains: "!=v1.2.0", expect: "v1.0.0"},
+		{constrains: ">=v0.2.0", expect: "v1.2.0"},
+		{constrains: "<=v0.2.0", expect: "v0.2.0"},
+		{constrains: ">=v0.2.0, <v1.0.0", expect: "v0.2.5"},
+		{constrains: "v0.1.0 || v0.2.0", expect: "v0.2.0"},
+		{constrains: "~v0.2.0", expect: "v0.2.5"},
+		{constrains: "~v1.0.0", expect: "v1.0.0"},
+		{constrains: "^v0.2.0", expect: "v0.2.5"},
+		{constrains: "^v1.0.0", expect: "v1.2.0"},
+		{constrains: "v0.3.0-rc1", expect: "v0.3.0-rc1"},
+		{constrains: ">=v0.3.0-rc1, <v1.0.0-0", expect: "v0.3.0-rc1"},
+		{constrains: ">=v0.3.0-rc1, <v1.0.0", expectErr: ErrCannotSatisfy},
+		{constrains: ">=v2.0.0-beta.1", expect: "v2.0.0-beta.1"},
+		{constrains: ">v1.2.0", expectErr: ErrCannotSatisfy},
+		{constrains: "~v0.4.0", expectErr: ErrCannotSatisfy},
+		{constrains: "newest", expectErr: ErrInvalidConstrain},
+		{constrains: "latest", prereleases: true, expect: "v2.0.0-beta.1"},
+		{constrains: ">=v0.2.0, <v1.0.0", prereleases: true, expect: "v0.3.0-rc1"},
+		{constrains: "~v0.2.0", prereleases: true, expect: "v0.2.5"},
+		{constrains: "^v1.0.0", prereleases: true, expect: "v1.2.0"},
+		{constrains: "v0.3.0", prereleases: true, expect: "v0.3.0-rc1"},
+		{constrains: ">v2.0.0", prereleases: true, expectErr: ErrCannotSatisfy},
+	}
+
+	for _, tc := range testCases {
+		title := tc.constrains
+		if tc.prereleases {
+			title += " including prereleases"
+		}
+
+		t.Run(title, func(t *testing.T) {
+			t.Parallel()
+
+			dep := Dependency{Name: "dep", Constrains: tc.constrains, IncludePrereleases: tc.prereleases}
+			mod, err := catalog.Resolve(context.TODO(), dep)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && mod.Version != tc.expect {
+				t.Fatalf("expected %s got %s", tc.expect, mod.Version)
+			}
+		})
+	}
+}
+
+// TestUnsatisfiedExplanation checks the error for unsatisfied constrains explains the versions available
+func TestUnsatisfiedExplanation(t *testing.T) {
+	t.Parallel()
+
+	catalog, err := NewCatalogFromJSON(bytes.NewBufferString(`{
+"dep": {"Module": "github.com/dep", "Versions": ["v0.2.0", "v0.1.0", "v0.3.0-rc1"]},
+"many": {"Module": "github.com/many", "Versions": [
+  "v0.1.0", "v0.2.0", "v0.3.0", "v0.4.0", "v0.5.0", "v0.6.0", "v0.7.0", "v0.8.0", "v0.9.0", "v0.10.0", "v0.11.0"
+]},
+"none": {"Module": "github.com/none", "Versions": []}
+}`))
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	testCases := []struct {
+		dep    string
+		constr string
+		expect string
+	}{
+		{
+			dep:    "dep",
+			constr: ">v0.3.0",
+			expect: "requested >v0.3.0 but only v0.1.0, v0.2.0, v0.3.0-rc1 available (closest v0.3.0-rc1)",
+		},
+		{
+			dep:    "dep",
+			constr: "<v0.1.0",
+			expect: "requested <v0.1.0 but only v0.1.0, v0.2.0, v0.3.0-rc1 available (closest v0.1.0)",
+		},
+		{
+			dep:    "dep",
+			constr: "~v0.3.0",
+			expect: "v0.3.0-rc1 satisfies it if prereleases are included",
+		},
+		{
+			dep:    "many",
+			constr: ">v1.0.0",
+			expect: "only v0.2.0, v0.3.0, v0.4.0, v0.5.0, v0.6.0, v0.7.0, v0.8.0, v0.9.0, v0.10.0, v0.11.0 (and 1 older)",
+		},
+		{
+			dep:    "none",
+			constr: "*",
+			expect: "requested * but no versions are available",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.dep+" "+tc.constr, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := catalog.Resolve(context.TODO(), Dependency{Name: tc.dep, Constrains: tc.constr})
+			if !errors.Is(err, ErrCannotSatisfy) {
+				t.Fatalf("expected %v got %v", ErrCannotSatisfy, err)
+			}
+
+			if !strings.Contains(err.Error(), tc.expect) {
+				t.Fatalf("expected %q in %q", tc.expect, err.Error())
+			}
+		})
+	}
+}
+
 func TestCatalogFromJSON(t *testing.T) {
 	t.Parallel()
 
@@ -147,6 +287,165 @@ func TestCatalogFromURL(t *testing.T) {
 	}
 }
 
+// countingTransport counts the requests made using it
+type countingTransport struct {
+	requests atomic.Int64
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestLoadCatalog(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		handler   http.HandlerFunc
+		closed    bool
+		expectErr error
+	}{
+		{
+			name: "download catalog",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(testCatalog))
+			},
+			expectErr: nil,
+		},
+		{
+			name: "unexpected status",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expectErr: ErrUnexpectedStatus,
+		},
+		{
+			name: "invalid catalog",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("not json"))
+			},
+			expectErr: ErrInvalidCatalog,
+		},
+		{
+			name: "timeout",
+			handler: func(_ http.ResponseWriter, r *http.Request) {
+				<-r.Context().Done()
+			},
+			expectErr: context.DeadlineExceeded,
+		},
+		{
+			name:      "host unreachable",
+			handler:   func(_ http.ResponseWriter, _ *http.Request) {},
+			closed:    true,
+			expectErr: ErrConnection,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(tc.handler)
+			if tc.closed {
+				srv.Close()
+			} else {
+				t.Cleanup(srv.Close)
+			}
+
+			transport := &countingTransport{}
+			opts := LoadOptions{
+				Client:  &http.Client{Transport: transport},
+				Timeout: 100 * time.Millisecond,
+			}
+
+			_, err := LoadCatalog(context.TODO(), srv.URL, opts)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil && !errors.Is(err, ErrDownload) {
+				t.Fatalf("expected %v got %v", ErrDownload, err)
+			}
+
+			if transport.requests.Load() != 1 {
+				t.Fatalf("expected the request to use the client")
+			}
+		})
+	}
+}
+
+func TestLoadCatalogRetries(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name           string
+		failures       int32
+		status         int
+		retries        int
+		expectErr      error
+		expectRequests int32
+	}{
+		{
+			name:           "recover from server errors",
+			failures:       2,
+			status:         http.StatusServiceUnavailable,
+			retries:        3,
+			expectRequests: 3,
+		},
+		{
+			name:           "retries exhausted",
+			failures:       5,
+			status:         http.StatusBadGateway,
+			retries:        2,
+			expectErr:      ErrUnexpectedStatus,
+			expectRequests: 3,
+		},
+		{
+			name:           "not found is not retried",
+			failures:       1,
+			status:         http.StatusNotFound,
+			retries:        3,
+			expectErr:      ErrUnexpectedStatus,
+			expectRequests: 1,
+		},
+		{
+			name:           "no retries",
+			failures:       1,
+			status:         http.StatusInternalServerError,
+			retries:        0,
+			expectErr:      ErrUnexpectedStatus,
+			expectRequests: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			requests := atomic.Int32{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if requests.Add(1) <= tc.failures {
+					w.WriteHeader(tc.status)
+					return
+				}
+				_, _ = w.Write([]byte(testCatalog))
+			}))
+			t.Cleanup(srv.Close)
+
+			opts := LoadOptions{Retries: tc.retries, RetryDelay: time.Millisecond}
+			_, err := LoadCatalog(context.TODO(), srv.URL, opts)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if requests.Load() != tc.expectRequests {
+				t.Fatalf("expected %d requests got %d", tc.expectRequests, requests.Load())
+			}
+		})
+	}
+}
+
 func TestCatalogFromFile(t *testing.T) {
 	t.Parallel()
 
@@ -185,3 +484,434 @@ func TestCatalogFromFile(t *testing.T) {
 		})
 	}
 }
+
+const overlayCatalog = `{
+"dep": {"Module": "github.com/private/dep", "Versions": ["v0.3.0"]},
+"private": {"Module": "github.com/private/ext", "Versions": ["v1.0.0"]}
+}`
+
+func TestMergedCatalog(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	baseFile := filepath.Join(dir, "base.json")
+	overlayFile := filepath.Join(dir, "overlay.json")
+	for file, content := range map[string]string{baseFile: testCatalog, overlayFile: overlayCatalog} {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("test setup: %v", err)
+		}
+	}
+
+	catalog, err := NewMergedCatalog(context.TODO(), baseFile, overlayFile)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		dep       Dependency
+		expect    Module
+		expectErr error
+	}{
+		{
+			title:  "overridden dependency",
+			dep:    Dependency{Name: "dep", Constrains: "*"},
+			expect: Module{Path: "github.com/private/dep", Version: "v0.3.0"},
+		},
+		{
+			title:     "overridden dependency does not fall back to versions in earlier catalog",
+			dep:       Dependency{Name: "dep", Constrains: "v0.1.0"},
+			expectErr: ErrCannotSatisfy,
+		},
+		{
+			title:  "dependency only in earlier catalog",
+			dep:    Dependency{Name: "dep2", Constrains: "*"},
+			expect: Module{Path: "github.com/dep2", Version: "v0.1.0", Cgo: true},
+		},
+		{
+			title:  "dependency only in later catalog",
+			dep:    Dependency{Name: "private", Constrains: "*"},
+			expect: Module{Path: "github.com/private/ext", Version: "v1.0.0"},
+		},
+		{
+			title:     "unknown dependency",
+			dep:       Dependency{Name: "unknown", Constrains: "*"},
+			expectErr: ErrUnknownDependency,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mod, err := catalog.Resolve(context.TODO(), tc.dep)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && mod != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, mod)
+			}
+		})
+	}
+}
+
+// noRawCatalog is a Catalog that doesn't implement RawCatalog
+type noRawCatalog struct {
+	Catalog
+}
+
+func TestRaw(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	baseFile := filepath.Join(dir, "base.json")
+	overlayFile := filepath.Join(dir, "overlay.json")
+	for file, content := range map[string]string{baseFile: testCatalog, overlayFile: overlayCatalog} {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("test setup: %v", err)
+		}
+	}
+
+	single, err := NewCatalogFromFile(baseFile)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	merged, err := NewMergedCatalog(context.TODO(), baseFile, overlayFile)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		catalog   Catalog
+		expect    map[string]entry
+		expectErr error
+	}{
+		{
+			title:   "single catalog",
+			catalog: single,
+			expect: map[string]entry{
+				"dep": {
+					Module:   "github.com/dep",
+					Versions: []string{"v0.1.0", "v0.2.0"},
+					Sums:     map[string]string{"v0.2.0": "h1:sum"},
+				},
+				"dep2": {Module: "github.com/dep2", Versions: []string{"v0.1.0"}, Cgo: true},
+			},
+		},
+		{
+			title:   "merged catalog",
+			catalog: merged,
+			expect: map[string]entry{
+				"dep":     {Module: "github.com/private/dep", Versions: []string{"v0.3.0"}},
+				"dep2":    {Module: "github.com/dep2", Versions: []string{"v0.1.0"}, Cgo: true},
+				"private": {Module: "github.com/private/ext", Versions: []string{"v1.0.0"}},
+			},
+		},
+		{
+			title:     "not supported",
+			catalog:   noRawCatalog{Catalog: single},
+			expectErr: ErrUnsupported,
+		},
+		{
+			title:     "merged catalog not supported",
+			catalog:   Merge(single, noRawCatalog{Catalog: single}),
+			expectErr: ErrUnsupported,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			raw, err := Raw(tc.catalog)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			entries := map[string]entry{}
+			if err = json.Unmarshal(raw, &entries); err != nil {
+				t.Fatalf("decoding catalog %v", err)
+			}
+
+			if !reflect.DeepEqual(entries, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, entries)
+			}
+		})
+	}
+}
+
+func TestReloadingCatalog(t *testing.T) {
+	t.Parallel()
+
+	catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(catalogFile, []byte(testCatalog), 0o644); err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	reloads := make(chan error, 10)
+	catalog, err := NewReloadingCatalog(context.TODO(), ReloadingCatalogConfig{
+		Sources:  []string{catalogFile},
+		OnReload: func(err error) { reloads <- err },
+	})
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	dep := Dependency{Name: "dep", Constrains: "*"}
+
+	// publish a new version
+	if err = os.WriteFile(catalogFile, []byte(overlayCatalog), 0o644); err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	if err = catalog.Reload(context.TODO()); err != nil {
+		t.Fatalf("reloading: %v", err)
+	}
+
+	mod, err := catalog.Resolve(context.TODO(), dep)
+	if err != nil || mod.Version != "v0.3.0" {
+		t.Fatalf("expected reloaded version v0.3.0 got %v %v", mod, err)
+	}
+
+	// a failed reload keeps the last catalog
+	if err = os.WriteFile(catalogFile, []byte("invalid"), 0o644); err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	if err = catalog.Reload(context.TODO()); !errors.Is(err, ErrInvalidCatalog) {
+		t.Fatalf("expected %v got %v", ErrInvalidCatalog, err)
+	}
+
+	mod, err = catalog.Resolve(context.TODO(), dep)
+	if err != nil || mod.Version != "v0.3.0" {
+		t.Fatalf("expected last loaded version v0.3.0 got %v %v", mod, err)
+	}
+
+	if len(reloads) != 2 || <-reloads != nil || <-reloads == nil {
+		t.Fatalf("expected reload results to be reported")
+	}
+}
+
+func TestReloadingCatalogInterval(t *testing.T) {
+	t.Parallel()
+
+	catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+	if err := os.WriteFile(catalogFile, []byte(testCatalog), 0o644); err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.TODO())
+	t.Cleanup(cancel)
+
+	reloads := make(chan error, 1)
+	_, err := NewReloadingCatalog(ctx, ReloadingCatalogConfig{
+		Sources:  []string{catalogFile},
+		Interval: 10 * time.Millisecond,
+		OnReload: func(err error) {
+			select {
+			case reloads <- err:
+			default:
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	select {
+	case err = <-reloads:
+		if err != nil {
+			t.Fatalf("reloading: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("catalog not reloaded")
+	}
+}
+
+func TestDependencies(t *testing.T) {
+	t.Parallel()
+
+	base, err := NewCatalogFromJSON(bytes.NewBufferString(testCatalog))
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	overlay, err := NewCatalogFromJSON(bytes.NewBufferString(overlayCatalog))
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	testCases := []struct {
+		title   string
+		catalog Catalog
+		expect  []string
+	}{
+		{
+			title:   "json catalog",
+			catalog: base,
+			expect:  []string{"dep", "dep2"},
+		},
+		{
+			title:   "merged catalog",
+			catalog: Merge(base, overlay),
+			expect:  []string{"dep", "dep2", "private"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			deps, err := tc.catalog.Dependencies(context.TODO())
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if !slices.Equal(deps, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, deps)
+			}
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	t.Parallel()
+
+	base, err := NewCatalogFromJSON(bytes.NewBufferString(testCatalog))
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	overlay, err := NewCatalogFromJSON(bytes.NewBufferString(overlayCatalog))
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	unsorted, err := NewCatalogFromJSON(bytes.NewBufferString(
+		`{"dep": {"Module": "github.com/dep", "Versions": ["v0.10.0", "v0.2.0", "v0.9.1"]}}`,
+	))
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		catalog   Catalog
+		dep       string
+		expect    []string
+		expectErr error
+	}{
+		{
+			title:   "json catalog",
+			catalog: base,
+			dep:     "dep",
+			expect:  []string{"v0.1.0", "v0.2.0"},
+		},
+		{
+			title:   "versions are sorted",
+			catalog: unsorted,
+			dep:     "dep",
+			expect:  []string{"v0.2.0", "v0.9.1", "v0.10.0"},
+		},
+		{
+			title:   "overridden dependency",
+			catalog: Merge(base, overlay),
+			dep:     "dep",
+			expect:  []string{"v0.3.0"},
+		},
+		{
+			title:   "dependency only in earlier catalog",
+			catalog: Merge(base, overlay),
+			dep:     "dep2",
+			expect:  []string{"v0.1.0"},
+		},
+		{
+			title:     "unknown dependency",
+			catalog:   base,
+			dep:       "unknown",
+			expectErr: ErrUnknownDependency,
+		},
+		{
+			title:     "unknown dependency in merged catalog",
+			catalog:   Merge(base, overlay),
+			dep:       "unknown",
+			expectErr: ErrUnknownDependency,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			versions, err := tc.catalog.Versions(context.TODO(), tc.dep)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && !slices.Equal(versions, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, versions)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		catalog   string
+		expectErr error
+		// dependencies expected to be reported in the error
+		expectInvalid []string
+	}{
+		{
+			title:   "valid catalog",
+			catalog: testCatalog,
+		},
+		{
+			title: "all problems reported",
+			catalog: `{
+"dep": {"Module": "github.com/dep", "Versions": ["v0.1.0"]},
+"no-module": {"Versions": ["v0.1.0"]},
+"no-versions": {"Module": "github.com/no-versions"},
+"bad-version": {"Module": "github.com/bad-version", "Versions": ["v0.1.0", "latest"]}
+}`,
+			expectErr:     ErrInvalidCatalog,
+			expectInvalid: []string{"no-module", "no-versions", "bad-version"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			catalog, err := NewCatalogFromJSON(bytes.NewBufferString(tc.catalog))
+			if err != nil {
+				t.Fatalf("test setup: %v", err)
+			}
+
+			err = Validate(context.TODO(), catalog)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			for _, dep := range tc.expectInvalid {
+				if !strings.Contains(err.Error(), dep+":") {
+					t.Fatalf("expected %q reported in %q", dep, err)
+				}
+			}
+
+			if tc.expectErr != nil && strings.Contains(err.Error(), "\ndep:") {
+				t.Fatalf("valid dependency reported in %q", err)
+			}
+		})
+	}
+}
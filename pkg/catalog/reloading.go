@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReloadingCatalogConfig defines the configuration of a ReloadingCatalog
+type ReloadingCatalogConfig struct {
+	// Locations of the catalogs. Later catalogs override earlier ones for the same dependency
+	Sources []string
+	// Time between reloads. If 0, the catalog is only reloaded by calling Reload
+	Interval time.Duration
+	// Function called after each reload attempt with its result. Optional
+	OnReload func(err error)
+	// Verification applied to each source when it is loaded. Optional
+	Verification Verification
+	// Client used for downloading the sources. Defaults to http.DefaultClient
+	Client *http.Client
+	// Maximum time for downloading a source. If 0, downloads are only limited by the context
+	Timeout time.Duration
+	// Number of times a failed download of a source is retried. If 0, downloads are not retried
+	Retries int
+	// Time before the first retry, doubled for each following retry. Defaults to DefaultRetryDelay
+	RetryDelay time.Duration
+}
+
+// ReloadingCatalog is a Catalog that is periodically reloaded from its sources.
+// If a reload fails, the last catalog successfully loaded is used.
+type ReloadingCatalog struct {
+	mutex    sync.RWMutex
+	catalog  Catalog
+	sources  []string
+	opts     LoadOptions
+	onReload func(err error)
+}
+
+// NewReloadingCatalog returns a catalog loaded from the given sources and reloaded on an interval
+// until the context is done. Fails if the initial load fails.
+func NewReloadingCatalog(ctx context.Context, config ReloadingCatalogConfig) (*ReloadingCatalog, error) {
+	opts := LoadOptions{
+		Verification: config.Verification,
+		Client:       config.Client,
+		Timeout:      config.Timeout,
+		Retries:      config.Retries,
+		RetryDelay:   config.RetryDelay,
+	}
+	catalog, err := LoadMergedCatalog(ctx, opts, config.Sources...)
+	if err != nil {
+		return nil, err
+	}
+
+	onReload := config.OnReload
+	if onReload == nil {
+		onReload = func(error) {}
+	}
+
+	reloading := &ReloadingCatalog{
+		catalog:  catalog,
+		sources:  config.Sources,
+		opts:     opts,
+		onReload: onReload,
+	}
+
+	if config.Interval > 0 {
+		go reloading.reloadLoop(ctx, config.Interval)
+	}
+
+	return reloading, nil
+}
+
+func (c *ReloadingCatalog) reloadLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			_ = c.Reload(ctx)
+		}
+	}
+}
+
+// Reload loads the catalog from its sources. If loading fails, the current catalog is kept.
+func (c *ReloadingCatalog) Reload(ctx context.Context) error {
+	catalog, err := LoadMergedCatalog(ctx, c.opts, c.sources...)
+	if err == nil {
+		c.mutex.Lock()
+		c.catalog = catalog
+		c.mutex.Unlock()
+	}
+
+	c.onReload(err)
+
+	return err
+}
+
+// Dependencies returns the sorted list of the dependencies in the last catalog loaded
+func (c *ReloadingCatalog) Dependencies(ctx context.Context) ([]string, error) {
+	c.mutex.RLock()
+	catalog := c.catalog
+	c.mutex.RUnlock()
+
+	return catalog.Dependencies(ctx)
+}
+
+// Versions returns the sorted list of the versions of a dependency in the last catalog loaded
+func (c *ReloadingCatalog) Versions(ctx context.Context, name string) ([]string, error) {
+	c.mutex.RLock()
+	catalog := c.catalog
+	c.mutex.RUnlock()
+
+	return catalog.Versions(ctx, name)
+}
+
+// Raw returns the content of the last catalog loaded
+func (c *ReloadingCatalog) Raw() ([]byte, error) {
+	c.mutex.RLock()
+	catalog := c.catalog
+	c.mutex.RUnlock()
+
+	return Raw(catalog)
+}
+
+// Resolve returns a Module that satisfies a Dependency using the last catalog loaded
+func (c *ReloadingCatalog) Resolve(ctx context.Context, dep Dependency) (Module, error) {
+	c.mutex.RLock()
+	catalog := c.catalog
+	c.mutex.RUnlock()
+
+	return catalog.Resolve(ctx, dep)
+}
@@ -0,0 +1,52 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Validate checks every dependency in the catalog has a module path and at least one valid version.
+// All the invalid dependencies are reported in the error, not only the first one.
+func Validate(ctx context.Context, c Catalog) error {
+	deps, err := c.Dependencies(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+	}
+
+	problems := []error{}
+	for _, name := range deps {
+		if err := validateDependency(ctx, c, name); err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %d invalid dependencies\n%w", ErrInvalidCatalog, len(problems), errors.Join(problems...))
+	}
+
+	return nil
+}
+
+func validateDependency(ctx context.Context, c Catalog, name string) error {
+	versions, err := c.Versions(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if len(versions) == 0 {
+		return errors.New("no versions")
+	}
+
+	// any version resolves to the dependency's module
+	mod, err := c.Resolve(ctx, Dependency{Name: name, Constrains: versions[0]})
+	if err != nil {
+		return err
+	}
+
+	if mod.Path == "" {
+		return errors.New("empty module path")
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package catalog
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// SignatureSuffix is appended to the catalog location to obtain the location of its signature
+const SignatureSuffix = ".sig"
+
+// Verification defines how a catalog is verified before it is loaded.
+// A zero Verification doesn't verify the catalog.
+type Verification struct {
+	// Expected sha256 checksum of the catalog, hex encoded. Optional
+	SHA256 string
+	// Public key used for verifying the catalog's detached ed25519 signature. Optional
+	PublicKey ed25519.PublicKey
+	// Location of the detached signature, a base64 encoded ed25519 signature of the catalog.
+	// Defaults to the catalog location with the SignatureSuffix.
+	SignatureURL string
+}
+
+// NewVerifiedCatalog returns a catalog loaded from a location after verifying it.
+// Returns ErrUntrustedCatalog if the catalog doesn't match the expected checksum or signature.
+func NewVerifiedCatalog(ctx context.Context, location string, verification Verification) (Catalog, error) {
+	return LoadCatalog(ctx, location, LoadOptions{Verification: verification})
+}
+
+// verify checks the content of the catalog matches the expected checksum and signature
+func (o LoadOptions) verify(ctx context.Context, location string, content []byte) error {
+	v := o.Verification
+	if v.SHA256 != "" {
+		checksum := sha256.Sum256(content)
+		if !strings.EqualFold(hex.EncodeToString(checksum[:]), v.SHA256) {
+			return fmt.Errorf("%w: checksum mismatch for %s", ErrUntrustedCatalog, location)
+		}
+	}
+
+	if v.PublicKey == nil {
+		return nil
+	}
+
+	sigLocation := v.SignatureURL
+	if sigLocation == "" {
+		sigLocation = location + SignatureSuffix
+	}
+
+	encoded, err := o.read(ctx, sigLocation)
+	if err != nil {
+		return fmt.Errorf("%w: reading signature %w", ErrUntrustedCatalog, err)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil {
+		return fmt.Errorf("%w: decoding signature %w", ErrUntrustedCatalog, err)
+	}
+
+	if !ed25519.Verify(v.PublicKey, content, signature) {
+		return fmt.Errorf("%w: invalid signature for %s", ErrUntrustedCatalog, location)
+	}
+
+	return nil
+}
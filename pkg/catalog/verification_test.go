@@ -0,0 +1,136 @@
+package catalog
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifiedCatalog(t *testing.T) {
+	t.Parallel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	otherKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	checksum := sha256.Sum256([]byte(testCatalog))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(testCatalog)))
+
+	dir := t.TempDir()
+	signedCatalog := filepath.Join(dir, "signed.json")
+	unsignedCatalog := filepath.Join(dir, "unsigned.json")
+	tamperedCatalog := filepath.Join(dir, "tampered.json")
+	files := map[string]string{
+		signedCatalog:                     testCatalog,
+		signedCatalog + SignatureSuffix:   signature,
+		unsignedCatalog:                   testCatalog,
+		tamperedCatalog:                   overlayCatalog,
+		tamperedCatalog + SignatureSuffix: signature,
+	}
+	for file, content := range files {
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatalf("test setup: %v", err)
+		}
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/catalog.json":
+			_, _ = w.Write([]byte(testCatalog))
+		case "/signatures/catalog.sig":
+			_, _ = w.Write([]byte(signature))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	testCases := []struct {
+		title        string
+		location     string
+		verification Verification
+		expectErr    error
+	}{
+		{
+			title:    "no verification",
+			location: unsignedCatalog,
+		},
+		{
+			title:        "checksum match",
+			location:     unsignedCatalog,
+			verification: Verification{SHA256: hex.EncodeToString(checksum[:])},
+		},
+		{
+			title:        "checksum mismatch",
+			location:     tamperedCatalog,
+			verification: Verification{SHA256: hex.EncodeToString(checksum[:])},
+			expectErr:    ErrUntrustedCatalog,
+		},
+		{
+			title:        "valid signature",
+			location:     signedCatalog,
+			verification: Verification{PublicKey: publicKey},
+		},
+		{
+			title:        "signature from other key",
+			location:     signedCatalog,
+			verification: Verification{PublicKey: otherKey},
+			expectErr:    ErrUntrustedCatalog,
+		},
+		{
+			title:        "tampered catalog",
+			location:     tamperedCatalog,
+			verification: Verification{PublicKey: publicKey},
+			expectErr:    ErrUntrustedCatalog,
+		},
+		{
+			title:        "missing signature",
+			location:     unsignedCatalog,
+			verification: Verification{PublicKey: publicKey},
+			expectErr:    ErrUntrustedCatalog,
+		},
+		{
+			title:    "signature url",
+			location: srv.URL + "/catalog.json",
+			verification: Verification{
+				PublicKey:    publicKey,
+				SignatureURL: srv.URL + "/signatures/catalog.sig",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			catalog, err := NewVerifiedCatalog(context.TODO(), tc.location, tc.verification)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			_, err = catalog.Resolve(context.TODO(), Dependency{Name: "dep", Constrains: "*"})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+		})
+	}
+}
@@ -4,14 +4,22 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/grafana/k6build"
 	"github.com/grafana/k6build/pkg/api"
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/util"
 )
 
 // ErrInvalidConfiguration signals an error in the configuration
@@ -22,9 +30,34 @@ const (
 
 	buildPath = "build"
 
+	buildBatchPath = "build/batch"
+
 	resolvePath = "resolve"
+
+	planPath = "plan"
+
+	artifactPath = "artifact"
+
+	infoPath = "info"
+
+	sbomPath = "sbom"
+
+	signaturePath = "signature"
+
+	catalogPath = "catalog"
+
+	dependenciesPath = "catalog/dependencies"
+
+	platformsPath = "platforms"
+
+	versionPath = "version"
+
+	versionsPath = "versions"
 )
 
+// AllPlatforms requests building for all the platforms supported by the build service
+const AllPlatforms = "all"
+
 // BuildServiceClientConfig defines the configuration for accessing a remote build service
 type BuildServiceClientConfig struct {
 	// URL to build service
@@ -39,10 +72,16 @@ type BuildServiceClientConfig struct {
 	Headers map[string]string
 	// HTTPClient custom http client
 	HTTPClient *http.Client
+	// VerifyKey public key for verifying the signature of the downloaded artifacts.
+	// If not set, the downloaded artifacts are only verified using their checksum.
+	VerifyKey ed25519.PublicKey
+	// ParallelDownloads number of byte ranges of the artifacts downloaded in parallel.
+	// Defaults to 1 (a single stream)
+	ParallelDownloads int
 }
 
 // NewBuildServiceClient returns a new client for a remote build service
-func NewBuildServiceClient(config BuildServiceClientConfig) (k6build.BuildService, error) {
+func NewBuildServiceClient(config BuildServiceClientConfig) (*BuildClient, error) {
 	if config.URL == "" {
 		return nil, ErrInvalidConfiguration
 	}
@@ -57,11 +96,13 @@ func NewBuildServiceClient(config BuildServiceClientConfig) (k6build.BuildServic
 		client = http.DefaultClient
 	}
 	return &BuildClient{
-		srvURL:   srvURL,
-		auth:     config.Authorization,
-		authType: config.AuthorizationType,
-		headers:  config.Headers,
-		client:   client,
+		srvURL:    srvURL,
+		auth:      config.Authorization,
+		authType:  config.AuthorizationType,
+		headers:   config.Headers,
+		client:    client,
+		verifyKey: config.VerifyKey,
+		parallel:  config.ParallelDownloads,
 	}, nil
 }
 
@@ -72,6 +113,13 @@ type BuildClient struct {
 	auth     string
 	headers  map[string]string
 	client   *http.Client
+	// public key for verifying the signature of the artifacts. Nil if signatures are not verified
+	verifyKey ed25519.PublicKey
+	// number of byte ranges of an artifact downloaded in parallel
+	parallel int
+	// platforms supported by the server, fetched on first use
+	platformsMutex sync.Mutex
+	platforms      []string
 }
 
 // Build request building an artifact to a build service
@@ -79,6 +127,8 @@ type BuildClient struct {
 // In case of error, the returned error is expected to match any of the errors
 // defined in the api package and calling errors.Unwrap(err) will provide
 // the cause, if available.
+// The expiration of the artifact's download URL can be requested using store.WithURLExpiration
+// and the build options using k6build.WithBuildOptions
 func (r *BuildClient) Build(
 	ctx context.Context,
 	platform string,
@@ -89,37 +139,65 @@ func (r *BuildClient) Build(
 		Platform:     platform,
 		K6Constrains: k6Constrains,
 		Dependencies: deps,
+		BuildOptions: k6build.BuildOptionsFromContext(ctx),
+	}
+
+	if expiration, ok := store.URLExpiration(ctx); ok {
+		buildRequest.URLExpiration = expiration.String()
 	}
 
 	buildResponse := api.BuildResponse{}
 
-	err := r.doRequest(ctx, buildPath, &buildRequest, &buildResponse)
+	err := r.doRequest(ctx, http.MethodPost, buildPath, &buildRequest, &buildResponse)
 	if err != nil {
 		return k6build.Artifact{}, err
 	}
 
 	if buildResponse.Error != nil {
-		return k6build.Artifact{}, buildResponse.Error
+		return k6build.Artifact{}, withRequestID(buildResponse.Error, buildResponse.RequestID)
 	}
 
 	return buildResponse.Artifact, nil
 }
 
+// BuildBatch requests building a batch of independent requests in one round trip.
+// The responses are returned in the same order as the requests. The error of a failed request
+// is reported in its response and doesn't affect the others. The returned error is only
+// set if the batch could not be processed.
+func (r *BuildClient) BuildBatch(ctx context.Context, requests []api.BuildRequest) ([]api.BuildResponse, error) {
+	responses := []api.BuildResponse{}
+
+	err := r.doRequest(ctx, http.MethodPost, buildBatchPath, requests, &responses)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(responses) != len(requests) {
+		return nil, k6build.NewWrappedError(
+			api.ErrRequestFailed,
+			fmt.Errorf("expected %d responses got %d", len(requests), len(responses)),
+		)
+	}
+
+	return responses, nil
+}
+
 // Resolve returns the versions that satisfy the given dependencies or an error if they cannot be
-// satisfied
+// satisfied. Prereleases can be included using the build options passed with k6build.WithBuildOptions
 func (r *BuildClient) Resolve(
 	ctx context.Context,
 	k6Constrains string,
 	deps []k6build.Dependency,
 ) (map[string]string, error) {
 	resolveRequest := api.ResolveRequest{
-		K6Constrains: k6Constrains,
-		Dependencies: deps,
+		K6Constrains:       k6Constrains,
+		Dependencies:       deps,
+		IncludePrereleases: k6build.BuildOptionsFromContext(ctx).IncludePrereleases,
 	}
 
 	resolveResponse := api.ResolveResponse{}
 
-	err := r.doRequest(ctx, resolvePath, &resolveRequest, &resolveResponse)
+	err := r.doRequest(ctx, http.MethodPost, resolvePath, &resolveRequest, &resolveResponse)
 	if err != nil {
 		return nil, err
 	}
@@ -131,19 +209,266 @@ func (r *BuildClient) Resolve(
 	return resolveResponse.Dependencies, nil
 }
 
-func (r *BuildClient) doRequest(ctx context.Context, path string, request any, response any) error {
-	marshaled := &bytes.Buffer{}
-	err := json.NewEncoder(marshaled).Encode(request)
+// Plan returns the plan for building an artifact that satisfies the dependencies without building it.
+// The build options can be passed using k6build.WithBuildOptions
+func (r *BuildClient) Plan(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.BuildPlan, error) {
+	planRequest := api.PlanRequest{
+		Platform:     platform,
+		K6Constrains: k6Constrains,
+		Dependencies: deps,
+		BuildOptions: k6build.BuildOptionsFromContext(ctx),
+	}
+
+	planResponse := api.PlanResponse{}
+
+	err := r.doRequest(ctx, http.MethodPost, planPath, &planRequest, &planResponse)
+	if err != nil {
+		return k6build.BuildPlan{}, err
+	}
+
+	if planResponse.Error != nil {
+		return k6build.BuildPlan{}, planResponse.Error
+	}
+
+	return planResponse.Plan, nil
+}
+
+// BuildInfo returns the build info of the artifact with the given id
+func (r *BuildClient) BuildInfo(ctx context.Context, id string) (k6build.BuildInfo, error) {
+	buildInfoResponse := api.BuildInfoResponse{}
+
+	path := artifactPath + "/" + url.PathEscape(id) + "/" + infoPath
+	err := r.doRequest(ctx, http.MethodGet, path, nil, &buildInfoResponse)
+	if err != nil {
+		return k6build.BuildInfo{}, err
+	}
+
+	if buildInfoResponse.Error != nil {
+		return k6build.BuildInfo{}, buildInfoResponse.Error
+	}
+
+	if buildInfoResponse.BuildInfo == nil {
+		return k6build.BuildInfo{}, nil
+	}
+
+	return *buildInfoResponse.BuildInfo, nil
+}
+
+// SBOM returns the SBOM of the artifact with the given id as a CycloneDX JSON document
+func (r *BuildClient) SBOM(ctx context.Context, id string) ([]byte, error) {
+	sbom := json.RawMessage{}
+
+	path := artifactPath + "/" + url.PathEscape(id) + "/" + sbomPath
+	err := r.doRequest(ctx, http.MethodGet, path, nil, &sbom)
+	if err != nil {
+		return nil, err
+	}
+
+	return sbom, nil
+}
+
+// Signature returns the base64 encoded ed25519 signature of the artifact with the given id
+func (r *BuildClient) Signature(ctx context.Context, id string) (string, error) {
+	signatureResponse := api.SignatureResponse{}
+
+	path := artifactPath + "/" + url.PathEscape(id) + "/" + signaturePath
+	err := r.doRequest(ctx, http.MethodGet, path, nil, &signatureResponse)
+	if err != nil {
+		return "", err
+	}
+
+	if signatureResponse.Error != nil {
+		return "", signatureResponse.Error
+	}
+
+	return signatureResponse.Signature, nil
+}
+
+// Download downloads the binary of the artifact to the output file as an executable, reporting the progress
+// of the download to the progress function, if not nil. If the artifact has a checksum, the binary must match it.
+// If the client has a VerifyKey, the binary must match the artifact's signature, obtained from the build service.
+// The output file is not written if the verification fails. If the client is configured for parallel downloads,
+// the binary is downloaded in byte ranges in parallel.
+func (r *BuildClient) Download(
+	ctx context.Context,
+	artifact k6build.Artifact,
+	output string,
+	progress util.ProgressFunc,
+) error {
+	var verifier util.Verifier
+	if r.verifyKey != nil {
+		signature, err := r.Signature(ctx, artifact.ID)
+		if err != nil {
+			return fmt.Errorf("%w: fetching signature %w", util.ErrSignatureMismatch, err)
+		}
+		verifier = util.SignatureVerifier(r.verifyKey, signature)
+	}
+
+	return util.DownloadWithOptions(ctx, artifact.URL, output, util.DownloadOptions{
+		Checksum: artifact.Checksum,
+		Progress: progress,
+		Verifier: verifier,
+		Size:     artifact.Size,
+		Parallel: r.parallel,
+	})
+}
+
+// FetchCatalog returns the catalog used by the build service for resolving dependencies, as a JSON document
+func (r *BuildClient) FetchCatalog(ctx context.Context) ([]byte, error) {
+	catalog := json.RawMessage{}
+
+	err := r.doRequest(ctx, http.MethodGet, catalogPath, nil, &catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Dependencies returns the sorted list of the dependencies supported by the build service
+func (r *BuildClient) Dependencies(ctx context.Context) ([]string, error) {
+	dependenciesResponse := api.DependenciesResponse{}
+
+	err := r.doRequest(ctx, http.MethodGet, dependenciesPath, nil, &dependenciesResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if dependenciesResponse.Error != nil {
+		return nil, dependenciesResponse.Error
+	}
+
+	return dependenciesResponse.Dependencies, nil
+}
+
+// Platforms returns the list of platforms supported by the build service.
+// The list is fetched once and reused in subsequent calls.
+func (r *BuildClient) Platforms(ctx context.Context) ([]string, error) {
+	r.platformsMutex.Lock()
+	defer r.platformsMutex.Unlock()
+
+	if r.platforms != nil {
+		return r.platforms, nil
+	}
+
+	platformsResponse := api.PlatformsResponse{}
+
+	err := r.doRequest(ctx, http.MethodGet, platformsPath, nil, &platformsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	if platformsResponse.Error != nil {
+		return nil, platformsResponse.Error
+	}
+
+	r.platforms = platformsResponse.Platforms
+
+	return r.platforms, nil
+}
+
+// Version returns the version information of the build server
+func (r *BuildClient) Version(ctx context.Context) (api.VersionResponse, error) {
+	versionResponse := api.VersionResponse{}
+
+	err := r.doRequest(ctx, http.MethodGet, versionPath, nil, &versionResponse)
+	if err != nil {
+		return api.VersionResponse{}, err
+	}
+
+	return versionResponse, nil
+}
+
+// ExpandPlatform returns the platforms requested by the platform argument, a comma-separated list of platforms.
+// An empty platform requests the host's platform. AllPlatforms requests the platforms supported by the build
+// service or api.DefaultPlatforms if the build service cannot list them.
+func ExpandPlatform(ctx context.Context, srv k6build.BuildService, platform string) []string {
+	return ExpandPlatforms(ctx, srv, platform, api.DefaultPlatforms)
+}
+
+// ExpandPlatforms is like ExpandPlatform but AllPlatforms falls back to the given platforms if the build service
+// cannot list its platforms. Duplicated platforms are requested once.
+func ExpandPlatforms(ctx context.Context, srv k6build.BuildService, platform string, all []string) []string {
+	if strings.TrimSpace(platform) == "" {
+		return []string{runtime.GOOS + "/" + runtime.GOARCH}
+	}
+
+	platforms := []string{}
+	for _, p := range strings.Split(platform, ",") {
+		p = strings.TrimSpace(p)
+		expanded := []string{p}
+		if p == AllPlatforms {
+			expanded = listPlatforms(ctx, srv, all)
+		}
+
+		for _, e := range expanded {
+			if e != "" && !slices.Contains(platforms, e) {
+				platforms = append(platforms, e)
+			}
+		}
+	}
+
+	return platforms
+}
+
+// listPlatforms returns the platforms supported by the build service or the default if it cannot list them
+func listPlatforms(ctx context.Context, srv k6build.BuildService, defaults []string) []string {
+	lister, ok := srv.(k6build.PlatformLister)
+	if !ok {
+		return defaults
+	}
+
+	platforms, err := lister.Platforms(ctx)
+	if err != nil || len(platforms) == 0 {
+		return defaults
+	}
+
+	return platforms
+}
+
+// Versions returns the sorted list of the versions of a dependency supported by the build service
+func (r *BuildClient) Versions(ctx context.Context, name string) ([]string, error) {
+	versionsResponse := api.VersionsResponse{}
+
+	// the name is escaped as it contains "/" (e.g. k6/x/kubernetes)
+	path := dependenciesPath + "/" + url.PathEscape(name) + "/" + versionsPath
+	err := r.doRequest(ctx, http.MethodGet, path, nil, &versionsResponse)
 	if err != nil {
-		return k6build.NewWrappedError(api.ErrInvalidRequest, err)
+		return nil, err
+	}
+
+	if versionsResponse.Error != nil {
+		return nil, versionsResponse.Error
+	}
+
+	return versionsResponse.Versions, nil
+}
+
+// doRequest sends a request to the build service. If request is nil, the request has no body
+func (r *BuildClient) doRequest(ctx context.Context, method string, path string, request any, response any) error {
+	var body io.Reader
+	if request != nil {
+		marshaled := &bytes.Buffer{}
+		err := json.NewEncoder(marshaled).Encode(request)
+		if err != nil {
+			return k6build.NewWrappedError(api.ErrInvalidRequest, err)
+		}
+		body = marshaled
 	}
 
 	reqURL := r.srvURL.JoinPath(path)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL.String(), marshaled)
+	req, err := http.NewRequestWithContext(ctx, method, reqURL.String(), body)
 	if err != nil {
 		return k6build.NewWrappedError(api.ErrRequestFailed, err)
 	}
-	req.Header.Add("Content-Type", "application/json")
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
 
 	// add authorization header "Authorization: <type> <auth>"
 	if r.auth != "" {
@@ -168,7 +493,16 @@ func (r *BuildClient) doRequest(ctx context.Context, path string, request any, r
 	}()
 
 	if resp.StatusCode != http.StatusOK {
-		return k6build.NewWrappedError(api.ErrRequestFailed, errors.New(resp.Status))
+		requestID := resp.Header.Get(api.RequestIDHeader)
+
+		// use the error reported by the server, if any, as reason
+		errResponse := struct {
+			Error *k6build.WrappedError `json:"error,omitempty"`
+		}{}
+		if json.NewDecoder(resp.Body).Decode(&errResponse) == nil && errResponse.Error != nil {
+			return k6build.NewWrappedError(statusError(resp.StatusCode), withRequestID(errResponse.Error, requestID))
+		}
+		return k6build.NewWrappedError(statusError(resp.StatusCode), withRequestID(errors.New(resp.Status), requestID))
 	}
 
 	err = json.NewDecoder(resp.Body).Decode(&response)
@@ -178,3 +512,28 @@ func (r *BuildClient) doRequest(ctx context.Context, path string, request any, r
 
 	return nil
 }
+
+// withRequestID adds the id of the request, if known, to the error, for correlating it with the server's logs
+func withRequestID(err error, requestID string) error {
+	if requestID == "" {
+		return err
+	}
+
+	return fmt.Errorf("%w (request id: %s)", err, requestID)
+}
+
+// statusError returns the error that corresponds to the status of a failed request
+func statusError(status int) error {
+	switch status {
+	case http.StatusBadRequest:
+		return api.ErrInvalidRequest
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return api.ErrUnauthorized
+	case http.StatusUnprocessableEntity:
+		return api.ErrCannotSatisfy
+	case http.StatusTooManyRequests:
+		return api.ErrTooManyBuilds
+	default:
+		return api.ErrRequestFailed
+	}
+}
@@ -3,15 +3,26 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/grafana/k6build"
 	"github.com/grafana/k6build/pkg/api"
+	"github.com/grafana/k6build/pkg/util"
 )
 
 // process request and return a boolean indicating if request should be passed to the next handler in the chain
@@ -105,12 +116,13 @@ func TestBuild(t *testing.T) {
 	t.Parallel()
 
 	testCases := []struct {
-		title     string
-		headers   map[string]string
-		auth      string
-		authType  string
-		handler   http.HandlerFunc
-		expectErr error
+		title      string
+		headers    map[string]string
+		auth       string
+		authType   string
+		handler    http.HandlerFunc
+		expectErr  error
+		expectCode k6build.ErrorCode
 	}{
 		{
 			title: "normal build",
@@ -127,6 +139,20 @@ func TestBuild(t *testing.T) {
 			),
 			expectErr: api.ErrBuildFailed,
 		},
+		{
+			title: "build request failed with code",
+			handler: handlerChain(
+				validateBuildRequest(),
+				response(http.StatusUnprocessableEntity, api.BuildResponse{
+					Error: k6build.NewWrappedError(
+						api.ErrBuildFailed,
+						k6build.NewCodedError(k6build.ErrorCodeCannotSatisfy, api.ErrCannotSatisfy, nil),
+					),
+				}),
+			),
+			expectErr:  api.ErrCannotSatisfy,
+			expectCode: k6build.ErrorCodeCannotSatisfy,
+		},
 		{
 			title:    "auth header",
 			auth:     "token",
@@ -155,6 +181,33 @@ func TestBuild(t *testing.T) {
 				validateBuildRequest(),
 				response(http.StatusUnauthorized, api.BuildResponse{Error: k6build.NewWrappedError(api.ErrRequestFailed, errors.New("unauthorized"))}),
 			),
+			expectErr: api.ErrUnauthorized,
+		},
+		{
+			title: "failed auth without error",
+			handler: handlerChain(
+				validateBuildRequest(),
+				response(http.StatusForbidden, nil),
+			),
+			expectErr: api.ErrUnauthorized,
+		},
+		{
+			title: "too many builds",
+			handler: handlerChain(
+				validateBuildRequest(),
+				response(http.StatusTooManyRequests, api.BuildResponse{
+					Error: k6build.NewCodedError(k6build.ErrorCodeTooManyBuilds, api.ErrTooManyBuilds, nil),
+				}),
+			),
+			expectErr:  api.ErrTooManyBuilds,
+			expectCode: k6build.ErrorCodeTooManyBuilds,
+		},
+		{
+			title: "server error",
+			handler: handlerChain(
+				validateBuildRequest(),
+				response(http.StatusBadGateway, nil),
+			),
 			expectErr: api.ErrRequestFailed,
 		},
 		{
@@ -202,6 +255,10 @@ func TestBuild(t *testing.T) {
 			if !errors.Is(err, tc.expectErr) {
 				t.Fatalf("expected %v got %v", tc.expectErr, err)
 			}
+
+			if code := k6build.ErrorCodeOf(err); code != tc.expectCode {
+				t.Fatalf("expected code %q got %q", tc.expectCode, code)
+			}
 		})
 	}
 }
@@ -267,3 +324,606 @@ func TestResolce(t *testing.T) {
 		})
 	}
 }
+
+func TestVersions(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		status    int
+		response  api.VersionsResponse
+		expect    []string
+		expectErr error
+	}{
+		{
+			title:    "list versions",
+			status:   http.StatusOK,
+			response: api.VersionsResponse{Name: "k6/x/test", Versions: []string{"v0.1.0", "v0.2.0"}},
+			expect:   []string{"v0.1.0", "v0.2.0"},
+		},
+		{
+			title:  "unknown dependency",
+			status: http.StatusNotFound,
+			response: api.VersionsResponse{
+				Error: k6build.NewWrappedError(api.ErrUnknownDependency, errors.New("k6/x/test")),
+			},
+			expectErr: api.ErrUnknownDependency,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /catalog/dependencies/{name}/versions", func(w http.ResponseWriter, r *http.Request) {
+				if r.PathValue("name") != "k6/x/test" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				response(tc.status, tc.response)(w, r)
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			client, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			var lister k6build.DependencyLister = client
+
+			versions, err := lister.Versions(context.TODO(), "k6/x/test")
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && !slices.Equal(versions, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, versions)
+			}
+		})
+	}
+}
+
+func TestBuildInfo(t *testing.T) {
+	t.Parallel()
+
+	info := k6build.BuildInfo{GoVersion: "go1.24.0", Modules: map[string]string{"go.k6.io/k6": "v1.0.0"}}
+
+	testCases := []struct {
+		title     string
+		status    int
+		response  api.BuildInfoResponse
+		expect    k6build.BuildInfo
+		expectErr error
+	}{
+		{
+			title:    "get build info",
+			status:   http.StatusOK,
+			response: api.BuildInfoResponse{ID: "artifact", BuildInfo: &info},
+			expect:   info,
+		},
+		{
+			title:  "unknown artifact",
+			status: http.StatusNotFound,
+			response: api.BuildInfoResponse{
+				Error: k6build.NewWrappedError(api.ErrUnknownArtifact, errors.New("artifact")),
+			},
+			expectErr: api.ErrUnknownArtifact,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /artifact/{id}/info", func(w http.ResponseWriter, r *http.Request) {
+				if r.PathValue("id") != "artifact" {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				response(tc.status, tc.response)(w, r)
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			client, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			var provider k6build.BuildInfoProvider = client
+
+			buildInfo, err := provider.BuildInfo(context.TODO(), "artifact")
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && !reflect.DeepEqual(buildInfo, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, buildInfo)
+			}
+		})
+	}
+}
+
+func TestSBOM(t *testing.T) {
+	t.Parallel()
+
+	sbom := []byte(`{"bomFormat":"CycloneDX","specVersion":"1.5"}`)
+
+	testCases := []struct {
+		title     string
+		status    int
+		response  any
+		expectErr error
+	}{
+		{
+			title:    "get sbom",
+			status:   http.StatusOK,
+			response: json.RawMessage(sbom),
+		},
+		{
+			title:  "sbom not available",
+			status: http.StatusNotFound,
+			response: api.BuildInfoResponse{
+				Error: k6build.NewWrappedError(api.ErrRequestFailed, k6build.ErrSBOMNotAvailable),
+			},
+			expectErr: k6build.ErrSBOMNotAvailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /artifact/{id}/sbom", func(w http.ResponseWriter, r *http.Request) {
+				response(tc.status, tc.response)(w, r)
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			client, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			var provider k6build.SBOMProvider = client
+
+			content, err := provider.SBOM(context.TODO(), "artifact")
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && !bytes.Equal(content, sbom) {
+				t.Fatalf("expected %s got %s", sbom, content)
+			}
+		})
+	}
+}
+
+func TestSignature(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		status    int
+		response  api.SignatureResponse
+		expect    string
+		expectErr error
+	}{
+		{
+			title:    "get signature",
+			status:   http.StatusOK,
+			response: api.SignatureResponse{ID: "artifact", Signature: "c2lnbmF0dXJl"},
+			expect:   "c2lnbmF0dXJl",
+		},
+		{
+			title:  "artifact not signed",
+			status: http.StatusNotFound,
+			response: api.SignatureResponse{
+				Error: k6build.NewWrappedError(api.ErrRequestFailed, k6build.ErrArtifactNotSigned),
+			},
+			expectErr: k6build.ErrArtifactNotSigned,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /artifact/{id}/signature", func(w http.ResponseWriter, r *http.Request) {
+				response(tc.status, tc.response)(w, r)
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			client, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			var provider k6build.SignatureProvider = client
+
+			signature, err := provider.Signature(context.TODO(), "artifact")
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if signature != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, signature)
+			}
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	t.Parallel()
+
+	binary := []byte("k6 binary")
+	checksum := fmt.Sprintf("sha256:%x", sha256.Sum256(binary))
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key %v", err)
+	}
+	otherKey, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key %v", err)
+	}
+	// the signature is over the artifact's checksum
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(checksum)))
+
+	testCases := []struct {
+		title     string
+		verifyKey ed25519.PublicKey
+		checksum  string
+		signature string
+		expectErr error
+	}{
+		{
+			title:    "checksum only",
+			checksum: checksum,
+		},
+		{
+			title:     "checksum mismatch",
+			checksum:  "sha256:0000",
+			expectErr: util.ErrChecksumMismatch,
+		},
+		{
+			title:     "valid signature",
+			verifyKey: publicKey,
+			checksum:  checksum,
+			signature: signature,
+		},
+		{
+			title:     "signature mismatch",
+			verifyKey: otherKey,
+			checksum:  checksum,
+			signature: signature,
+			expectErr: util.ErrSignatureMismatch,
+		},
+		{
+			title:     "artifact not signed",
+			verifyKey: publicKey,
+			checksum:  checksum,
+			expectErr: k6build.ErrArtifactNotSigned,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /binary", func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write(binary)
+			})
+			mux.HandleFunc("GET /artifact/{id}/signature", func(w http.ResponseWriter, r *http.Request) {
+				if tc.signature == "" {
+					err := k6build.NewWrappedError(api.ErrRequestFailed, k6build.ErrArtifactNotSigned)
+					response(http.StatusNotFound, api.SignatureResponse{Error: err})(w, r)
+					return
+				}
+				response(http.StatusOK, api.SignatureResponse{Signature: tc.signature})(w, r)
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			client, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL, VerifyKey: tc.verifyKey})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			artifact := k6build.Artifact{ID: "artifact", URL: srv.URL + "/binary", Checksum: tc.checksum}
+			output := filepath.Join(t.TempDir(), "k6")
+
+			err = client.Download(context.TODO(), artifact, output, nil)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			// the binary is only written if verified
+			_, err = os.Stat(output)
+			if written := err == nil; written != (tc.expectErr == nil) {
+				t.Fatalf("expected written %t got %t", tc.expectErr == nil, written)
+			}
+		})
+	}
+}
+
+func TestFetchCatalog(t *testing.T) {
+	t.Parallel()
+
+	raw := json.RawMessage(`{"k6":{"module":"go.k6.io/k6","versions":["v1.0.0"]}}`)
+
+	testCases := []struct {
+		title     string
+		status    int
+		response  any
+		expectErr error
+	}{
+		{
+			title:    "fetch catalog",
+			status:   http.StatusOK,
+			response: raw,
+		},
+		{
+			title:  "catalog not supported",
+			status: http.StatusNotImplemented,
+			response: api.DependenciesResponse{
+				Error: k6build.NewWrappedError(api.ErrRequestFailed, errors.New("not supported")),
+			},
+			expectErr: api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /catalog", func(w http.ResponseWriter, r *http.Request) {
+				response(tc.status, tc.response)(w, r)
+			})
+
+			srv := httptest.NewServer(mux)
+			defer srv.Close()
+
+			buildClient, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			catalog, err := buildClient.FetchCatalog(context.TODO())
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && !bytes.Equal(catalog, raw) {
+				t.Fatalf("expected %s got %s", raw, catalog)
+			}
+		})
+	}
+}
+
+func TestExpandPlatform(t *testing.T) {
+	t.Parallel()
+
+	serverPlatforms := []string{"linux/amd64", "linux/arm64"}
+
+	testCases := []struct {
+		title    string
+		platform string
+		all      []string
+		status   int
+		expect   []string
+	}{
+		{
+			title:    "single platform",
+			platform: "linux/amd64",
+			status:   http.StatusOK,
+			expect:   []string{"linux/amd64"},
+		},
+		{
+			title:    "platforms from server",
+			platform: AllPlatforms,
+			status:   http.StatusOK,
+			expect:   serverPlatforms,
+		},
+		{
+			title:    "fallback to default platforms",
+			platform: AllPlatforms,
+			status:   http.StatusNotFound,
+			expect:   api.DefaultPlatforms,
+		},
+		{
+			title:    "fallback to given platforms",
+			platform: AllPlatforms,
+			all:      []string{"linux/amd64"},
+			status:   http.StatusNotFound,
+			expect:   []string{"linux/amd64"},
+		},
+		{
+			title:    "host platform",
+			platform: "",
+			status:   http.StatusOK,
+			expect:   []string{runtime.GOOS + "/" + runtime.GOARCH},
+		},
+		{
+			title:    "list of platforms",
+			platform: "windows/amd64, all,linux/arm64",
+			status:   http.StatusOK,
+			expect:   []string{"windows/amd64", "linux/amd64", "linux/arm64"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			requests := atomic.Int32{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if r.URL.Path != "/platforms" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				response(tc.status, api.PlatformsResponse{Platforms: serverPlatforms})(w, r)
+			}))
+			defer srv.Close()
+
+			client, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			platforms := ExpandPlatform(context.TODO(), client, tc.platform)
+			if tc.all != nil {
+				platforms = ExpandPlatforms(context.TODO(), client, tc.platform, tc.all)
+			}
+			if !slices.Equal(platforms, tc.expect) {
+				t.Fatalf("expected %v got %v", tc.expect, platforms)
+			}
+
+			// the platforms are fetched once
+			_ = ExpandPlatform(context.TODO(), client, tc.platform)
+			if tc.platform == AllPlatforms && tc.status == http.StatusOK && requests.Load() != 1 {
+				t.Fatalf("expected platforms fetched once got %d requests", requests.Load())
+			}
+		})
+	}
+}
+
+func TestServerVersion(t *testing.T) {
+	t.Parallel()
+
+	version := api.VersionResponse{Version: "v0.1.0", Commit: "0123456789"}
+
+	srv := httptest.NewServer(handlerChain(response(http.StatusOK, version)))
+	defer srv.Close()
+
+	buildClient, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	got, err := buildClient.Version(context.TODO())
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if got != version {
+		t.Fatalf("expected %v got %v", version, got)
+	}
+}
+
+func TestBuildBatch(t *testing.T) {
+	t.Parallel()
+
+	requests := []api.BuildRequest{
+		{Platform: "linux/amd64", K6Constrains: "v0.1.0"},
+		{Platform: "linux/arm64", K6Constrains: "v0.1.0"},
+	}
+
+	testCases := []struct {
+		title     string
+		handlers  []requestHandler
+		expectErr error
+	}{
+		{
+			title: "batch processed",
+			handlers: []requestHandler{
+				response(http.StatusOK, []api.BuildResponse{
+					{Artifact: k6build.Artifact{Platform: "linux/amd64"}},
+					{Error: k6build.NewWrappedError(api.ErrBuildFailed, errors.New("build failed"))},
+				}),
+			},
+		},
+		{
+			title: "invalid batch",
+			handlers: []requestHandler{
+				response(http.StatusBadRequest, api.BuildResponse{
+					Error: k6build.NewWrappedError(api.ErrInvalidRequest, errors.New("batch too large")),
+				}),
+			},
+			expectErr: api.ErrInvalidRequest,
+		},
+		{
+			title: "missing responses",
+			handlers: []requestHandler{
+				response(http.StatusOK, []api.BuildResponse{
+					{Artifact: k6build.Artifact{Platform: "linux/amd64"}},
+				}),
+			},
+			expectErr: api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(handlerChain(tc.handlers...))
+			defer srv.Close()
+
+			buildClient, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			responses, err := buildClient.BuildBatch(context.TODO(), requests)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			if responses[0].Error != nil || responses[0].Artifact.Platform != "linux/amd64" {
+				t.Fatalf("unexpected first response %v", responses[0])
+			}
+
+			if !errors.Is(responses[1].Error, api.ErrBuildFailed) {
+				t.Fatalf("expected %v got %v", api.ErrBuildFailed, responses[1].Error)
+			}
+		})
+	}
+}
+
+func TestRequestIDInErrors(t *testing.T) {
+	t.Parallel()
+
+	const requestID = "0123abcd"
+
+	setID := func(w http.ResponseWriter, _ *http.Request) bool {
+		w.Header().Set(api.RequestIDHeader, requestID)
+		return true
+	}
+	resp := api.BuildResponse{Error: k6build.NewWrappedError(api.ErrCannotSatisfy, errors.New("unknown"))}
+
+	srv := httptest.NewServer(handlerChain(setID, response(http.StatusUnprocessableEntity, resp)))
+	defer srv.Close()
+
+	client, err := NewBuildServiceClient(BuildServiceClientConfig{URL: srv.URL})
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	_, err = client.Build(context.TODO(), "linux/amd64", "v0.1.0", nil)
+	if !errors.Is(err, api.ErrCannotSatisfy) {
+		t.Fatalf("expected %v got %v", api.ErrCannotSatisfy, err)
+	}
+
+	if !strings.Contains(err.Error(), requestID) {
+		t.Fatalf("expected request id in error %q", err.Error())
+	}
+}
@@ -0,0 +1,66 @@
+package httpserver
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the response header with the id of the request, if any, included in the access log
+const RequestIDHeader = "X-Request-ID"
+
+// responseRecorder records the status and the size of the response
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(content []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(content)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap returns the original ResponseWriter, so http.ResponseController can access its features
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// accessLog logs each request served by the handler
+func accessLog(log *slog.Logger, handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &responseRecorder{ResponseWriter: w}
+
+		handler.ServeHTTP(recorder, r)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		attrs := []any{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"duration", time.Since(start).String(),
+			"bytes", recorder.bytes,
+		}
+		if id := w.Header().Get(RequestIDHeader); id != "" {
+			attrs = append(attrs, "request_id", id)
+		}
+
+		log.Info("request", attrs...)
+	})
+}
@@ -0,0 +1,66 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		handler   http.HandlerFunc
+		requestID string
+		expect    map[string]any
+	}{
+		{
+			title: "implicit status",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("content"))
+			},
+			expect: map[string]any{"method": "GET", "path": "/path", "status": float64(200), "bytes": float64(7)},
+		},
+		{
+			title: "explicit status",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			expect: map[string]any{"status": float64(404), "bytes": float64(0)},
+		},
+		{
+			title: "request id",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set(RequestIDHeader, "id")
+			},
+			expect: map[string]any{"status": float64(200), "request_id": "id"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			out := &bytes.Buffer{}
+			log := slog.New(slog.NewJSONHandler(out, nil))
+
+			req := httptest.NewRequest(http.MethodGet, "/path", nil)
+			accessLog(log, tc.handler).ServeHTTP(httptest.NewRecorder(), req)
+
+			record := map[string]any{}
+			if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+				t.Fatalf("unmarshalling log record %v", err)
+			}
+
+			for attr, value := range tc.expect {
+				if record[attr] != value {
+					t.Fatalf("%s: expected %v got %v", attr, value, record[attr])
+				}
+			}
+		})
+	}
+}
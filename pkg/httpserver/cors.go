@@ -0,0 +1,85 @@
+package httpserver
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultCORSMaxAge is the time the browsers can cache the response to a preflight request
+const DefaultCORSMaxAge = 10 * time.Minute
+
+var (
+	// DefaultCORSMethods are the methods allowed in cross-origin requests if none are configured
+	DefaultCORSMethods = []string{http.MethodGet, http.MethodPost}
+	// DefaultCORSHeaders are the headers allowed in cross-origin requests if none are configured
+	DefaultCORSHeaders = []string{"Authorization", "Content-Type", RequestIDHeader}
+)
+
+// CORSConfig defines the cross-origin requests allowed by a server
+type CORSConfig struct {
+	// Origins allowed to make requests (e.g. https://ui.example.com). "*" allows any origin.
+	// If empty, cross-origin requests are not allowed.
+	AllowedOrigins []string
+	// Methods allowed in the requests. Defaults to DefaultCORSMethods
+	AllowedMethods []string
+	// Headers allowed in the requests. Defaults to DefaultCORSHeaders
+	AllowedHeaders []string
+}
+
+// Enabled returns true if cross-origin requests are allowed
+func (c CORSConfig) Enabled() bool {
+	return len(c.AllowedOrigins) > 0
+}
+
+// CORS returns a handler that allows the cross-origin requests defined in the config and answers
+// the preflight requests. If no origins are allowed, returns the handler unchanged.
+func CORS(config CORSConfig, handler http.Handler) http.Handler {
+	if !config.Enabled() {
+		return handler
+	}
+
+	methods := config.AllowedMethods
+	if len(methods) == 0 {
+		methods = DefaultCORSMethods
+	}
+
+	headers := config.AllowedHeaders
+	if len(headers) == 0 {
+		headers = DefaultCORSHeaders
+	}
+
+	anyOrigin := slices.Contains(config.AllowedOrigins, "*")
+	allowedMethods := strings.Join(methods, ", ")
+	allowedHeaders := strings.Join(headers, ", ")
+	maxAge := strconv.Itoa(int(DefaultCORSMaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" || (!anyOrigin && !slices.Contains(config.AllowedOrigins, origin)) {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if anyOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Expose-Headers", RequestIDHeader)
+
+		// preflight request
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+			w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+			w.Header().Set("Access-Control-Max-Age", maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		handler.ServeHTTP(w, r)
+	})
+}
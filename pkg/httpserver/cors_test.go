@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		config        CORSConfig
+		method        string
+		origin        string
+		preflight     bool
+		expectStatus  int
+		expectOrigin  string
+		expectMethods string
+	}{
+		{
+			title:        "cors disabled",
+			method:       http.MethodGet,
+			origin:       "https://ui.example.com",
+			expectStatus: http.StatusOK,
+		},
+		{
+			title:        "allowed origin",
+			config:       CORSConfig{AllowedOrigins: []string{"https://ui.example.com"}},
+			method:       http.MethodGet,
+			origin:       "https://ui.example.com",
+			expectStatus: http.StatusOK,
+			expectOrigin: "https://ui.example.com",
+		},
+		{
+			title:        "origin not allowed",
+			config:       CORSConfig{AllowedOrigins: []string{"https://ui.example.com"}},
+			method:       http.MethodGet,
+			origin:       "https://other.example.com",
+			expectStatus: http.StatusOK,
+		},
+		{
+			title:        "any origin",
+			config:       CORSConfig{AllowedOrigins: []string{"*"}},
+			method:       http.MethodGet,
+			origin:       "https://ui.example.com",
+			expectStatus: http.StatusOK,
+			expectOrigin: "*",
+		},
+		{
+			title:         "preflight",
+			config:        CORSConfig{AllowedOrigins: []string{"https://ui.example.com"}},
+			method:        http.MethodOptions,
+			origin:        "https://ui.example.com",
+			preflight:     true,
+			expectStatus:  http.StatusNoContent,
+			expectOrigin:  "https://ui.example.com",
+			expectMethods: "GET, POST",
+		},
+		{
+			title: "preflight with methods",
+			config: CORSConfig{
+				AllowedOrigins: []string{"https://ui.example.com"},
+				AllowedMethods: []string{http.MethodPost},
+			},
+			method:        http.MethodOptions,
+			origin:        "https://ui.example.com",
+			preflight:     true,
+			expectStatus:  http.StatusNoContent,
+			expectOrigin:  "https://ui.example.com",
+			expectMethods: "POST",
+		},
+		{
+			title:        "preflight origin not allowed",
+			config:       CORSConfig{AllowedOrigins: []string{"https://ui.example.com"}},
+			method:       http.MethodOptions,
+			origin:       "https://other.example.com",
+			preflight:    true,
+			expectStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("GET /build", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tc.method, "/build", nil)
+			req.Header.Set("Origin", tc.origin)
+			if tc.preflight {
+				req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			}
+
+			resp := httptest.NewRecorder()
+			CORS(tc.config, mux).ServeHTTP(resp, req)
+
+			if resp.Code != tc.expectStatus {
+				t.Fatalf("expected status %d got %d", tc.expectStatus, resp.Code)
+			}
+
+			if origin := resp.Header().Get("Access-Control-Allow-Origin"); origin != tc.expectOrigin {
+				t.Fatalf("expected origin %q got %q", tc.expectOrigin, origin)
+			}
+
+			if methods := resp.Header().Get("Access-Control-Allow-Methods"); methods != tc.expectMethods {
+				t.Fatalf("expected methods %q got %q", tc.expectMethods, methods)
+			}
+		})
+	}
+}
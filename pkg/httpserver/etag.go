@@ -0,0 +1,17 @@
+package httpserver
+
+import "strings"
+
+// ETagMatches returns true if any of the ETags in the If-None-Match header matches the etag.
+// As required for If-None-Match, ETags are compared ignoring the weak indicator.
+func ETagMatches(ifNoneMatch string, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,64 @@
+package httpserver
+
+import "testing"
+
+func TestETagMatches(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		ifNoneMatch string
+		etag        string
+		expect      bool
+	}{
+		{
+			title:       "strong match",
+			ifNoneMatch: `"abc"`,
+			etag:        `"abc"`,
+			expect:      true,
+		},
+		{
+			title:       "weak request matches strong etag",
+			ifNoneMatch: `W/"abc"`,
+			etag:        `"abc"`,
+			expect:      true,
+		},
+		{
+			title:       "strong request matches weak etag",
+			ifNoneMatch: `"abc"`,
+			etag:        `W/"abc"`,
+			expect:      true,
+		},
+		{
+			title:       "any of a list",
+			ifNoneMatch: `"other", "abc"`,
+			etag:        `"abc"`,
+			expect:      true,
+		},
+		{
+			title:       "wildcard",
+			ifNoneMatch: `*`,
+			etag:        `"abc"`,
+			expect:      true,
+		},
+		{
+			title:       "no match",
+			ifNoneMatch: `"other"`,
+			etag:        `"abc"`,
+		},
+		{
+			title: "no header",
+			etag:  `"abc"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if matches := ETagMatches(tc.ifNoneMatch, tc.etag); matches != tc.expect {
+				t.Fatalf("expected %t got %t", tc.expect, matches)
+			}
+		})
+	}
+}
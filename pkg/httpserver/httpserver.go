@@ -3,6 +3,7 @@ package httpserver
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -22,6 +23,9 @@ const (
 	DefaultReadHeaderTimeout = 5 * time.Second
 )
 
+// DefaultReadinessProbePath is the path for the readiness probe handler
+const DefaultReadinessProbePath = "/readyz"
+
 // ServerConfig holds the configuration for the http server
 type ServerConfig struct {
 	// Logger is the logger used by the server
@@ -34,9 +38,14 @@ type ServerConfig struct {
 	LivenessProbe bool
 	// LivenessProbePath is the path for the liveness probe handler. Default is DefaultLivenessProbePath
 	LivenessProbePath string
+	// ReadinessCheck enables the readiness probe handler at DefaultReadinessProbePath.
+	// The server is ready if the check doesn't fail (e.g. its backends are reachable).
+	ReadinessCheck func(ctx context.Context) error
 	// ReadHeaderTimeout is the maximum duration before timing out read of the request headers.
 	// Defaults to DefaultReadHeaderTimeout
 	ReadHeaderTimeout time.Duration
+	// AccessLog enables logging the method, path, status, duration and size of the response of each request
+	AccessLog bool
 }
 
 // Server is a http server that implements common requirements such as liveness probe, exposing metrics and
@@ -47,6 +56,7 @@ type Server struct {
 	port              int
 	readHeaderTimeout time.Duration
 	shutdownTimeout   time.Duration
+	accessLog         bool
 }
 
 // livenessHandler is a simple handler that returns a 200 status code.
@@ -54,8 +64,38 @@ func livenessHandler(w http.ResponseWriter, _ *http.Request) {
 	w.WriteHeader(http.StatusOK)
 }
 
+// readinessStatus is the response of the readiness probe handler
+type readinessStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// readinessHandler returns a handler that reports the result of the readiness check.
+// Returns 200 if the check succeeds and 503 with the error otherwise.
+func readinessHandler(log *slog.Logger, check func(ctx context.Context) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		err := check(r.Context())
+		if err != nil {
+			log.Warn("readiness check failed", "error", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(readinessStatus{Status: "unavailable", Error: err.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_ = json.NewEncoder(w).Encode(readinessStatus{Status: "ready"})
+	}
+}
+
 // NewServer creates a new http server with the given configuration.
 func NewServer(config ServerConfig) *Server {
+	log := config.Logger
+	if log == nil {
+		log = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	}
+
 	srv := http.NewServeMux()
 	if config.EnableMetrics {
 		srv.Handle("/metrics", promhttp.Handler())
@@ -69,22 +109,22 @@ func NewServer(config ServerConfig) *Server {
 		srv.HandleFunc(livenessProbePath, livenessHandler)
 	}
 
+	if config.ReadinessCheck != nil {
+		srv.HandleFunc(DefaultReadinessProbePath, readinessHandler(log, config.ReadinessCheck))
+	}
+
 	readHeaderTimeout := config.ReadHeaderTimeout
 	if readHeaderTimeout == 0 {
 		readHeaderTimeout = DefaultReadHeaderTimeout
 	}
 
-	log := config.Logger
-	if log == nil {
-		log = slog.New(slog.NewJSONHandler(io.Discard, nil))
-	}
-
 	return &Server{
 		log:               log,
 		port:              config.Port,
 		srv:               srv,
 		readHeaderTimeout: readHeaderTimeout,
 		shutdownTimeout:   5 * time.Second,
+		accessLog:         config.AccessLog,
 	}
 }
 
@@ -98,9 +138,14 @@ func (s *Server) Handle(pattern string, handler http.Handler) {
 func (s *Server) Start(ctx context.Context) error {
 	serverErrors := make(chan error, 1)
 
+	var handler http.Handler = s.srv
+	if s.accessLog {
+		handler = accessLog(s.log, handler)
+	}
+
 	srv := http.Server{
 		Addr:              fmt.Sprintf(":%d", s.port),
-		Handler:           s.srv,
+		Handler:           handler,
 		ReadHeaderTimeout: s.readHeaderTimeout,
 	}
 
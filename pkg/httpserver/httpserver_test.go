@@ -0,0 +1,61 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadinessHandler(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		check        func(ctx context.Context) error
+		expectStatus int
+		expect       readinessStatus
+	}{
+		{
+			title:        "ready",
+			check:        func(context.Context) error { return nil },
+			expectStatus: http.StatusOK,
+			expect:       readinessStatus{Status: "ready"},
+		},
+		{
+			title:        "not ready",
+			check:        func(context.Context) error { return errors.New("store unavailable") },
+			expectStatus: http.StatusServiceUnavailable,
+			expect:       readinessStatus{Status: "unavailable", Error: "store unavailable"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			log := slog.New(slog.NewTextHandler(io.Discard, nil))
+			srv := NewServer(ServerConfig{Logger: log, ReadinessCheck: tc.check})
+
+			w := httptest.NewRecorder()
+			srv.srv.ServeHTTP(w, httptest.NewRequest(http.MethodGet, DefaultReadinessProbePath, nil))
+
+			if w.Code != tc.expectStatus {
+				t.Fatalf("expected status %d got %d", tc.expectStatus, w.Code)
+			}
+
+			status := readinessStatus{}
+			if err := json.NewDecoder(w.Body).Decode(&status); err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if status != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, status)
+			}
+		})
+	}
+}
@@ -3,9 +3,14 @@ package local
 
 import (
 	"context"
+	"errors"
+	"path/filepath"
 
 	"github.com/grafana/k6build"
 	"github.com/grafana/k6build/pkg/builder"
+	"github.com/grafana/k6build/pkg/lock"
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/store/client"
 	"github.com/grafana/k6build/pkg/store/file"
 )
 
@@ -22,11 +27,25 @@ type Config struct {
 	Catalog string
 	// path to object store dir
 	StoreDir string
+	// URL of a store server shared with other builders. If set, the artifacts are stored in this
+	// store instead of StoreDir.
+	StoreURL string
+	// path to the dir where the objects of the StoreURL's store are cached, so artifacts already
+	// downloaded don't access the network. Requires StoreURL. Optional
+	StoreCacheDir string
+	// version of k6build reported in the build info of the artifacts
+	Version string
 }
 
 // NewBuildService creates a local build service using the given configuration
 func NewBuildService(ctx context.Context, config Config) (k6build.BuildService, error) {
-	store, err := file.NewFileStore(config.StoreDir)
+	objectStore, err := NewStore(config)
+	if err != nil {
+		return nil, k6build.NewWrappedError(builder.ErrInitializingBuilder, err)
+	}
+
+	// prevent concurrent builds of the same artifact by processes sharing the store dir
+	fileLock, err := lock.NewFileLock(filepath.Join(config.StoreDir, ".locks"))
 	if err != nil {
 		return nil, k6build.NewWrappedError(builder.ErrInitializingBuilder, err)
 	}
@@ -34,6 +53,36 @@ func NewBuildService(ctx context.Context, config Config) (k6build.BuildService,
 	return builder.New(ctx, builder.Config{
 		Opts:    config.Opts,
 		Catalog: config.Catalog,
-		Store:   store,
+		Store:   objectStore,
+		Lock:    fileLock,
+		Version: config.Version,
 	})
 }
+
+// NewStore returns the object store for the configuration: a file store in StoreDir or the
+// store server at StoreURL, with its objects cached in StoreCacheDir
+func NewStore(config Config) (store.ObjectStore, error) {
+	if config.StoreURL == "" {
+		if config.StoreCacheDir != "" {
+			return nil, errors.New("store cache dir requires a store url")
+		}
+
+		return file.NewFileStore(config.StoreDir)
+	}
+
+	remote, err := client.NewStoreClient(client.StoreClientConfig{Server: config.StoreURL})
+	if err != nil {
+		return nil, err
+	}
+
+	if config.StoreCacheDir == "" {
+		return remote, nil
+	}
+
+	cache, err := file.NewFileStore(config.StoreCacheDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.NewTieredStore(cache, remote)
+}
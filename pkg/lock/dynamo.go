@@ -0,0 +1,184 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/grafana/k6build"
+)
+
+const (
+	// DefaultDynamoRetryInterval is the time between attempts to acquire a DynamoDB lock
+	DefaultDynamoRetryInterval = 100 * time.Millisecond
+
+	// attributes of the lock items
+	dynamoIDAttr      = "id"
+	dynamoOwnerAttr   = "owner"
+	dynamoExpiresAttr = "expires"
+)
+
+// DynamoClient defines the DynamoDB operations used by the DynamoLock
+type DynamoClient interface {
+	PutItem(
+		ctx context.Context,
+		params *dynamodb.PutItemInput,
+		optFns ...func(*dynamodb.Options),
+	) (*dynamodb.PutItemOutput, error)
+	DeleteItem(
+		ctx context.Context,
+		params *dynamodb.DeleteItemInput,
+		optFns ...func(*dynamodb.Options),
+	) (*dynamodb.DeleteItemOutput, error)
+}
+
+// DynamoConfig DynamoDB Lock configuration
+type DynamoConfig struct {
+	// Name of the DynamoDB table. The table must have a string partition key named "id".
+	// Enabling TTL on the "expires" attribute is recommended for removing expired locks.
+	Table string
+	// DynamoDB client
+	Client DynamoClient
+	// AWS endpoint (used for testing)
+	Endpoint string
+	// AWS Region
+	Region string
+	// Time after which a lock is considered expired and can be taken by another process.
+	// The lock is not renewed while held, so the lease must exceed the worst-case build time.
+	// Defaults to DefaultLeaseDuration
+	LeaseDuration time.Duration
+	// Time between attempts to acquire a lock. Defaults to DefaultDynamoRetryInterval
+	RetryInterval time.Duration
+}
+
+// DynamoLock is a Lock backed by a DynamoDB table.
+//
+// The lock is acquired by conditionally creating an item for the id if it doesn't exist or its lease
+// has expired, and released by conditionally deleting the item if it is owned by the caller.
+type DynamoLock struct {
+	table         string
+	client        DynamoClient
+	leaseDuration time.Duration
+	retryInterval time.Duration
+}
+
+// NewDynamoLock creates a lock backed by a DynamoDB table
+func NewDynamoLock(conf DynamoConfig) (*DynamoLock, error) {
+	if conf.Table == "" {
+		return nil, fmt.Errorf("%w: table name cannot be empty", ErrInitializingLock)
+	}
+
+	client := conf.Client
+	if client == nil {
+		opts := []func(*config.LoadOptions) error{}
+		if conf.Region != "" {
+			opts = append(opts, config.WithRegion(conf.Region))
+		}
+
+		cfg, err := config.LoadDefaultConfig(context.TODO(), opts...)
+		if err != nil {
+			return nil, k6build.NewWrappedError(ErrInitializingLock, err)
+		}
+
+		client = dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+			if conf.Endpoint != "" {
+				o.BaseEndpoint = aws.String(conf.Endpoint)
+			}
+		})
+	}
+
+	leaseDuration := conf.LeaseDuration
+	if leaseDuration == 0 {
+		leaseDuration = DefaultLeaseDuration
+	}
+
+	retryInterval := conf.RetryInterval
+	if retryInterval == 0 {
+		retryInterval = DefaultDynamoRetryInterval
+	}
+
+	return &DynamoLock{
+		table:         conf.Table,
+		client:        client,
+		leaseDuration: leaseDuration,
+		retryInterval: retryInterval,
+	}, nil
+}
+
+// Lock blocks until the lock on the id is acquired or the context is done.
+// Returns a function for releasing the lock.
+func (l *DynamoLock) Lock(ctx context.Context, id string) (func(), error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: id cannot be empty", ErrLocking)
+	}
+
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+	owner := hex.EncodeToString(token)
+
+	for {
+		now := time.Now()
+		_, err := l.client.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(l.table),
+			Item: map[string]types.AttributeValue{
+				dynamoIDAttr:      &types.AttributeValueMemberS{Value: id},
+				dynamoOwnerAttr:   &types.AttributeValueMemberS{Value: owner},
+				dynamoExpiresAttr: unixTime(now.Add(l.leaseDuration)),
+			},
+			ConditionExpression: aws.String("attribute_not_exists(#id) OR #expires < :now"),
+			ExpressionAttributeNames: map[string]string{
+				"#id":      dynamoIDAttr,
+				"#expires": dynamoExpiresAttr,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":now": unixTime(now),
+			},
+		})
+		if err == nil {
+			break
+		}
+
+		var conflict *types.ConditionalCheckFailedException
+		if !errors.As(err, &conflict) {
+			return nil, k6build.NewWrappedError(ErrLocking, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, k6build.NewWrappedError(ErrLocking, ctx.Err())
+		case <-time.After(l.retryInterval):
+		}
+	}
+
+	release := func() {
+		_, _ = l.client.DeleteItem(context.WithoutCancel(ctx), &dynamodb.DeleteItemInput{
+			TableName: aws.String(l.table),
+			Key: map[string]types.AttributeValue{
+				dynamoIDAttr: &types.AttributeValueMemberS{Value: id},
+			},
+			ConditionExpression: aws.String("#owner = :owner"),
+			ExpressionAttributeNames: map[string]string{
+				"#owner": dynamoOwnerAttr,
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":owner": &types.AttributeValueMemberS{Value: owner},
+			},
+		})
+	}
+
+	return release, nil
+}
+
+// unixTime returns the time as a number attribute with the seconds since epoch, as expected by DynamoDB's TTL
+func unixTime(t time.Time) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: strconv.FormatInt(t.Unix(), 10)}
+}
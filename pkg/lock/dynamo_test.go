@@ -0,0 +1,179 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type dynamoItem struct {
+	owner   string
+	expires int64
+}
+
+// mockDynamo simulates the conditional writes used by the DynamoLock
+type mockDynamo struct {
+	mutex sync.Mutex
+	items map[string]dynamoItem
+}
+
+func newMockDynamo() *mockDynamo {
+	return &mockDynamo{items: map[string]dynamoItem{}}
+}
+
+func attrString(attrs map[string]types.AttributeValue, name string) string {
+	if v, ok := attrs[name].(*types.AttributeValueMemberS); ok {
+		return v.Value
+	}
+	return ""
+}
+
+func attrInt(attrs map[string]types.AttributeValue, name string) int64 {
+	if v, ok := attrs[name].(*types.AttributeValueMemberN); ok {
+		n, _ := strconv.ParseInt(v.Value, 10, 64)
+		return n
+	}
+	return 0
+}
+
+// PutItem creates the item if it doesn't exist or it has expired
+func (m *mockDynamo) PutItem(
+	_ context.Context,
+	params *dynamodb.PutItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.PutItemOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	id := attrString(params.Item, dynamoIDAttr)
+	now := attrInt(params.ExpressionAttributeValues, ":now")
+
+	if item, found := m.items[id]; found && item.expires >= now {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("conditional check failed")}
+	}
+
+	m.items[id] = dynamoItem{
+		owner:   attrString(params.Item, dynamoOwnerAttr),
+		expires: attrInt(params.Item, dynamoExpiresAttr),
+	}
+
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+// DeleteItem deletes the item if it is owned by the caller
+func (m *mockDynamo) DeleteItem(
+	_ context.Context,
+	params *dynamodb.DeleteItemInput,
+	_ ...func(*dynamodb.Options),
+) (*dynamodb.DeleteItemOutput, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	id := attrString(params.Key, dynamoIDAttr)
+	owner := attrString(params.ExpressionAttributeValues, ":owner")
+
+	if item, found := m.items[id]; !found || item.owner != owner {
+		return nil, &types.ConditionalCheckFailedException{Message: aws.String("conditional check failed")}
+	}
+
+	delete(m.items, id)
+
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+func TestDynamoLock(t *testing.T) {
+	t.Parallel()
+
+	client := newMockDynamo()
+	l, err := NewDynamoLock(DynamoConfig{Table: "locks", Client: client, RetryInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+
+	// other ids are not affected
+	releaseOther, err := l.Lock(context.TODO(), "other")
+	if err != nil {
+		t.Fatalf("acquiring lock on other id %v", err)
+	}
+	releaseOther()
+
+	// lock is held, attempt should fail when the context is done
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	_, err = l.Lock(ctx, "object")
+	if !errors.Is(err, ErrLocking) {
+		t.Fatalf("expected %v got %v", ErrLocking, err)
+	}
+
+	release()
+
+	if _, found := client.items["object"]; found {
+		t.Fatalf("lock item not removed")
+	}
+
+	// once released the lock can be acquired again
+	release, err = l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring released lock %v", err)
+	}
+	release()
+}
+
+func TestDynamoLockExpiration(t *testing.T) {
+	t.Parallel()
+
+	client := newMockDynamo()
+	l, err := NewDynamoLock(DynamoConfig{Table: "locks", Client: client, RetryInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+
+	// simulate the lease expired
+	client.mutex.Lock()
+	item := client.items["object"]
+	item.expires = time.Now().Add(-time.Minute).Unix()
+	client.items["object"] = item
+	client.mutex.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	releaseOther, err := l.Lock(ctx, "object")
+	if err != nil {
+		t.Fatalf("acquiring expired lock %v", err)
+	}
+
+	// releasing the expired lock must not release the lock taken by other owner
+	release()
+
+	if _, found := client.items["object"]; !found {
+		t.Fatalf("released lock owned by other")
+	}
+
+	releaseOther()
+}
+
+func TestDynamoLockInvalidConfig(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewDynamoLock(DynamoConfig{Client: newMockDynamo()})
+	if !errors.Is(err, ErrInitializingLock) {
+		t.Fatalf("expected %v got %v", ErrInitializingLock, err)
+	}
+}
@@ -0,0 +1,70 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/internal/dirlock"
+)
+
+// defaultFilePollInterval is the time between attempts to acquire a file lock
+const defaultFilePollInterval = 100 * time.Millisecond
+
+// FileLock is a Lock backed by advisory file locks in a directory.
+// It can be used for preventing concurrent operations by multiple processes in the same host.
+//
+// Each id is locked using a lock on a directory with the id's name. As the lock is released by
+// the operating system when the process exits, locks are never stale and don't require a lease.
+// The directories are kept when the locks are released: removing them could allow another process
+// waiting on the removed lock file to acquire the lock at the same time as a process creating it again.
+type FileLock struct {
+	dir string
+}
+
+// NewFileLock creates a lock backed by file locks in the given directory
+func NewFileLock(dir string) (*FileLock, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, k6build.NewWrappedError(ErrInitializingLock, err)
+	}
+
+	return &FileLock{dir: dir}, nil
+}
+
+// Lock blocks until the lock on the id is acquired or the context is done.
+// Returns a function for releasing the lock.
+func (l *FileLock) Lock(ctx context.Context, id string) (func(), error) {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return nil, fmt.Errorf("%w: invalid id %q", ErrLocking, id)
+	}
+
+	lockDir := filepath.Join(l.dir, id)
+	if err := os.MkdirAll(lockDir, 0o750); err != nil {
+		return nil, k6build.NewWrappedError(ErrLocking, err)
+	}
+
+	idLock := dirlock.New(lockDir)
+	for {
+		err := idLock.TryLock()
+		if err == nil {
+			return func() {
+				_ = idLock.Unlock()
+			}, nil
+		}
+
+		if !errors.Is(err, dirlock.ErrLocked) {
+			return nil, k6build.NewWrappedError(ErrLocking, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, k6build.NewWrappedError(ErrLocking, ctx.Err())
+		case <-time.After(defaultFilePollInterval):
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileLock(t *testing.T) {
+	t.Parallel()
+
+	l, err := NewFileLock(t.TempDir())
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+
+	// other ids are not affected
+	releaseOther, err := l.Lock(context.TODO(), "other")
+	if err != nil {
+		t.Fatalf("acquiring lock on other id %v", err)
+	}
+	releaseOther()
+
+	// lock is held, attempt should fail when the context is done
+	ctx, cancel := context.WithTimeout(context.TODO(), 200*time.Millisecond)
+	defer cancel()
+	_, err = l.Lock(ctx, "object")
+	if !errors.Is(err, ErrLocking) {
+		t.Fatalf("expected %v got %v", ErrLocking, err)
+	}
+
+	// wait for the lock to be released
+	acquired := make(chan error)
+	go func() {
+		release, err := l.Lock(context.TODO(), "object")
+		if err == nil {
+			release()
+		}
+		acquired <- err
+	}()
+
+	release()
+
+	select {
+	case err = <-acquired:
+		if err != nil {
+			t.Fatalf("acquiring released lock %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timeout waiting for released lock")
+	}
+}
+
+func TestFileLockLeftover(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	l, err := NewFileLock(dir)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	// simulate a lock file left by a crashed process. As the process no longer holds the lock,
+	// it must be acquired immediately.
+	if err = os.MkdirAll(filepath.Join(dir, "object"), 0o750); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "object", ".lock"), nil, 0o600); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+	release, err := l.Lock(ctx, "object")
+	if err != nil {
+		t.Fatalf("acquiring leftover lock %v", err)
+	}
+	release()
+}
+
+func TestFileLockInvalidID(t *testing.T) {
+	t.Parallel()
+
+	l, err := NewFileLock(t.TempDir())
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	for _, id := range []string{"", "..", "../object", "dir/object"} {
+		if _, err = l.Lock(context.TODO(), id); !errors.Is(err, ErrLocking) {
+			t.Fatalf("id %q: expected %v got %v", id, ErrLocking, err)
+		}
+	}
+}
+
+// TestFileLockContention checks that waiting for a lock doesn't leak file descriptors.
+// It is not parallel, so the open file descriptors are not affected by other tests.
+func TestFileLockContention(t *testing.T) { //nolint:paralleltest
+	if _, err := os.ReadDir("/proc/self/fd"); err != nil {
+		t.Skip("open file descriptors cannot be counted")
+	}
+
+	l, err := NewFileLock(t.TempDir())
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+	defer release()
+
+	before := openFiles(t)
+
+	// retry the lock several times until the context is done
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*defaultFilePollInterval)
+	defer cancel()
+	if _, err = l.Lock(ctx, "object"); !errors.Is(err, ErrLocking) {
+		t.Fatalf("expected %v got %v", ErrLocking, err)
+	}
+
+	if after := openFiles(t); after > before {
+		t.Fatalf("expected at most %d open files got %d", before, after)
+	}
+}
+
+func openFiles(t *testing.T) int {
+	t.Helper()
+
+	files, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		t.Fatalf("reading open files %v", err)
+	}
+
+	return len(files)
+}
@@ -0,0 +1,21 @@
+// Package lock defines a lock that can be shared by multiple processes
+// for preventing concurrent operations over the same resource, for example,
+// multiple build servers building the same artifact.
+package lock
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrInitializingLock = errors.New("initializing lock") //nolint:revive
+	ErrLocking          = errors.New("acquiring lock")
+)
+
+// Lock defines a lock over a resource identified by an id
+type Lock interface {
+	// Lock blocks until the lock on the id is acquired or the context is done.
+	// Returns a function for releasing the lock.
+	Lock(ctx context.Context, id string) (func(), error)
+}
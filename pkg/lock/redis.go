@@ -0,0 +1,150 @@
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/grafana/k6build"
+)
+
+const (
+	// DefaultRedisRetryInterval is the time between attempts to acquire a redis lock
+	DefaultRedisRetryInterval = 100 * time.Millisecond
+)
+
+// releaseScript deletes the lock key only if it is owned by the caller
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// renewScript extends the lease of the lock key only if it is owned by the caller
+var renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`)
+
+// RedisOpts defines the options for the Redis Lock
+type RedisOpts struct {
+	// Prefix of the lock keys. Defaults to DefaultLockPrefix
+	Prefix string
+	// Time after which a lock that is not renewed expires. Defaults to DefaultLeaseDuration
+	LeaseDuration time.Duration
+	// Time between attempts to acquire a lock. Defaults to DefaultRedisRetryInterval
+	RetryInterval time.Duration
+	// Time between renewals of the lease while the lock is held. Defaults to a third of the LeaseDuration
+	RenewInterval time.Duration
+}
+
+// RedisLock is a Lock backed by Redis.
+//
+// The lock is acquired using SET NX with the lease as expiration and released using a
+// compare-and-delete script so a lock can only be released by its owner.
+// While the lock is held, its lease is periodically renewed.
+type RedisLock struct {
+	client redis.Cmdable
+	opts   RedisOpts
+}
+
+// NewRedisLock creates a lock backed by Redis
+func NewRedisLock(client redis.Cmdable, opts RedisOpts) (*RedisLock, error) {
+	if client == nil {
+		return nil, fmt.Errorf("%w: redis client cannot be nil", ErrInitializingLock)
+	}
+
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultLockPrefix
+	}
+
+	if opts.LeaseDuration == 0 {
+		opts.LeaseDuration = DefaultLeaseDuration
+	}
+
+	if opts.RetryInterval == 0 {
+		opts.RetryInterval = DefaultRedisRetryInterval
+	}
+
+	if opts.RenewInterval == 0 {
+		opts.RenewInterval = opts.LeaseDuration / 3
+	}
+
+	if opts.RenewInterval >= opts.LeaseDuration {
+		return nil, fmt.Errorf("%w: renew interval must be shorter than the lease", ErrInitializingLock)
+	}
+
+	return &RedisLock{
+		client: client,
+		opts:   opts,
+	}, nil
+}
+
+// Lock blocks until the lock on the id is acquired or the context is done.
+// Returns a function for releasing the lock.
+func (l *RedisLock) Lock(ctx context.Context, id string) (func(), error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: id cannot be empty", ErrLocking)
+	}
+
+	key := l.opts.Prefix + id
+
+	token := make([]byte, 16)
+	_, _ = rand.Read(token)
+	owner := hex.EncodeToString(token)
+
+	for {
+		acquired, err := l.client.SetNX(ctx, key, owner, l.opts.LeaseDuration).Result()
+		if err != nil {
+			return nil, k6build.NewWrappedError(ErrLocking, err)
+		}
+
+		if acquired {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, k6build.NewWrappedError(ErrLocking, ctx.Err())
+		case <-time.After(l.opts.RetryInterval):
+		}
+	}
+
+	// renew the lease until the lock is released
+	renewCtx, stopRenew := context.WithCancel(context.WithoutCancel(ctx))
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(l.opts.RenewInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-renewCtx.Done():
+				return
+			case <-ticker.C:
+				_ = renewScript.Run(renewCtx, l.client, []string{key}, owner, l.opts.LeaseDuration.Milliseconds()).Err()
+			}
+		}
+	}()
+
+	once := sync.Once{}
+	return func() {
+		once.Do(func() {
+			stopRenew()
+			wg.Wait()
+
+			_ = releaseScript.Run(context.WithoutCancel(ctx), l.client, []string{key}, owner).Err()
+		})
+	}, nil
+}
@@ -0,0 +1,131 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupRedisLock(t *testing.T, opts RedisOpts) (*RedisLock, *miniredis.Miniredis) {
+	t.Helper()
+
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	l, err := NewRedisLock(client, opts)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	return l, srv
+}
+
+func TestRedisLock(t *testing.T) {
+	t.Parallel()
+
+	l, srv := setupRedisLock(t, RedisOpts{RetryInterval: 10 * time.Millisecond})
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+
+	if !srv.Exists(DefaultLockPrefix + "object") {
+		t.Fatalf("lock key not created")
+	}
+
+	// lock is held, attempt should fail when the context is done
+	ctx, cancel := context.WithTimeout(context.TODO(), 100*time.Millisecond)
+	defer cancel()
+	_, err = l.Lock(ctx, "object")
+	if !errors.Is(err, ErrLocking) {
+		t.Fatalf("expected %v got %v", ErrLocking, err)
+	}
+
+	release()
+	// releasing twice is safe
+	release()
+
+	if srv.Exists(DefaultLockPrefix + "object") {
+		t.Fatalf("lock key not removed")
+	}
+
+	release, err = l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring released lock %v", err)
+	}
+	release()
+}
+
+func TestRedisLockReleaseNotOwned(t *testing.T) {
+	t.Parallel()
+
+	l, srv := setupRedisLock(t, RedisOpts{})
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+
+	// simulate the lock expired and was acquired by another owner
+	if err = srv.Set(DefaultLockPrefix+"object", "other"); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	release()
+
+	if value, _ := srv.Get(DefaultLockPrefix + "object"); value != "other" {
+		t.Fatalf("released lock owned by other")
+	}
+}
+
+func TestRedisLockRenewal(t *testing.T) {
+	t.Parallel()
+
+	lease := 300 * time.Millisecond
+	l, srv := setupRedisLock(t, RedisOpts{LeaseDuration: lease, RenewInterval: 50 * time.Millisecond})
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+
+	// miniredis doesn't expire keys in real time. Fast forward the clock past the lease
+	// in steps shorter than the lease, allowing the lock to be renewed.
+	for range 5 {
+		time.Sleep(100 * time.Millisecond)
+		srv.FastForward(100 * time.Millisecond)
+	}
+
+	if !srv.Exists(DefaultLockPrefix + "object") {
+		t.Fatalf("lock expired while held")
+	}
+
+	release()
+
+	if srv.Exists(DefaultLockPrefix + "object") {
+		t.Fatalf("lock key not removed")
+	}
+}
+
+func TestRedisLockInvalidOpts(t *testing.T) {
+	t.Parallel()
+
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+
+	_, err := NewRedisLock(client, RedisOpts{LeaseDuration: time.Second, RenewInterval: time.Second})
+	if !errors.Is(err, ErrInitializingLock) {
+		t.Fatalf("expected %v got %v", ErrInitializingLock, err)
+	}
+}
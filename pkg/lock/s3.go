@@ -0,0 +1,244 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/s3client"
+)
+
+const (
+	// DefaultLeaseDuration is the time after which a lock is considered expired
+	DefaultLeaseDuration = 5 * time.Minute
+	// DefaultLockPrefix is the prefix of the keys of the lock objects in the bucket
+	DefaultLockPrefix = "locks/"
+	// DefaultS3RetryInterval is the initial time between attempts to acquire a S3 lock
+	DefaultS3RetryInterval = 50 * time.Millisecond
+	// DefaultS3MaxRetryInterval is the maximum time between attempts to acquire a S3 lock
+	DefaultS3MaxRetryInterval = 2 * time.Second
+)
+
+// S3Config S3 Lock configuration
+type S3Config struct {
+	// Name of the S3 bucket
+	Bucket string
+	// S3 Client
+	Client *s3.Client
+	// AWS endpoint (used for testing)
+	Endpoint string
+	// AWS Region
+	Region string
+	// Use path-style addressing, required by some s3-compatible services (e.g. MinIO)
+	UsePathStyle bool
+	// Static credentials. If not given, the default credential chain is used
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Use http instead of https for accessing the endpoint
+	DisableSSL bool
+	// Time after which a lock is considered expired and can be taken by another process.
+	// The S3 lock is not renewed while held, so the lease must exceed the worst-case build time.
+	// Defaults to DefaultLeaseDuration
+	LeaseDuration time.Duration
+	// Initial time between attempts to acquire a lock. It is doubled after each attempt, up to
+	// MaxRetryInterval, with a random jitter. Defaults to DefaultS3RetryInterval
+	RetryInterval time.Duration
+	// Maximum time between attempts to acquire a lock. Defaults to DefaultS3MaxRetryInterval
+	MaxRetryInterval time.Duration
+	// Registerer for the lock's metrics. Optional. If not set, the metrics are not exposed
+	Registerer prometheus.Registerer
+}
+
+// S3Lock is a Lock backed by objects in a S3 bucket.
+//
+// Each attempt to lock an id creates an object under the id's prefix whose key starts with the
+// creation time. The lock is granted to the oldest non-expired object. Expired objects are removed.
+type S3Lock struct {
+	bucket           string
+	client           *s3.Client
+	leaseDuration    time.Duration
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+	// attempts to acquire a lock that found it held by another process
+	waits prometheus.Counter
+}
+
+// NewS3Lock creates a lock backed by a S3 bucket
+func NewS3Lock(conf S3Config) (*S3Lock, error) {
+	if conf.Bucket == "" {
+		return nil, fmt.Errorf("%w: bucket name cannot be empty", ErrInitializingLock)
+	}
+
+	client := conf.Client
+	if client == nil {
+		var err error
+		client, err = s3client.New(s3client.Config{
+			Endpoint:        conf.Endpoint,
+			Region:          conf.Region,
+			UsePathStyle:    conf.UsePathStyle,
+			AccessKeyID:     conf.AccessKeyID,
+			SecretAccessKey: conf.SecretAccessKey,
+			SessionToken:    conf.SessionToken,
+			DisableSSL:      conf.DisableSSL,
+		})
+		if err != nil {
+			return nil, k6build.NewWrappedError(ErrInitializingLock, err)
+		}
+	}
+
+	leaseDuration := conf.LeaseDuration
+	if leaseDuration == 0 {
+		leaseDuration = DefaultLeaseDuration
+	}
+
+	retryInterval := conf.RetryInterval
+	if retryInterval == 0 {
+		retryInterval = DefaultS3RetryInterval
+	}
+
+	maxRetryInterval := conf.MaxRetryInterval
+	if maxRetryInterval == 0 {
+		maxRetryInterval = DefaultS3MaxRetryInterval
+	}
+
+	waits := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "k6build",
+		Name:      "s3_lock_wait_iterations_total",
+		Help:      "The total number of attempts to acquire a S3 lock held by another process",
+	})
+	if conf.Registerer != nil {
+		if err := conf.Registerer.Register(waits); err != nil {
+			return nil, k6build.NewWrappedError(ErrInitializingLock, err)
+		}
+	}
+
+	return &S3Lock{
+		bucket:           conf.Bucket,
+		client:           client,
+		leaseDuration:    leaseDuration,
+		retryInterval:    retryInterval,
+		maxRetryInterval: max(maxRetryInterval, retryInterval),
+		waits:            waits,
+	}, nil
+}
+
+// Lock blocks until the lock on the id is acquired or the context is done.
+// Returns a function for releasing the lock.
+func (l *S3Lock) Lock(ctx context.Context, id string) (func(), error) {
+	if id == "" {
+		return nil, fmt.Errorf("%w: id cannot be empty", ErrLocking)
+	}
+
+	suffix := make([]byte, 8)
+	_, _ = rand.Read(suffix)
+
+	prefix := DefaultLockPrefix + id + "/"
+	key := fmt.Sprintf("%s%020d-%s", prefix, time.Now().UnixNano(), hex.EncodeToString(suffix))
+
+	// the context may be canceled when the lock is released or the attempt fails
+	release := func() {
+		_, _ = l.client.DeleteObject(context.WithoutCancel(ctx), &s3.DeleteObjectInput{
+			Bucket: aws.String(l.bucket),
+			Key:    aws.String(key),
+		})
+	}
+
+	// remove the lock object if the lock is not acquired, so it doesn't block other processes until it expires.
+	// This includes a failed put, as the object may have been created if the request was canceled.
+	acquired := false
+	defer func() {
+		if !acquired {
+			release()
+		}
+	}()
+
+	_, err := l.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(l.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(nil),
+	})
+	if err != nil {
+		return nil, k6build.NewWrappedError(ErrLocking, err)
+	}
+
+	interval := l.retryInterval
+	for {
+		owner, err := l.owner(ctx, prefix)
+		if err != nil {
+			return nil, k6build.NewWrappedError(ErrLocking, err)
+		}
+
+		if owner == key {
+			acquired = true
+			return release, nil
+		}
+
+		l.waits.Inc()
+
+		var wait time.Duration
+		wait, interval = l.backoff(interval)
+
+		select {
+		case <-ctx.Done():
+			return nil, k6build.NewWrappedError(ErrLocking, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+}
+
+// backoff returns the time to wait before the next attempt to acquire a lock and the interval for the
+// following attempt. The wait is between half and the whole interval, so processes waiting for the same
+// lock don't list the objects at the same time.
+func (l *S3Lock) backoff(interval time.Duration) (time.Duration, time.Duration) {
+	wait := interval/2 + mathrand.N(interval/2+1)
+
+	return wait, min(2*interval, l.maxRetryInterval)
+}
+
+// owner returns the key of the oldest non-expired lock object under the prefix.
+// Expired lock objects are deleted.
+func (l *S3Lock) owner(ctx context.Context, prefix string) (string, error) {
+	owner := ""
+
+	paginator := s3.NewListObjectsV2Paginator(l.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(l.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		for _, obj := range page.Contents {
+			if obj.LastModified != nil && time.Since(*obj.LastModified) > l.leaseDuration {
+				_, _ = l.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+					Bucket: aws.String(l.bucket),
+					Key:    obj.Key,
+				})
+				continue
+			}
+
+			if owner == "" || aws.ToString(obj.Key) < owner {
+				owner = aws.ToString(obj.Key)
+			}
+		}
+	}
+
+	if owner == "" {
+		return "", errors.New("lock object not found")
+	}
+
+	return owner, nil
+}
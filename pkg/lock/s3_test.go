@@ -0,0 +1,228 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/docker/go-connections/nat"
+
+	"github.com/testcontainers/testcontainers-go/modules/localstack"
+)
+
+func s3Client(ctx context.Context, l *localstack.LocalStackContainer) (*s3.Client, error) {
+	region := "us-east-1"
+	host, err := l.Host(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mappedPort, err := l.MappedPort(ctx, nat.Port("4566/tcp"))
+	if err != nil {
+		return nil, err
+	}
+
+	awsEndP := fmt.Sprintf("http://%s:%s", host, mappedPort.Port()) //nolint:nosprintfhostport
+	awsCfg, err := config.LoadDefaultConfig(context.TODO(),         //nolint:contextcheck
+		config.WithRegion(region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("accesskey", "secretkey", "token")),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(awsEndP)
+		o.UsePathStyle = true
+	})
+
+	return client, nil
+}
+
+func setupS3Lock(conf S3Config) (*S3Lock, error) {
+	bucket := "test"
+
+	localstack, err := localstack.Run(context.TODO(), "localstack/localstack:latest")
+	if err != nil {
+		return nil, fmt.Errorf("localstack setup %w", err)
+	}
+
+	client, err := s3Client(context.TODO(), localstack)
+	if err != nil {
+		return nil, fmt.Errorf("creating s3 client %w", err)
+	}
+
+	_, err = client.CreateBucket(context.TODO(), &s3.CreateBucketInput{
+		Bucket: aws.String(bucket),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 setup %w", err)
+	}
+
+	conf.Client = client
+	conf.Bucket = bucket
+
+	return NewS3Lock(conf)
+}
+
+func TestS3Lock(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("Skipping test: localstack test container is failing in darwin and windows")
+	}
+
+	l, err := setupS3Lock(S3Config{})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+
+	// other ids are not affected
+	releaseOther, err := l.Lock(context.TODO(), "other")
+	if err != nil {
+		t.Fatalf("acquiring lock on other id %v", err)
+	}
+	releaseOther()
+
+	// lock is held, attempt should time out
+	ctx, cancel := context.WithTimeout(context.TODO(), 2*time.Second)
+	defer cancel()
+	_, err = l.Lock(ctx, "object")
+	if !errors.Is(err, ErrLocking) {
+		t.Fatalf("expected %v got %v", ErrLocking, err)
+	}
+
+	release()
+
+	// once released the lock can be acquired again
+	release, err = l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring released lock %v", err)
+	}
+	release()
+}
+
+func TestS3LockCancel(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("Skipping test: localstack test container is failing in darwin and windows")
+	}
+
+	l, err := setupS3Lock(S3Config{RetryInterval: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	release, err := l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+	defer release()
+
+	// cancel while waiting for the lock
+	ctx, cancel := context.WithCancel(context.TODO())
+	time.AfterFunc(time.Second, cancel)
+	_, err = l.Lock(ctx, "object")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+
+	// only the lock object of the holder remains
+	objects, err := l.client.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(l.bucket),
+		Prefix: aws.String(DefaultLockPrefix + "object/"),
+	})
+	if err != nil {
+		t.Fatalf("listing lock objects %v", err)
+	}
+
+	if len(objects.Contents) != 1 {
+		t.Fatalf("expected 1 lock object got %d", len(objects.Contents))
+	}
+}
+
+func TestS3LockLeaseExpiration(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS != "linux" {
+		t.Skip("Skipping test: localstack test container is failing in darwin and windows")
+	}
+
+	l, err := setupS3Lock(S3Config{LeaseDuration: time.Second, RetryInterval: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	// the lock is never released
+	_, err = l.Lock(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("acquiring lock %v", err)
+	}
+
+	// once the lease expires, the lock can be taken
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Second)
+	defer cancel()
+	release, err := l.Lock(ctx, "object")
+	if err != nil {
+		t.Fatalf("acquiring expired lock %v", err)
+	}
+	release()
+}
+
+func TestS3LockBackoff(t *testing.T) {
+	t.Parallel()
+
+	l := &S3Lock{retryInterval: 100 * time.Millisecond, maxRetryInterval: time.Second}
+
+	testCases := []struct {
+		title      string
+		interval   time.Duration
+		expectNext time.Duration
+	}{
+		{
+			title:      "initial interval",
+			interval:   100 * time.Millisecond,
+			expectNext: 200 * time.Millisecond,
+		},
+		{
+			title:      "capped interval",
+			interval:   800 * time.Millisecond,
+			expectNext: time.Second,
+		},
+		{
+			title:      "max interval",
+			interval:   time.Second,
+			expectNext: time.Second,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			for range 100 {
+				wait, next := l.backoff(tc.interval)
+				if wait < tc.interval/2 || wait > tc.interval {
+					t.Fatalf("expected wait between %s and %s got %s", tc.interval/2, tc.interval, wait)
+				}
+
+				if next != tc.expectNext {
+					t.Fatalf("expected next interval %s got %s", tc.expectNext, next)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Package manifest defines a multi-platform manifest.
+//
+// A manifest is stored in the object store under a platform-independent id and lists the
+// artifacts built for each platform from the same set of dependencies. This allows clients
+// to use a single "universal" reference that is resolved to the binary for their platform.
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/store/downloader"
+)
+
+var (
+	ErrInvalidManifest  = errors.New("invalid manifest") //nolint:revive
+	ErrFetchingManifest = errors.New("fetching manifest")
+	ErrStoringManifest  = errors.New("storing manifest")
+	ErrPlatformNotFound = errors.New("platform not found in manifest")
+)
+
+// Entry references the artifact built for a platform
+type Entry struct {
+	// Artifact id
+	ID string `json:"id,omitempty"`
+	// binary checksum: the hex digest for sha256 or <algorithm>:<hex digest> for other algorithms
+	Checksum string `json:"checksum,omitempty"`
+	// URL to fetch the artifact's binary
+	URL string `json:"url,omitempty"`
+}
+
+// Manifest lists the artifacts that satisfy a set of dependencies for multiple platforms
+type Manifest struct {
+	// Platform-independent id
+	ID string `json:"id,omitempty"`
+	// URL to fetch the manifest
+	URL string `json:"url,omitempty"`
+	// List of dependencies that the artifacts provide
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	// Artifacts indexed by platform
+	Artifacts map[string]Entry `json:"artifacts,omitempty"`
+}
+
+// New creates a Manifest with the given id from a list of artifacts.
+// All artifacts must provide the same dependencies and target a different platform.
+func New(id string, artifacts []k6build.Artifact) (Manifest, error) {
+	if id == "" {
+		return Manifest{}, fmt.Errorf("%w: id cannot be empty", ErrInvalidManifest)
+	}
+
+	if len(artifacts) == 0 {
+		return Manifest{}, fmt.Errorf("%w: no artifacts", ErrInvalidManifest)
+	}
+
+	manifest := Manifest{
+		ID:           id,
+		Dependencies: artifacts[0].Dependencies,
+		Artifacts:    map[string]Entry{},
+	}
+
+	for _, a := range artifacts {
+		if !maps.Equal(a.Dependencies, manifest.Dependencies) {
+			return Manifest{}, fmt.Errorf("%w: artifact %s has different dependencies", ErrInvalidManifest, a.ID)
+		}
+
+		if _, found := manifest.Artifacts[a.Platform]; found {
+			return Manifest{}, fmt.Errorf("%w: duplicated platform %s", ErrInvalidManifest, a.Platform)
+		}
+
+		manifest.Artifacts[a.Platform] = Entry{ID: a.ID, Checksum: a.Checksum, URL: a.URL}
+	}
+
+	return manifest, nil
+}
+
+// Platforms returns the sorted list of platforms in the manifest
+func (m Manifest) Platforms() []string {
+	return slices.Sorted(maps.Keys(m.Artifacts))
+}
+
+// Resolve returns the entry for the given platform
+func (m Manifest) Resolve(platform string) (Entry, error) {
+	entry, found := m.Artifacts[platform]
+	if !found {
+		return Entry{}, fmt.Errorf("%w: %s", ErrPlatformNotFound, platform)
+	}
+
+	return entry, nil
+}
+
+// Store stores the manifest in the object store under its id and returns the manifest
+// with its download URL. If the manifest already exists, the existing object is used.
+func Store(ctx context.Context, objectStore store.ObjectStore, manifest Manifest) (Manifest, error) {
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return Manifest{}, k6build.NewWrappedError(ErrStoringManifest, err)
+	}
+
+	object, err := objectStore.Put(ctx, manifest.ID, bytes.NewReader(content))
+	if errors.Is(err, store.ErrDuplicateObject) {
+		object, err = objectStore.Get(ctx, manifest.ID)
+	}
+	if err != nil {
+		return Manifest{}, k6build.NewWrappedError(ErrStoringManifest, err)
+	}
+
+	manifest.URL = object.URL
+
+	return manifest, nil
+}
+
+// Fetch downloads the manifest from the given URL
+func Fetch(ctx context.Context, client *http.Client, url string) (Manifest, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	content, err := downloader.Download(ctx, client, store.Object{URL: url})
+	if err != nil {
+		return Manifest{}, k6build.NewWrappedError(ErrFetchingManifest, err)
+	}
+	defer content.Close() //nolint:errcheck
+
+	manifest := Manifest{}
+	if err = json.NewDecoder(content).Decode(&manifest); err != nil {
+		return Manifest{}, k6build.NewWrappedError(ErrFetchingManifest, err)
+	}
+
+	manifest.URL = url
+
+	return manifest, nil
+}
+
+// ResolveURL fetches the manifest from the given URL and returns the entry for the platform
+func ResolveURL(ctx context.Context, client *http.Client, url string, platform string) (Entry, error) {
+	manifest, err := Fetch(ctx, client, url)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	return manifest.Resolve(platform)
+}
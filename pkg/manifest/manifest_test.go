@@ -0,0 +1,203 @@
+package manifest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/store/file"
+)
+
+func TestNewManifest(t *testing.T) {
+	t.Parallel()
+
+	deps := map[string]string{"k6": "v0.1.0"}
+
+	testCases := []struct {
+		title     string
+		id        string
+		artifacts []k6build.Artifact
+		expectErr error
+	}{
+		{
+			title: "multiple platforms",
+			id:    "manifest",
+			artifacts: []k6build.Artifact{
+				{ID: "linux", Platform: "linux/amd64", Dependencies: deps},
+				{ID: "darwin", Platform: "darwin/arm64", Dependencies: deps},
+			},
+		},
+		{
+			title:     "empty id",
+			id:        "",
+			artifacts: []k6build.Artifact{{ID: "linux", Platform: "linux/amd64", Dependencies: deps}},
+			expectErr: ErrInvalidManifest,
+		},
+		{
+			title:     "no artifacts",
+			id:        "manifest",
+			expectErr: ErrInvalidManifest,
+		},
+		{
+			title: "duplicated platform",
+			id:    "manifest",
+			artifacts: []k6build.Artifact{
+				{ID: "linux", Platform: "linux/amd64", Dependencies: deps},
+				{ID: "linux2", Platform: "linux/amd64", Dependencies: deps},
+			},
+			expectErr: ErrInvalidManifest,
+		},
+		{
+			title: "different dependencies",
+			id:    "manifest",
+			artifacts: []k6build.Artifact{
+				{ID: "linux", Platform: "linux/amd64", Dependencies: deps},
+				{ID: "darwin", Platform: "darwin/arm64", Dependencies: map[string]string{"k6": "v0.2.0"}},
+			},
+			expectErr: ErrInvalidManifest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := New(tc.id, tc.artifacts)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			for _, a := range tc.artifacts {
+				entry, err := m.Resolve(a.Platform)
+				if err != nil {
+					t.Fatalf("resolving %s: %v", a.Platform, err)
+				}
+				if entry.ID != a.ID {
+					t.Fatalf("expected %s got %s", a.ID, entry.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveManifest(t *testing.T) {
+	t.Parallel()
+
+	objectStore, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	deps := map[string]string{"k6": "v0.1.0"}
+	m, err := New("manifest", []k6build.Artifact{
+		{ID: "linux", Platform: "linux/amd64", Dependencies: deps, URL: "http://store/linux"},
+		{ID: "darwin", Platform: "darwin/arm64", Dependencies: deps, URL: "http://store/darwin"},
+	})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	m, err = Store(context.TODO(), objectStore, m)
+	if err != nil {
+		t.Fatalf("storing manifest %v", err)
+	}
+
+	// storing the same manifest again returns the existing object
+	if _, err = Store(context.TODO(), objectStore, m); err != nil {
+		t.Fatalf("storing duplicated manifest %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		platform  string
+		expect    string
+		expectErr error
+	}{
+		{
+			title:    "resolve linux",
+			platform: "linux/amd64",
+			expect:   "http://store/linux",
+		},
+		{
+			title:    "resolve darwin",
+			platform: "darwin/arm64",
+			expect:   "http://store/darwin",
+		},
+		{
+			title:     "platform not in manifest",
+			platform:  "windows/amd64",
+			expectErr: ErrPlatformNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			entry, err := ResolveURL(context.TODO(), nil, m.URL, tc.platform)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && entry.URL != tc.expect {
+				t.Fatalf("expected %s got %s", tc.expect, entry.URL)
+			}
+		})
+	}
+}
+
+func TestFetchManifest(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		handler   http.HandlerFunc
+		expectErr error
+	}{
+		{
+			title: "fetch manifest",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"id":"manifest","artifacts":{"linux/amd64":{"id":"linux"}}}`))
+			},
+		},
+		{
+			title: "manifest not found",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			expectErr: ErrFetchingManifest,
+		},
+		{
+			title: "invalid manifest",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`not a manifest`))
+			},
+			expectErr: ErrFetchingManifest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			srv := httptest.NewServer(tc.handler)
+			t.Cleanup(srv.Close)
+
+			m, err := Fetch(context.TODO(), srv.Client(), srv.URL)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && m.ID != "manifest" {
+				t.Fatalf("expected manifest id got %q", m.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Package s3client implements a helper function for creating s3 clients
+package s3client
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrInvalidCredentials signals the static credentials are incomplete
+var ErrInvalidCredentials = errors.New("access key id and secret access key must be given together")
+
+// Config defines the configuration of the s3 client
+type Config struct {
+	// AWS endpoint (used for testing)
+	Endpoint string
+	// AWS Region
+	Region string
+	// Use path-style addressing (e.g. http://endpoint/bucket/key), required by some s3-compatible
+	// services (e.g. MinIO). Always used if an Endpoint is given.
+	UsePathStyle bool
+	// Static credentials. If not given, the default credential chain is used (e.g. environment variables)
+	AccessKeyID     string
+	SecretAccessKey string
+	// Session token for temporary static credentials. Optional
+	SessionToken string
+	// Use http instead of https for accessing the endpoint
+	DisableSSL bool
+}
+
+// returns the S3 client options
+func (c Config) s3Opts() []func(o *s3.Options) {
+	opts := []func(o *s3.Options){}
+
+	if c.Endpoint != "" {
+		opts = append(opts, func(o *s3.Options) {
+			o.BaseEndpoint = aws.String(c.Endpoint)
+			o.UsePathStyle = true
+		})
+	}
+
+	if c.UsePathStyle {
+		opts = append(opts, func(o *s3.Options) {
+			o.UsePathStyle = true
+		})
+	}
+
+	if c.DisableSSL {
+		opts = append(opts, func(o *s3.Options) {
+			o.EndpointOptions.DisableHTTPS = true
+		})
+	}
+
+	return opts
+}
+
+// returns the aws configuration load options from Config
+func (c Config) awsOpts() []func(*config.LoadOptions) error {
+	opts := []func(*config.LoadOptions) error{}
+
+	if c.Region != "" {
+		opts = append(opts, config.WithRegion(c.Region))
+	}
+
+	if c.AccessKeyID != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(c.AccessKeyID, c.SecretAccessKey, c.SessionToken),
+		))
+	}
+
+	return opts
+}
+
+// New returns a s3 client using the default aws configuration and the given options
+func New(conf Config) (*s3.Client, error) {
+	if (conf.AccessKeyID == "") != (conf.SecretAccessKey == "") {
+		return nil, ErrInvalidCredentials
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), conf.awsOpts()...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s3.NewFromConfig(cfg, conf.s3Opts()...), nil
+}
@@ -0,0 +1,65 @@
+package s3client
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		conf            Config
+		expectErr       error
+		expectKeyID     string
+		expectPathStyle bool
+	}{
+		{
+			title:           "static credentials",
+			conf:            Config{Region: "us-east-1", AccessKeyID: "id", SecretAccessKey: "secret", UsePathStyle: true},
+			expectKeyID:     "id",
+			expectPathStyle: true,
+		},
+		{
+			title:     "missing secret access key",
+			conf:      Config{Region: "us-east-1", AccessKeyID: "id"},
+			expectErr: ErrInvalidCredentials,
+		},
+		{
+			title:     "missing access key id",
+			conf:      Config{Region: "us-east-1", SecretAccessKey: "secret"},
+			expectErr: ErrInvalidCredentials,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := New(tc.conf)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			opts := client.Options()
+			if opts.UsePathStyle != tc.expectPathStyle {
+				t.Fatalf("expected path style %t got %t", tc.expectPathStyle, opts.UsePathStyle)
+			}
+
+			creds, err := opts.Credentials.Retrieve(context.TODO())
+			if err != nil {
+				t.Fatalf("retrieving credentials %v", err)
+			}
+
+			if creds.AccessKeyID != tc.expectKeyID {
+				t.Fatalf("expected access key id %q got %q", tc.expectKeyID, creds.AccessKeyID)
+			}
+		})
+	}
+}
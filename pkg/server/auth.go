@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/k6build"
+)
+
+// BuildSemversAuthorizer decides if a request can build versions with build metadata (e.g. v0.0.0+build)
+// and dependencies from a commit. The build service only allows them if its configuration also allows them.
+type BuildSemversAuthorizer func(r *http.Request) bool
+
+// TokenAuthorizer returns a BuildSemversAuthorizer that only authorizes the requests with one of the given
+// tokens in their Authorization header ("<type> <token>")
+func TokenAuthorizer(tokens []string) BuildSemversAuthorizer {
+	// compare hashes, so the comparison takes the same time regardless of the tokens' length
+	hashes := make([][32]byte, 0, len(tokens))
+	for _, token := range tokens {
+		hashes = append(hashes, sha256.Sum256([]byte(token)))
+	}
+
+	return func(r *http.Request) bool {
+		_, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || token == "" {
+			return false
+		}
+
+		hash := sha256.Sum256([]byte(token))
+		for _, h := range hashes {
+			if subtle.ConstantTimeCompare(hash[:], h[:]) == 1 {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// authorize returns a context that carries the authorization decisions for the request, if any
+func (a *APIServer) authorize(ctx context.Context, r *http.Request) context.Context {
+	if a.authorizer == nil {
+		return ctx
+	}
+
+	return k6build.WithBuildSemversAllowed(ctx, a.authorizer(r))
+}
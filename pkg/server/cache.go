@@ -0,0 +1,65 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/k6build/pkg/httpserver"
+)
+
+// writeCacheable writes a successful response that can be cached by clients and edge caches.
+//
+// The ETag is derived from the response's content, so it changes when the catalog changes.
+// The ETag is weak because the response can be compressed by the compressor.
+// If the request's If-None-Match header matches the ETag, the content is not sent.
+// Responses to requests with credentials can only be cached by the client.
+func (a *APIServer) writeCacheable(w http.ResponseWriter, r *http.Request, resp any) {
+	body := &bytes.Buffer{}
+	_ = json.NewEncoder(body).Encode(resp) //nolint:errchkjson
+
+	etag := fmt.Sprintf("W/\"%x\"", sha256.Sum256(body.Bytes()))
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl(a.cacheMaxAge, r.Header.Get("Authorization") != ""))
+	w.Header().Set("Vary", "Accept-Encoding")
+
+	if httpserver.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body.Bytes())
+}
+
+// cacheControl returns the Cache-Control header for cacheable responses with the given max-age in seconds.
+// If the max-age is 0, caches must revalidate the response before using it.
+// Private responses can only be cached by the client, not by shared caches.
+func cacheControl(maxAge int, private bool) string {
+	switch {
+	case maxAge <= 0 && private:
+		return "private, no-cache"
+	case maxAge <= 0:
+		return "no-cache"
+	case private:
+		return fmt.Sprintf("private, max-age=%d", maxAge)
+	default:
+		return fmt.Sprintf("public, max-age=%d", maxAge)
+	}
+}
+
+// acceptsGzip returns true if the request accepts gzip encoded responses
+func acceptsGzip(r *http.Request) bool {
+	for _, encoding := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(encoding), ";")
+		if strings.TrimSpace(name) == "gzip" && strings.ReplaceAll(params, " ", "") != "q=0" {
+			return true
+		}
+	}
+
+	return false
+}
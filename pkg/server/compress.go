@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// compressor compresses the JSON responses larger than a minimum size if the request accepts gzip.
+// Other responses (e.g. binary content) and responses already encoded are sent as they are.
+type compressor struct {
+	minSize int
+}
+
+func (c compressor) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, minSize: c.minSize}
+		defer cw.close()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+// compressWriter buffers the response until it reaches the minimum size to decide if it is compressed
+type compressWriter struct {
+	http.ResponseWriter
+	minSize int
+	status  int
+	buffer  bytes.Buffer
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (c *compressWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+}
+
+func (c *compressWriter) Write(content []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+
+	if c.gz != nil {
+		return c.gz.Write(content)
+	}
+
+	if c.decided {
+		return c.ResponseWriter.Write(content)
+	}
+
+	c.buffer.Write(content)
+	if c.buffer.Len() < c.minSize {
+		return len(content), nil
+	}
+
+	if err := c.flush(c.compressible()); err != nil {
+		return 0, err
+	}
+
+	return len(content), nil
+}
+
+// Unwrap returns the original ResponseWriter, so http.ResponseController can access its features
+func (c *compressWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// compressible returns true if the response is JSON and is not already encoded
+func (c *compressWriter) compressible() bool {
+	if c.Header().Get("Content-Encoding") != "" {
+		return false
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Header().Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+// flush sends the header and the buffered content, compressed if required
+func (c *compressWriter) flush(compress bool) error {
+	c.decided = true
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+
+	if compress {
+		if !strings.Contains(c.Header().Get("Vary"), "Accept-Encoding") {
+			c.Header().Add("Vary", "Accept-Encoding")
+		}
+		c.Header().Set("Content-Encoding", "gzip")
+		c.Header().Del("Content-Length")
+		c.gz = gzip.NewWriter(c.ResponseWriter)
+	}
+
+	c.ResponseWriter.WriteHeader(c.status)
+
+	if c.buffer.Len() == 0 {
+		return nil
+	}
+
+	var err error
+	if c.gz != nil {
+		_, err = c.gz.Write(c.buffer.Bytes())
+	} else {
+		_, err = c.ResponseWriter.Write(c.buffer.Bytes())
+	}
+	c.buffer.Reset()
+
+	return err
+}
+
+// close sends the responses smaller than the minimum size and completes the compressed ones
+func (c *compressWriter) close() {
+	if !c.decided {
+		_ = c.flush(false)
+	}
+
+	if c.gz != nil {
+		_ = c.gz.Close()
+	}
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6build/pkg/api"
+)
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+
+	large := strings.Repeat("x", 2*DefaultCompressMinSize)
+
+	testCases := []struct {
+		title          string
+		acceptEncoding string
+		contentType    string
+		encoding       string
+		content        string
+		expectEncoding string
+	}{
+		{
+			title:          "large json response",
+			acceptEncoding: "gzip, deflate",
+			contentType:    "application/json",
+			content:        large,
+			expectEncoding: "gzip",
+		},
+		{
+			title:          "small json response",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			content:        "small",
+		},
+		{
+			title:       "gzip not accepted",
+			contentType: "application/json",
+			content:     large,
+		},
+		{
+			title:          "gzip rejected",
+			acceptEncoding: "gzip;q=0",
+			contentType:    "application/json",
+			content:        large,
+		},
+		{
+			title:          "binary response",
+			acceptEncoding: "gzip",
+			contentType:    "application/octet-stream",
+			content:        large,
+		},
+		{
+			title:          "encoded response",
+			acceptEncoding: "gzip",
+			contentType:    "application/json",
+			encoding:       "identity",
+			content:        large,
+			expectEncoding: "identity",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			handler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tc.contentType)
+				if tc.encoding != "" {
+					w.Header().Set("Content-Encoding", tc.encoding)
+				}
+				w.WriteHeader(http.StatusCreated)
+				// write in two parts to check the content is buffered until reaching the minimum size
+				half := len(tc.content) / 2
+				_, _ = w.Write([]byte(tc.content[:half]))
+				_, _ = w.Write([]byte(tc.content[half:]))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			resp := httptest.NewRecorder()
+
+			compressor{minSize: DefaultCompressMinSize}.handler(handler).ServeHTTP(resp, req)
+
+			if resp.Code != http.StatusCreated {
+				t.Fatalf("expected status %d got %d", http.StatusCreated, resp.Code)
+			}
+
+			encoding := resp.Header().Get("Content-Encoding")
+			if encoding != tc.expectEncoding {
+				t.Fatalf("expected encoding %q got %q", tc.expectEncoding, encoding)
+			}
+
+			var body io.Reader = resp.Body
+			if encoding == "gzip" {
+				gz, err := gzip.NewReader(resp.Body)
+				if err != nil {
+					t.Fatalf("decompressing response %v", err)
+				}
+				body = gz
+			}
+
+			content, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("reading response %v", err)
+			}
+
+			if string(content) != tc.content {
+				t.Fatalf("expected %d bytes got %d", len(tc.content), len(content))
+			}
+		})
+	}
+}
+
+func TestCompressAPIResponses(t *testing.T) {
+	t.Parallel()
+
+	platforms := []string{}
+	for range 100 {
+		platforms = append(platforms, "linux/amd64")
+	}
+
+	apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: &mockBuilder{}, Platforms: platforms}))
+	t.Cleanup(apiserver.Close)
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, apiserver.URL+"/platforms", nil)
+	if err != nil {
+		t.Fatalf("creating request %v", err)
+	}
+	// setting the header explicitly disables the transparent decompression of the client
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("making request %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected gzip encoding got %q", resp.Header.Get("Content-Encoding"))
+	}
+
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("decompressing response %v", err)
+	}
+
+	platformsResp := api.PlatformsResponse{}
+	if err = json.NewDecoder(gz).Decode(&platformsResp); err != nil {
+		t.Fatalf("decoding response %v", err)
+	}
+
+	if len(platformsResp.Platforms) != len(platforms) {
+		t.Fatalf("expected %d platforms got %d", len(platforms), len(platformsResp.Platforms))
+	}
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/api"
+)
+
+// idempotentRequest is the outcome of a request made with an idempotency key
+type idempotentRequest struct {
+	// identifies the content of the request, so the key is not reused for a different request
+	fingerprint string
+	// closed when the request is completed
+	done    chan struct{}
+	resp    api.BuildResponse
+	status  int
+	expires time.Time
+}
+
+// idempotencyKeys remembers the requests made with an idempotency key, so repeated requests
+// return the outcome of the first one instead of processing it again, until the key expires
+type idempotencyKeys struct {
+	mutex    sync.Mutex
+	ttl      time.Duration
+	requests map[string]*idempotentRequest
+}
+
+func newIdempotencyKeys(ttl time.Duration) *idempotencyKeys {
+	return &idempotencyKeys{
+		ttl:      ttl,
+		requests: map[string]*idempotentRequest{},
+	}
+}
+
+// start returns the request made with the key and true if this is the first request using it.
+// Otherwise, the request may be in progress and its outcome is available once it is done.
+func (k *idempotencyKeys) start(key string, fingerprint string) (*idempotentRequest, bool) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	now := time.Now()
+	for id, req := range k.requests {
+		if !req.expires.IsZero() && now.After(req.expires) {
+			delete(k.requests, id)
+		}
+	}
+
+	if req, found := k.requests[key]; found {
+		return req, false
+	}
+
+	req := &idempotentRequest{fingerprint: fingerprint, done: make(chan struct{})}
+	k.requests[key] = req
+
+	return req, true
+}
+
+// finish records the outcome of the request made with the key.
+// Failed requests are forgotten, so they can be retried using the same key.
+func (k *idempotencyKeys) finish(key string, req *idempotentRequest, resp api.BuildResponse, status int) {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	req.resp = resp
+	req.status = status
+	req.expires = time.Now().Add(k.ttl)
+	close(req.done)
+
+	if resp.Error != nil {
+		delete(k.requests, key)
+	}
+}
+
+// idempotentBuild processes a build request. If the request has an idempotency key that was already
+// used by the requester, returns the outcome of the previous request, waiting for it if in progress.
+// Reusing a key for a different request is rejected.
+func (a *APIServer) idempotentBuild(r *http.Request, req api.BuildRequest, verbose bool) (api.BuildResponse, int) {
+	key := r.Header.Get(api.IdempotencyKeyHeader)
+	if key == "" || a.idempotencyKeys == nil {
+		return a.build(r, req, verbose)
+	}
+
+	// keys are scoped to the requester
+	key = identity(r) + ":" + key
+
+	content, _ := json.Marshal(req) //nolint:errchkjson
+	fingerprint := fmt.Sprintf("%x:%t", sha256.Sum256(content), verbose)
+
+	previous, first := a.idempotencyKeys.start(key, fingerprint)
+	if first {
+		resp, status := a.build(r, req, verbose)
+		a.idempotencyKeys.finish(key, previous, resp, status)
+		return resp, status
+	}
+
+	resp := api.BuildResponse{}
+	if previous.fingerprint != fingerprint {
+		resp.Error = k6build.NewCodedError(
+			k6build.ErrorCodeInvalidRequest,
+			api.ErrInvalidRequest,
+			errors.New("idempotency key used for a different request"),
+		)
+		return resp, http.StatusBadRequest
+	}
+
+	a.logger(r).Debug("waiting for request with same idempotency key")
+
+	select {
+	case <-previous.done:
+		return previous.resp, previous.status
+	case <-r.Context().Done():
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, r.Context().Err())
+		return resp, http.StatusServiceUnavailable
+	}
+}
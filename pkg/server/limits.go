@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// errIdentityLimit signals the requester identity exceeded its limit of concurrent builds
+var errIdentityLimit = errors.New("limit of concurrent builds per identity exceeded")
+
+// buildLimiter limits the number of concurrent builds globally and per requester identity
+type buildLimiter struct {
+	// global build slots. Nil if there is no global limit
+	slots chan struct{}
+	// maximum concurrent builds per identity. 0 means no limit
+	maxPerIdentity int
+	mutex          sync.Mutex
+	active         map[string]int
+}
+
+func newBuildLimiter(maxBuilds int, maxPerIdentity int) *buildLimiter {
+	limiter := &buildLimiter{
+		maxPerIdentity: maxPerIdentity,
+		active:         map[string]int{},
+	}
+
+	if maxBuilds > 0 {
+		limiter.slots = make(chan struct{}, maxBuilds)
+	}
+
+	return limiter
+}
+
+// identity returns the identity of the requester, derived from its auth token.
+// Requests without a token share the same identity.
+func identity(r *http.Request) string {
+	token := r.Header.Get("Authorization")
+	if token == "" {
+		return ""
+	}
+
+	// avoid keeping tokens in memory
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// acquire obtains a build slot for the identity. Returns errIdentityLimit if the identity exceeded its limit.
+// If all global slots are in use, waits until one is released or the context is done, returning the
+// context's error in the latter case. The returned function must be called to release the slot.
+func (l *buildLimiter) acquire(ctx context.Context, id string) (func(), error) {
+	l.mutex.Lock()
+	if l.maxPerIdentity > 0 && l.active[id] >= l.maxPerIdentity {
+		l.mutex.Unlock()
+		return nil, errIdentityLimit
+	}
+	l.active[id]++
+	l.mutex.Unlock()
+
+	releaseIdentity := func() {
+		l.mutex.Lock()
+		defer l.mutex.Unlock()
+
+		l.active[id]--
+		if l.active[id] == 0 {
+			delete(l.active, id)
+		}
+	}
+
+	if l.slots == nil {
+		return releaseIdentity, nil
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		releaseIdentity()
+		return nil, ctx.Err()
+	}
+
+	return func() {
+		<-l.slots
+		releaseIdentity()
+	}, nil
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec is the OpenAPI 3 document describing the build service and the store server APIs.
+// It must be kept in sync with the request and response types of the api packages.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI implements the request handler for the OpenAPI document of the API
+func (a *APIServer) OpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPISpec)
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/api"
+	"github.com/grafana/k6build/pkg/store"
+	storeapi "github.com/grafana/k6build/pkg/store/api"
+)
+
+type openAPIDoc struct {
+	OpenAPI    string         `json:"openapi"`
+	Paths      map[string]any `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]any `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+// jsonFields returns the names of the fields of a struct in its json serialization
+func jsonFields(t reflect.Type) []string {
+	fields := []string{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		tag, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		switch {
+		case tag == "-" || !field.IsExported():
+			continue
+		case field.Anonymous && tag == "":
+			fields = append(fields, jsonFields(field.Type)...)
+		case tag == "":
+			fields = append(fields, field.Name)
+		default:
+			fields = append(fields, tag)
+		}
+	}
+
+	return fields
+}
+
+func propertyNames(properties map[string]any) []string {
+	names := []string{}
+	for name := range properties {
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// collectRefs returns the $ref attributes of the document
+func collectRefs(node any) []string {
+	refs := []string{}
+	switch n := node.(type) {
+	case map[string]any:
+		for key, value := range n {
+			if ref, ok := value.(string); ok && key == "$ref" {
+				refs = append(refs, ref)
+				continue
+			}
+			refs = append(refs, collectRefs(value)...)
+		}
+	case []any:
+		for _, value := range n {
+			refs = append(refs, collectRefs(value)...)
+		}
+	}
+
+	return refs
+}
+
+// TestOpenAPISchemas checks the schemas of the OpenAPI document are in sync with the api types
+func TestOpenAPISchemas(t *testing.T) {
+	t.Parallel()
+
+	doc := openAPIDoc{}
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("unmarshalling document %v", err)
+	}
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Fatalf("expected OpenAPI 3 document got %q", doc.OpenAPI)
+	}
+
+	types := map[string]reflect.Type{
+		"BuildRequest":         reflect.TypeFor[api.BuildRequest](),
+		"BuildResponse":        reflect.TypeFor[api.BuildResponse](),
+		"PlanRequest":          reflect.TypeFor[api.PlanRequest](),
+		"PlanResponse":         reflect.TypeFor[api.PlanResponse](),
+		"ResolveRequest":       reflect.TypeFor[api.ResolveRequest](),
+		"ResolveResponse":      reflect.TypeFor[api.ResolveResponse](),
+		"PlatformsResponse":    reflect.TypeFor[api.PlatformsResponse](),
+		"DependenciesResponse": reflect.TypeFor[api.DependenciesResponse](),
+		"VersionsResponse":     reflect.TypeFor[api.VersionsResponse](),
+		"BuildInfoResponse":    reflect.TypeFor[api.BuildInfoResponse](),
+		"SignatureResponse":    reflect.TypeFor[api.SignatureResponse](),
+		"VersionResponse":      reflect.TypeFor[api.VersionResponse](),
+		"Dependency":           reflect.TypeFor[k6build.Dependency](),
+		"Constraint":           reflect.TypeFor[k6build.Constraint](),
+		"Artifact":             reflect.TypeFor[k6build.Artifact](),
+		"BuildInfo":            reflect.TypeFor[k6build.BuildInfo](),
+		"BuildPlan":            reflect.TypeFor[k6build.BuildPlan](),
+		"DependencyResolution": reflect.TypeFor[k6build.DependencyResolution](),
+		"StoreResponse":        reflect.TypeFor[storeapi.StoreResponse](),
+		"Object":               reflect.TypeFor[store.Object](),
+	}
+
+	expected := map[string][]string{}
+	for name, typ := range types {
+		expected[name] = jsonFields(typ)
+	}
+
+	// errors have a custom serialization
+	wrapped, _ := json.Marshal(k6build.NewCodedError(
+		k6build.ErrorCodeBuildFailed,
+		api.ErrBuildFailed,
+		k6build.NewWrappedError(errors.New("error"), errors.New("reason")),
+	))
+	errorFields := map[string]any{}
+	_ = json.Unmarshal(wrapped, &errorFields)
+	expected["Error"] = propertyNames(errorFields)
+
+	for name, fields := range expected {
+		schema, found := doc.Components.Schemas[name]
+		if !found {
+			t.Errorf("schema %s not documented", name)
+			continue
+		}
+
+		properties := propertyNames(schema.Properties)
+		slices.Sort(properties)
+		slices.Sort(fields)
+		if !slices.Equal(properties, fields) {
+			t.Errorf("schema %s: expected properties %v got %v", name, fields, properties)
+		}
+	}
+
+	for name := range doc.Components.Schemas {
+		if _, found := expected[name]; !found {
+			t.Errorf("schema %s does not correspond to an api type", name)
+		}
+	}
+}
+
+func TestOpenAPIRefs(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]any{}
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("unmarshalling document %v", err)
+	}
+
+	for _, ref := range collectRefs(doc) {
+		var node any = doc
+		for _, elem := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			obj, ok := node.(map[string]any)
+			if !ok {
+				node = nil
+				break
+			}
+			node = obj[elem]
+		}
+
+		if node == nil {
+			t.Errorf("unresolved reference %s", ref)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	t.Parallel()
+
+	apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: &mockBuilder{}}))
+	t.Cleanup(apiserver.Close)
+
+	resp, err := http.Get(apiserver.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("requesting document %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	doc := openAPIDoc{}
+	if err = json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("decoding document %v", err)
+	}
+
+	for _, path := range []string{"/build", "/resolve", "/store/{id}", "/store/{id}/download"} {
+		if _, found := doc.Paths[path]; !found {
+			t.Errorf("path %s not documented", path)
+		}
+	}
+}
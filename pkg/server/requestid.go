@@ -0,0 +1,58 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/api"
+)
+
+// maximum length of the request ids accepted from the clients
+const maxRequestIDLength = 128
+
+// withRequestID identifies each request using the id given in the request, if valid, or a generated one.
+// The id is returned in the response and carried in the request's context.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(api.RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		w.Header().Set(api.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(k6build.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID checks the id is not empty and has only printable ascii characters, so it can be logged safely
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// logger returns the logger for the request, which identifies the request
+func (a *APIServer) logger(r *http.Request) *slog.Logger {
+	if id := k6build.RequestID(r.Context()); id != "" {
+		return a.log.With("request_id", id)
+	}
+
+	return a.log
+}
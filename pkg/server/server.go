@@ -4,24 +4,90 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/grafana/k6build"
 	"github.com/grafana/k6build/pkg/api"
+	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/grafana/k6build/pkg/httpserver"
+	"github.com/grafana/k6build/pkg/store"
+)
+
+const (
+	// DefaultMaxURLExpiration is the default maximum expiration that can be requested for download URLs
+	DefaultMaxURLExpiration = 7 * 24 * time.Hour
+	// DefaultMaxBatchSize is the default maximum number of requests in a batch
+	DefaultMaxBatchSize = 100
+	// DefaultBatchConcurrency is the default number of requests of a batch processed concurrently
+	DefaultBatchConcurrency = 4
+	// DefaultIdempotencyKeyTTL is the default time the outcome of a request with an idempotency key is kept
+	DefaultIdempotencyKeyTTL = 10 * time.Minute
+	// DefaultCompressMinSize is the default minimum size in bytes of the JSON responses compressed
+	DefaultCompressMinSize = 1024
 )
 
 // APIServerConfig defines the configuration for the APIServer
 type APIServerConfig struct {
 	BuildService k6build.BuildService
 	Log          *slog.Logger
+	// Maximum expiration that can be requested for the artifact's download URL.
+	// Longer expirations are limited to this value. Defaults to DefaultMaxURLExpiration
+	MaxURLExpiration time.Duration
+	// Maximum number of concurrent builds. Requests exceeding the limit wait for a build to complete.
+	// 0 means no limit.
+	MaxConcurrentBuilds int
+	// Maximum number of concurrent builds per requester identity, determined by its auth token.
+	// Requests exceeding the limit are rejected with status 429. 0 means no limit.
+	MaxConcurrentBuildsPerIdentity int
+	// Platforms supported by the server. Defaults to api.DefaultPlatforms
+	Platforms []string
+	// Time the catalog and resolve responses can be cached by clients and edge caches.
+	// If 0, caches must revalidate the responses before using them.
+	CacheMaxAge time.Duration
+	// Version information of the server, returned at /version
+	Version api.VersionResponse
+	// Maximum number of requests in a batch. Defaults to DefaultMaxBatchSize
+	MaxBatchSize int
+	// Number of requests of a batch processed concurrently. Defaults to DefaultBatchConcurrency
+	BatchConcurrency int
+	// Time the outcome of a build request with an idempotency key is kept, so repeated requests
+	// with the same key return it instead of building again. If 0, idempotency keys are ignored.
+	IdempotencyKeyTTL time.Duration
+	// Rewrites the download URL of the artifacts returned to the clients. Optional.
+	// If not set, the URL generated by the store is returned.
+	DownloadURLRewriter DownloadURLRewriter
+	// Decides if a request can build versions with build metadata and dependencies from a commit,
+	// if the build service allows them. Optional. If not set, the build service's setting applies.
+	BuildSemversAuthorizer BuildSemversAuthorizer
+	// Cross-origin requests allowed, for example from browser-based tools. Optional.
+	// If no origins are configured, cross-origin requests are not allowed.
+	CORS httpserver.CORSConfig
+	// Minimum size in bytes of the JSON responses compressed for clients that accept gzip.
+	// Defaults to DefaultCompressMinSize
+	CompressMinSize int
 }
 
 // APIServer defines a k6build API server
 type APIServer struct {
-	srv k6build.BuildService
-	log *slog.Logger
+	srv              k6build.BuildService
+	log              *slog.Logger
+	maxURLExpiration time.Duration
+	limiter          *buildLimiter
+	platforms        []string
+	cacheMaxAge      int
+	version          api.VersionResponse
+	maxBatchSize     int
+	batchConcurrency int
+	idempotencyKeys  *idempotencyKeys
+	urlRewriter      DownloadURLRewriter
+	authorizer       BuildSemversAuthorizer
 }
 
 // NewAPIServer creates a new build service API server
@@ -36,16 +102,71 @@ func NewAPIServer(config APIServerConfig) http.Handler {
 			),
 		)
 	}
+	maxURLExpiration := config.MaxURLExpiration
+	if maxURLExpiration == 0 {
+		maxURLExpiration = DefaultMaxURLExpiration
+	}
+
+	platforms := config.Platforms
+	if len(platforms) == 0 {
+		platforms = api.DefaultPlatforms
+	}
+
+	maxBatchSize := config.MaxBatchSize
+	if maxBatchSize <= 0 {
+		maxBatchSize = DefaultMaxBatchSize
+	}
+
+	batchConcurrency := config.BatchConcurrency
+	if batchConcurrency <= 0 {
+		batchConcurrency = DefaultBatchConcurrency
+	}
+
+	compressMinSize := config.CompressMinSize
+	if compressMinSize <= 0 {
+		compressMinSize = DefaultCompressMinSize
+	}
+
+	var keys *idempotencyKeys
+	if config.IdempotencyKeyTTL > 0 {
+		keys = newIdempotencyKeys(config.IdempotencyKeyTTL)
+	}
+
 	server := &APIServer{
-		srv: config.BuildService,
-		log: log,
+		srv:              config.BuildService,
+		log:              log,
+		maxURLExpiration: maxURLExpiration,
+		limiter:          newBuildLimiter(config.MaxConcurrentBuilds, config.MaxConcurrentBuildsPerIdentity),
+		platforms:        platforms,
+		cacheMaxAge:      int(config.CacheMaxAge.Seconds()),
+		version:          config.Version,
+		maxBatchSize:     maxBatchSize,
+		batchConcurrency: batchConcurrency,
+		idempotencyKeys:  keys,
+		urlRewriter:      config.DownloadURLRewriter,
+		authorizer:       config.BuildSemversAuthorizer,
 	}
 
 	handler := http.NewServeMux()
 	handler.HandleFunc("POST /build", server.Build)
+	handler.HandleFunc("POST /build/batch", server.BuildBatch)
+	handler.HandleFunc("POST /build/download", server.BuildDownload)
 	handler.HandleFunc("POST /resolve", server.Resolve)
+	handler.HandleFunc("POST /plan", server.Plan)
+	handler.HandleFunc("GET /platforms", server.Platforms)
+	handler.HandleFunc("GET /version", server.Version)
+	handler.HandleFunc("GET /openapi.json", server.OpenAPI)
+	handler.HandleFunc("GET /artifact/{id}/info", server.BuildInfo)
+	handler.HandleFunc("GET /artifact/{id}/sbom", server.SBOM)
+	handler.HandleFunc("GET /artifact/{id}/signature", server.Signature)
+	handler.HandleFunc("GET /catalog", server.Catalog)
+	handler.HandleFunc("GET /catalog/dependencies", server.Dependencies)
+	// dependency names contain "/" so they must be escaped (e.g. k6%2Fx%2Fkubernetes)
+	handler.HandleFunc("GET /catalog/dependencies/{name}/versions", server.Versions)
 
-	return handler
+	compress := compressor{minSize: compressMinSize}
+
+	return httpserver.CORS(config.CORS, withRequestID(compress.handler(handler)))
 }
 
 // Build implements the request handler for the build request
@@ -57,43 +178,613 @@ func (a *APIServer) Build(w http.ResponseWriter, r *http.Request) {
 	// ensure errors are reported and logged
 	defer func() {
 		if resp.Error != nil {
-			a.log.Error(resp.Error.Error())
+			a.logger(r).Error(resp.Error.Error())
+			resp.RequestID = k6build.RequestID(r.Context())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	req := api.BuildRequest{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
+		return
+	}
+
+	verbose, err := verboseParam(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
+		return
+	}
+
+	var status int
+	resp, status = a.idempotentBuild(r, req, verbose)
+	if resp.Error != nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	resp.RequestID = k6build.RequestID(r.Context())
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+}
+
+// BuildDownload implements the request handler for building an artifact and returning its content instead of
+// its details. The artifact is kept in the store as with any other build.
+func (a *APIServer) BuildDownload(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+
+	resp := api.BuildResponse{}
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			resp.RequestID = k6build.RequestID(r.Context())
 			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
 		}
 	}()
 
+	downloader, ok := a.srv.(k6build.ArtifactDownloader)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(
+			api.ErrRequestFailed,
+			errors.New("build service does not support downloading artifacts"),
+		)
+		return
+	}
+
 	req := api.BuildRequest{}
 	decoder := json.NewDecoder(r.Body)
 	decoder.DisallowUnknownFields()
 	err := decoder.Decode(&req)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		resp.Error = k6build.NewWrappedError(api.ErrInvalidRequest, err)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
+		return
+	}
+
+	var status int
+	resp, status = a.idempotentBuild(r, req, false)
+	if resp.Error != nil {
+		w.WriteHeader(status)
+		return
+	}
+
+	content, err := downloader.DownloadArtifact(r.Context(), resp.Artifact.ID)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+	defer content.Close() //nolint:errcheck
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set(api.ArtifactIDHeader, resp.Artifact.ID)
+	w.Header().Set(api.ArtifactChecksumHeader, resp.Artifact.Checksum)
+	if resp.Artifact.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(resp.Artifact.Size, 10))
+	}
+	w.WriteHeader(http.StatusOK)
+
+	// the status was already sent, so errors can only be logged
+	if _, err = io.Copy(w, content); err != nil {
+		a.logger(r).Error("sending artifact", "id", resp.Artifact.ID, "error", err.Error())
+	}
+}
+
+// BuildBatch implements the request handler for building a batch of independent requests.
+// The responses are returned in the same order as the requests. A failed request doesn't
+// affect the others and its response reports the error.
+func (a *APIServer) BuildBatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+
+	resp := api.BuildResponse{}
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			resp.RequestID = k6build.RequestID(r.Context())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	reqs := []api.BuildRequest{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&reqs)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
+		return
+	}
+
+	if len(reqs) > a.maxBatchSize {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = k6build.NewCodedError(
+			k6build.ErrorCodeInvalidRequest,
+			api.ErrInvalidRequest,
+			fmt.Errorf("batch exceeds the maximum of %d requests", a.maxBatchSize),
+		)
+		return
+	}
+
+	verbose, err := verboseParam(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
 		return
 	}
 
-	a.log.Debug("processing", "request", req.String())
+	// don't exceed the builds allowed for the requester, so requests are not rejected by the limiter
+	concurrency := a.batchConcurrency
+	if a.limiter.maxPerIdentity > 0 {
+		concurrency = min(concurrency, a.limiter.maxPerIdentity)
+	}
+
+	responses := make([]api.BuildResponse, len(reqs))
+	pending := make(chan int)
+	wg := sync.WaitGroup{}
+	for range min(concurrency, len(reqs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				responses[i], _ = a.build(r, reqs[i], verbose)
+				responses[i].RequestID = k6build.RequestID(r.Context())
+				if responses[i].Error != nil {
+					a.logger(r).Error(responses[i].Error.Error())
+				}
+			}
+		}()
+	}
+
+	for i := range reqs {
+		pending <- i
+	}
+	close(pending)
+	wg.Wait()
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(responses) //nolint:errchkjson
+}
+
+// build processes a build request and returns the response and its HTTP status
+func (a *APIServer) build(r *http.Request, req api.BuildRequest, verbose bool) (api.BuildResponse, int) {
+	resp := api.BuildResponse{}
+
+	err := req.Validate()
+	if err != nil {
+		resp.Error, _ = k6build.AsError(err)
+		return resp, http.StatusBadRequest
+	}
+
+	req.Dependencies, err = api.ConvertConstraints(req.Dependencies)
+	if err != nil {
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
+		return resp, http.StatusBadRequest
+	}
+
+	a.logger(r).Debug("processing", "request", req.String())
+
+	ctx := k6build.WithRequestID(context.Background(), k6build.RequestID(r.Context()))
+	ctx = a.authorize(k6build.WithBuildOptions(ctx, req.BuildOptions), r)
+	if req.URLExpiration != "" {
+		expiration, err := time.ParseDuration(req.URLExpiration)
+		if err != nil || expiration <= 0 {
+			resp.Error = k6build.NewCodedError(
+				k6build.ErrorCodeInvalidRequest,
+				api.ErrInvalidRequest,
+				fmt.Errorf("invalid url expiration %q", req.URLExpiration),
+			)
+			return resp, http.StatusBadRequest
+		}
+		ctx = store.WithURLExpiration(ctx, min(expiration, a.maxURLExpiration))
+	}
+
+	release, err := a.limiter.acquire(r.Context(), identity(r))
+	if errors.Is(err, errIdentityLimit) {
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeTooManyBuilds, api.ErrTooManyBuilds, err)
+		return resp, http.StatusTooManyRequests
+	}
+	// the request was cancelled while waiting for a build slot
+	if err != nil {
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return resp, http.StatusServiceUnavailable
+	}
+	defer release()
 
 	artifact, err := a.srv.Build( //nolint:contextcheck
-		context.Background(),
+		ctx,
 		req.Platform,
 		req.K6Constrains,
 		req.Dependencies,
 	)
 	if err != nil {
-		w.WriteHeader(http.StatusOK)
 		resp.Error = k6build.NewWrappedError(api.ErrBuildFailed, err)
-		return
+		return resp, errorStatus(err)
+	}
+
+	if a.urlRewriter != nil {
+		artifact.URL = a.urlRewriter(
+			store.Object{ID: artifact.ID, Checksum: artifact.Checksum, URL: artifact.URL},
+			r,
+		)
+		if artifact.SBOMURL != "" {
+			artifact.SBOMURL = a.urlRewriter(store.Object{ID: artifact.ID, URL: artifact.SBOMURL}, r)
+		}
 	}
 
 	resp.Artifact = artifact
 
-	a.log.Debug("returning", "response", resp.String())
+	// resolution details are optional, failing to obtain them doesn't fail the build request
+	if resolver, ok := a.srv.(k6build.DetailedResolver); verbose && ok {
+		resolution, err := resolver.ResolveDetails(ctx, req.K6Constrains, req.Dependencies) //nolint:contextcheck
+		if err != nil {
+			a.logger(r).Warn("resolving details", "error", err.Error())
+		}
+		resp.Resolution = resolution
+	}
+
+	a.logger(r).Debug("returning", "response", resp.String())
+
+	return resp, http.StatusOK
+}
+
+// verboseParam returns the value of the verbose query parameter. Defaults to false
+func verboseParam(r *http.Request) (bool, error) {
+	v := r.URL.Query().Get("verbose")
+	if v == "" {
+		return false, nil
+	}
+
+	verbose, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid verbose %q", v)
+	}
+
+	return verbose, nil
+}
+
+// Plan implements the request handler for the plan request
+func (a *APIServer) Plan(w http.ResponseWriter, r *http.Request) {
+	resp := api.PlanResponse{}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	planner, ok := a.srv.(k6build.Planner)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(api.ErrPlanFailed, errors.New("build service does not support plans"))
+		return
+	}
+
+	req := api.PlanRequest{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(&req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
+		return
+	}
+
+	err = req.Validate()
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error, _ = k6build.AsError(err)
+		return
+	}
+
+	req.Dependencies, err = api.ConvertConstraints(req.Dependencies)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
+		return
+	}
+
+	a.logger(r).Debug("processing", "request", req.String())
+
+	ctx := k6build.WithRequestID(context.Background(), k6build.RequestID(r.Context()))
+	plan, err := planner.Plan( //nolint:contextcheck
+		a.authorize(k6build.WithBuildOptions(ctx, req.BuildOptions), r),
+		req.Platform,
+		req.K6Constrains,
+		req.Dependencies,
+	)
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		resp.Error = k6build.NewWrappedError(api.ErrPlanFailed, err)
+		return
+	}
+
+	resp.Plan = plan
+
+	a.logger(r).Debug("returning", "response", resp.String())
 
 	w.WriteHeader(http.StatusOK)
 	_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
 }
 
+// Version implements the request handler for the version of the server
+func (a *APIServer) Version(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(a.version) //nolint:errchkjson
+}
+
+// Platforms implements the request handler for listing the supported platforms
+func (a *APIServer) Platforms(w http.ResponseWriter, _ *http.Request) {
+	resp := api.PlatformsResponse{Platforms: a.platforms}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+}
+
+// BuildInfo returns the build info of an artifact
+func (a *APIServer) BuildInfo(w http.ResponseWriter, r *http.Request) {
+	resp := api.BuildInfoResponse{ID: r.PathValue("id")}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	provider, ok := a.srv.(k6build.BuildInfoProvider)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(
+			api.ErrRequestFailed,
+			errors.New("build service does not support returning the build info"),
+		)
+		return
+	}
+
+	info, err := provider.BuildInfo(context.Background(), resp.ID) //nolint:contextcheck
+	if errors.Is(err, k6build.ErrUnknownArtifact) {
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = k6build.NewWrappedError(api.ErrUnknownArtifact, err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+
+	resp.BuildInfo = &info
+
+	a.writeCacheable(w, r, resp)
+}
+
+// SBOM returns the SBOM of an artifact
+func (a *APIServer) SBOM(w http.ResponseWriter, r *http.Request) {
+	// the SBOM is returned as is, so the response only has content on errors
+	resp := struct {
+		Error *k6build.WrappedError `json:"error,omitempty"`
+	}{}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	provider, ok := a.srv.(k6build.SBOMProvider)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(
+			api.ErrRequestFailed,
+			errors.New("build service does not support returning the SBOM"),
+		)
+		return
+	}
+
+	sbom, err := provider.SBOM(context.Background(), r.PathValue("id")) //nolint:contextcheck
+	if errors.Is(err, k6build.ErrUnknownArtifact) {
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = k6build.NewWrappedError(api.ErrUnknownArtifact, err)
+		return
+	}
+	if errors.Is(err, k6build.ErrSBOMNotAvailable) {
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+
+	a.writeCacheable(w, r, json.RawMessage(sbom))
+}
+
+// Signature returns the signature of an artifact
+func (a *APIServer) Signature(w http.ResponseWriter, r *http.Request) {
+	resp := api.SignatureResponse{ID: r.PathValue("id")}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	provider, ok := a.srv.(k6build.SignatureProvider)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(
+			api.ErrRequestFailed,
+			errors.New("build service does not support returning the signature"),
+		)
+		return
+	}
+
+	signature, err := provider.Signature(context.Background(), resp.ID) //nolint:contextcheck
+	if errors.Is(err, k6build.ErrUnknownArtifact) {
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = k6build.NewWrappedError(api.ErrUnknownArtifact, err)
+		return
+	}
+	if errors.Is(err, k6build.ErrArtifactNotSigned) {
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+
+	resp.Signature = signature
+
+	a.writeCacheable(w, r, resp)
+}
+
+// Catalog returns the catalog used by the build service for resolving dependencies
+func (a *APIServer) Catalog(w http.ResponseWriter, r *http.Request) {
+	// the catalog is returned as is, so the response only has content on errors
+	resp := struct {
+		Error *k6build.WrappedError `json:"error,omitempty"`
+	}{}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	provider, ok := a.srv.(k6build.CatalogProvider)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(
+			api.ErrRequestFailed,
+			errors.New("build service does not support returning the catalog"),
+		)
+		return
+	}
+
+	raw, err := provider.Catalog(context.Background()) //nolint:contextcheck
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+
+	a.writeCacheable(w, r, json.RawMessage(raw))
+}
+
+// Dependencies implements the request handler for listing the supported dependencies
+func (a *APIServer) Dependencies(w http.ResponseWriter, r *http.Request) {
+	resp := api.DependenciesResponse{}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	lister, ok := a.srv.(k6build.DependencyLister)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(
+			api.ErrRequestFailed,
+			errors.New("build service does not support listing dependencies"),
+		)
+		return
+	}
+
+	deps, err := lister.Dependencies(context.Background()) //nolint:contextcheck
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+
+	resp.Dependencies = deps
+
+	a.writeCacheable(w, r, resp)
+}
+
+// Versions implements the request handler for listing the supported versions of a dependency
+func (a *APIServer) Versions(w http.ResponseWriter, r *http.Request) {
+	resp := api.VersionsResponse{Name: r.PathValue("name")}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			a.logger(r).Error(resp.Error.Error())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	lister, ok := a.srv.(k6build.DependencyLister)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(
+			api.ErrRequestFailed,
+			errors.New("build service does not support listing dependencies"),
+		)
+		return
+	}
+
+	versions, err := lister.Versions(context.Background(), resp.Name) //nolint:contextcheck
+	if errors.Is(err, k6build.ErrUnknownDependency) {
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = k6build.NewWrappedError(api.ErrUnknownDependency, err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(errorStatus(err))
+		resp.Error = k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return
+	}
+
+	resp.Versions = versions
+
+	a.writeCacheable(w, r, resp)
+}
+
 // Resolve implements the request handler for the resolve request
 func (a *APIServer) Resolve(w http.ResponseWriter, r *http.Request) {
 	resp := api.ResolveResponse{}
@@ -103,7 +794,7 @@ func (a *APIServer) Resolve(w http.ResponseWriter, r *http.Request) {
 	// ensure errors are reported and logged
 	defer func() {
 		if resp.Error != nil {
-			a.log.Error(resp.Error.Error())
+			a.logger(r).Error(resp.Error.Error())
 			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
 		}
 	}()
@@ -115,26 +806,61 @@ func (a *APIServer) Resolve(w http.ResponseWriter, r *http.Request) {
 	// err := json.NewDecoder(r.Body).Decode(&req)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		resp.Error = k6build.NewWrappedError(api.ErrInvalidRequest, err)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
+		return
+	}
+
+	req.Dependencies, err = api.ConvertConstraints(req.Dependencies)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, api.ErrInvalidRequest, err)
 		return
 	}
 
-	a.log.Debug("processing", "request", req.String())
+	a.logger(r).Debug("processing", "request", req.String())
 
+	ctx := k6build.WithRequestID(context.Background(), k6build.RequestID(r.Context()))
+	ctx = k6build.WithBuildOptions(ctx, k6build.BuildOptions{IncludePrereleases: req.IncludePrereleases})
 	deps, err := a.srv.Resolve( //nolint:contextcheck
-		context.Background(),
+		a.authorize(ctx, r),
 		req.K6Constrains,
 		req.Dependencies,
 	)
 	if err != nil {
-		w.WriteHeader(http.StatusOK)
+		w.WriteHeader(errorStatus(err))
 		resp.Error = k6build.NewWrappedError(api.ErrResolveFailed, err)
 		return
 	}
 
-	a.log.Debug("returning", "response", resp.String())
+	a.logger(r).Debug("returning", "response", resp.String())
 
 	resp.Dependencies = deps
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+	a.writeCacheable(w, r, resp)
+}
+
+// errorStatus returns the HTTP status for an error reported by the build service.
+// The status is based on the code of the error, if any, or the errors in its reason chain.
+func errorStatus(err error) int {
+	switch k6build.ErrorCodeOf(err) {
+	case k6build.ErrorCodeInvalidPlatform, k6build.ErrorCodeInvalidRequest:
+		return http.StatusBadRequest
+	case k6build.ErrorCodeUnknownDependency, k6build.ErrorCodeCannotSatisfy:
+		return http.StatusUnprocessableEntity
+	case k6build.ErrorCodeTooManyBuilds:
+		return http.StatusTooManyRequests
+	case k6build.ErrorCodeDownloadFailed:
+		return http.StatusBadGateway
+	case k6build.ErrorCodeBuildFailed:
+		return http.StatusInternalServerError
+	}
+
+	switch {
+	case errors.Is(err, api.ErrInvalidRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, catalog.ErrCannotSatisfy), errors.Is(err, api.ErrCannotSatisfy),
+		errors.Is(err, k6build.ErrUnknownDependency):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
 }
@@ -2,18 +2,26 @@ package server
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/grafana/k6build"
 	"github.com/grafana/k6build/pkg/api"
+	"github.com/grafana/k6build/pkg/catalog"
+	"github.com/grafana/k6build/pkg/store"
 )
 
 type mockBuilder struct {
@@ -48,6 +56,25 @@ func (m mockBuilder) Resolve(
 	return m.deps, nil
 }
 
+// planBuilder is a mockBuilder that also implements the Planner interface
+type planBuilder struct {
+	mockBuilder
+	plan k6build.BuildPlan
+}
+
+func (m planBuilder) Plan(
+	_ context.Context,
+	_ string,
+	_ string,
+	_ []k6build.Dependency,
+) (k6build.BuildPlan, error) {
+	if m.err != nil {
+		return k6build.BuildPlan{}, m.err
+	}
+
+	return m.plan, nil
+}
+
 // extracts the Error field from the struct s using reflection
 // if the fields does not exist or is not of type error, returns nil
 func extractError(s any) error {
@@ -103,9 +130,53 @@ func TestAPI(t *testing.T) {
 			path:         "build",
 			req:          &api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"},
 			resp:         &api.BuildResponse{},
-			expectStatus: http.StatusOK,
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrBuildFailed,
+		},
+		{
+			title: "build error with code",
+			builder: mockBuilder{
+				err: k6build.NewCodedError(k6build.ErrorCodeCannotSatisfy, api.ErrCannotSatisfy, nil),
+			},
+			path:         "build",
+			req:          &api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"},
+			resp:         &api.BuildResponse{},
+			expectStatus: http.StatusUnprocessableEntity,
+			expectErr:    api.ErrCannotSatisfy,
+		},
+		{
+			title: "build error invalid platform",
+			builder: mockBuilder{
+				err: k6build.NewCodedError(k6build.ErrorCodeInvalidPlatform, api.ErrInvalidRequest, nil),
+			},
+			path:         "build",
+			req:          &api.BuildRequest{Platform: "linux", K6Constrains: "v0.1.0"},
+			resp:         &api.BuildResponse{},
+			expectStatus: http.StatusBadRequest,
+			expectErr:    api.ErrInvalidRequest,
+		},
+		{
+			title: "build error downloading dependencies",
+			builder: mockBuilder{
+				err: k6build.NewCodedError(k6build.ErrorCodeDownloadFailed, k6build.ErrBuildFailed, nil),
+			},
+			path:         "build",
+			req:          &api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"},
+			resp:         &api.BuildResponse{},
+			expectStatus: http.StatusBadGateway,
 			expectErr:    api.ErrBuildFailed,
 		},
+		{
+			title: "build error unsatisfied constrains",
+			builder: mockBuilder{
+				err: k6build.NewWrappedError(k6build.ErrBuildFailed, catalog.ErrCannotSatisfy),
+			},
+			path:         "build",
+			req:          &api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"},
+			resp:         &api.BuildResponse{},
+			expectStatus: http.StatusUnprocessableEntity,
+			expectErr:    api.ErrCannotSatisfy,
+		},
 		{
 			title: "invalid build request (empty request object)",
 			builder: mockBuilder{
@@ -150,7 +221,7 @@ func TestAPI(t *testing.T) {
 			path:         "resolve",
 			req:          &api.ResolveRequest{K6Constrains: "v0.1.0"},
 			resp:         &api.ResolveResponse{},
-			expectStatus: http.StatusOK,
+			expectStatus: http.StatusUnprocessableEntity,
 			expectErr:    api.ErrCannotSatisfy,
 		},
 		{
@@ -175,6 +246,53 @@ func TestAPI(t *testing.T) {
 			expectStatus:  http.StatusBadRequest,
 			expectErr:     nil,
 		},
+		{
+			title: "plan request",
+			builder: planBuilder{
+				plan: k6build.BuildPlan{ID: "id", Platform: "linux/amd64", Cached: true},
+			},
+			path: "plan",
+			req:  &api.PlanRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"},
+			resp: &api.PlanResponse{},
+			expectReponse: &api.PlanResponse{
+				Plan: k6build.BuildPlan{ID: "id", Platform: "linux/amd64", Cached: true},
+			},
+			expectStatus: http.StatusOK,
+			expectErr:    nil,
+		},
+		{
+			title: "plan error",
+			builder: planBuilder{
+				mockBuilder: mockBuilder{err: api.ErrCannotSatisfy},
+			},
+			path:         "plan",
+			req:          &api.PlanRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"},
+			resp:         &api.PlanResponse{},
+			expectStatus: http.StatusUnprocessableEntity,
+			expectErr:    api.ErrPlanFailed,
+		},
+		{
+			title: "invalid plan request",
+			builder: planBuilder{
+				plan: k6build.BuildPlan{ID: "id", Platform: "linux/amd64", Cached: true},
+			},
+			path:         "plan",
+			req:          &api.PlanRequest{Platform: "linux-amd64", K6Constrains: "v0.1.0"},
+			resp:         &api.PlanResponse{},
+			expectStatus: http.StatusBadRequest,
+			expectErr:    api.ErrInvalidRequest,
+		},
+		{
+			title: "plan not supported",
+			builder: mockBuilder{
+				deps: map[string]string{"k6": "v0.1.0"},
+			},
+			path:         "plan",
+			req:          &api.PlanRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"},
+			resp:         &api.PlanResponse{},
+			expectStatus: http.StatusNotImplemented,
+			expectErr:    nil,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -206,8 +324,8 @@ func TestAPI(t *testing.T) {
 				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
 			}
 
-			// if non 200 response is expected, don't validate response
-			if tc.expectStatus != http.StatusOK {
+			// if non 200 response is expected and no error, don't validate response
+			if tc.expectStatus != http.StatusOK && tc.expectErr == nil {
 				return
 			}
 
@@ -227,10 +345,2081 @@ func TestAPI(t *testing.T) {
 				return
 			}
 
-			if !cmp.Equal(tc.resp, tc.expectReponse) {
-				t.Fatalf("%s", cmp.Diff(tc.resp, tc.expectReponse))
+			// request ids are generated by the server
+			ignoreID := cmpopts.IgnoreFields(api.BuildResponse{}, "RequestID")
+			if !cmp.Equal(tc.resp, tc.expectReponse, ignoreID) {
+				t.Fatalf("%s", cmp.Diff(tc.resp, tc.expectReponse, ignoreID))
 				// t.Fatalf("expected %v got %v", tc.expectReponse, tc.resp)
 			}
 		})
 	}
 }
+
+// expirationBuilder records the download URL expiration requested in the context
+type expirationBuilder struct {
+	mockBuilder
+	expiration chan time.Duration
+}
+
+func (m expirationBuilder) Build(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.Artifact, error) {
+	expiration, _ := store.URLExpiration(ctx)
+	m.expiration <- expiration
+	return m.mockBuilder.Build(ctx, platform, k6Constrains, deps)
+}
+
+func TestURLExpiration(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		expiration   string
+		expectStatus int
+		expect       time.Duration
+	}{
+		{
+			title:        "no expiration",
+			expiration:   "",
+			expectStatus: http.StatusOK,
+			expect:       0,
+		},
+		{
+			title:        "expiration under maximum",
+			expiration:   "15m",
+			expectStatus: http.StatusOK,
+			expect:       15 * time.Minute,
+		},
+		{
+			title:        "expiration over maximum",
+			expiration:   "48h",
+			expectStatus: http.StatusOK,
+			expect:       24 * time.Hour,
+		},
+		{
+			title:        "zero expiration",
+			expiration:   "0s",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			title:        "negative expiration",
+			expiration:   "-1h",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			title:        "invalid expiration",
+			expiration:   "tomorrow",
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			builder := expirationBuilder{
+				mockBuilder: mockBuilder{deps: map[string]string{"k6": "v0.1.0"}},
+				expiration:  make(chan time.Duration, 1),
+			}
+			config := APIServerConfig{
+				BuildService:     builder,
+				MaxURLExpiration: 24 * time.Hour,
+			}
+			apiserver := httptest.NewServer(NewAPIServer(config))
+			t.Cleanup(apiserver.Close)
+
+			req := &bytes.Buffer{}
+			err := json.NewEncoder(req).Encode(api.BuildRequest{
+				Platform:      "linux/amd64",
+				K6Constrains:  "v0.1.0",
+				URLExpiration: tc.expiration,
+			})
+			if err != nil {
+				t.Fatalf("encoding request %v", err)
+			}
+
+			resp, err := http.Post(apiserver.URL+"/build", "application/json", req)
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			if tc.expectStatus != http.StatusOK {
+				return
+			}
+
+			if expiration := <-builder.expiration; expiration != tc.expect {
+				t.Fatalf("expected expiration %s got %s", tc.expect, expiration)
+			}
+		})
+	}
+}
+
+// blockingBuilder blocks builds for the "block" k6 constrains until unblock is closed
+type blockingBuilder struct {
+	mockBuilder
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (m blockingBuilder) Build(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.Artifact, error) {
+	if k6Constrains == "block" {
+		m.started <- struct{}{}
+		<-m.unblock
+	}
+
+	return m.mockBuilder.Build(ctx, platform, k6Constrains, deps)
+}
+
+func TestConcurrentBuildsPerIdentity(t *testing.T) {
+	t.Parallel()
+
+	builder := blockingBuilder{
+		started: make(chan struct{}),
+		unblock: make(chan struct{}),
+	}
+
+	apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{
+		BuildService:                   builder,
+		MaxConcurrentBuilds:            2,
+		MaxConcurrentBuildsPerIdentity: 1,
+	}))
+	t.Cleanup(apiserver.Close)
+
+	build := func(token string, k6 string) (int, error) {
+		body := &bytes.Buffer{}
+		_ = json.NewEncoder(body).Encode(api.BuildRequest{Platform: "linux/amd64", K6Constrains: k6})
+
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, apiserver.URL+"/build", body)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close() //nolint:errcheck
+
+		buildResp := api.BuildResponse{}
+		_ = json.NewDecoder(resp.Body).Decode(&buildResp)
+		if buildResp.Error != nil {
+			return resp.StatusCode, buildResp.Error
+		}
+
+		return resp.StatusCode, nil
+	}
+
+	// identity "a" uses its only build slot
+	done := make(chan error)
+	go func() {
+		_, err := build("a", "block")
+		done <- err
+	}()
+	<-builder.started
+
+	status, err := build("a", "v0.1.0")
+	if status != http.StatusTooManyRequests || !errors.Is(err, api.ErrTooManyBuilds) {
+		t.Fatalf("expected %d %v got %d %v", http.StatusTooManyRequests, api.ErrTooManyBuilds, status, err)
+	}
+
+	// other identities are not affected
+	status, err = build("b", "v0.1.0")
+	if status != http.StatusOK || err != nil {
+		t.Fatalf("expected %d got %d %v", http.StatusOK, status, err)
+	}
+
+	close(builder.unblock)
+	if err = <-done; err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	// once the build completed, the identity can build again
+	status, err = build("a", "v0.1.0")
+	if status != http.StatusOK || err != nil {
+		t.Fatalf("expected %d got %d %v", http.StatusOK, status, err)
+	}
+}
+
+func TestBuildLimiterCancelled(t *testing.T) {
+	t.Parallel()
+
+	limiter := newBuildLimiter(1, 0)
+
+	release, err := limiter.acquire(context.TODO(), "a")
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	defer release()
+
+	// waiting for a global slot is not an identity limit
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+
+	_, err = limiter.acquire(ctx, "b")
+	if !errors.Is(err, context.Canceled) || errors.Is(err, errIdentityLimit) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+}
+
+// catalogBuilder is a mockBuilder that also lists the supported dependencies
+type catalogBuilder struct {
+	mockBuilder
+	catalog  []string
+	versions map[string][]string
+	raw      []byte
+}
+
+func (m catalogBuilder) Catalog(_ context.Context) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.raw, nil
+}
+
+func (m catalogBuilder) Dependencies(_ context.Context) ([]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	return m.catalog, nil
+}
+
+func (m catalogBuilder) Versions(_ context.Context, name string) ([]string, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	versions, found := m.versions[name]
+	if !found {
+		return nil, k6build.NewWrappedError(k6build.ErrUnknownDependency, errors.New(name))
+	}
+
+	return versions, nil
+}
+
+func TestDependencies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		builder      k6build.BuildService
+		expectStatus int
+		expect       []string
+		expectErr    error
+	}{
+		{
+			title:        "list dependencies",
+			builder:      catalogBuilder{catalog: []string{"k6", "k6/x/ext"}},
+			expectStatus: http.StatusOK,
+			expect:       []string{"k6", "k6/x/ext"},
+		},
+		{
+			title:        "error listing dependencies",
+			builder:      catalogBuilder{mockBuilder: mockBuilder{err: errors.New("catalog error")}},
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrRequestFailed,
+		},
+		{
+			title:        "listing not supported",
+			builder:      mockBuilder{},
+			expectStatus: http.StatusNotImplemented,
+			expectErr:    api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: tc.builder}))
+			t.Cleanup(apiserver.Close)
+
+			resp, err := http.Get(apiserver.URL + "/catalog/dependencies")
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			depsResp := api.DependenciesResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&depsResp)
+			if err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if tc.expectErr != nil {
+				if !errors.Is(depsResp.Error, tc.expectErr) {
+					t.Fatalf("expected error: %q got %q", tc.expectErr, depsResp.Error)
+				}
+				return
+			}
+
+			if !cmp.Equal(depsResp.Dependencies, tc.expect) {
+				t.Fatalf("%s", cmp.Diff(tc.expect, depsResp.Dependencies))
+			}
+		})
+	}
+}
+
+func TestCatalog(t *testing.T) {
+	t.Parallel()
+
+	raw := []byte(`{"k6":{"module":"go.k6.io/k6","versions":["v1.0.0"]}}`)
+
+	testCases := []struct {
+		title        string
+		builder      k6build.BuildService
+		expectStatus int
+		expectErr    error
+	}{
+		{
+			title:        "get catalog",
+			builder:      catalogBuilder{raw: raw},
+			expectStatus: http.StatusOK,
+		},
+		{
+			title:        "error getting catalog",
+			builder:      catalogBuilder{mockBuilder: mockBuilder{err: errors.New("catalog error")}},
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrRequestFailed,
+		},
+		{
+			title:        "catalog not supported",
+			builder:      mockBuilder{},
+			expectStatus: http.StatusNotImplemented,
+			expectErr:    api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: tc.builder}))
+			t.Cleanup(apiserver.Close)
+
+			resp, err := http.Get(apiserver.URL + "/catalog")
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			if tc.expectErr != nil {
+				errResp := api.DependenciesResponse{}
+				err = json.NewDecoder(resp.Body).Decode(&errResp)
+				if err != nil {
+					t.Fatalf("decoding response %v", err)
+				}
+				if !errors.Is(errResp.Error, tc.expectErr) {
+					t.Fatalf("expected error: %q got %q", tc.expectErr, errResp.Error)
+				}
+				return
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("reading response %v", err)
+			}
+
+			if !bytes.Equal(bytes.TrimSpace(body), raw) {
+				t.Fatalf("expected %s got %s", raw, body)
+			}
+
+			if resp.Header.Get("ETag") == "" {
+				t.Fatalf("expected ETag header")
+			}
+		})
+	}
+}
+
+// buildInfoBuilder is a mockBuilder that also returns the build info of its artifacts
+type buildInfoBuilder struct {
+	mockBuilder
+	infos map[string]k6build.BuildInfo
+}
+
+func (m buildInfoBuilder) BuildInfo(_ context.Context, id string) (k6build.BuildInfo, error) {
+	if m.err != nil {
+		return k6build.BuildInfo{}, m.err
+	}
+
+	info, found := m.infos[id]
+	if !found {
+		return k6build.BuildInfo{}, k6build.NewWrappedError(k6build.ErrUnknownArtifact, errors.New(id))
+	}
+
+	return info, nil
+}
+
+func TestBuildInfo(t *testing.T) {
+	t.Parallel()
+
+	info := k6build.BuildInfo{GoVersion: "go1.24.0", Modules: map[string]string{"go.k6.io/k6": "v1.0.0"}}
+
+	testCases := []struct {
+		title        string
+		builder      k6build.BuildService
+		id           string
+		expectStatus int
+		expect       *k6build.BuildInfo
+		expectErr    error
+	}{
+		{
+			title:        "get build info",
+			builder:      buildInfoBuilder{infos: map[string]k6build.BuildInfo{"artifact": info}},
+			id:           "artifact",
+			expectStatus: http.StatusOK,
+			expect:       &info,
+		},
+		{
+			title:        "unknown artifact",
+			builder:      buildInfoBuilder{},
+			id:           "unknown",
+			expectStatus: http.StatusNotFound,
+			expectErr:    api.ErrUnknownArtifact,
+		},
+		{
+			title:        "error getting build info",
+			builder:      buildInfoBuilder{mockBuilder: mockBuilder{err: errors.New("store error")}},
+			id:           "artifact",
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrRequestFailed,
+		},
+		{
+			title:        "build info not supported",
+			builder:      mockBuilder{},
+			id:           "artifact",
+			expectStatus: http.StatusNotImplemented,
+			expectErr:    api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: tc.builder}))
+			t.Cleanup(apiserver.Close)
+
+			resp, err := http.Get(apiserver.URL + "/artifact/" + tc.id + "/info")
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			infoResp := api.BuildInfoResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&infoResp)
+			if err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if tc.expectErr != nil {
+				if !errors.Is(infoResp.Error, tc.expectErr) {
+					t.Fatalf("expected error: %q got %q", tc.expectErr, infoResp.Error)
+				}
+				return
+			}
+
+			if !cmp.Equal(infoResp.BuildInfo, tc.expect) {
+				t.Fatalf("%s", cmp.Diff(tc.expect, infoResp.BuildInfo))
+			}
+		})
+	}
+}
+
+// sbomBuilder is a mockBuilder that also returns the SBOM of its artifacts
+type sbomBuilder struct {
+	mockBuilder
+	sboms map[string][]byte
+}
+
+func (m sbomBuilder) SBOM(_ context.Context, id string) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	sbom, found := m.sboms[id]
+	if !found {
+		return nil, k6build.NewWrappedError(k6build.ErrUnknownArtifact, errors.New(id))
+	}
+	if sbom == nil {
+		return nil, k6build.NewWrappedError(k6build.ErrSBOMNotAvailable, errors.New(id))
+	}
+
+	return sbom, nil
+}
+
+func TestSBOM(t *testing.T) {
+	t.Parallel()
+
+	sbom := []byte(`{"bomFormat":"CycloneDX","specVersion":"1.5"}`)
+	builder := sbomBuilder{sboms: map[string][]byte{"artifact": sbom, "nosbom": nil}}
+
+	testCases := []struct {
+		title        string
+		builder      k6build.BuildService
+		id           string
+		expectStatus int
+		expectErr    error
+	}{
+		{
+			title:        "get sbom",
+			builder:      builder,
+			id:           "artifact",
+			expectStatus: http.StatusOK,
+		},
+		{
+			title:        "unknown artifact",
+			builder:      builder,
+			id:           "unknown",
+			expectStatus: http.StatusNotFound,
+			expectErr:    api.ErrUnknownArtifact,
+		},
+		{
+			title:        "sbom not available",
+			builder:      builder,
+			id:           "nosbom",
+			expectStatus: http.StatusNotFound,
+			expectErr:    k6build.ErrSBOMNotAvailable,
+		},
+		{
+			title:        "error getting sbom",
+			builder:      sbomBuilder{mockBuilder: mockBuilder{err: errors.New("store error")}},
+			id:           "artifact",
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrRequestFailed,
+		},
+		{
+			title:        "sbom not supported",
+			builder:      mockBuilder{},
+			id:           "artifact",
+			expectStatus: http.StatusNotImplemented,
+			expectErr:    api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: tc.builder}))
+			t.Cleanup(apiserver.Close)
+
+			resp, err := http.Get(apiserver.URL + "/artifact/" + tc.id + "/sbom")
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("reading response %v", err)
+			}
+
+			if tc.expectErr != nil {
+				errResp := api.BuildInfoResponse{}
+				if err = json.Unmarshal(body, &errResp); err != nil {
+					t.Fatalf("decoding response %v", err)
+				}
+				if !errors.Is(errResp.Error, tc.expectErr) {
+					t.Fatalf("expected error: %q got %q", tc.expectErr, errResp.Error)
+				}
+				return
+			}
+
+			if !bytes.Equal(bytes.TrimSpace(body), sbom) {
+				t.Fatalf("expected %s got %s", sbom, body)
+			}
+		})
+	}
+}
+
+// signatureBuilder is a mockBuilder that also returns the signature of its artifacts
+type signatureBuilder struct {
+	mockBuilder
+	signatures map[string]string
+}
+
+func (m signatureBuilder) Signature(_ context.Context, id string) (string, error) {
+	if m.err != nil {
+		return "", m.err
+	}
+
+	signature, found := m.signatures[id]
+	if !found {
+		return "", k6build.NewWrappedError(k6build.ErrUnknownArtifact, errors.New(id))
+	}
+	if signature == "" {
+		return "", k6build.NewWrappedError(k6build.ErrArtifactNotSigned, errors.New(id))
+	}
+
+	return signature, nil
+}
+
+func TestSignature(t *testing.T) {
+	t.Parallel()
+
+	builder := signatureBuilder{signatures: map[string]string{"artifact": "c2lnbmF0dXJl", "unsigned": ""}}
+
+	testCases := []struct {
+		title        string
+		builder      k6build.BuildService
+		id           string
+		expectStatus int
+		expect       string
+		expectErr    error
+	}{
+		{
+			title:        "get signature",
+			builder:      builder,
+			id:           "artifact",
+			expectStatus: http.StatusOK,
+			expect:       "c2lnbmF0dXJl",
+		},
+		{
+			title:        "unknown artifact",
+			builder:      builder,
+			id:           "unknown",
+			expectStatus: http.StatusNotFound,
+			expectErr:    api.ErrUnknownArtifact,
+		},
+		{
+			title:        "artifact not signed",
+			builder:      builder,
+			id:           "unsigned",
+			expectStatus: http.StatusNotFound,
+			expectErr:    k6build.ErrArtifactNotSigned,
+		},
+		{
+			title:        "error getting signature",
+			builder:      signatureBuilder{mockBuilder: mockBuilder{err: errors.New("store error")}},
+			id:           "artifact",
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrRequestFailed,
+		},
+		{
+			title:        "signature not supported",
+			builder:      mockBuilder{},
+			id:           "artifact",
+			expectStatus: http.StatusNotImplemented,
+			expectErr:    api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: tc.builder}))
+			t.Cleanup(apiserver.Close)
+
+			resp, err := http.Get(apiserver.URL + "/artifact/" + tc.id + "/signature")
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			signatureResp := api.SignatureResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&signatureResp)
+			if err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if tc.expectErr != nil {
+				if !errors.Is(signatureResp.Error, tc.expectErr) {
+					t.Fatalf("expected error: %q got %q", tc.expectErr, signatureResp.Error)
+				}
+				return
+			}
+
+			if signatureResp.Signature != tc.expect {
+				t.Fatalf("expected signature %q got %q", tc.expect, signatureResp.Signature)
+			}
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	t.Parallel()
+
+	builder := catalogBuilder{versions: map[string][]string{"k6/x/ext": {"v0.1.0", "v0.2.0"}}}
+
+	testCases := []struct {
+		title        string
+		builder      k6build.BuildService
+		path         string
+		expectStatus int
+		expect       []string
+		expectErr    error
+	}{
+		{
+			title:        "list versions",
+			builder:      builder,
+			path:         "/catalog/dependencies/k6%2Fx%2Fext/versions",
+			expectStatus: http.StatusOK,
+			expect:       []string{"v0.1.0", "v0.2.0"},
+		},
+		{
+			title:        "unknown dependency",
+			builder:      builder,
+			path:         "/catalog/dependencies/k6%2Fx%2Funknown/versions",
+			expectStatus: http.StatusNotFound,
+			expectErr:    api.ErrUnknownDependency,
+		},
+		{
+			title:        "error listing versions",
+			builder:      catalogBuilder{mockBuilder: mockBuilder{err: errors.New("catalog error")}},
+			path:         "/catalog/dependencies/k6%2Fx%2Fext/versions",
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrRequestFailed,
+		},
+		{
+			title:        "listing not supported",
+			builder:      mockBuilder{},
+			path:         "/catalog/dependencies/k6%2Fx%2Fext/versions",
+			expectStatus: http.StatusNotImplemented,
+			expectErr:    api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: tc.builder}))
+			t.Cleanup(apiserver.Close)
+
+			resp, err := http.Get(apiserver.URL + tc.path)
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			versionsResp := api.VersionsResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&versionsResp)
+			if err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if tc.expectErr != nil {
+				if !errors.Is(versionsResp.Error, tc.expectErr) {
+					t.Fatalf("expected error: %q got %q", tc.expectErr, versionsResp.Error)
+				}
+				return
+			}
+
+			if !cmp.Equal(versionsResp.Versions, tc.expect) {
+				t.Fatalf("%s", cmp.Diff(tc.expect, versionsResp.Versions))
+			}
+		})
+	}
+}
+
+// resolutionBuilder is a mockBuilder that also describes the resolution of the dependencies
+func TestCacheableResponses(t *testing.T) {
+	t.Parallel()
+
+	// get returns the response to a request to the path with the given headers
+	get := func(t *testing.T, srvURL string, path string, headers map[string]string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequestWithContext(context.TODO(), http.MethodGet, srvURL+path, nil)
+		if err != nil {
+			t.Fatalf("creating request %v", err)
+		}
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("making request %v", err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		return resp
+	}
+
+	builder := catalogBuilder{catalog: []string{"k6", "k6/x/ext"}}
+	apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{
+		BuildService: builder,
+		CacheMaxAge:  time.Minute,
+		// the cacheable responses are compressed as any other response
+		CompressMinSize: 1,
+	}))
+	t.Cleanup(apiserver.Close)
+
+	resp := get(t, apiserver.URL, "/catalog/dependencies", map[string]string{"Accept-Encoding": "gzip"})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code: %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if cc := resp.Header.Get("Cache-Control"); cc != "public, max-age=60" {
+		t.Fatalf("expected cache control %q got %q", "public, max-age=60", cc)
+	}
+
+	if ce := resp.Header.Get("Content-Encoding"); ce != "gzip" {
+		t.Fatalf("expected content encoding %q got %q", "gzip", ce)
+	}
+
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("etag not set")
+	}
+
+	body, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("reading compressed body %v", err)
+	}
+	depsResp := api.DependenciesResponse{}
+	err = json.NewDecoder(body).Decode(&depsResp)
+	if err != nil {
+		t.Fatalf("decoding response %v", err)
+	}
+	if !cmp.Equal(depsResp.Dependencies, builder.catalog) {
+		t.Fatalf("%s", cmp.Diff(builder.catalog, depsResp.Dependencies))
+	}
+
+	// the same content is not sent again
+	resp = get(t, apiserver.URL, "/catalog/dependencies", map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected status code: %d got %d", http.StatusNotModified, resp.StatusCode)
+	}
+
+	// responses to requests with credentials are not stored by shared caches
+	resp = get(t, apiserver.URL, "/catalog/dependencies", map[string]string{"Authorization": "Bearer token"})
+	if cc := resp.Header.Get("Cache-Control"); cc != "private, max-age=60" {
+		t.Fatalf("expected cache control %q got %q", "private, max-age=60", cc)
+	}
+
+	// the etag changes when the catalog changes
+	changed := catalogBuilder{catalog: []string{"k6", "k6/x/ext", "k6/x/other"}}
+	changedServer := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: changed}))
+	t.Cleanup(changedServer.Close)
+
+	resp = get(t, changedServer.URL, "/catalog/dependencies", map[string]string{"If-None-Match": etag})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code: %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if changedEtag := resp.Header.Get("ETag"); changedEtag == "" || changedEtag == etag {
+		t.Fatalf("expected etag to change, got %q", changedEtag)
+	}
+
+	if cc := resp.Header.Get("Cache-Control"); cc != "no-cache" {
+		t.Fatalf("expected cache control %q got %q", "no-cache", cc)
+	}
+}
+
+type resolutionBuilder struct {
+	mockBuilder
+	resolution []k6build.DependencyResolution
+}
+
+func (m resolutionBuilder) ResolveDetails(
+	_ context.Context,
+	_ string,
+	_ []k6build.Dependency,
+) ([]k6build.DependencyResolution, error) {
+	return m.resolution, nil
+}
+
+func TestVerboseBuild(t *testing.T) {
+	t.Parallel()
+
+	resolution := []k6build.DependencyResolution{
+		{Name: "k6", Constraints: "*", Version: "v0.2.0", Latest: true, Available: []string{"v0.1.0", "v0.2.0"}},
+	}
+
+	testCases := []struct {
+		title        string
+		builder      k6build.BuildService
+		query        string
+		expectStatus int
+		expect       []k6build.DependencyResolution
+	}{
+		{
+			title:        "verbose build",
+			builder:      resolutionBuilder{resolution: resolution},
+			query:        "?verbose=true",
+			expectStatus: http.StatusOK,
+			expect:       resolution,
+		},
+		{
+			title:        "non verbose build",
+			builder:      resolutionBuilder{resolution: resolution},
+			query:        "",
+			expectStatus: http.StatusOK,
+			expect:       nil,
+		},
+		{
+			title:        "resolution details not supported",
+			builder:      mockBuilder{},
+			query:        "?verbose=true",
+			expectStatus: http.StatusOK,
+			expect:       nil,
+		},
+		{
+			title:        "invalid verbose",
+			builder:      resolutionBuilder{resolution: resolution},
+			query:        "?verbose=maybe",
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: tc.builder}))
+			t.Cleanup(apiserver.Close)
+
+			req := &bytes.Buffer{}
+			err := json.NewEncoder(req).Encode(api.BuildRequest{Platform: "linux/amd64", K6Constrains: "*"})
+			if err != nil {
+				t.Fatalf("encoding request %v", err)
+			}
+
+			resp, err := http.Post(apiserver.URL+"/build"+tc.query, "application/json", req)
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			if tc.expectStatus != http.StatusOK {
+				return
+			}
+
+			buildResp := api.BuildResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&buildResp)
+			if err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if !cmp.Equal(buildResp.Resolution, tc.expect) {
+				t.Fatalf("%s", cmp.Diff(tc.expect, buildResp.Resolution))
+			}
+		})
+	}
+}
+
+func TestPlatforms(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		platforms []string
+		expect    []string
+	}{
+		{
+			title:  "default platforms",
+			expect: api.DefaultPlatforms,
+		},
+		{
+			title:     "configured platforms",
+			platforms: []string{"linux/amd64"},
+			expect:    []string{"linux/amd64"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			config := APIServerConfig{BuildService: mockBuilder{}, Platforms: tc.platforms}
+			apiserver := httptest.NewServer(NewAPIServer(config))
+			t.Cleanup(apiserver.Close)
+
+			resp, err := http.Get(apiserver.URL + "/platforms")
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status code: %d got %d", http.StatusOK, resp.StatusCode)
+			}
+
+			platformsResp := api.PlatformsResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&platformsResp)
+			if err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if !cmp.Equal(platformsResp.Platforms, tc.expect) {
+				t.Fatalf("%s", cmp.Diff(tc.expect, platformsResp.Platforms))
+			}
+		})
+	}
+}
+
+// catalogResolver is a mockBuilder that resolves the dependencies using a catalog
+type catalogResolver struct {
+	mockBuilder
+	catalog catalog.Catalog
+}
+
+func (m catalogResolver) Resolve(
+	ctx context.Context,
+	_ string,
+	deps []k6build.Dependency,
+) (map[string]string, error) {
+	resolved := map[string]string{}
+	for _, dep := range deps {
+		mod, err := m.catalog.Resolve(ctx, catalog.Dependency{Name: dep.Name, Constrains: dep.Constraints})
+		if err != nil {
+			return nil, err
+		}
+		resolved[dep.Name] = mod.Version
+	}
+
+	return resolved, nil
+}
+
+func TestStructuredConstraints(t *testing.T) {
+	t.Parallel()
+
+	ctlg, err := catalog.NewCatalogFromJSON(strings.NewReader(
+		`{"k6/x/ext": {"module": "go.k6.io/k6ext", "versions": ["v0.1.0", "v0.2.0", "v0.3.0"]}}`,
+	))
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{
+		BuildService: catalogResolver{catalog: ctlg},
+	}))
+	t.Cleanup(apiserver.Close)
+
+	resolve := func(dep k6build.Dependency) (int, api.ResolveResponse) {
+		body := &bytes.Buffer{}
+		_ = json.NewEncoder(body).Encode(api.ResolveRequest{Dependencies: []k6build.Dependency{dep}})
+
+		resp, err := http.Post(apiserver.URL+"/resolve", "application/json", body)
+		if err != nil {
+			t.Fatalf("making request %v", err)
+		}
+		defer resp.Body.Close() //nolint:errcheck
+
+		resolveResp := api.ResolveResponse{}
+		_ = json.NewDecoder(resp.Body).Decode(&resolveResp)
+
+		return resp.StatusCode, resolveResp
+	}
+
+	testCases := []struct {
+		title       string
+		clauses     []k6build.Constraint
+		constraints string
+		expectErr   bool
+	}{
+		{
+			title:       "exact version",
+			clauses:     []k6build.Constraint{{Version: "v0.2.0"}},
+			constraints: "v0.2.0",
+		},
+		{
+			title:       "operator",
+			clauses:     []k6build.Constraint{{Operator: ">", Version: "v0.1.0"}},
+			constraints: ">v0.1.0",
+		},
+		{
+			title:       "multiple clauses",
+			clauses:     []k6build.Constraint{{Operator: ">=", Version: "v0.1.0"}, {Operator: "<", Version: "v0.3.0"}},
+			constraints: ">=v0.1.0, <v0.3.0",
+		},
+		{
+			title:     "invalid operator",
+			clauses:   []k6build.Constraint{{Operator: "=>", Version: "v0.1.0"}},
+			expectErr: true,
+		},
+		{
+			title:     "invalid version",
+			clauses:   []k6build.Constraint{{Operator: ">", Version: "v0.1.0 || v0.3.0"}},
+			expectErr: true,
+		},
+		{
+			title:       "constraints and clauses",
+			clauses:     []k6build.Constraint{{Version: "v0.1.0"}},
+			constraints: "v0.1.0",
+			expectErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.expectErr {
+				status, resp := resolve(k6build.Dependency{
+					Name:        "k6/x/ext",
+					Constraints: tc.constraints,
+					Clauses:     tc.clauses,
+				})
+				if status != http.StatusBadRequest || !errors.Is(resp.Error, api.ErrInvalidRequest) {
+					t.Fatalf("expected %d %v got %d %v", http.StatusBadRequest, api.ErrInvalidRequest, status, resp.Error)
+				}
+				return
+			}
+
+			_, expected := resolve(k6build.Dependency{Name: "k6/x/ext", Constraints: tc.constraints})
+			if expected.Error != nil {
+				t.Fatalf("resolving string constraints %v", expected.Error)
+			}
+
+			status, resp := resolve(k6build.Dependency{Name: "k6/x/ext", Clauses: tc.clauses})
+			if status != http.StatusOK || resp.Error != nil {
+				t.Fatalf("resolving structured constraints %d %v", status, resp.Error)
+			}
+
+			if !cmp.Equal(resp.Dependencies, expected.Dependencies) {
+				t.Fatalf("%s", cmp.Diff(expected.Dependencies, resp.Dependencies))
+			}
+		})
+	}
+}
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	version := api.VersionResponse{
+		Version:   "v0.1.0",
+		Commit:    "0123456789",
+		BuildDate: "2025-01-01T00:00:00Z",
+		GoVersion: "go1.23.6",
+		Platform:  "linux/amd64",
+	}
+
+	apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: mockBuilder{}, Version: version}))
+	t.Cleanup(apiserver.Close)
+
+	resp, err := http.Get(apiserver.URL + "/version")
+	if err != nil {
+		t.Fatalf("making request %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status code: %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	versionResp := api.VersionResponse{}
+	err = json.NewDecoder(resp.Body).Decode(&versionResp)
+	if err != nil {
+		t.Fatalf("decoding response %v", err)
+	}
+
+	if !cmp.Equal(versionResp, version) {
+		t.Fatalf("%s", cmp.Diff(version, versionResp))
+	}
+}
+
+// batchBuilder fails the builds for the "fail" k6 constrains and records the maximum concurrent builds
+type batchBuilder struct {
+	mockBuilder
+	active    *atomic.Int32
+	maxActive *atomic.Int32
+}
+
+func (m batchBuilder) Build(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.Artifact, error) {
+	active := m.active.Add(1)
+	defer m.active.Add(-1)
+
+	for {
+		current := m.maxActive.Load()
+		if active <= current || m.maxActive.CompareAndSwap(current, active) {
+			break
+		}
+	}
+
+	// give other builds the opportunity to run concurrently
+	time.Sleep(10 * time.Millisecond)
+
+	if k6Constrains == "fail" {
+		return k6build.Artifact{}, k6build.NewCodedError(
+			k6build.ErrorCodeCannotSatisfy,
+			catalog.ErrCannotSatisfy,
+			errors.New("k6"),
+		)
+	}
+
+	return m.mockBuilder.Build(ctx, platform, k6Constrains, deps)
+}
+
+func TestBuildBatch(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title          string
+		body           string
+		maxBatchSize   int
+		expectStatus   int
+		expectCodes    []k6build.ErrorCode
+		expectPlatform []string
+	}{
+		{
+			title: "all succeed",
+			body: `[{"platform":"linux/amd64","k6":"v0.1.0"},` +
+				`{"platform":"linux/arm64","k6":"v0.1.0"},` +
+				`{"platform":"darwin/arm64","k6":"v0.1.0"}]`,
+			expectStatus:   http.StatusOK,
+			expectCodes:    []k6build.ErrorCode{"", "", ""},
+			expectPlatform: []string{"linux/amd64", "linux/arm64", "darwin/arm64"},
+		},
+		{
+			title: "failed request",
+			body: `[{"platform":"linux/amd64","k6":"v0.1.0"},` +
+				`{"platform":"linux/arm64","k6":"fail"},` +
+				`{"platform":"darwin/arm64","k6":"v0.1.0","url_expiration":"invalid"}]`,
+			expectStatus: http.StatusOK,
+			expectCodes: []k6build.ErrorCode{
+				"",
+				k6build.ErrorCodeCannotSatisfy,
+				k6build.ErrorCodeInvalidRequest,
+			},
+			expectPlatform: []string{"linux/amd64", "", ""},
+		},
+		{
+			title:        "empty batch",
+			body:         `[]`,
+			expectStatus: http.StatusOK,
+		},
+		{
+			title:        "invalid batch",
+			body:         `{"platform":"linux/amd64","k6":"v0.1.0"}`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			title:        "batch too large",
+			body:         `[{"platform":"linux/amd64","k6":"v0.1.0"},{"platform":"linux/arm64","k6":"v0.1.0"}]`,
+			maxBatchSize: 1,
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			builder := batchBuilder{active: &atomic.Int32{}, maxActive: &atomic.Int32{}}
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{
+				BuildService: builder,
+				MaxBatchSize: tc.maxBatchSize,
+			}))
+			t.Cleanup(apiserver.Close)
+
+			resp, err := http.Post(apiserver.URL+"/build/batch", "application/json", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				buildResp := api.BuildResponse{}
+				err = json.NewDecoder(resp.Body).Decode(&buildResp)
+				if err != nil || !errors.Is(buildResp.Error, api.ErrInvalidRequest) {
+					t.Fatalf("expected %v got %v (%v)", api.ErrInvalidRequest, buildResp.Error, err)
+				}
+				return
+			}
+
+			responses := []api.BuildResponse{}
+			err = json.NewDecoder(resp.Body).Decode(&responses)
+			if err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if len(responses) != len(tc.expectCodes) {
+				t.Fatalf("expected %d responses got %d", len(tc.expectCodes), len(responses))
+			}
+
+			for i, r := range responses {
+				code := k6build.ErrorCode("")
+				if r.Error != nil {
+					code = k6build.ErrorCodeOf(r.Error)
+				}
+				if code != tc.expectCodes[i] {
+					t.Fatalf("response %d: expected code %q got %q", i, tc.expectCodes[i], code)
+				}
+				if r.Artifact.Platform != tc.expectPlatform[i] {
+					t.Fatalf("response %d: expected platform %q got %q", i, tc.expectPlatform[i], r.Artifact.Platform)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildBatchConcurrency(t *testing.T) {
+	t.Parallel()
+
+	builder := batchBuilder{active: &atomic.Int32{}, maxActive: &atomic.Int32{}}
+	apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{
+		BuildService:     builder,
+		BatchConcurrency: 2,
+	}))
+	t.Cleanup(apiserver.Close)
+
+	reqs := make([]api.BuildRequest, 8)
+	for i := range reqs {
+		reqs[i] = api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"}
+	}
+
+	body := &bytes.Buffer{}
+	_ = json.NewEncoder(body).Encode(reqs)
+
+	resp, err := http.Post(apiserver.URL+"/build/batch", "application/json", body)
+	if err != nil {
+		t.Fatalf("making request %v", err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected status %d got %d", http.StatusOK, resp.StatusCode)
+	}
+
+	if maxActive := builder.maxActive.Load(); maxActive != 2 {
+		t.Fatalf("expected 2 concurrent builds got %d", maxActive)
+	}
+}
+
+// countingBuilder is a mockBuilder that counts the builds and fails building the "fail" version
+type countingBuilder struct {
+	mockBuilder
+	builds  *atomic.Int32
+	unblock chan struct{}
+}
+
+func (m countingBuilder) Build(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.Artifact, error) {
+	m.builds.Add(1)
+
+	switch k6Constrains {
+	case "fail":
+		return k6build.Artifact{}, k6build.NewWrappedError(k6build.ErrBuildFailed, errors.New("fail"))
+	case "block":
+		<-m.unblock
+	}
+
+	return m.mockBuilder.Build(ctx, platform, k6Constrains, deps)
+}
+
+type keyRequest struct {
+	token string
+	key   string
+	k6    string
+}
+
+func buildWithKey(url string, r keyRequest) (int, error) {
+	body := &bytes.Buffer{}
+	_ = json.NewEncoder(body).Encode(api.BuildRequest{Platform: "linux/amd64", K6Constrains: r.k6})
+
+	req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, url+"/build", body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.token)
+	if r.key != "" {
+		req.Header.Set(api.IdempotencyKeyHeader, r.key)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	buildResp := api.BuildResponse{}
+	_ = json.NewDecoder(resp.Body).Decode(&buildResp)
+	if buildResp.Error != nil {
+		return resp.StatusCode, buildResp.Error
+	}
+
+	return resp.StatusCode, nil
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		ttl          time.Duration
+		requests     []keyRequest
+		expectStatus []int
+		expectBuilds int32
+	}{
+		{
+			title:        "repeated key",
+			ttl:          time.Minute,
+			requests:     []keyRequest{{key: "key", k6: "v0.1.0"}, {key: "key", k6: "v0.1.0"}},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+			expectBuilds: 1,
+		},
+		{
+			title:        "no key",
+			ttl:          time.Minute,
+			requests:     []keyRequest{{k6: "v0.1.0"}, {k6: "v0.1.0"}},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+			expectBuilds: 2,
+		},
+		{
+			title:        "different keys",
+			ttl:          time.Minute,
+			requests:     []keyRequest{{key: "key", k6: "v0.1.0"}, {key: "other", k6: "v0.1.0"}},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+			expectBuilds: 2,
+		},
+		{
+			title: "key scoped to requester",
+			ttl:   time.Minute,
+			requests: []keyRequest{
+				{token: "a", key: "key", k6: "v0.1.0"},
+				{token: "b", key: "key", k6: "v0.1.0"},
+			},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+			expectBuilds: 2,
+		},
+		{
+			title:        "key reused for different request",
+			ttl:          time.Minute,
+			requests:     []keyRequest{{key: "key", k6: "v0.1.0"}, {key: "key", k6: "v0.2.0"}},
+			expectStatus: []int{http.StatusOK, http.StatusBadRequest},
+			expectBuilds: 1,
+		},
+		{
+			title:        "failed build is retried",
+			ttl:          time.Minute,
+			requests:     []keyRequest{{key: "key", k6: "fail"}, {key: "key", k6: "fail"}},
+			expectStatus: []int{http.StatusInternalServerError, http.StatusInternalServerError},
+			expectBuilds: 2,
+		},
+		{
+			title:        "expired key",
+			ttl:          time.Nanosecond,
+			requests:     []keyRequest{{key: "key", k6: "v0.1.0"}, {key: "key", k6: "v0.1.0"}},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+			expectBuilds: 2,
+		},
+		{
+			title:        "keys disabled",
+			ttl:          0,
+			requests:     []keyRequest{{key: "key", k6: "v0.1.0"}, {key: "key", k6: "v0.2.0"}},
+			expectStatus: []int{http.StatusOK, http.StatusOK},
+			expectBuilds: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			builder := countingBuilder{builds: &atomic.Int32{}}
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{
+				BuildService:      builder,
+				IdempotencyKeyTTL: tc.ttl,
+			}))
+			t.Cleanup(apiserver.Close)
+
+			for i, r := range tc.requests {
+				status, err := buildWithKey(apiserver.URL, r)
+				if status != tc.expectStatus[i] {
+					t.Fatalf("request %d: expected status %d got %d %v", i, tc.expectStatus[i], status, err)
+				}
+			}
+
+			if builds := builder.builds.Load(); builds != tc.expectBuilds {
+				t.Fatalf("expected %d builds got %d", tc.expectBuilds, builds)
+			}
+		})
+	}
+}
+
+func TestIdempotencyKeyInProgress(t *testing.T) {
+	t.Parallel()
+
+	builder := countingBuilder{builds: &atomic.Int32{}, unblock: make(chan struct{})}
+	apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{
+		BuildService:      builder,
+		IdempotencyKeyTTL: time.Minute,
+	}))
+	t.Cleanup(apiserver.Close)
+
+	const requests = 3
+	results := make(chan int, requests)
+	for range requests {
+		go func() {
+			status, _ := buildWithKey(apiserver.URL, keyRequest{key: "key", k6: "block"})
+			results <- status
+		}()
+	}
+
+	// give the repeated requests time to wait for the first one
+	time.Sleep(50 * time.Millisecond)
+	close(builder.unblock)
+
+	for range requests {
+		if status := <-results; status != http.StatusOK {
+			t.Fatalf("expected status %d got %d", http.StatusOK, status)
+		}
+	}
+
+	if builds := builder.builds.Load(); builds != 1 {
+		t.Fatalf("expected 1 build got %d", builds)
+	}
+}
+
+// urlBuilder is a mockBuilder that returns artifacts with a download URL
+type urlBuilder struct {
+	mockBuilder
+	url string
+}
+
+func (m urlBuilder) Build(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.Artifact, error) {
+	artifact, err := m.mockBuilder.Build(ctx, platform, k6Constrains, deps)
+	artifact.URL = m.url
+	return artifact, err
+}
+
+func TestPublicURL(t *testing.T) {
+	t.Parallel()
+
+	const downloadURL = "http://localhost:9000/store/id/download?signature=abc"
+
+	testCases := []struct {
+		title     string
+		publicURL string
+		expectURL string
+		expectErr bool
+	}{
+		{
+			title:     "no public url",
+			publicURL: "",
+			expectURL: downloadURL,
+		},
+		{
+			title:     "public host",
+			publicURL: "https://k6build.example.com",
+			expectURL: "https://k6build.example.com/store/id/download?signature=abc",
+		},
+		{
+			title:     "public host with path",
+			publicURL: "https://example.com/k6build/",
+			expectURL: "https://example.com/k6build/store/id/download?signature=abc",
+		},
+		{
+			title:     "missing host",
+			publicURL: "/k6build",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			config := APIServerConfig{BuildService: urlBuilder{url: downloadURL}}
+			if tc.publicURL != "" {
+				rewriter, err := PublicURLRewriter(tc.publicURL)
+				if tc.expectErr != (err != nil) {
+					t.Fatalf("expected error %t got %v", tc.expectErr, err)
+				}
+				if err != nil {
+					return
+				}
+				config.DownloadURLRewriter = rewriter
+			}
+
+			apiserver := httptest.NewServer(NewAPIServer(config))
+			t.Cleanup(apiserver.Close)
+
+			body := &bytes.Buffer{}
+			_ = json.NewEncoder(body).Encode(api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"})
+
+			resp, err := http.Post(apiserver.URL+"/build", "application/json", body)
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			buildResp := api.BuildResponse{}
+			if err = json.NewDecoder(resp.Body).Decode(&buildResp); err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if buildResp.Artifact.URL != tc.expectURL {
+				t.Fatalf("expected %q got %q", tc.expectURL, buildResp.Artifact.URL)
+			}
+		})
+	}
+}
+
+func TestBuildRequestValidation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		req          api.BuildRequest
+		expectStatus int
+		expectErrs   []string
+	}{
+		{
+			title: "valid request",
+			req: api.BuildRequest{
+				Platform:     "linux/amd64",
+				K6Constrains: "v0.1.0",
+				Dependencies: []k6build.Dependency{{Name: "k6/x/ext", Constraints: "*"}},
+			},
+			expectStatus: http.StatusOK,
+		},
+		{
+			title: "identical duplicate dependencies",
+			req: api.BuildRequest{
+				Platform:     "linux/amd64",
+				K6Constrains: "v0.1.0",
+				Dependencies: []k6build.Dependency{
+					{Name: "k6/x/ext", Constraints: "*"},
+					{Name: "k6/x/ext", Constraints: "*"},
+				},
+			},
+			expectStatus: http.StatusOK,
+		},
+		{
+			title:        "empty k6 constraints",
+			req:          api.BuildRequest{Platform: "linux/amd64"},
+			expectStatus: http.StatusBadRequest,
+			expectErrs:   []string{"k6 constraints cannot be empty"},
+		},
+		{
+			title:        "malformed platform",
+			req:          api.BuildRequest{Platform: "linux-amd64", K6Constrains: "v0.1.0"},
+			expectStatus: http.StatusBadRequest,
+			expectErrs:   []string{`invalid platform "linux-amd64"`},
+		},
+		{
+			title: "invalid dependency name",
+			req: api.BuildRequest{
+				Platform:     "linux/amd64",
+				K6Constrains: "v0.1.0",
+				Dependencies: []k6build.Dependency{{Name: "github.com/example/ext", Constraints: "*"}},
+			},
+			expectStatus: http.StatusBadRequest,
+			expectErrs:   []string{`invalid dependency name "github.com/example/ext"`},
+		},
+		{
+			title: "multiple problems",
+			req: api.BuildRequest{
+				Platform: "linux",
+				Dependencies: []k6build.Dependency{
+					{Name: "k6/x/ext", Constraints: "v0.1.0"},
+					{Name: "k6/x/ext", Constraints: "v0.2.0"},
+				},
+			},
+			expectStatus: http.StatusBadRequest,
+			expectErrs: []string{
+				"k6 constraints cannot be empty",
+				`invalid platform "linux"`,
+				`conflicting duplicate dependency "k6/x/ext"`,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: mockBuilder{}}))
+			t.Cleanup(apiserver.Close)
+
+			body := &bytes.Buffer{}
+			_ = json.NewEncoder(body).Encode(tc.req)
+
+			resp, err := http.Post(apiserver.URL+"/build", "application/json", body)
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			buildResp := api.BuildResponse{}
+			if err = json.NewDecoder(resp.Body).Decode(&buildResp); err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if len(tc.expectErrs) == 0 {
+				if buildResp.Error != nil {
+					t.Fatalf("unexpected %v", buildResp.Error)
+				}
+				return
+			}
+
+			if !errors.Is(buildResp.Error, api.ErrInvalidRequest) {
+				t.Fatalf("expected %v got %v", api.ErrInvalidRequest, buildResp.Error)
+			}
+
+			for _, expected := range tc.expectErrs {
+				if !strings.Contains(buildResp.Error.Error(), expected) {
+					t.Fatalf("expected %q in %q", expected, buildResp.Error.Error())
+				}
+			}
+		})
+	}
+}
+
+// authorizationBuilder is a mockBuilder that fails if the request is not authorized to build semvers
+type authorizationBuilder struct {
+	mockBuilder
+}
+
+func (m authorizationBuilder) Build(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.Artifact, error) {
+	if allowed, decided := k6build.BuildSemversAllowed(ctx); decided && !allowed {
+		return k6build.Artifact{}, k6build.NewCodedError(k6build.ErrorCodeInvalidRequest, errors.New("not allowed"), nil)
+	}
+	return m.mockBuilder.Build(ctx, platform, k6Constrains, deps)
+}
+
+func TestBuildSemversAuthorization(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title         string
+		tokens        []string
+		authorization string
+		expectStatus  int
+	}{
+		{
+			title:        "no authorizer",
+			expectStatus: http.StatusOK,
+		},
+		{
+			title:         "authorized token",
+			tokens:        []string{"other", "secret"},
+			authorization: "Bearer secret",
+			expectStatus:  http.StatusOK,
+		},
+		{
+			title:         "unknown token",
+			tokens:        []string{"secret"},
+			authorization: "Bearer unknown",
+			expectStatus:  http.StatusBadRequest,
+		},
+		{
+			title:        "missing token",
+			tokens:       []string{"secret"},
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			config := APIServerConfig{BuildService: authorizationBuilder{}}
+			if tc.tokens != nil {
+				config.BuildSemversAuthorizer = TokenAuthorizer(tc.tokens)
+			}
+
+			apiserver := httptest.NewServer(NewAPIServer(config))
+			t.Cleanup(apiserver.Close)
+
+			body := &bytes.Buffer{}
+			_ = json.NewEncoder(body).Encode(api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"})
+
+			req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, apiserver.URL+"/build", body)
+			if err != nil {
+				t.Fatalf("creating request %v", err)
+			}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+// requestIDBuilder is a mockBuilder that returns the request id carried by the context as the artifact id
+type requestIDBuilder struct {
+	mockBuilder
+}
+
+func (m requestIDBuilder) Build(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.Artifact, error) {
+	artifact, err := m.mockBuilder.Build(ctx, platform, k6Constrains, deps)
+	artifact.ID = k6build.RequestID(ctx)
+	return artifact, err
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		requestID string
+		expectID  string
+	}{
+		{
+			title:     "request id given",
+			requestID: "0123-abcd",
+			expectID:  "0123-abcd",
+		},
+		{
+			title:     "no request id",
+			requestID: "",
+		},
+		{
+			title:     "invalid request id",
+			requestID: "invalid id",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: requestIDBuilder{}}))
+			t.Cleanup(apiserver.Close)
+
+			body := &bytes.Buffer{}
+			_ = json.NewEncoder(body).Encode(api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"})
+
+			req, err := http.NewRequestWithContext(context.TODO(), http.MethodPost, apiserver.URL+"/build", body)
+			if err != nil {
+				t.Fatalf("creating request %v", err)
+			}
+			if tc.requestID != "" {
+				req.Header.Set(api.RequestIDHeader, tc.requestID)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			requestID := resp.Header.Get(api.RequestIDHeader)
+			if requestID == "" || (tc.expectID != "" && requestID != tc.expectID) {
+				t.Fatalf("expected request id %q got %q", tc.expectID, requestID)
+			}
+
+			buildResp := api.BuildResponse{}
+			if err = json.NewDecoder(resp.Body).Decode(&buildResp); err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			if buildResp.RequestID != requestID {
+				t.Fatalf("expected request id %q in response got %q", requestID, buildResp.RequestID)
+			}
+
+			if buildResp.Artifact.ID != requestID {
+				t.Fatalf("expected request id %q in build context got %q", requestID, buildResp.Artifact.ID)
+			}
+		})
+	}
+}
+
+// downloadBuilder is a mockBuilder that also implements the ArtifactDownloader interface
+type downloadBuilder struct {
+	mockBuilder
+	content     []byte
+	downloadErr error
+}
+
+func (m downloadBuilder) Build(
+	ctx context.Context,
+	platform string,
+	k6Constrains string,
+	deps []k6build.Dependency,
+) (k6build.Artifact, error) {
+	artifact, err := m.mockBuilder.Build(ctx, platform, k6Constrains, deps)
+	if err != nil {
+		return artifact, err
+	}
+
+	artifact.ID = "artifact"
+	artifact.Checksum = "sha256:checksum"
+	artifact.Size = int64(len(m.content))
+
+	return artifact, nil
+}
+
+func (m downloadBuilder) DownloadArtifact(_ context.Context, id string) (io.ReadCloser, error) {
+	if m.downloadErr != nil {
+		return nil, m.downloadErr
+	}
+
+	if id != "artifact" {
+		return nil, k6build.ErrUnknownArtifact
+	}
+
+	return io.NopCloser(bytes.NewReader(m.content)), nil
+}
+
+func TestBuildDownload(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("k6 binary")
+
+	testCases := []struct {
+		title        string
+		builder      k6build.BuildService
+		expectStatus int
+		expectErr    error
+	}{
+		{
+			title:        "download artifact",
+			builder:      downloadBuilder{content: content},
+			expectStatus: http.StatusOK,
+		},
+		{
+			title: "build failed",
+			builder: downloadBuilder{
+				mockBuilder: mockBuilder{err: k6build.NewWrappedError(api.ErrBuildFailed, errors.New("compile error"))},
+			},
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrBuildFailed,
+		},
+		{
+			title:        "download failed",
+			builder:      downloadBuilder{downloadErr: errors.New("store error")},
+			expectStatus: http.StatusInternalServerError,
+			expectErr:    api.ErrRequestFailed,
+		},
+		{
+			title:        "download not supported",
+			builder:      mockBuilder{},
+			expectStatus: http.StatusNotImplemented,
+			expectErr:    api.ErrRequestFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			apiserver := httptest.NewServer(NewAPIServer(APIServerConfig{BuildService: tc.builder}))
+			t.Cleanup(apiserver.Close)
+
+			req, _ := json.Marshal(api.BuildRequest{Platform: "linux/amd64", K6Constrains: "v0.1.0"})
+			resp, err := http.Post(apiserver.URL+"/build/download", "application/json", bytes.NewReader(req))
+			if err != nil {
+				t.Fatalf("making request %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.expectStatus {
+				t.Fatalf("expected status code: %d got %d", tc.expectStatus, resp.StatusCode)
+			}
+
+			if tc.expectErr != nil {
+				buildResp := api.BuildResponse{}
+				if err = json.NewDecoder(resp.Body).Decode(&buildResp); err != nil {
+					t.Fatalf("decoding response %v", err)
+				}
+
+				if !errors.Is(buildResp.Error, tc.expectErr) {
+					t.Fatalf("expected error: %q got %q", tc.expectErr, buildResp.Error)
+				}
+				return
+			}
+
+			if resp.Header.Get("Content-Type") != "application/octet-stream" {
+				t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+			}
+
+			if resp.Header.Get(api.ArtifactIDHeader) != "artifact" ||
+				resp.Header.Get(api.ArtifactChecksumHeader) != "sha256:checksum" {
+				t.Fatalf("unexpected artifact headers %v", resp.Header)
+			}
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("reading response %v", err)
+			}
+
+			if !bytes.Equal(body, content) {
+				t.Fatalf("expected content %q got %q", content, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/grafana/k6build/pkg/store"
+)
+
+// DownloadURLRewriter returns the download URL of an artifact's object returned to the client that
+// made the request. It allows returning an address reachable by the clients (e.g. through a gateway)
+// instead of the URL generated by the store.
+type DownloadURLRewriter func(object store.Object, r *http.Request) string
+
+// PublicURLRewriter returns a DownloadURLRewriter that replaces the scheme and host of the download URLs
+// with those of the public URL and prefixes their path with the public URL's path.
+// For example, with the public URL https://example.com/k6build, the download URL
+// http://localhost:9000/store/<id>/download is rewritten as https://example.com/k6build/store/<id>/download
+func PublicURLRewriter(publicURL string) (DownloadURLRewriter, error) {
+	public, err := url.Parse(publicURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public url %w", err)
+	}
+
+	if public.Scheme == "" || public.Host == "" {
+		return nil, fmt.Errorf("public url %q must have a scheme and host", publicURL)
+	}
+
+	return func(object store.Object, _ *http.Request) string {
+		download, err := url.Parse(object.URL)
+		if err != nil {
+			return object.URL
+		}
+
+		download.Scheme = public.Scheme
+		download.Host = public.Host
+		download.Path = strings.TrimSuffix(public.Path, "/") + download.Path
+		download.RawPath = ""
+
+		return download.String()
+	}, nil
+}
@@ -0,0 +1,76 @@
+// Package signing implements the parsing of the ed25519 keys used for signing catalogs and artifacts,
+// and the signing of artifacts
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/grafana/k6build/pkg/util"
+)
+
+// ParsePublicKey parses a PEM encoded ed25519 public key
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid public key: no PEM data")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid public key: not an ed25519 key")
+	}
+
+	return edKey, nil
+}
+
+// ParsePrivateKey parses a PEM encoded ed25519 private key in PKCS #8 form
+// (e.g. generated with openssl genpkey -algorithm ed25519)
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid private key: no PEM data")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, errors.New("invalid private key: not an ed25519 key")
+	}
+
+	return edKey, nil
+}
+
+// Message returns the message signed for an artifact with the given checksum: the checksum in
+// the form <algorithm>:<hex digest>, regardless of the form reported in the artifact.
+func Message(checksum string) ([]byte, error) {
+	algorithm, digest, err := util.ParseChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(algorithm + ":" + digest), nil
+}
+
+// SignChecksum returns the base64 encoded ed25519 signature of an artifact with the given checksum
+func SignChecksum(key ed25519.PrivateKey, checksum string) (string, error) {
+	message, err := Message(checksum)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, message)), nil
+}
@@ -0,0 +1,122 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+)
+
+func TestParsePublicKey(t *testing.T) {
+	t.Parallel()
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	parsed, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if !parsed.Equal(publicKey) {
+		t.Fatalf("parsed key doesn't match")
+	}
+
+	_, err = ParsePublicKey([]byte("not a key"))
+	if err == nil {
+		t.Fatalf("expected error parsing invalid key")
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	t.Parallel()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	parsed, err := ParsePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if !key.Equal(parsed) {
+		t.Fatalf("parsed key doesn't match")
+	}
+
+	_, err = ParsePrivateKey([]byte("not a key"))
+	if err == nil {
+		t.Fatalf("expected error parsing invalid key")
+	}
+}
+
+func TestSignChecksum(t *testing.T) {
+	t.Parallel()
+
+	publicKey, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	digest := "7f06720503c80153816b4ef9f58571c2fce620e0447fba1bb092188ff87e322d"
+
+	testCases := []struct {
+		title     string
+		checksum  string
+		expectErr bool
+	}{
+		{
+			title:    "checksum with algorithm",
+			checksum: "sha256:" + digest,
+		},
+		{
+			title:    "checksum without algorithm",
+			checksum: digest,
+		},
+		{
+			title:     "invalid checksum",
+			checksum:  "md5:" + digest,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			signature, err := SignChecksum(key, tc.checksum)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("expected error %t got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr {
+				return
+			}
+
+			decoded, err := base64.StdEncoding.DecodeString(signature)
+			if err != nil {
+				t.Fatalf("decoding signature %v", err)
+			}
+
+			// the message is the checksum including the algorithm
+			if !ed25519.Verify(publicKey, []byte("sha256:"+digest), decoded) {
+				t.Fatalf("invalid signature")
+			}
+		})
+	}
+}
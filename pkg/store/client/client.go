@@ -2,6 +2,7 @@
 package client
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,55 +10,176 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/grafana/k6build"
 	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/store/api"
+	"github.com/grafana/k6build/pkg/util"
 )
 
+// DefaultUnhealthyBackoff is the time a server that failed is tried only after the healthy servers
+const DefaultUnhealthyBackoff = 30 * time.Second
+
 // ErrInvalidConfig signals an error with the client configuration
 var ErrInvalidConfig = errors.New("invalid configuration")
 
 // StoreClientConfig defines the configuration for accessing a remote object store service
 type StoreClientConfig struct {
-	Server     string
+	// URL of the store server
+	Server string
+	// URLs of additional store servers. Requests fail over to the next server on connection errors.
+	Servers    []string
 	HTTPClient *http.Client
+	// Time a server that failed is tried only after the healthy servers. Defaults to DefaultUnhealthyBackoff
+	UnhealthyBackoff time.Duration
+	// Number of byte ranges of an object downloaded in parallel. The ranges are written to a temporary file,
+	// that is removed when the content is closed.
+	// Objects are downloaded in a single stream if their size is not known or the download url doesn't
+	// support ranges. Defaults to 1 (a single stream)
+	ParallelDownloads int
+}
+
+// server tracks the health of a store server
+type server struct {
+	url            *url.URL
+	unhealthyUntil time.Time
 }
 
 // StoreClient access blobs in a StoreServer
 type StoreClient struct {
-	server *url.URL
-	client *http.Client
+	mutex   sync.Mutex
+	servers []*server
+	backoff time.Duration
+	client  *http.Client
+	// number of byte ranges downloaded in parallel
+	parallel int
 }
 
 // NewStoreClient returns a client for an object store server
 func NewStoreClient(config StoreClientConfig) (*StoreClient, error) {
-	srvURL, err := url.Parse(config.Server)
-	if err != nil {
-		return nil, k6build.NewWrappedError(ErrInvalidConfig, err)
+	servers := []*server{}
+	for _, s := range append([]string{config.Server}, config.Servers...) {
+		if s == "" {
+			continue
+		}
+
+		srvURL, err := url.Parse(s)
+		if err != nil {
+			return nil, k6build.NewWrappedError(ErrInvalidConfig, err)
+		}
+		servers = append(servers, &server{url: srvURL})
+	}
+
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("%w: server url cannot be empty", ErrInvalidConfig)
 	}
 
 	client := config.HTTPClient
 	if client == nil {
 		client = http.DefaultClient
 	}
+
+	backoff := config.UnhealthyBackoff
+	if backoff == 0 {
+		backoff = DefaultUnhealthyBackoff
+	}
+
 	return &StoreClient{
-		server: srvURL,
-		client: client,
+		servers:  servers,
+		backoff:  backoff,
+		client:   client,
+		parallel: config.ParallelDownloads,
 	}, nil
 }
 
-// Get retrieves an objects if exists in the store or an error otherwise
-func (c *StoreClient) Get(ctx context.Context, id string) (store.Object, error) {
-	reqURL := *c.server.JoinPath("store", id)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+// candidates returns the servers in the order they should be tried: healthy servers first,
+// followed by the servers that recently failed
+func (c *StoreClient) candidates() []*server {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	healthy := []*server{}
+	unhealthy := []*server{}
+	for _, s := range c.servers {
+		if now.Before(s.unhealthyUntil) {
+			unhealthy = append(unhealthy, s)
+			continue
+		}
+		healthy = append(healthy, s)
+	}
+
+	return append(healthy, unhealthy...)
+}
+
+func (c *StoreClient) setHealth(s *server, healthy bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if healthy {
+		s.unhealthyUntil = time.Time{}
+		return
+	}
+	s.unhealthyUntil = time.Now().Add(c.backoff)
+}
+
+// do sends the request created by newRequest to the given servers in order until one responds.
+// Fails over to the next server only on connection errors.
+func (c *StoreClient) do(
+	servers []*server,
+	newRequest func(srvURL *url.URL) (*http.Request, error),
+) (*http.Response, error) {
+	var err error
+	for _, s := range servers {
+		var req *http.Request
+		req, err = newRequest(s.url)
+		if err != nil {
+			return nil, k6build.NewWrappedError(api.ErrInvalidRequest, err)
+		}
+
+		var resp *http.Response
+		resp, err = c.client.Do(req)
+		if err == nil {
+			c.setHealth(s, true)
+			return resp, nil
+		}
+
+		// don't fail over if the request was cancelled
+		if req.Context().Err() != nil {
+			break
+		}
+
+		c.setHealth(s, false)
+	}
+
+	return nil, k6build.NewWrappedError(api.ErrRequestFailed, err)
+}
+
+// HealthCheck checks at least one of the store servers is reachable.
+// Any response from the server is considered healthy, as only the connection is checked.
+func (c *StoreClient) HealthCheck(ctx context.Context) error {
+	resp, err := c.do(c.candidates(), func(srvURL *url.URL) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodHead, srvURL.String(), nil)
+	})
 	if err != nil {
-		return store.Object{}, k6build.NewWrappedError(api.ErrInvalidRequest, err)
+		return k6build.NewWrappedError(store.ErrUnavailable, err)
 	}
+	_ = resp.Body.Close()
+
+	return nil
+}
 
-	resp, err := c.client.Do(req)
+// Get retrieves an objects if exists in the store or an error otherwise
+func (c *StoreClient) Get(ctx context.Context, id string) (store.Object, error) {
+	resp, err := c.do(c.candidates(), func(srvURL *url.URL) (*http.Request, error) {
+		reqURL := srvURL.JoinPath("store", id)
+		return http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+	})
 	if err != nil {
-		return store.Object{}, k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return store.Object{}, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -85,21 +207,36 @@ func (c *StoreClient) Get(ctx context.Context, id string) (store.Object, error)
 
 // Put stores the object and returns the metadata
 func (c *StoreClient) Put(ctx context.Context, id string, content io.Reader) (store.Object, error) {
-	reqURL := *c.server.JoinPath("store", id)
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		reqURL.String(),
-		content,
-	)
-	if err != nil {
-		return store.Object{}, k6build.NewWrappedError(api.ErrInvalidRequest, err)
+	// the content must be sent again if the request fails over to another server
+	seeker, seekable := content.(io.ReadSeeker)
+	if !seekable && len(c.servers) > 1 {
+		buffer, err := io.ReadAll(content)
+		if err != nil {
+			return store.Object{}, k6build.NewWrappedError(api.ErrInvalidRequest, err)
+		}
+		seeker = bytes.NewReader(buffer)
 	}
 
-	req.Header.Set("Content-Type", "application/octet-stream")
-	resp, err := c.client.Do(req)
+	resp, err := c.do(c.candidates(), func(srvURL *url.URL) (*http.Request, error) {
+		body := content
+		if seeker != nil {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			body = seeker
+		}
+
+		reqURL := srvURL.JoinPath("store", id)
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL.String(), body)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/octet-stream")
+
+		return req, nil
+	})
 	if err != nil {
-		return store.Object{}, k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return store.Object{}, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
@@ -121,21 +258,71 @@ func (c *StoreClient) Put(ctx context.Context, id string, content io.Reader) (st
 	return storeResponse.Object, nil
 }
 
-// Download returns the content of the object given its url
+// relativePath returns the path of the object url relative to the server url, if the object is in the server
+func relativePath(objectURL *url.URL, srvURL *url.URL) (string, bool) {
+	if !strings.EqualFold(objectURL.Scheme, srvURL.Scheme) || !strings.EqualFold(objectURL.Host, srvURL.Host) {
+		return "", false
+	}
+
+	prefix := strings.TrimSuffix(srvURL.Path, "/")
+	path, found := strings.CutPrefix(objectURL.Path, prefix)
+	if !found || (path != "" && !strings.HasPrefix(path, "/")) {
+		return "", false
+	}
+
+	return path, true
+}
+
+// Download returns the content of the object given its url.
+// If the url references one of the store servers, the download fails over to the other servers.
+// If the client is configured for parallel downloads, the object is downloaded in byte ranges
+// from the server that responds and its content is verified with the object's checksum.
 func (c *StoreClient) Download(ctx context.Context, object store.Object) (io.ReadCloser, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, object.URL, nil)
+	objectURL, err := url.Parse(object.URL)
 	if err != nil {
-		return nil, k6build.NewWrappedError(api.ErrInvalidRequest, err)
+		return nil, k6build.NewWrappedError(api.ErrRequestFailed, err)
 	}
 
-	resp, err := c.client.Do(req) //nolint:bodyclose
+	// path of the object relative to the server that returned it
+	objectPath := ""
+	servers := []*server{{url: nil}}
+	for _, s := range c.servers {
+		if path, found := relativePath(objectURL, s.url); found {
+			objectPath = path
+			servers = c.candidates()
+			break
+		}
+	}
+
+	chunkSize := util.ChunkSize(object.Size, c.parallel)
+	resp, err := c.do(servers, func(srvURL *url.URL) (*http.Request, error) {
+		reqURL := *objectURL
+		if srvURL != nil {
+			reqURL.Scheme = srvURL.Scheme
+			reqURL.User = srvURL.User
+			reqURL.Host = srvURL.Host
+			reqURL.Path = strings.TrimSuffix(srvURL.Path, "/") + objectPath
+			reqURL.RawPath = ""
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+		if err == nil && chunkSize > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", chunkSize-1))
+		}
+		return req, err
+	})
 	if err != nil {
-		return nil, k6build.NewWrappedError(api.ErrRequestFailed, err)
+		return nil, err
+	}
+
+	// if the server doesn't support ranges, it returns the whole content
+	if chunkSize > 0 && resp.StatusCode == http.StatusPartialContent {
+		return c.parallelDownload(ctx, resp, object, chunkSize)
 	}
 
 	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
 		return nil, k6build.NewWrappedError(api.ErrRequestFailed, fmt.Errorf("status %s", resp.Status))
 	}
 
-	return resp.Request.Body, nil
+	return resp.Body, nil
 }
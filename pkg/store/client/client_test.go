@@ -5,13 +5,19 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/grafana/k6build"
 	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/store/api"
+	"github.com/grafana/k6build/pkg/util"
 )
 
 // returns a HandleFunc that returns a canned status and response
@@ -192,3 +198,355 @@ func TestStoreClientDownload(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreClientParallelDownload(t *testing.T) {
+	t.Parallel()
+
+	content := bytes.Repeat([]byte("0123456789"), (3*util.MinChunkSize+5)/10)
+	checksum, err := util.Checksum(util.DefaultChecksumAlgorithm, content)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	// serves the content supporting ranges
+	ranges := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}
+
+	// serves the ranges without their last byte
+	shortRanges := func(w http.ResponseWriter, r *http.Request) {
+		var start, end int64
+		_, _ = fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content[start:end])
+	}
+
+	testCases := []struct {
+		title          string
+		parallel       int
+		handler        http.HandlerFunc
+		size           int64
+		checksum       string
+		expectRequests int64
+		expectErr      error
+	}{
+		{
+			title:          "parallel download",
+			parallel:       3,
+			handler:        ranges,
+			size:           int64(len(content)),
+			checksum:       checksum,
+			expectRequests: 3,
+		},
+		{
+			title:          "single stream",
+			parallel:       1,
+			handler:        ranges,
+			size:           int64(len(content)),
+			checksum:       checksum,
+			expectRequests: 1,
+		},
+		{
+			title:          "unknown size",
+			parallel:       3,
+			handler:        ranges,
+			checksum:       checksum,
+			expectRequests: 1,
+		},
+		{
+			title:          "ranges not supported",
+			parallel:       3,
+			handler:        downloadMock(http.StatusOK, content),
+			size:           int64(len(content)),
+			checksum:       checksum,
+			expectRequests: 1,
+		},
+		{
+			title:          "checksum mismatch",
+			parallel:       3,
+			handler:        ranges,
+			size:           int64(len(content)),
+			checksum:       "sha256:0000",
+			expectRequests: 3,
+			expectErr:      util.ErrChecksumMismatch,
+		},
+		{
+			title:          "incomplete ranges",
+			parallel:       3,
+			handler:        shortRanges,
+			size:           int64(len(content)),
+			checksum:       checksum,
+			expectRequests: 1,
+			expectErr:      util.ErrDownloadFailed,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			requests := atomic.Int64{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				tc.handler(w, r)
+			}))
+			t.Cleanup(srv.Close)
+
+			client, err := NewStoreClient(StoreClientConfig{Server: srv.URL, ParallelDownloads: tc.parallel})
+			if err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+
+			obj := store.Object{ID: "object", URL: srv.URL, Size: tc.size, Checksum: tc.checksum}
+			downloaded := []byte{}
+			download, err := client.Download(context.TODO(), obj)
+			if err == nil {
+				downloaded, err = io.ReadAll(download)
+				_ = download.Close()
+			}
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && !bytes.Equal(downloaded, content) {
+				t.Fatalf("downloaded content doesn't match")
+			}
+
+			if requests.Load() != tc.expectRequests {
+				t.Fatalf("expected %d requests got %d", tc.expectRequests, requests.Load())
+			}
+		})
+	}
+}
+
+// returns the url of a server that refuses connections
+func failingServer() string {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	return srv.URL
+}
+
+func TestStoreClientFailover(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("object content")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /store/{id}", handlerMock(http.StatusOK, &api.StoreResponse{}))
+	mux.HandleFunc("POST /store/{id}", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !bytes.Equal(body, content) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		handlerMock(http.StatusOK, &api.StoreResponse{})(w, r)
+	})
+	mux.HandleFunc("GET /store/{id}/download", downloadMock(http.StatusOK, content))
+
+	healthy := httptest.NewServer(mux)
+	t.Cleanup(healthy.Close)
+
+	failing := failingServer()
+
+	client, err := NewStoreClient(StoreClientConfig{Server: failing, Servers: []string{healthy.URL}})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	_, err = client.Get(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("get: unexpected %v", err)
+	}
+
+	// the content is not seekable, it must be buffered to be sent again
+	_, err = client.Put(context.TODO(), "object", io.NopCloser(bytes.NewReader(content)))
+	if err != nil {
+		t.Fatalf("put: unexpected %v", err)
+	}
+
+	// object returned by the failing server
+	download, err := client.Download(context.TODO(), store.Object{ID: "object", URL: failing + "/store/object/download"})
+	if err != nil {
+		t.Fatalf("download: unexpected %v", err)
+	}
+	defer download.Close() //nolint:errcheck
+
+	downloaded, err := io.ReadAll(download)
+	if err != nil {
+		t.Fatalf("download: reading content %v", err)
+	}
+
+	if !bytes.Equal(downloaded, content) {
+		t.Fatalf("expected %q got %q", content, downloaded)
+	}
+}
+
+// countingTransport counts the requests sent to each host
+type countingTransport struct {
+	failing  string
+	attempts atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if "http://"+req.URL.Host == c.failing {
+		c.attempts.Add(1)
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestStoreClientHealthTracking(t *testing.T) {
+	t.Parallel()
+
+	healthy := httptest.NewServer(handlerMock(http.StatusOK, &api.StoreResponse{}))
+	t.Cleanup(healthy.Close)
+
+	transport := &countingTransport{failing: failingServer()}
+
+	client, err := NewStoreClient(StoreClientConfig{
+		Server:     transport.failing,
+		Servers:    []string{healthy.URL},
+		HTTPClient: &http.Client{Transport: transport},
+	})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	for range 3 {
+		_, err = client.Get(context.TODO(), "object")
+		if err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+	}
+
+	// once failed, the server is not tried again while healthy servers are available
+	if attempts := transport.attempts.Load(); attempts != 1 {
+		t.Fatalf("expected 1 attempt to failing server got %d", attempts)
+	}
+}
+
+func TestStoreClientAllServersFailing(t *testing.T) {
+	t.Parallel()
+
+	client, err := NewStoreClient(StoreClientConfig{Server: failingServer(), Servers: []string{failingServer()}})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	_, err = client.Get(context.TODO(), "object")
+	if !errors.Is(err, api.ErrRequestFailed) {
+		t.Fatalf("expected %v got %v", api.ErrRequestFailed, err)
+	}
+}
+
+func TestStoreClientHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	// any response means the server is reachable
+	reachable := httptest.NewServer(handlerMock(http.StatusNotFound, nil))
+	t.Cleanup(reachable.Close)
+
+	testCases := []struct {
+		title     string
+		servers   []string
+		expectErr error
+	}{
+		{
+			title:   "server reachable",
+			servers: []string{reachable.URL},
+		},
+		{
+			title:   "fail over to reachable server",
+			servers: []string{failingServer(), reachable.URL},
+		},
+		{
+			title:     "no server reachable",
+			servers:   []string{failingServer(), failingServer()},
+			expectErr: store.ErrUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			client, err := NewStoreClient(StoreClientConfig{Servers: tc.servers})
+			if err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+
+			err = client.HealthCheck(context.TODO())
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestRelativePath(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		objectURL   string
+		serverURL   string
+		expectPath  string
+		expectFound bool
+	}{
+		{
+			title:       "server without path",
+			objectURL:   "http://store:9000/store/object/download",
+			serverURL:   "http://store:9000",
+			expectPath:  "/store/object/download",
+			expectFound: true,
+		},
+		{
+			title:       "server with path",
+			objectURL:   "http://store:9000/prefix/store/object/download?token=abc",
+			serverURL:   "http://store:9000/prefix/",
+			expectPath:  "/store/object/download",
+			expectFound: true,
+		},
+		{
+			title:       "host case",
+			objectURL:   "http://STORE:9000/store/object/download",
+			serverURL:   "http://store:9000",
+			expectPath:  "/store/object/download",
+			expectFound: true,
+		},
+		{
+			title:     "other host",
+			objectURL: "http://other:9000/store/object/download",
+			serverURL: "http://store:9000",
+		},
+		{
+			title:     "other scheme",
+			objectURL: "https://store:9000/store/object/download",
+			serverURL: "http://store:9000",
+		},
+		{
+			title:     "other port",
+			objectURL: "http://store:9001/store/object/download",
+			serverURL: "http://store:9000",
+		},
+		{
+			title:     "partial path",
+			objectURL: "http://store:9000/prefixed/store/object/download",
+			serverURL: "http://store:9000/prefix",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			objectURL, _ := url.Parse(tc.objectURL)
+			serverURL, _ := url.Parse(tc.serverURL)
+
+			path, found := relativePath(objectURL, serverURL)
+			if found != tc.expectFound || path != tc.expectPath {
+				t.Fatalf("expected %q %t got %q %t", tc.expectPath, tc.expectFound, path, found)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/store/api"
+	"github.com/grafana/k6build/pkg/util"
+)
+
+// downloadedContent is the content of a parallel download, kept in a temporary file that is removed when closed
+type downloadedContent struct {
+	*os.File
+}
+
+// Close closes and removes the temporary file
+func (d downloadedContent) Close() error {
+	err := d.File.Close()
+	_ = os.Remove(d.Name())
+
+	return err
+}
+
+// parallelDownload returns the content of the object, downloading the remaining chunks after the first one,
+// returned in the response, in parallel from the same url. The chunks are written at their offset in a
+// temporary file and the content is verified with the object's checksum before it is returned.
+func (c *StoreClient) parallelDownload(
+	ctx context.Context,
+	first *http.Response,
+	object store.Object,
+	chunkSize int64,
+) (io.ReadCloser, error) {
+	defer first.Body.Close() //nolint:errcheck
+
+	file, err := os.CreateTemp("", "k6build-download-*")
+	if err != nil {
+		return nil, k6build.NewWrappedError(api.ErrRequestFailed, err)
+	}
+
+	content := downloadedContent{File: file}
+	if err = c.downloadChunks(ctx, first, object, chunkSize, file); err != nil {
+		_ = content.Close()
+		return nil, k6build.NewWrappedError(api.ErrRequestFailed, err)
+	}
+
+	return content, nil
+}
+
+// downloadChunks writes the chunks of the object to the file and verifies its content
+func (c *StoreClient) downloadChunks(
+	ctx context.Context,
+	first *http.Response,
+	object store.Object,
+	chunkSize int64,
+	file *os.File,
+) error {
+	size, err := util.WriteRange(first, 0, chunkSize-1, file)
+	if err != nil {
+		return err
+	}
+	if size != object.Size {
+		return fmt.Errorf("expected %d bytes got %d", object.Size, size)
+	}
+
+	err = util.DownloadRanges(ctx, c.client, first.Request.URL.String(), chunkSize, size, chunkSize, file)
+	if err != nil {
+		return err
+	}
+
+	if object.Checksum != "" {
+		if err = util.VerifyChecksum(file.Name(), object.Checksum); err != nil {
+			return err
+		}
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+
+	return err
+}
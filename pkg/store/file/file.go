@@ -2,24 +2,40 @@
 package file
 
 import (
-	"bytes"
 	"context"
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/internal/dirlock"
 	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/util"
 )
 
+const (
+	// pinnedMarker is the file in the directory of a pinned object
+	pinnedMarker = "pinned"
+	// accessedFile is the file in the directory of an object that keeps the last time it was touched
+	accessedFile = "accessed"
+)
+
 // Store a ObjectStore backed by a file system
 type Store struct {
-	dir string
+	dir       string
+	algorithm string
+}
+
+// Config file Store configuration
+type Config struct {
+	// Directory where the objects are stored
+	Dir string
+	// Algorithm used for the objects' checksum (e.g. sha512). Defaults to util.DefaultChecksumAlgorithm
+	ChecksumAlgorithm string
 }
 
 // NewTempFileStore creates a file object store using a temporary file
@@ -29,19 +45,53 @@ func NewTempFileStore() (store.ObjectStore, error) {
 
 // NewFileStore creates an object store backed by a directory
 func NewFileStore(dir string) (store.ObjectStore, error) {
-	err := os.MkdirAll(dir, 0o750)
+	return New(Config{Dir: dir})
+}
+
+// New creates an object store backed by a directory with the given configuration
+func New(conf Config) (store.ObjectStore, error) {
+	algorithm := conf.ChecksumAlgorithm
+	if algorithm == "" {
+		algorithm = util.DefaultChecksumAlgorithm
+	}
+
+	if _, err := util.NewChecksumHash(algorithm); err != nil {
+		return nil, k6build.NewWrappedError(store.ErrInitializingStore, err)
+	}
+
+	err := os.MkdirAll(conf.Dir, 0o750)
 	if err != nil {
 		return nil, k6build.NewWrappedError(store.ErrInitializingStore, err)
 	}
 
 	return &Store{
-		dir: dir,
+		dir:       conf.Dir,
+		algorithm: algorithm,
 	}, nil
 }
 
+// HealthCheck checks the store's directory is writable
+func (f *Store) HealthCheck(_ context.Context) error {
+	probe, err := os.CreateTemp(f.dir, ".healthcheck-*")
+	if err != nil {
+		return k6build.NewWrappedError(store.ErrUnavailable, err)
+	}
+	_ = probe.Close()
+
+	if err = os.Remove(probe.Name()); err != nil {
+		return k6build.NewWrappedError(store.ErrUnavailable, err)
+	}
+
+	return nil
+}
+
 // Put stores the object and returns the metadata
 // Fails if the object already exists
-func (f *Store) Put(_ context.Context, id string, content io.Reader) (store.Object, error) {
+func (f *Store) Put(ctx context.Context, id string, content io.Reader) (store.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
+	}
+
 	if id == "" {
 		return store.Object{}, fmt.Errorf("%w: id cannot be empty", store.ErrCreatingObject)
 	}
@@ -62,46 +112,67 @@ func (f *Store) Put(_ context.Context, id string, content io.Reader) (store.Obje
 		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
 	}
 
+	checksum, size, err := f.writeObject(ctx, id, content)
+	if err != nil {
+		// the object is removed once the lock is released, so concurrent readers find it missing
+		_ = os.RemoveAll(objectDir)
+		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
+	}
+
+	objectURL, _ := util.URLFromFilePath(filepath.Join(objectDir, "data"))
+	return store.Object{
+		ID:       id,
+		Checksum: checksum,
+		URL:      objectURL.String(),
+		Size:     size,
+	}, nil
+}
+
+// writeObject writes the content and checksum of the object while holding its lock.
+// The copy stops if the context is canceled.
+func (f *Store) writeObject(ctx context.Context, id string, content io.Reader) (string, int64, error) {
 	// prevent concurrent modification of an object
 	unlock, err := f.lockObject(id)
 	if err != nil {
-		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
+		return "", 0, err
 	}
 	defer unlock()
 
+	objectDir := filepath.Join(f.dir, id)
 	objectFile, err := os.Create(filepath.Join(objectDir, "data")) //nolint:gosec
 	if err != nil {
-		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
+		return "", 0, err
 	}
 	defer objectFile.Close() //nolint:errcheck
 
-	// write content to object file and copy to buffer to calculate checksum
-	// TODO: optimize memory by copying content in blocks
-	buff := bytes.Buffer{}
-	_, err = io.Copy(objectFile, io.TeeReader(content, &buff))
+	hash, err := util.NewChecksumHash(f.algorithm)
 	if err != nil {
-		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
+		return "", 0, err
 	}
 
-	// calculate checksum
-	checksum := fmt.Sprintf("%x", sha256.Sum256(buff.Bytes()))
+	// write content to object file calculating the checksum as it is copied.
+	size, err := io.Copy(objectFile, util.NewContextReader(ctx, io.TeeReader(content, hash)))
+	if err != nil {
+		return "", 0, err
+	}
+
+	checksum := util.FormatChecksum(f.algorithm, hash.Sum(nil))
 
 	// write metadata
 	err = os.WriteFile(filepath.Join(objectDir, "checksum"), []byte(checksum), 0o644) //nolint:gosec
 	if err != nil {
-		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
+		return "", 0, err
 	}
 
-	objectURL, _ := util.URLFromFilePath(objectFile.Name())
-	return store.Object{
-		ID:       id,
-		Checksum: checksum,
-		URL:      objectURL.String(),
-	}, nil
+	return checksum, size, nil
 }
 
 // Get retrieves an objects if exists in the object store or an error otherwise
-func (f *Store) Get(_ context.Context, id string) (store.Object, error) {
+func (f *Store) Get(ctx context.Context, id string) (store.Object, error) {
+	if err := ctx.Err(); err != nil {
+		return store.Object{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
 	objectDir := filepath.Join(f.dir, id)
 	_, err := os.Stat(objectDir)
 
@@ -120,30 +191,267 @@ func (f *Store) Get(_ context.Context, id string) (store.Object, error) {
 	}
 	defer unlock()
 
+	// the object may have been removed by a failed Put while waiting for the lock
 	checksum, err := os.ReadFile(filepath.Join(objectDir, "checksum")) //nolint:gosec
+	if errors.Is(err, os.ErrNotExist) {
+		return store.Object{}, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
 	if err != nil {
 		return store.Object{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
 	}
 
-	objectURL, err := util.URLFromFilePath(filepath.Join(objectDir, "data"))
+	// objects stored before recording the algorithm have only the sha256 digest
+	algorithm, digest, err := util.ParseChecksum(string(checksum))
+	if err != nil {
+		return store.Object{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	objectFile := filepath.Join(objectDir, "data")
+	info, err := os.Stat(objectFile)
+	if err != nil {
+		return store.Object{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	objectURL, err := util.URLFromFilePath(objectFile)
 	if err != nil {
 		return store.Object{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
 	}
 	return store.Object{
 		ID:       id,
-		Checksum: string(checksum),
+		Checksum: algorithm + ":" + digest,
 		URL:      objectURL.String(),
+		Size:     info.Size(),
+		Created:  info.ModTime(),
 	}, nil
 }
 
+// List returns the objects in the object store.
+// Directories that are not objects, such as hidden directories (e.g. .locks) and objects still being
+// written or left partially written, are skipped.
+func (f *Store) List(ctx context.Context) ([]store.Object, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	objects := []store.Object{}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		// the checksum is written once the object's content is complete
+		if _, err = os.Stat(filepath.Join(f.dir, entry.Name(), "checksum")); err != nil {
+			continue
+		}
+
+		object, err := f.Get(ctx, entry.Name())
+		// the object may have been deleted after listing the directory
+		if errors.Is(err, store.ErrObjectNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, object)
+	}
+
+	return objects, nil
+}
+
+// Delete removes an object from the object store
+func (f *Store) Delete(_ context.Context, id string) error {
+	if id == "" || strings.Contains(id, "/") {
+		return fmt.Errorf("%w: invalid id %q", store.ErrDeletingObject, id)
+	}
+
+	objectDir := filepath.Join(f.dir, id)
+	if _, err := os.Stat(objectDir); errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	// prevent deleting the object while it is being written or accessed
+	unlock, err := f.lockObject(id)
+	if err != nil {
+		return k6build.NewWrappedError(store.ErrDeletingObject, err)
+	}
+	defer unlock()
+
+	if err := os.RemoveAll(objectDir); err != nil {
+		return k6build.NewWrappedError(store.ErrDeletingObject, err)
+	}
+
+	return nil
+}
+
+// Download returns the content of an object. The content can be seeked.
+// Reading the content fails once the context is done.
+func (f *Store) Download(ctx context.Context, object store.Object) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	if object.ID == "" || strings.Contains(object.ID, "/") {
+		return nil, fmt.Errorf("%w: invalid id %q", store.ErrAccessingObject, object.ID)
+	}
+
+	objectFile, err := os.Open(filepath.Join(f.dir, object.ID, "data"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, object.ID)
+	}
+	if err != nil {
+		return nil, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	return &objectContent{ctx: ctx, file: objectFile}, nil
+}
+
+// objectContent is the content of an object's data file that stops reading once the context is done.
+// It doesn't expose the file's WriterTo, so copying the content reads it through Read.
+type objectContent struct {
+	ctx  context.Context
+	file *os.File
+}
+
+func (o *objectContent) Read(b []byte) (int, error) {
+	if err := o.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return o.file.Read(b)
+}
+
+func (o *objectContent) Seek(offset int64, whence int) (int64, error) {
+	return o.file.Seek(offset, whence)
+}
+
+func (o *objectContent) Close() error {
+	return o.file.Close()
+}
+
+// Pin pins the object by creating a marker file in the object's directory
+func (f *Store) Pin(_ context.Context, id string) error {
+	unlock, err := f.lockExisting(id, store.ErrPinningObject)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = os.WriteFile(filepath.Join(f.dir, id, pinnedMarker), nil, 0o644) //nolint:gosec
+	if err != nil {
+		return k6build.NewWrappedError(store.ErrPinningObject, err)
+	}
+
+	return nil
+}
+
+// Unpin unpins the object by removing its marker file
+func (f *Store) Unpin(_ context.Context, id string) error {
+	unlock, err := f.lockExisting(id, store.ErrPinningObject)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = os.Remove(filepath.Join(f.dir, id, pinnedMarker))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return k6build.NewWrappedError(store.ErrPinningObject, err)
+	}
+
+	return nil
+}
+
+// Pinned returns if the object's directory has the pinned marker file
+func (f *Store) Pinned(_ context.Context, id string) (bool, error) {
+	if id == "" || strings.Contains(id, "/") {
+		return false, fmt.Errorf("%w: invalid id %q", store.ErrAccessingObject, id)
+	}
+
+	if _, err := os.Stat(filepath.Join(f.dir, id)); errors.Is(err, os.ErrNotExist) {
+		return false, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	_, err := os.Stat(filepath.Join(f.dir, id, pinnedMarker))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	return true, nil
+}
+
+// Touch records the object was accessed by writing the current time to a file in the object's directory
+func (f *Store) Touch(_ context.Context, id string) error {
+	unlock, err := f.lockExisting(id, store.ErrAccessingObject)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	accessed := []byte(time.Now().UTC().Format(time.RFC3339Nano))
+	err = os.WriteFile(filepath.Join(f.dir, id, accessedFile), accessed, 0o644) //nolint:gosec
+	if err != nil {
+		return k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	return nil
+}
+
+// Accessed returns the last time the object was touched
+func (f *Store) Accessed(_ context.Context, id string) (time.Time, error) {
+	if id == "" || strings.Contains(id, "/") {
+		return time.Time{}, fmt.Errorf("%w: invalid id %q", store.ErrAccessingObject, id)
+	}
+
+	if _, err := os.Stat(filepath.Join(f.dir, id)); errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	content, err := os.ReadFile(filepath.Join(f.dir, id, accessedFile)) //nolint:gosec
+	if errors.Is(err, os.ErrNotExist) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	accessed, err := time.Parse(time.RFC3339Nano, string(content))
+	if err != nil {
+		return time.Time{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	return accessed, nil
+}
+
+// lockExisting locks the directory of an existing object. Failures are wrapped in the given error.
+// Fails with ErrObjectNotFound if the object doesn't exist.
+func (f *Store) lockExisting(id string, opErr error) (func(), error) {
+	if id == "" || strings.Contains(id, "/") {
+		return nil, fmt.Errorf("%w: invalid id %q", opErr, id)
+	}
+
+	if _, err := os.Stat(filepath.Join(f.dir, id)); errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	unlock, err := f.lockObject(id)
+	if err != nil {
+		return nil, k6build.NewWrappedError(opErr, err)
+	}
+
+	return unlock, nil
+}
+
 // lockObject creates a lock for an object's directory using a file lock
 func (f *Store) lockObject(id string) (func(), error) {
-	objLock := newDirLock(filepath.Join(f.dir, id))
-	if err := objLock.lock(0); err != nil {
+	objLock := dirlock.New(filepath.Join(f.dir, id))
+	if err := objLock.Lock(0); err != nil {
 		return nil, err
 	}
 
 	return func() {
-		_ = objLock.unlock()
+		_ = objLock.Unlock()
 	}, nil
 }
@@ -3,11 +3,15 @@ package file
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/sha512"
 	"errors"
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/util"
@@ -118,6 +122,10 @@ func TestFileStoreStoreObject(t *testing.T) {
 			if !bytes.Equal(tc.content, content) {
 				t.Fatalf("expected %v got %v", tc.content, content)
 			}
+
+			if obj.Size != int64(len(tc.content)) {
+				t.Fatalf("expected size %d got %d", len(tc.content), obj.Size)
+			}
 		})
 	}
 }
@@ -182,9 +190,321 @@ func TestFileStoreGet(t *testing.T) {
 				t.Fatalf("reading object url %v", err)
 			}
 
+			if obj.Size != int64(len(tc.expected)) {
+				t.Fatalf("expected size %d got %d", len(tc.expected), obj.Size)
+			}
+
 			if !bytes.Equal(data, tc.expected) {
 				t.Fatalf("expected %v got %v", tc.expected, data)
 			}
 		})
 	}
 }
+
+func TestFileStoreListDelete(t *testing.T) {
+	t.Parallel()
+
+	preload := []object{
+		{id: "object", content: []byte("content")},
+		{id: "other", content: []byte("other content")},
+	}
+
+	storeDir := t.TempDir()
+	fileStore, err := setupStore(storeDir, preload)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	// directories that are not objects: locks and a partially written object
+	for _, dir := range []string{".locks", "partial"} {
+		if err = os.MkdirAll(filepath.Join(storeDir, dir), 0o750); err != nil {
+			t.Fatalf("test setup: %v", err)
+		}
+	}
+	if err = os.WriteFile(filepath.Join(storeDir, "partial", "data"), []byte("part"), 0o600); err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	lister, _ := fileStore.(store.ObjectLister)
+	deleter, _ := fileStore.(store.ObjectDeleter)
+
+	if err = deleter.Delete(context.TODO(), "object"); err != nil {
+		t.Fatalf("deleting object %v", err)
+	}
+
+	err = deleter.Delete(context.TODO(), "object")
+	if !errors.Is(err, store.ErrObjectNotFound) {
+		t.Fatalf("expected %v got %v", store.ErrObjectNotFound, err)
+	}
+
+	objects, err := lister.List(context.TODO())
+	if err != nil {
+		t.Fatalf("listing objects %v", err)
+	}
+
+	if len(objects) != 1 || objects[0].ID != "other" {
+		t.Fatalf("expected only %q got %v", "other", objects)
+	}
+
+	if objects[0].Size != int64(len("other content")) || objects[0].Created.IsZero() {
+		t.Fatalf("unexpected object metadata %v", objects[0])
+	}
+}
+
+func TestFileStoreHealthCheck(t *testing.T) {
+	t.Parallel()
+
+	storeDir := t.TempDir()
+	fileStore, err := NewFileStore(storeDir)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	if err = store.HealthCheck(context.TODO(), fileStore); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	// the probe must not leave files in the store
+	entries, err := os.ReadDir(storeDir)
+	if err != nil {
+		t.Fatalf("reading store dir %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("expected empty store dir got %d entries", len(entries))
+	}
+
+	if err = os.RemoveAll(storeDir); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	err = store.HealthCheck(context.TODO(), fileStore)
+	if !errors.Is(err, store.ErrUnavailable) {
+		t.Fatalf("expected %v got %v", store.ErrUnavailable, err)
+	}
+}
+
+func TestFileStoreChecksum(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("content")
+
+	testCases := []struct {
+		title     string
+		algorithm string
+		expect    string
+		expectErr error
+	}{
+		{
+			title:  "default algorithm",
+			expect: fmt.Sprintf("sha256:%x", sha256.Sum256(content)),
+		},
+		{
+			title:     "sha512",
+			algorithm: util.ChecksumSHA512,
+			expect:    fmt.Sprintf("sha512:%x", sha512.Sum512(content)),
+		},
+		{
+			title:     "unsupported algorithm",
+			algorithm: "md5",
+			expectErr: store.ErrInitializingStore,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			fileStore, err := New(Config{Dir: t.TempDir(), ChecksumAlgorithm: tc.algorithm})
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			obj, err := fileStore.Put(context.TODO(), "object", bytes.NewReader(content))
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if obj.Checksum != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, obj.Checksum)
+			}
+
+			obj, err = fileStore.Get(context.TODO(), "object")
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			if obj.Checksum != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, obj.Checksum)
+			}
+		})
+	}
+}
+
+func TestFileStoreLegacyChecksum(t *testing.T) {
+	t.Parallel()
+
+	storeDir := t.TempDir()
+	fileStore, err := setupStore(storeDir, []object{{id: "object", content: []byte("content")}})
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	// objects stored by previous versions have only the sha256 digest
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte("content")))
+	err = os.WriteFile(filepath.Join(storeDir, "object", "checksum"), []byte(digest), 0o600)
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	obj, err := fileStore.Get(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if expected := "sha256:" + digest; obj.Checksum != expected {
+		t.Fatalf("expected %q got %q", expected, obj.Checksum)
+	}
+}
+
+// cancelReader cancels the context after the first read
+type cancelReader struct {
+	cancel context.CancelFunc
+}
+
+func (c *cancelReader) Read(b []byte) (int, error) {
+	c.cancel()
+	return len(b), nil
+}
+
+func TestFileStoreCancel(t *testing.T) {
+	t.Parallel()
+
+	storeDir := t.TempDir()
+	fileStore, err := setupStore(storeDir, []object{{id: "object", content: []byte("content")}})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the copy of an endless content stops when the context is canceled
+	_, err = fileStore.Put(ctx, "canceled", &cancelReader{cancel: cancel})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+
+	if _, err = os.Stat(filepath.Join(storeDir, "canceled")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected partial object removed got %v", err)
+	}
+
+	if _, err = fileStore.Get(context.TODO(), "canceled"); !errors.Is(err, store.ErrObjectNotFound) {
+		t.Fatalf("expected %v got %v", store.ErrObjectNotFound, err)
+	}
+
+	if _, err = fileStore.Get(ctx, "object"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+
+	obj, err := fileStore.Get(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	content, err := fileStore.(store.ObjectDownloader).Download(context.TODO(), obj)
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	defer content.Close() //nolint:errcheck
+
+	if _, err = fileStore.(store.ObjectDownloader).Download(ctx, obj); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+}
+
+func TestFileStorePin(t *testing.T) {
+	t.Parallel()
+
+	fileStore, err := setupStore(t.TempDir(), []object{{id: "object", content: []byte("content")}})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+	pinner := fileStore.(store.ObjectPinner)
+
+	for _, step := range []struct {
+		action func(context.Context, string) error
+		pinned bool
+	}{
+		{action: pinner.Pin, pinned: true},
+		{action: pinner.Pin, pinned: true},
+		{action: pinner.Unpin, pinned: false},
+		{action: pinner.Unpin, pinned: false},
+	} {
+		if err = step.action(context.TODO(), "object"); err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+
+		pinned, err := pinner.Pinned(context.TODO(), "object")
+		if err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+		if pinned != step.pinned {
+			t.Fatalf("expected pinned %t got %t", step.pinned, pinned)
+		}
+	}
+
+	// the marker is not part of the object
+	if _, err = fileStore.Get(context.TODO(), "object"); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	if err = pinner.Pin(context.TODO(), "missing"); !errors.Is(err, store.ErrObjectNotFound) {
+		t.Fatalf("expected %v got %v", store.ErrObjectNotFound, err)
+	}
+
+	if _, err = pinner.Pinned(context.TODO(), "missing"); !errors.Is(err, store.ErrObjectNotFound) {
+		t.Fatalf("expected %v got %v", store.ErrObjectNotFound, err)
+	}
+}
+
+func TestFileStoreTouch(t *testing.T) {
+	t.Parallel()
+
+	fileStore, err := setupStore(t.TempDir(), []object{{id: "object", content: []byte("content")}})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+	toucher := fileStore.(store.ObjectToucher)
+
+	accessed, err := toucher.Accessed(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if !accessed.IsZero() {
+		t.Fatalf("expected object never accessed got %v", accessed)
+	}
+
+	before := time.Now()
+	if err = toucher.Touch(context.TODO(), "object"); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	accessed, err = toucher.Accessed(context.TODO(), "object")
+	if err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+	if accessed.Before(before) {
+		t.Fatalf("expected access after %v got %v", before, accessed)
+	}
+
+	if err = toucher.Touch(context.TODO(), "missing"); !errors.Is(err, store.ErrObjectNotFound) {
+		t.Fatalf("expected %v got %v", store.ErrObjectNotFound, err)
+	}
+
+	if _, err = toucher.Accessed(context.TODO(), "missing"); !errors.Is(err, store.ErrObjectNotFound) {
+		t.Fatalf("expected %v got %v", store.ErrObjectNotFound, err)
+	}
+}
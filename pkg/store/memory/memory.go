@@ -0,0 +1,232 @@
+// Package memory implements an in-memory object store
+package memory
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/util"
+)
+
+// Scheme is the scheme of the URLs of the objects in a memory store
+const Scheme = "memory"
+
+type object struct {
+	checksum string
+	content  []byte
+	created  time.Time
+	accessed time.Time
+	pinned   bool
+}
+
+// Store an ObjectStore that keeps the objects in memory.
+// The objects' content can only be obtained using the Download method.
+type Store struct {
+	mutex   sync.RWMutex
+	objects map[string]object
+}
+
+// NewMemoryStore creates an empty in-memory object store
+func NewMemoryStore() *Store {
+	return &Store{
+		objects: map[string]object{},
+	}
+}
+
+// Put stores the object and returns the metadata
+// Fails if the object already exists
+func (m *Store) Put(_ context.Context, id string, content io.Reader) (store.Object, error) {
+	if id == "" {
+		return store.Object{}, fmt.Errorf("%w: id cannot be empty", store.ErrCreatingObject)
+	}
+
+	if strings.Contains(id, "/") {
+		return store.Object{}, fmt.Errorf("%w id cannot contain '/'", store.ErrCreatingObject)
+	}
+
+	buff := bytes.Buffer{}
+	_, err := buff.ReadFrom(content)
+	if err != nil {
+		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
+	}
+
+	digest := sha256.Sum256(buff.Bytes())
+	checksum := util.FormatChecksum(util.ChecksumSHA256, digest[:])
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, found := m.objects[id]; found {
+		return store.Object{}, fmt.Errorf("%w: %q", store.ErrDuplicateObject, id)
+	}
+
+	obj := object{checksum: checksum, content: buff.Bytes(), created: time.Now()}
+	m.objects[id] = obj
+
+	return obj.metadata(id), nil
+}
+
+// Get retrieves an objects if exists in the object store or an error otherwise
+func (m *Store) Get(_ context.Context, id string) (store.Object, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	obj, found := m.objects[id]
+	if !found {
+		return store.Object{}, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	return obj.metadata(id), nil
+}
+
+// List returns the objects in the store
+func (m *Store) List(_ context.Context) ([]store.Object, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	objects := make([]store.Object, 0, len(m.objects))
+	for id, obj := range m.objects {
+		objects = append(objects, obj.metadata(id))
+	}
+
+	return objects, nil
+}
+
+// Delete removes an object from the store
+func (m *Store) Delete(_ context.Context, id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if _, found := m.objects[id]; !found {
+		return fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	delete(m.objects, id)
+
+	return nil
+}
+
+// Pin pins an object in the store
+func (m *Store) Pin(_ context.Context, id string) error {
+	return m.setPinned(id, true)
+}
+
+// Unpin unpins an object in the store
+func (m *Store) Unpin(_ context.Context, id string) error {
+	return m.setPinned(id, false)
+}
+
+// Pinned returns if an object in the store is pinned
+func (m *Store) Pinned(_ context.Context, id string) (bool, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	obj, found := m.objects[id]
+	if !found {
+		return false, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	return obj.pinned, nil
+}
+
+// Touch records the object was accessed
+func (m *Store) Touch(_ context.Context, id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	obj, found := m.objects[id]
+	if !found {
+		return fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	obj.accessed = time.Now()
+	m.objects[id] = obj
+
+	return nil
+}
+
+// Accessed returns the last time the object was touched
+func (m *Store) Accessed(_ context.Context, id string) (time.Time, error) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	obj, found := m.objects[id]
+	if !found {
+		return time.Time{}, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	return obj.accessed, nil
+}
+
+func (m *Store) setPinned(id string, pinned bool) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	obj, found := m.objects[id]
+	if !found {
+		return fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	obj.pinned = pinned
+	m.objects[id] = obj
+
+	return nil
+}
+
+// Download returns the content of an object given its URL
+func (m *Store) Download(_ context.Context, object store.Object) (io.ReadCloser, error) {
+	u, err := url.Parse(object.URL)
+	if err != nil {
+		return nil, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	if u.Scheme != Scheme {
+		return nil, fmt.Errorf("%w unsupported schema: %s", store.ErrInvalidURL, u.Scheme)
+	}
+
+	id := strings.TrimPrefix(u.Path, "/")
+
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	obj, found := m.objects[id]
+	if !found {
+		return nil, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+	}
+
+	return content{bytes.NewReader(obj.content)}, nil
+}
+
+// content is a ReadCloser that can be seeked, for serving ranges of the object's content
+type content struct {
+	*bytes.Reader
+}
+
+// Close implements the io.Closer interface
+func (content) Close() error {
+	return nil
+}
+
+// metadata returns the metadata of the object with the given id
+func (o object) metadata(id string) store.Object {
+	return store.Object{
+		ID:       id,
+		Checksum: o.checksum,
+		URL:      objectURL(id),
+		Size:     int64(len(o.content)),
+		Created:  o.created,
+	}
+}
+
+func objectURL(id string) string {
+	return (&url.URL{Scheme: Scheme, Path: "/" + id}).String()
+}
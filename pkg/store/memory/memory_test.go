@@ -0,0 +1,212 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/grafana/k6build/pkg/store"
+)
+
+func TestMemoryStorePut(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		preload   map[string][]byte
+		id        string
+		content   []byte
+		expectErr error
+	}{
+		{
+			title:   "store object",
+			id:      "object",
+			content: []byte("content"),
+		},
+		{
+			title:     "store existing object",
+			preload:   map[string][]byte{"object": []byte("content")},
+			id:        "object",
+			content:   []byte("new content"),
+			expectErr: store.ErrDuplicateObject,
+		},
+		{
+			title:   "store empty object",
+			id:      "empty",
+			content: nil,
+		},
+		{
+			title:     "store empty id",
+			id:        "",
+			content:   []byte("content"),
+			expectErr: store.ErrCreatingObject,
+		},
+		{
+			title:     "store invalid id",
+			id:        "invalid/",
+			content:   []byte("content"),
+			expectErr: store.ErrCreatingObject,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			memStore := NewMemoryStore()
+			for id, content := range tc.preload {
+				if _, err := memStore.Put(context.TODO(), id, bytes.NewBuffer(content)); err != nil {
+					t.Fatalf("test setup: %v", err)
+				}
+			}
+
+			obj, err := memStore.Put(context.TODO(), tc.id, bytes.NewBuffer(tc.content))
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			reader, err := memStore.Download(context.TODO(), obj)
+			if err != nil {
+				t.Fatalf("downloading object %v", err)
+			}
+			defer reader.Close() //nolint:errcheck
+
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatalf("reading object %v", err)
+			}
+
+			if !bytes.Equal(tc.content, content) {
+				t.Fatalf("expected %v got %v", tc.content, content)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreGet(t *testing.T) {
+	t.Parallel()
+
+	memStore := NewMemoryStore()
+	stored, err := memStore.Put(context.TODO(), "object", bytes.NewBufferString("content"))
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		id        string
+		expect    store.Object
+		expectErr error
+	}{
+		{
+			title:  "retrieve existing object",
+			id:     "object",
+			expect: stored,
+		},
+		{
+			title:     "retrieve non existing object",
+			id:        "another object",
+			expectErr: store.ErrObjectNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			obj, err := memStore.Get(context.TODO(), tc.id)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && obj != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, obj)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreDownload(t *testing.T) {
+	t.Parallel()
+
+	memStore := NewMemoryStore()
+	_, err := memStore.Put(context.TODO(), "object", bytes.NewBufferString("content"))
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		url       string
+		expectErr error
+	}{
+		{
+			title: "download existing object",
+			url:   "memory:///object",
+		},
+		{
+			title:     "download non existing object",
+			url:       "memory:///another",
+			expectErr: store.ErrObjectNotFound,
+		},
+		{
+			title:     "download url with other scheme",
+			url:       "file:///object",
+			expectErr: store.ErrInvalidURL,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			reader, err := memStore.Download(context.TODO(), store.Object{URL: tc.url})
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if reader != nil {
+				_ = reader.Close()
+			}
+		})
+	}
+}
+
+func TestMemoryStoreListDelete(t *testing.T) {
+	t.Parallel()
+
+	memStore := NewMemoryStore()
+	for _, id := range []string{"object", "other"} {
+		if _, err := memStore.Put(context.TODO(), id, bytes.NewBufferString("content")); err != nil {
+			t.Fatalf("test setup: %v", err)
+		}
+	}
+
+	if err := memStore.Delete(context.TODO(), "object"); err != nil {
+		t.Fatalf("deleting object %v", err)
+	}
+
+	err := memStore.Delete(context.TODO(), "object")
+	if !errors.Is(err, store.ErrObjectNotFound) {
+		t.Fatalf("expected %v got %v", store.ErrObjectNotFound, err)
+	}
+
+	objects, err := memStore.List(context.TODO())
+	if err != nil {
+		t.Fatalf("listing objects %v", err)
+	}
+
+	if len(objects) != 1 || objects[0].ID != "other" {
+		t.Fatalf("expected only %q got %v", "other", objects)
+	}
+
+	if objects[0].Created.IsZero() {
+		t.Fatalf("expected creation time")
+	}
+}
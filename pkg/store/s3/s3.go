@@ -4,21 +4,23 @@ package s3
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"net/url"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/smithy-go"
 
 	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/s3client"
 	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/util"
 )
 
 // DefaultURLExpiration Default expiration for the presigned download URLs.
@@ -26,11 +28,32 @@ import (
 // TODO: check this default (AWS default is 900 seconds)
 const DefaultURLExpiration = time.Hour * 24
 
+// MaxURLExpiration is the maximum expiration allowed by S3 for presigned URLs
+const MaxURLExpiration = time.Hour * 24 * 7
+
+// checksumMetadata is the object metadata that keeps the checksum in the form <algorithm>:<hex digest>
+const checksumMetadata = "checksum"
+
+// DefaultExpirationTag is the default key of the tag used by bucket lifecycle rules for expiring objects
+const DefaultExpirationTag = "expire-after"
+
+const (
+	// pinnedTag is the tag set on the pinned objects
+	pinnedTag = "k6build-pinned"
+	// pinnedExpirationTag keeps the value of the expiration tag of a pinned object, for restoring it when unpinned
+	pinnedExpirationTag = "k6build-pinned-expiration"
+	// accessedTag is the tag that keeps the last time the object was touched, in RFC 3339 format
+	accessedTag = "k6build-accessed"
+)
+
 // Store a ObjectStore backed by a S3 bucket
 type Store struct {
-	bucket     string
-	client     *s3.Client
-	expiration time.Duration
+	bucket        string
+	client        *s3.Client
+	expiration    time.Duration
+	tags          map[string]string
+	expirationTag string
+	algorithm     string
 }
 
 // Config S3 Store configuration
@@ -43,32 +66,26 @@ type Config struct {
 	Endpoint string
 	// AWS Region
 	Region string
+	// Use path-style addressing, required by some s3-compatible services (e.g. MinIO)
+	UsePathStyle bool
+	// Static credentials. If not given, the default credential chain is used
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Use http instead of https for accessing the endpoint
+	DisableSSL bool
 	// Expiration for the presigned download URLs
 	URLExpiration time.Duration
-}
-
-// returns the S3 client options
-func (c Config) s3Opts() []func(o *s3.Options) {
-	opts := []func(o *s3.Options){}
-
-	if c.Endpoint != "" {
-		opts = append(opts, func(o *s3.Options) {
-			o.BaseEndpoint = aws.String(c.Endpoint)
-			o.UsePathStyle = true
-		})
-	}
-	return opts
-}
-
-// returns the aws configuration load options from Config
-func (c Config) awsOpts() []func(*config.LoadOptions) error {
-	opts := []func(*config.LoadOptions) error{}
-
-	if c.Region != "" {
-		opts = append(opts, config.WithRegion(c.Region))
-	}
-
-	return opts
+	// Tags set on the objects stored (e.g. expire-after=7d), which a bucket lifecycle policy can act on.
+	// Tags requested in the context with store.WithObjectTags override them.
+	Tags map[string]string
+	// Key of the tag used by the bucket lifecycle rules for expiring objects. Bucket lifecycle rules
+	// cannot exclude objects by tag, so pinned objects have this tag removed until they are unpinned.
+	// Defaults to DefaultExpirationTag.
+	ExpirationTag string
+	// Algorithm used for the objects' checksum (e.g. sha512). Defaults to util.DefaultChecksumAlgorithm.
+	// Only sha256 checksums are verified by S3, other algorithms are kept in the object's metadata.
+	ChecksumAlgorithm string
 }
 
 // WithExpiration sets the expiration for the presigned URL
@@ -84,23 +101,49 @@ func New(conf Config) (store.ObjectStore, error) {
 		return nil, fmt.Errorf("%w: bucket name cannot be empty", store.ErrInitializingStore)
 	}
 
+	algorithm := conf.ChecksumAlgorithm
+	if algorithm == "" {
+		algorithm = util.DefaultChecksumAlgorithm
+	}
+
+	if _, err := util.NewChecksumHash(algorithm); err != nil {
+		return nil, k6build.NewWrappedError(store.ErrInitializingStore, err)
+	}
+
 	client := conf.Client
 	if client == nil {
-		cfg, err := config.LoadDefaultConfig(context.TODO(), conf.awsOpts()...)
+		var err error
+		client, err = s3client.New(s3client.Config{
+			Endpoint:        conf.Endpoint,
+			Region:          conf.Region,
+			UsePathStyle:    conf.UsePathStyle,
+			AccessKeyID:     conf.AccessKeyID,
+			SecretAccessKey: conf.SecretAccessKey,
+			SessionToken:    conf.SessionToken,
+			DisableSSL:      conf.DisableSSL,
+		})
 		if err != nil {
 			return nil, k6build.NewWrappedError(store.ErrInitializingStore, err)
 		}
-		client = s3.NewFromConfig(cfg, conf.s3Opts()...)
 	}
 
 	expiration := conf.URLExpiration
 	if expiration == 0 {
 		expiration = DefaultURLExpiration
 	}
+
+	expirationTag := conf.ExpirationTag
+	if expirationTag == "" {
+		expirationTag = DefaultExpirationTag
+	}
+
 	return &Store{
-		client:     client,
-		bucket:     conf.Bucket,
-		expiration: expiration,
+		client:        client,
+		bucket:        conf.Bucket,
+		expiration:    expiration,
+		tags:          conf.Tags,
+		expirationTag: expirationTag,
+		algorithm:     algorithm,
 	}, nil
 }
 
@@ -116,18 +159,29 @@ func (s *Store) Put(ctx context.Context, id string, content io.Reader) (store.Ob
 		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
 	}
 
-	checksum := sha256.Sum256(buff)
-	_, err = s.client.PutObject(
-		ctx,
-		&s3.PutObjectInput{
-			Bucket:            aws.String(s.bucket),
-			Key:               aws.String(id),
-			Body:              bytes.NewReader(buff),
-			ChecksumAlgorithm: types.ChecksumAlgorithmSha256,
-			ChecksumSHA256:    aws.String(base64.StdEncoding.EncodeToString(checksum[:])),
-			IfNoneMatch:       aws.String("*"),
-		},
-	)
+	hash, err := util.NewChecksumHash(s.algorithm)
+	if err != nil {
+		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
+	}
+	_, _ = hash.Write(buff)
+	digest := hash.Sum(nil)
+	checksum := util.FormatChecksum(s.algorithm, digest)
+
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(id),
+		Body:        bytes.NewReader(buff),
+		IfNoneMatch: aws.String("*"),
+		Tagging:     s.tagging(ctx),
+		Metadata:    map[string]string{checksumMetadata: checksum},
+	}
+	// S3 verifies the content of the object only for the algorithms it supports
+	if s.algorithm == util.ChecksumSHA256 {
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmSha256
+		input.ChecksumSHA256 = aws.String(base64.StdEncoding.EncodeToString(digest))
+	}
+
+	_, err = s.client.PutObject(ctx, input)
 	if err != nil {
 		// check for duplicated object
 		var aerr smithy.APIError
@@ -137,15 +191,16 @@ func (s *Store) Put(ctx context.Context, id string, content io.Reader) (store.Ob
 		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
 	}
 
-	url, err := s.getDownloadURL(ctx, id)
+	downloadURL, err := s.getDownloadURL(ctx, id)
 	if err != nil {
 		return store.Object{}, k6build.NewWrappedError(store.ErrCreatingObject, err)
 	}
 
 	return store.Object{
 		ID:       id,
-		Checksum: fmt.Sprintf("%x", checksum),
-		URL:      url,
+		Checksum: checksum,
+		URL:      downloadURL,
+		Size:     int64(len(buff)),
 	}, nil
 }
 
@@ -159,6 +214,7 @@ func (s *Store) Get(ctx context.Context, id string) (store.Object, error) {
 			ObjectAttributes: []types.ObjectAttributes{
 				types.ObjectAttributesChecksum,
 				types.ObjectAttributesEtag,
+				types.ObjectAttributesObjectSize,
 			},
 		},
 	)
@@ -172,30 +228,245 @@ func (s *Store) Get(ctx context.Context, id string) (store.Object, error) {
 		return store.Object{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
 	}
 
-	url, err := s.getDownloadURL(ctx, id)
+	downloadURL, err := s.getDownloadURL(ctx, id)
 	if err != nil {
 		return store.Object{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
 	}
 
-	checksum, err := base64.StdEncoding.DecodeString(*obj.Checksum.ChecksumSHA256)
+	checksum, err := s.checksum(ctx, id, obj.Checksum)
 	if err != nil {
 		return store.Object{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
 	}
+
 	return store.Object{
 		ID:       id,
-		Checksum: fmt.Sprintf("%x", checksum),
-		URL:      url,
+		Checksum: checksum,
+		URL:      downloadURL,
+		Size:     aws.ToInt64(obj.ObjectSize),
+		Created:  aws.ToTime(obj.LastModified),
 	}, nil
 }
 
+// checksum returns the checksum of the object. sha256 checksums are obtained from the object's attributes
+// and other algorithms from the object's metadata.
+func (s *Store) checksum(ctx context.Context, id string, attributes *types.Checksum) (string, error) {
+	if attributes != nil && attributes.ChecksumSHA256 != nil {
+		digest, err := base64.StdEncoding.DecodeString(*attributes.ChecksumSHA256)
+		if err != nil {
+			return "", err
+		}
+		return util.FormatChecksum(util.ChecksumSHA256, digest), nil
+	}
+
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(id)})
+	if err != nil {
+		return "", err
+	}
+
+	checksum, found := head.Metadata[checksumMetadata]
+	if !found {
+		return "", fmt.Errorf("%w: object has no checksum", util.ErrInvalidChecksum)
+	}
+
+	return checksum, nil
+}
+
+// HealthCheck checks the bucket exists and is accessible
+func (s *Store) HealthCheck(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.bucket)})
+	if err != nil {
+		return k6build.NewWrappedError(store.ErrUnavailable, err)
+	}
+
+	return nil
+}
+
+// List returns the objects in the bucket. The objects don't have their checksum and URL.
+func (s *Store) List(ctx context.Context) ([]store.Object, error) {
+	objects := []store.Object{}
+
+	pages := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{Bucket: aws.String(s.bucket)})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, k6build.NewWrappedError(store.ErrAccessingObject, err)
+		}
+
+		for _, obj := range page.Contents {
+			objects = append(objects, store.Object{
+				ID:      aws.ToString(obj.Key),
+				Size:    aws.ToInt64(obj.Size),
+				Created: aws.ToTime(obj.LastModified),
+			})
+		}
+	}
+
+	return objects, nil
+}
+
+// Delete removes an object from the bucket
+func (s *Store) Delete(ctx context.Context, id string) error {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(id)})
+	if err != nil {
+		var aerr smithy.APIError
+		if errors.As(err, &aerr) && (aerr.ErrorCode() == "NotFound" || aerr.ErrorCode() == "NoSuchKey") {
+			return fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+		}
+		return k6build.NewWrappedError(store.ErrDeletingObject, err)
+	}
+
+	_, err = s.client.DeleteObject(ctx, &s3.DeleteObjectInput{Bucket: aws.String(s.bucket), Key: aws.String(id)})
+	if err != nil {
+		return k6build.NewWrappedError(store.ErrDeletingObject, err)
+	}
+
+	return nil
+}
+
+// Pin pins the object by setting the pinned tag, keeping its other tags.
+// The expiration tag is removed, so the object is not expired by the bucket lifecycle rules, and its
+// value is kept for restoring it when the object is unpinned.
+func (s *Store) Pin(ctx context.Context, id string) error {
+	return s.updateTags(ctx, id, store.ErrPinningObject, func(tags map[string]string) {
+		tags[pinnedTag] = "true"
+		if expiration, found := tags[s.expirationTag]; found {
+			tags[pinnedExpirationTag] = expiration
+			delete(tags, s.expirationTag)
+		}
+	})
+}
+
+// Unpin unpins the object by removing the pinned tag and restoring its expiration tag
+func (s *Store) Unpin(ctx context.Context, id string) error {
+	return s.updateTags(ctx, id, store.ErrPinningObject, func(tags map[string]string) {
+		delete(tags, pinnedTag)
+		if expiration, found := tags[pinnedExpirationTag]; found {
+			tags[s.expirationTag] = expiration
+			delete(tags, pinnedExpirationTag)
+		}
+	})
+}
+
+// Pinned returns if the object has the pinned tag
+func (s *Store) Pinned(ctx context.Context, id string) (bool, error) {
+	tags, err := s.getTags(ctx, id)
+	if err != nil {
+		return false, err
+	}
+
+	_, pinned := tags[pinnedTag]
+	return pinned, nil
+}
+
+// Touch records the object was accessed by setting the accessed tag, keeping its other tags
+func (s *Store) Touch(ctx context.Context, id string) error {
+	accessed := time.Now().UTC().Format(time.RFC3339)
+	return s.updateTags(ctx, id, store.ErrAccessingObject, func(tags map[string]string) { tags[accessedTag] = accessed })
+}
+
+// Accessed returns the last time the object was touched, from its accessed tag
+func (s *Store) Accessed(ctx context.Context, id string) (time.Time, error) {
+	tags, err := s.getTags(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	value, found := tags[accessedTag]
+	if !found {
+		return time.Time{}, nil
+	}
+
+	accessed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	return accessed, nil
+}
+
+// getTags returns the tags of the object
+func (s *Store) getTags(ctx context.Context, id string) (map[string]string, error) {
+	resp, err := s.client.GetObjectTagging(
+		ctx,
+		&s3.GetObjectTaggingInput{Bucket: aws.String(s.bucket), Key: aws.String(id)},
+	)
+	if err != nil {
+		var aerr smithy.APIError
+		if errors.As(err, &aerr) && (aerr.ErrorCode() == "NotFound" || aerr.ErrorCode() == "NoSuchKey") {
+			return nil, fmt.Errorf("%w (%s)", store.ErrObjectNotFound, id)
+		}
+		return nil, k6build.NewWrappedError(store.ErrAccessingObject, err)
+	}
+
+	tags := map[string]string{}
+	for _, tag := range resp.TagSet {
+		tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+
+	return tags, nil
+}
+
+// updateTags replaces the tags of the object with the tags returned by the update function.
+// Failures updating the tags are wrapped in the given error.
+func (s *Store) updateTags(ctx context.Context, id string, opErr error, update func(map[string]string)) error {
+	tags, err := s.getTags(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	update(tags)
+
+	tagSet := []types.Tag{}
+	for k, v := range tags {
+		tagSet = append(tagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+
+	_, err = s.client.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(s.bucket),
+		Key:     aws.String(id),
+		Tagging: &types.Tagging{TagSet: tagSet},
+	})
+	if err != nil {
+		return k6build.NewWrappedError(opErr, err)
+	}
+
+	return nil
+}
+
+// tagging returns the tags for an object, encoded as URL query parameters as expected by S3.
+// Returns nil if there are no tags.
+func (s *Store) tagging(ctx context.Context) *string {
+	tags := maps.Clone(s.tags)
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	maps.Copy(tags, store.ObjectTags(ctx))
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	values := url.Values{}
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+
+	return aws.String(values.Encode())
+}
+
 func (s *Store) getDownloadURL(ctx context.Context, id string) (string, error) {
+	expiration := s.expiration
+	if requested, ok := store.URLExpiration(ctx); ok {
+		expiration = min(requested, MaxURLExpiration)
+	}
+
 	// create a presigned get request to get the download URL
 	request, err := s3.NewPresignClient(s.client).PresignGetObject(
 		ctx, &s3.GetObjectInput{
 			Bucket: aws.String(s.bucket),
 			Key:    aws.String(id),
 		},
-		WithExpiration(s.expiration),
+		WithExpiration(expiration),
 	)
 	if err != nil {
 		return "", k6build.NewWrappedError(store.ErrCreatingObject, err)
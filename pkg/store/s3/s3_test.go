@@ -5,13 +5,17 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/xml"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"runtime"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -19,6 +23,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/docker/go-connections/nat"
+	"github.com/google/go-cmp/cmp"
 	"github.com/grafana/k6build/pkg/store"
 
 	"github.com/testcontainers/testcontainers-go/modules/localstack"
@@ -273,3 +278,236 @@ func TestGetObject(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadURLExpiration(t *testing.T) {
+	t.Parallel()
+
+	// presigning URLs doesn't require access to s3
+	awsCfg, err := config.LoadDefaultConfig(context.TODO(),
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("accesskey", "secretkey", "token")),
+	)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	s, err := New(Config{Client: s3.NewFromConfig(awsCfg), Bucket: "test", URLExpiration: time.Hour})
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	testCases := []struct {
+		title      string
+		expiration time.Duration
+		expect     string
+	}{
+		{
+			title:  "store default",
+			expect: "3600",
+		},
+		{
+			title:      "requested expiration",
+			expiration: 15 * time.Minute,
+			expect:     "900",
+		},
+		{
+			title:      "requested expiration over maximum",
+			expiration: 30 * 24 * time.Hour,
+			expect:     "604800",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.TODO()
+			if tc.expiration > 0 {
+				ctx = store.WithURLExpiration(ctx, tc.expiration)
+			}
+
+			downloadURL, err := s.(*Store).getDownloadURL(ctx, "object")
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+
+			parsed, err := url.Parse(downloadURL)
+			if err != nil {
+				t.Fatalf("invalid url %v", err)
+			}
+
+			if expires := parsed.Query().Get("X-Amz-Expires"); expires != tc.expect {
+				t.Fatalf("expected expiration %s got %s", tc.expect, expires)
+			}
+		})
+	}
+}
+
+func TestPutObjectTags(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		storeTags map[string]string
+		ctxTags   map[string]string
+		expected  url.Values
+	}{
+		{
+			title:    "no tags",
+			expected: url.Values{},
+		},
+		{
+			title:     "store tags",
+			storeTags: map[string]string{"expire-after": "7d"},
+			expected:  url.Values{"expire-after": {"7d"}},
+		},
+		{
+			title:     "context tags override store tags",
+			storeTags: map[string]string{"expire-after": "7d", "team": "k6"},
+			ctxTags:   map[string]string{"expire-after": "1d"},
+			expected:  url.Values{"expire-after": {"1d"}, "team": {"k6"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			// record the tagging header sent with the PutObject request
+			tagging := make(chan string, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodPut {
+					tagging <- r.Header.Get("X-Amz-Tagging")
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			t.Cleanup(srv.Close)
+
+			client := s3.New(s3.Options{
+				Region:       "us-east-1",
+				BaseEndpoint: aws.String(srv.URL),
+				UsePathStyle: true,
+				Credentials:  credentials.NewStaticCredentialsProvider("accesskey", "secretkey", "token"),
+			})
+
+			s, err := New(Config{Client: client, Bucket: "test", Tags: tc.storeTags})
+			if err != nil {
+				t.Fatalf("creating store %v", err)
+			}
+
+			ctx := store.WithObjectTags(context.TODO(), tc.ctxTags)
+			_, err = s.Put(ctx, "object", bytes.NewBufferString("content"))
+			if err != nil {
+				t.Fatalf("storing object %v", err)
+			}
+
+			tags, err := url.ParseQuery(<-tagging)
+			if err != nil {
+				t.Fatalf("parsing tags %v", err)
+			}
+
+			if tags.Encode() != tc.expected.Encode() {
+				t.Fatalf("expected tags %q got %q", tc.expected.Encode(), tags.Encode())
+			}
+		})
+	}
+}
+
+// tagServer emulates the object tagging API of a bucket with a single object
+type tagServer struct {
+	mutex sync.Mutex
+	tags  map[string]string
+}
+
+type tag struct {
+	Key   string `xml:"Key"`
+	Value string `xml:"Value"`
+}
+
+type tagging struct {
+	XMLName xml.Name `xml:"Tagging"`
+	TagSet  []tag    `xml:"TagSet>Tag"`
+}
+
+func (s *tagServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		response := tagging{}
+		for k, v := range s.tags {
+			response.TagSet = append(response.TagSet, tag{Key: k, Value: v})
+		}
+		_ = xml.NewEncoder(w).Encode(response)
+	case http.MethodPut:
+		request := tagging{}
+		if err := xml.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		s.tags = map[string]string{}
+		for _, t := range request.TagSet {
+			s.tags[t.Key] = t.Value
+		}
+	}
+}
+
+func TestPinExpirationTag(t *testing.T) {
+	t.Parallel()
+
+	tags := &tagServer{tags: map[string]string{"expire-after": "7d", "team": "k6"}}
+	srv := httptest.NewServer(tags)
+	t.Cleanup(srv.Close)
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(srv.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("accesskey", "secretkey", "token"),
+	})
+
+	s, err := New(Config{Client: client, Bucket: "test"})
+	if err != nil {
+		t.Fatalf("creating store %v", err)
+	}
+	pinner, _ := s.(store.ObjectPinner)
+
+	for _, step := range []struct {
+		action func(context.Context, string) error
+		pinned bool
+		expect map[string]string
+	}{
+		{
+			action: pinner.Pin,
+			pinned: true,
+			expect: map[string]string{pinnedTag: "true", pinnedExpirationTag: "7d", "team": "k6"},
+		},
+		{
+			action: pinner.Pin,
+			pinned: true,
+			expect: map[string]string{pinnedTag: "true", pinnedExpirationTag: "7d", "team": "k6"},
+		},
+		{
+			action: pinner.Unpin,
+			pinned: false,
+			expect: map[string]string{"expire-after": "7d", "team": "k6"},
+		},
+	} {
+		if err = step.action(context.TODO(), "object"); err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+
+		pinned, err := pinner.Pinned(context.TODO(), "object")
+		if err != nil {
+			t.Fatalf("unexpected %v", err)
+		}
+		if pinned != step.pinned {
+			t.Fatalf("expected pinned %t got %t", step.pinned, pinned)
+		}
+
+		if diff := cmp.Diff(step.expect, tags.tags); diff != "" {
+			t.Fatalf("unexpected tags (-want +got):\n%s", diff)
+		}
+	}
+}
@@ -10,8 +10,12 @@ import (
 	"log/slog"
 	"net/http"
 	"net/url"
+	"path"
+	"strconv"
+	"time"
 
 	"github.com/grafana/k6build"
+	"github.com/grafana/k6build/pkg/httpserver"
 	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/store/api"
 	"github.com/grafana/k6build/pkg/store/downloader"
@@ -31,6 +35,8 @@ type StoreServerConfig struct {
 	Store      store.ObjectStore
 	Log        *slog.Logger
 	HTTPClient *http.Client
+	// Cross-origin requests allowed. If no origins are configured, cross-origin requests are not allowed.
+	CORS httpserver.CORSConfig
 }
 
 // NewStoreServer returns a StoreServer backed by a file object store
@@ -73,8 +79,10 @@ func NewStoreServer(config StoreServerConfig) (http.Handler, error) {
 	handler.HandleFunc("POST /store/{id}", storeSrv.Store)
 	handler.HandleFunc("GET /store/{id}", storeSrv.Get)
 	handler.HandleFunc("GET /store/{id}/download", storeSrv.Download)
+	handler.HandleFunc("POST /store/{id}/pin", storeSrv.Pin)
+	handler.HandleFunc("DELETE /store/{id}/pin", storeSrv.Unpin)
 
-	return handler, nil
+	return httpserver.CORS(config.CORS, handler), nil
 }
 
 // Get retrieves an objects if exists in the object store or an error otherwise
@@ -109,6 +117,7 @@ func (s *StoreServer) Get(w http.ResponseWriter, r *http.Request) {
 		ID:       id,
 		Checksum: object.Checksum,
 		URL:      downloadURL,
+		Size:     object.Size,
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -148,6 +157,78 @@ func (s *StoreServer) Store(w http.ResponseWriter, r *http.Request) {
 		ID:       id,
 		Checksum: object.Checksum,
 		URL:      downloadURL,
+		Size:     object.Size,
+	}
+
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+}
+
+// Pin protects an object from expiring
+func (s *StoreServer) Pin(w http.ResponseWriter, r *http.Request) {
+	s.setPinned(w, r, true)
+}
+
+// Unpin removes the protection of a pinned object
+func (s *StoreServer) Unpin(w http.ResponseWriter, r *http.Request) {
+	s.setPinned(w, r, false)
+}
+
+// setPinned pins or unpins an object and returns its metadata
+func (s *StoreServer) setPinned(w http.ResponseWriter, r *http.Request, pin bool) {
+	resp := api.StoreResponse{}
+
+	w.Header().Add("Content-Type", "application/json")
+
+	// ensure errors are reported and logged
+	defer func() {
+		if resp.Error != nil {
+			s.log.Error(resp.Error.Error())
+			_ = json.NewEncoder(w).Encode(resp) //nolint:errchkjson
+		}
+	}()
+
+	pinner, ok := s.store.(store.ObjectPinner)
+	if !ok {
+		w.WriteHeader(http.StatusNotImplemented)
+		resp.Error = k6build.NewWrappedError(api.ErrObjectStoreAccess, store.ErrNotSupported)
+		return
+	}
+
+	id := r.PathValue("id")
+	ctx := context.Background()
+
+	var err error
+	if pin {
+		err = pinner.Pin(ctx, id) //nolint:contextcheck
+	} else {
+		err = pinner.Unpin(ctx, id) //nolint:contextcheck
+	}
+	if errors.Is(err, store.ErrObjectNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = k6build.NewWrappedError(api.ErrObjectStoreAccess, err)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = k6build.NewWrappedError(api.ErrObjectStoreAccess, err)
+		return
+	}
+
+	s.log.Info("object pin changed", "id", id, "pinned", pin)
+
+	object, err := s.store.Get(ctx, id) //nolint:contextcheck
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = k6build.NewWrappedError(api.ErrObjectStoreAccess, err)
+		return
+	}
+
+	resp.Object = store.Object{
+		ID:       id,
+		Checksum: object.Checksum,
+		URL:      getDownloadURL(s.baseURL, r),
+		Size:     object.Size,
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -167,13 +248,15 @@ func getDownloadURL(baseURL *url.URL, r *http.Request) string {
 	url := url.URL{
 		Scheme: scheme,
 		Host:   r.Host,
-		Path:   r.URL.JoinPath("download").String(),
+		Path:   "/" + path.Join("store", r.PathValue("id"), "download"),
 	}
 
 	return url.String()
 }
 
-// Download returns an object's content given its id
+// Download returns an object's content given its id.
+// Honors the If-None-Match header, returning 304 if the client's ETag matches the object's.
+// If the store provides seekable content, range requests are supported.
 func (s *StoreServer) Download(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -191,7 +274,21 @@ func (s *StoreServer) Download(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	objectContent, err := downloader.Download(context.Background(), s.client, object) //nolint:contextcheck
+	// the checksum identifies the content, so the ETag is strong
+	etag := fmt.Sprintf("%q", object.Checksum)
+	w.Header().Set("ETag", etag)
+
+	if httpserver.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var objectContent io.ReadCloser
+	if d, ok := s.store.(store.ObjectDownloader); ok {
+		objectContent, err = d.Download(context.Background(), object) //nolint:contextcheck
+	} else {
+		objectContent, err = downloader.Download(context.Background(), s.client, object) //nolint:contextcheck
+	}
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -200,8 +297,18 @@ func (s *StoreServer) Download(w http.ResponseWriter, r *http.Request) {
 		_ = objectContent.Close()
 	}()
 
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="k6"`)
+
+	// if the content can be seeked, serve range requests for resuming interrupted downloads
+	if seeker, ok := objectContent.(io.ReadSeeker); ok {
+		http.ServeContent(w, r, "", time.Time{}, seeker)
+		return
+	}
+
+	if object.Size > 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(object.Size, 10))
+	}
 	w.WriteHeader(http.StatusOK)
-	w.Header().Add("Content-Type", "application/octet-stream")
-	w.Header().Add("ETag", object.ID)
 	_, _ = io.Copy(w, objectContent)
 }
@@ -9,8 +9,10 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/grafana/k6build/pkg/store"
 	"github.com/grafana/k6build/pkg/store/api"
 	"github.com/grafana/k6build/pkg/store/file"
+	"github.com/grafana/k6build/pkg/store/memory"
 )
 
 func TestStoreServerGet(t *testing.T) {
@@ -241,6 +243,15 @@ func TestStoreServerDownload(t *testing.T) {
 				return
 			}
 
+			if resp.ContentLength != int64(len(tc.content)) {
+				t.Fatalf("expected content length %d got %d", len(tc.content), resp.ContentLength)
+			}
+
+			disposition := `attachment; filename="k6"`
+			if resp.Header.Get("Content-Disposition") != disposition {
+				t.Fatalf("expected content disposition %s got %s", disposition, resp.Header.Get("Content-Disposition"))
+			}
+
 			content := bytes.Buffer{}
 			_, err = content.ReadFrom(resp.Body)
 			if err != nil {
@@ -253,3 +264,380 @@ func TestStoreServerDownload(t *testing.T) {
 		})
 	}
 }
+
+func TestStoreServerDownloadMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	store := memory.NewMemoryStore()
+	content := []byte("content object 1")
+	if _, err := store.Put(context.TODO(), "object1", bytes.NewBuffer(content)); err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	storeSrv, err := NewStoreServer(StoreServerConfig{Store: store})
+	if err != nil {
+		t.Fatalf("creating store server %v", err)
+	}
+
+	srv := httptest.NewServer(storeSrv)
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(fmt.Sprintf("%s/store/object1/download", srv.URL))
+	if err != nil {
+		t.Fatalf("accessing server %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected %s got %s", http.StatusText(http.StatusOK), resp.Status)
+	}
+
+	downloaded := bytes.Buffer{}
+	_, err = downloaded.ReadFrom(resp.Body)
+	if err != nil {
+		t.Fatalf("reading content %v", err)
+	}
+
+	if !bytes.Equal(downloaded.Bytes(), content) {
+		t.Fatalf("expected %q got %q", content, downloaded.Bytes())
+	}
+}
+
+func TestStoreServerConditionalDownload(t *testing.T) {
+	t.Parallel()
+
+	store := memory.NewMemoryStore()
+	content := []byte("content object 1")
+	object, err := store.Put(context.TODO(), "object1", bytes.NewBuffer(content))
+	if err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+	etag := fmt.Sprintf("%q", object.Checksum)
+
+	storeSrv, err := NewStoreServer(StoreServerConfig{Store: store})
+	if err != nil {
+		t.Fatalf("creating store server %v", err)
+	}
+
+	srv := httptest.NewServer(storeSrv)
+	t.Cleanup(srv.Close)
+
+	testCases := []struct {
+		title       string
+		ifNoneMatch string
+		status      int
+	}{
+		{
+			title:       "no condition",
+			ifNoneMatch: "",
+			status:      http.StatusOK,
+		},
+		{
+			title:       "matching etag",
+			ifNoneMatch: etag,
+			status:      http.StatusNotModified,
+		},
+		{
+			title:       "matching weak etag",
+			ifNoneMatch: "W/" + etag,
+			status:      http.StatusNotModified,
+		},
+		{
+			title:       "matching etag in list",
+			ifNoneMatch: `"other", ` + etag,
+			status:      http.StatusNotModified,
+		},
+		{
+			title:       "any etag",
+			ifNoneMatch: "*",
+			status:      http.StatusNotModified,
+		},
+		{
+			title:       "not matching etag",
+			ifNoneMatch: `"other"`,
+			status:      http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			req, err := http.NewRequestWithContext(
+				context.TODO(),
+				http.MethodGet,
+				fmt.Sprintf("%s/store/object1/download", srv.URL),
+				nil,
+			)
+			if err != nil {
+				t.Fatalf("creating request %v", err)
+			}
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("accessing server %v", err)
+			}
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected %s got %s", http.StatusText(tc.status), resp.Status)
+			}
+
+			if resp.Header.Get("ETag") != etag {
+				t.Fatalf("expected etag %s got %s", etag, resp.Header.Get("ETag"))
+			}
+
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			if resp.ContentLength != int64(len(content)) {
+				t.Fatalf("expected content length %d got %d", len(content), resp.ContentLength)
+			}
+
+			downloaded := bytes.Buffer{}
+			_, err = downloaded.ReadFrom(resp.Body)
+			if err != nil {
+				t.Fatalf("reading content %v", err)
+			}
+
+			if !bytes.Equal(downloaded.Bytes(), content) {
+				t.Fatalf("expected %q got %q", content, downloaded.Bytes())
+			}
+		})
+	}
+}
+
+func TestStoreServerRangeDownload(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("content object 1")
+
+	fileStore, err := file.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("creating test file store %v", err)
+	}
+
+	testCases := []struct {
+		title         string
+		store         store.ObjectStore
+		rangeHeader   string
+		status        int
+		expected      []byte
+		expectedRange string
+	}{
+		{
+			title:    "file store full content",
+			store:    fileStore,
+			status:   http.StatusOK,
+			expected: content,
+		},
+		{
+			title:         "file store resume download",
+			store:         fileStore,
+			rangeHeader:   "bytes=8-",
+			status:        http.StatusPartialContent,
+			expected:      content[8:],
+			expectedRange: fmt.Sprintf("bytes 8-%d/%d", len(content)-1, len(content)),
+		},
+		{
+			title:         "memory store range",
+			store:         memory.NewMemoryStore(),
+			rangeHeader:   "bytes=0-6",
+			status:        http.StatusPartialContent,
+			expected:      content[:7],
+			expectedRange: fmt.Sprintf("bytes 0-6/%d", len(content)),
+		},
+		{
+			title:       "invalid range",
+			store:       memory.NewMemoryStore(),
+			rangeHeader: fmt.Sprintf("bytes=%d-", len(content)+1),
+			status:      http.StatusRequestedRangeNotSatisfiable,
+		},
+	}
+
+	// the file store is shared, so the object is stored once
+	if _, err = fileStore.Put(context.TODO(), "object1", bytes.NewBuffer(content)); err != nil {
+		t.Fatalf("test setup: %v", err)
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.store != fileStore {
+				if _, err := tc.store.Put(context.TODO(), "object1", bytes.NewBuffer(content)); err != nil {
+					t.Fatalf("test setup: %v", err)
+				}
+			}
+
+			storeSrv, err := NewStoreServer(StoreServerConfig{Store: tc.store})
+			if err != nil {
+				t.Fatalf("creating store server %v", err)
+			}
+
+			srv := httptest.NewServer(storeSrv)
+			t.Cleanup(srv.Close)
+
+			req, err := http.NewRequestWithContext(
+				context.TODO(),
+				http.MethodGet,
+				fmt.Sprintf("%s/store/object1/download", srv.URL),
+				nil,
+			)
+			if err != nil {
+				t.Fatalf("creating request %v", err)
+			}
+			if tc.rangeHeader != "" {
+				req.Header.Set("Range", tc.rangeHeader)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("accessing server %v", err)
+			}
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected %s got %s", http.StatusText(tc.status), resp.Status)
+			}
+
+			if tc.status == http.StatusRequestedRangeNotSatisfiable {
+				return
+			}
+
+			if resp.Header.Get("Accept-Ranges") != "bytes" {
+				t.Fatalf("expected accept ranges %q got %q", "bytes", resp.Header.Get("Accept-Ranges"))
+			}
+
+			if resp.Header.Get("Content-Range") != tc.expectedRange {
+				t.Fatalf("expected content range %q got %q", tc.expectedRange, resp.Header.Get("Content-Range"))
+			}
+
+			downloaded := bytes.Buffer{}
+			_, err = downloaded.ReadFrom(resp.Body)
+			if err != nil {
+				t.Fatalf("reading content %v", err)
+			}
+
+			if !bytes.Equal(downloaded.Bytes(), tc.expected) {
+				t.Fatalf("expected %q got %q", tc.expected, downloaded.Bytes())
+			}
+		})
+	}
+}
+
+func TestStoreServerPin(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title        string
+		store        store.ObjectStore
+		pinned       bool
+		method       string
+		id           string
+		status       int
+		expectPinned bool
+	}{
+		{
+			title:        "pin object",
+			store:        memory.NewMemoryStore(),
+			method:       http.MethodPost,
+			id:           "object",
+			status:       http.StatusOK,
+			expectPinned: true,
+		},
+		{
+			title:  "unpin object",
+			store:  memory.NewMemoryStore(),
+			pinned: true,
+			method: http.MethodDelete,
+			id:     "object",
+			status: http.StatusOK,
+		},
+		{
+			title:  "object not found",
+			store:  memory.NewMemoryStore(),
+			method: http.MethodPost,
+			id:     "not_found",
+			status: http.StatusNotFound,
+		},
+		{
+			title:  "store cannot pin",
+			store:  struct{ store.ObjectStore }{memory.NewMemoryStore()},
+			method: http.MethodPost,
+			id:     "object",
+			status: http.StatusNotImplemented,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if _, err := tc.store.Put(context.TODO(), "object", bytes.NewBufferString("content")); err != nil {
+				t.Fatalf("test setup: %v", err)
+			}
+			if tc.pinned {
+				if err := tc.store.(store.ObjectPinner).Pin(context.TODO(), "object"); err != nil {
+					t.Fatalf("test setup: %v", err)
+				}
+			}
+
+			storeSrv, err := NewStoreServer(StoreServerConfig{Store: tc.store})
+			if err != nil {
+				t.Fatalf("creating store server %v", err)
+			}
+			srv := httptest.NewServer(storeSrv)
+			t.Cleanup(srv.Close)
+
+			req, err := http.NewRequestWithContext(
+				context.TODO(), tc.method, fmt.Sprintf("%s/store/%s/pin", srv.URL, tc.id), nil,
+			)
+			if err != nil {
+				t.Fatalf("creating request %v", err)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("accessing server %v", err)
+			}
+			defer resp.Body.Close() //nolint:errcheck
+
+			if resp.StatusCode != tc.status {
+				t.Fatalf("expected %s got %s", http.StatusText(tc.status), resp.Status)
+			}
+
+			if tc.status != http.StatusOK {
+				return
+			}
+
+			storeResp := api.StoreResponse{}
+			if err = json.NewDecoder(resp.Body).Decode(&storeResp); err != nil {
+				t.Fatalf("decoding response %v", err)
+			}
+
+			expectURL := fmt.Sprintf("%s/store/%s/download", srv.URL, tc.id)
+			if storeResp.Object.URL != expectURL {
+				t.Fatalf("expected url %s got %s", expectURL, storeResp.Object.URL)
+			}
+
+			pinned, err := store.IsPinned(context.TODO(), tc.store, tc.id)
+			if err != nil {
+				t.Fatalf("unexpected %v", err)
+			}
+			if pinned != tc.expectPinned {
+				t.Fatalf("expected pinned %t got %t", tc.expectPinned, pinned)
+			}
+		})
+	}
+}
@@ -7,25 +7,32 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"time"
 )
 
 var (
 	ErrAccessingObject   = errors.New("accessing object") //nolint:revive
 	ErrCreatingObject    = errors.New("creating object")
+	ErrDeletingObject    = errors.New("deleting object")
 	ErrInitializingStore = errors.New("initializing store")
 	ErrInvalidURL        = errors.New("invalid object URL")
 	ErrObjectNotFound    = errors.New("object not found")
+	ErrPinningObject     = errors.New("pinning object")
 	ErrNotSupported      = errors.New("not supported")
 	ErrDuplicateObject   = errors.New("duplicate object")
+	ErrUnavailable       = errors.New("store unavailable")
 )
 
 // Object represents an object stored in the store
-// TODO: add metadata (e.g creation data, size)
 type Object struct {
 	ID       string
 	Checksum string
 	// an url for downloading the object's content
 	URL string
+	// size of the object's content in bytes
+	Size int64
+	// time the object was stored. Zero if the store doesn't report it
+	Created time.Time
 }
 
 func (o Object) String() string {
@@ -33,6 +40,7 @@ func (o Object) String() string {
 	buffer.WriteString(fmt.Sprintf("id: %s", o.ID))
 	buffer.WriteString(fmt.Sprintf(" checksum: %s", o.Checksum))
 	buffer.WriteString(fmt.Sprintf("url: %s", o.URL))
+	buffer.WriteString(fmt.Sprintf(" size: %d", o.Size))
 
 	return buffer.String()
 }
@@ -44,3 +52,97 @@ type ObjectStore interface {
 	// Put stores the object and returns the metadata
 	Put(ctx context.Context, id string, content io.Reader) (Object, error)
 }
+
+// ObjectDownloader defines the interface of stores that provide the content of their objects.
+// Used for stores whose object URLs cannot be downloaded directly (e.g. in-memory store)
+type ObjectDownloader interface {
+	// Download returns the content of the object
+	Download(ctx context.Context, object Object) (io.ReadCloser, error)
+}
+
+// ObjectLister defines the interface of stores that can list their objects
+type ObjectLister interface {
+	// List returns the objects in the store. The objects may not have their checksum and URL.
+	List(ctx context.Context) ([]Object, error)
+}
+
+// ObjectDeleter defines the interface of stores that can delete their objects
+type ObjectDeleter interface {
+	// Delete removes the object from the store. Fails with ErrObjectNotFound if it doesn't exist.
+	Delete(ctx context.Context, id string) error
+}
+
+// ObjectPinner defines the interface of stores that can pin their objects.
+// Pinned objects are not deleted when they expire, but can still be deleted explicitly.
+type ObjectPinner interface {
+	// Pin protects the object from expiring. Fails with ErrObjectNotFound if it doesn't exist.
+	Pin(ctx context.Context, id string) error
+	// Unpin removes the protection of the object. Fails with ErrObjectNotFound if it doesn't exist.
+	Unpin(ctx context.Context, id string) error
+	// Pinned returns if the object is pinned. Fails with ErrObjectNotFound if it doesn't exist.
+	Pinned(ctx context.Context, id string) (bool, error)
+}
+
+// IsPinned returns if the object is pinned. Objects in stores that don't implement ObjectPinner are not pinned.
+func IsPinned(ctx context.Context, store ObjectStore, id string) (bool, error) {
+	pinner, ok := store.(ObjectPinner)
+	if !ok {
+		return false, nil
+	}
+
+	return pinner.Pinned(ctx, id)
+}
+
+// ObjectToucher defines the interface of stores that can record when their objects were last accessed.
+// Used for not deleting the objects in use when they expire, even if accessed by other processes.
+type ObjectToucher interface {
+	// Touch records the object was accessed now. Fails with ErrObjectNotFound if it doesn't exist.
+	Touch(ctx context.Context, id string) error
+	// Accessed returns the last time the object was touched, or the zero time if it was never touched.
+	// Fails with ErrObjectNotFound if it doesn't exist.
+	Accessed(ctx context.Context, id string) (time.Time, error)
+}
+
+// HealthChecker defines the interface of stores that can check if their backend is reachable
+type HealthChecker interface {
+	// HealthCheck returns an error if the store cannot be used (e.g. its backend is unreachable)
+	HealthCheck(ctx context.Context) error
+}
+
+// HealthCheck checks if the store can be used. Stores that don't implement HealthChecker are assumed healthy.
+func HealthCheck(ctx context.Context, store ObjectStore) error {
+	checker, ok := store.(HealthChecker)
+	if !ok {
+		return nil
+	}
+
+	return checker.HealthCheck(ctx)
+}
+
+type urlExpirationKey struct{}
+
+// WithURLExpiration returns a context that requests the given expiration for the download URLs
+// returned by the store. Stores whose URLs don't expire (e.g. file store) ignore it.
+func WithURLExpiration(ctx context.Context, expiration time.Duration) context.Context {
+	return context.WithValue(ctx, urlExpirationKey{}, expiration)
+}
+
+// URLExpiration returns the download URL expiration requested in the context, if any
+func URLExpiration(ctx context.Context) (time.Duration, bool) {
+	expiration, ok := ctx.Value(urlExpirationKey{}).(time.Duration)
+	return expiration, ok && expiration > 0
+}
+
+type objectTagsKey struct{}
+
+// WithObjectTags returns a context that requests the given tags to be set on the objects stored.
+// Stores that don't support tags (e.g. file store) ignore them.
+func WithObjectTags(ctx context.Context, tags map[string]string) context.Context {
+	return context.WithValue(ctx, objectTagsKey{}, tags)
+}
+
+// ObjectTags returns the object tags requested in the context, if any
+func ObjectTags(ctx context.Context) map[string]string {
+	tags, _ := ctx.Value(objectTagsKey{}).(map[string]string)
+	return tags
+}
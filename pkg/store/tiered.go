@@ -0,0 +1,197 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/grafana/k6build/pkg/util"
+)
+
+// TieredStore is an ObjectStore that keeps a local copy of the objects of a remote store.
+// Objects are retrieved from the local store when present and otherwise copied from the remote store.
+// Objects are stored in both stores.
+type TieredStore struct {
+	local      ObjectStore
+	downloader ObjectDownloader
+	remote     ObjectStore
+	client     *http.Client
+}
+
+// NewTieredStore returns a TieredStore that keeps a copy of the objects of the remote store in the local store.
+// The local store must provide the content of its objects (e.g. file store).
+func NewTieredStore(local ObjectStore, remote ObjectStore) (*TieredStore, error) {
+	if local == nil || remote == nil {
+		return nil, fmt.Errorf("%w: local and remote stores are required", ErrInitializingStore)
+	}
+
+	downloader, ok := local.(ObjectDownloader)
+	if !ok {
+		return nil, fmt.Errorf("%w: local store must provide the content of its objects", ErrInitializingStore)
+	}
+
+	return &TieredStore{
+		local:      local,
+		downloader: downloader,
+		remote:     remote,
+		client:     http.DefaultClient,
+	}, nil
+}
+
+// HealthCheck checks both the local and the remote stores
+func (t *TieredStore) HealthCheck(ctx context.Context) error {
+	if err := HealthCheck(ctx, t.local); err != nil {
+		return err
+	}
+
+	return HealthCheck(ctx, t.remote)
+}
+
+// Put stores the object in the remote store and keeps a copy in the local store.
+// The content is first copied to the local store and then uploaded from the local copy to the remote store,
+// so it is never held in memory. If the upload fails, the local copy is removed.
+func (t *TieredStore) Put(ctx context.Context, id string, content io.Reader) (Object, error) {
+	object, err := t.local.Put(ctx, id, content)
+	// the object may have been copied concurrently, but it may still be missing in the remote store
+	duplicate := errors.Is(err, ErrDuplicateObject)
+	if duplicate {
+		object, err = t.local.Get(ctx, id)
+	}
+	if err != nil {
+		return Object{}, err
+	}
+
+	localContent, err := t.downloader.Download(ctx, object)
+	if err != nil {
+		return Object{}, err
+	}
+	defer localContent.Close() //nolint:errcheck
+
+	_, err = t.remote.Put(ctx, id, localContent)
+	if err != nil {
+		if deleter, ok := t.local.(ObjectDeleter); ok && !duplicate {
+			_ = deleter.Delete(ctx, id)
+		}
+		return Object{}, err
+	}
+
+	return object, nil
+}
+
+// Get retrieves the object from the local store. If not present, copies it from the remote store.
+func (t *TieredStore) Get(ctx context.Context, id string) (Object, error) {
+	object, err := t.local.Get(ctx, id)
+	if !errors.Is(err, ErrObjectNotFound) {
+		return object, err
+	}
+
+	remoteObject, err := t.remote.Get(ctx, id)
+	if err != nil {
+		return Object{}, err
+	}
+
+	content, err := t.fetch(ctx, remoteObject)
+	if err != nil {
+		return Object{}, err
+	}
+	defer content.Close() //nolint:errcheck
+
+	// the content is verified using the remote checksum's algorithm, which may differ from the local store's
+	algorithm, expected := util.DefaultChecksumAlgorithm, ""
+	if remoteObject.Checksum != "" {
+		algorithm, expected, err = util.ParseChecksum(remoteObject.Checksum)
+		if err != nil {
+			return Object{}, fmt.Errorf("%w: %w", ErrAccessingObject, err)
+		}
+	}
+
+	hash, err := util.NewChecksumHash(algorithm)
+	if err != nil {
+		return Object{}, fmt.Errorf("%w: %w", ErrAccessingObject, err)
+	}
+
+	object, err = t.local.Put(ctx, id, io.TeeReader(content, hash))
+	// the object may have been copied concurrently
+	if errors.Is(err, ErrDuplicateObject) {
+		return t.local.Get(ctx, id)
+	}
+	if err != nil {
+		return Object{}, err
+	}
+
+	if expected != "" && fmt.Sprintf("%x", hash.Sum(nil)) != expected {
+		if deleter, ok := t.local.(ObjectDeleter); ok {
+			_ = deleter.Delete(ctx, id)
+		}
+		return Object{}, fmt.Errorf("%w: checksum mismatch copying %q", ErrAccessingObject, id)
+	}
+
+	return object, nil
+}
+
+// Download returns the content of the object from the local store.
+// If not present, the content is obtained from the remote store.
+func (t *TieredStore) Download(ctx context.Context, object Object) (io.ReadCloser, error) {
+	content, err := t.downloader.Download(ctx, object)
+	if !errors.Is(err, ErrObjectNotFound) {
+		return content, err
+	}
+
+	remoteObject, err := t.remote.Get(ctx, object.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return t.fetch(ctx, remoteObject)
+}
+
+// fetch returns the content of an object of the remote store
+func (t *TieredStore) fetch(ctx context.Context, object Object) (io.ReadCloser, error) {
+	if downloader, ok := t.remote.(ObjectDownloader); ok {
+		return downloader.Download(ctx, object)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, object.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingObject, err)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAccessingObject, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("%w: HTTP response: %s", ErrAccessingObject, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// Pin pins the object in the remote store. Fails with ErrNotSupported if the remote store cannot pin objects.
+func (t *TieredStore) Pin(ctx context.Context, id string) error {
+	pinner, ok := t.remote.(ObjectPinner)
+	if !ok {
+		return fmt.Errorf("%w: remote store cannot pin objects", ErrNotSupported)
+	}
+
+	return pinner.Pin(ctx, id)
+}
+
+// Unpin unpins the object in the remote store. Fails with ErrNotSupported if the remote store cannot pin objects.
+func (t *TieredStore) Unpin(ctx context.Context, id string) error {
+	pinner, ok := t.remote.(ObjectPinner)
+	if !ok {
+		return fmt.Errorf("%w: remote store cannot pin objects", ErrNotSupported)
+	}
+
+	return pinner.Unpin(ctx, id)
+}
+
+// Pinned returns if the object is pinned in the remote store
+func (t *TieredStore) Pinned(ctx context.Context, id string) (bool, error) {
+	return IsPinned(ctx, t.remote, id)
+}
@@ -0,0 +1,230 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/k6build/pkg/store"
+	"github.com/grafana/k6build/pkg/store/memory"
+)
+
+// httpStore is an ObjectStore whose objects are downloaded from an http server
+type httpStore struct {
+	store *memory.Store
+	url   string
+}
+
+func (h httpStore) Get(ctx context.Context, id string) (store.Object, error) {
+	object, err := h.store.Get(ctx, id)
+	object.URL = h.url + "/" + id
+	return object, err
+}
+
+func (h httpStore) Put(ctx context.Context, id string, content io.Reader) (store.Object, error) {
+	return h.store.Put(ctx, id, content)
+}
+
+func TestTieredStore(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		preload   map[string]string
+		id        string
+		expect    string
+		expectErr error
+	}{
+		{
+			title:   "object in remote store",
+			preload: map[string]string{"object": "content"},
+			id:      "object",
+			expect:  "content",
+		},
+		{
+			title:     "object not found",
+			id:        "object",
+			expectErr: store.ErrObjectNotFound,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			remotes := map[string]store.ObjectStore{}
+
+			remote := memory.NewMemoryStore()
+			remotes["downloader"] = remote
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				object, err := remote.Get(r.Context(), r.URL.Path[1:])
+				if err != nil {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				content, _ := remote.Download(r.Context(), object)
+				_, _ = io.Copy(w, content)
+			}))
+			t.Cleanup(srv.Close)
+			remotes["http"] = httpStore{store: remote, url: srv.URL}
+
+			for id, content := range tc.preload {
+				if _, err := remote.Put(context.TODO(), id, bytes.NewBufferString(content)); err != nil {
+					t.Fatalf("test setup %v", err)
+				}
+			}
+
+			for name, remote := range remotes {
+				local := memory.NewMemoryStore()
+				tiered, err := store.NewTieredStore(local, remote)
+				if err != nil {
+					t.Fatalf("creating store %v", err)
+				}
+
+				object, err := tiered.Get(context.TODO(), tc.id)
+				if !errors.Is(err, tc.expectErr) {
+					t.Fatalf("%s: expected %v got %v", name, tc.expectErr, err)
+				}
+
+				if tc.expectErr != nil {
+					continue
+				}
+
+				// the object is copied to the local store
+				if _, err = local.Get(context.TODO(), tc.id); err != nil {
+					t.Fatalf("%s: object not copied to local store %v", name, err)
+				}
+
+				content, err := tiered.Download(context.TODO(), object)
+				if err != nil {
+					t.Fatalf("%s: downloading object %v", name, err)
+				}
+				data, _ := io.ReadAll(content)
+				_ = content.Close()
+
+				if string(data) != tc.expect {
+					t.Fatalf("%s: expected %q got %q", name, tc.expect, string(data))
+				}
+			}
+		})
+	}
+}
+
+// checksumStore is a memory store that reports the given checksum for its objects
+type checksumStore struct {
+	*memory.Store
+	checksum string
+}
+
+func (c checksumStore) Get(ctx context.Context, id string) (store.Object, error) {
+	object, err := c.Store.Get(ctx, id)
+	object.Checksum = c.checksum
+	return object, err
+}
+
+func TestTieredStoreChecksum(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("content")
+
+	testCases := []struct {
+		title     string
+		checksum  string
+		expectErr error
+	}{
+		{
+			title:    "checksum with a different algorithm",
+			checksum: fmt.Sprintf("sha512:%x", sha512.Sum512(content)),
+		},
+		{
+			title:    "checksum without algorithm",
+			checksum: fmt.Sprintf("%x", sha256.Sum256(content)),
+		},
+		{
+			title:     "checksum mismatch",
+			checksum:  fmt.Sprintf("sha512:%x", sha512.Sum512([]byte("other content"))),
+			expectErr: store.ErrAccessingObject,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			remote := checksumStore{Store: memory.NewMemoryStore(), checksum: tc.checksum}
+			if _, err := remote.Put(context.TODO(), "object", bytes.NewReader(content)); err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+
+			local := memory.NewMemoryStore()
+			tiered, err := store.NewTieredStore(local, remote)
+			if err != nil {
+				t.Fatalf("creating store %v", err)
+			}
+
+			_, err = tiered.Get(context.TODO(), "object")
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestTieredStorePut(t *testing.T) {
+	t.Parallel()
+
+	local := memory.NewMemoryStore()
+	remote := memory.NewMemoryStore()
+	tiered, err := store.NewTieredStore(local, remote)
+	if err != nil {
+		t.Fatalf("creating store %v", err)
+	}
+
+	if _, err = tiered.Put(context.TODO(), "object", bytes.NewBufferString("content")); err != nil {
+		t.Fatalf("storing object %v", err)
+	}
+
+	for name, s := range map[string]store.ObjectStore{"local": local, "remote": remote} {
+		if _, err = s.Get(context.TODO(), "object"); err != nil {
+			t.Fatalf("object not stored in %s store %v", name, err)
+		}
+	}
+
+	_, err = tiered.Put(context.TODO(), "object", bytes.NewBufferString("content"))
+	if !errors.Is(err, store.ErrDuplicateObject) {
+		t.Fatalf("expected %v got %v", store.ErrDuplicateObject, err)
+	}
+}
+
+func TestTieredStorePutRemoteFails(t *testing.T) {
+	t.Parallel()
+
+	local := memory.NewMemoryStore()
+	remote := memory.NewMemoryStore()
+	tiered, err := store.NewTieredStore(local, remote)
+	if err != nil {
+		t.Fatalf("creating store %v", err)
+	}
+
+	if _, err = remote.Put(context.TODO(), "object", bytes.NewBufferString("content")); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	_, err = tiered.Put(context.TODO(), "object", bytes.NewBufferString("content"))
+	if !errors.Is(err, store.ErrDuplicateObject) {
+		t.Fatalf("expected %v got %v", store.ErrDuplicateObject, err)
+	}
+
+	// the local copy is removed if the object cannot be stored in the remote store
+	if _, err = local.Get(context.TODO(), "object"); !errors.Is(err, store.ErrObjectNotFound) {
+		t.Fatalf("expected %v got %v", store.ErrObjectNotFound, err)
+	}
+}
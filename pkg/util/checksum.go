@@ -0,0 +1,90 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+// Checksum algorithms
+const (
+	ChecksumSHA256 = "sha256" //nolint:revive
+	ChecksumSHA512 = "sha512"
+
+	// DefaultChecksumAlgorithm is the algorithm used if none is specified
+	DefaultChecksumAlgorithm = ChecksumSHA256
+)
+
+// ErrInvalidChecksum is returned when a checksum or its algorithm is not valid
+var ErrInvalidChecksum = errors.New("invalid checksum")
+
+// NewChecksumHash returns a hash for the checksum algorithm. If empty, DefaultChecksumAlgorithm is used.
+func NewChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case ChecksumSHA256, "":
+		return sha256.New(), nil
+	case ChecksumSHA512:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidChecksum, algorithm)
+	}
+}
+
+// FormatChecksum returns the checksum in the form <algorithm>:<hex digest>
+func FormatChecksum(algorithm string, digest []byte) string {
+	if algorithm == "" {
+		algorithm = DefaultChecksumAlgorithm
+	}
+
+	return fmt.Sprintf("%s:%x", algorithm, digest)
+}
+
+// ParseChecksum returns the algorithm and the hex digest of a checksum in the form <algorithm>:<hex digest>.
+// A digest without algorithm is assumed to be sha256, as checksums were recorded before supporting other
+// algorithms.
+func ParseChecksum(checksum string) (string, string, error) {
+	algorithm, digest, found := strings.Cut(checksum, ":")
+	if !found {
+		algorithm, digest = ChecksumSHA256, checksum
+	}
+
+	if _, err := NewChecksumHash(algorithm); err != nil || algorithm == "" {
+		return "", "", fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidChecksum, algorithm)
+	}
+
+	if digest == "" {
+		return "", "", fmt.Errorf("%w: empty digest", ErrInvalidChecksum)
+	}
+
+	return algorithm, strings.ToLower(digest), nil
+}
+
+// CompactChecksum returns the checksum in the form reported for artifacts: the hex digest for sha256,
+// as artifacts were reported before supporting other algorithms, and <algorithm>:<hex digest> otherwise.
+// Checksums that cannot be parsed are returned unchanged.
+func CompactChecksum(checksum string) string {
+	algorithm, digest, err := ParseChecksum(checksum)
+	if err != nil {
+		return checksum
+	}
+
+	if algorithm == ChecksumSHA256 {
+		return digest
+	}
+
+	return algorithm + ":" + digest
+}
+
+// Checksum returns the checksum of the content using the given algorithm, in the form <algorithm>:<hex digest>
+func Checksum(algorithm string, content []byte) (string, error) {
+	hash, err := NewChecksumHash(algorithm)
+	if err != nil {
+		return "", err
+	}
+	_, _ = hash.Write(content)
+
+	return FormatChecksum(algorithm, hash.Sum(nil)), nil
+}
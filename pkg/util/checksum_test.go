@@ -0,0 +1,162 @@
+package util
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseChecksum(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title           string
+		checksum        string
+		expectAlgorithm string
+		expectDigest    string
+		expectErr       error
+	}{
+		{
+			title:           "sha256",
+			checksum:        "sha256:abcdef",
+			expectAlgorithm: ChecksumSHA256,
+			expectDigest:    "abcdef",
+		},
+		{
+			title:           "sha512",
+			checksum:        "sha512:ABCDEF",
+			expectAlgorithm: ChecksumSHA512,
+			expectDigest:    "abcdef",
+		},
+		{
+			title:           "bare digest is sha256",
+			checksum:        "abcdef",
+			expectAlgorithm: ChecksumSHA256,
+			expectDigest:    "abcdef",
+		},
+		{
+			title:     "unsupported algorithm",
+			checksum:  "md5:abcdef",
+			expectErr: ErrInvalidChecksum,
+		},
+		{
+			title:     "missing algorithm",
+			checksum:  ":abcdef",
+			expectErr: ErrInvalidChecksum,
+		},
+		{
+			title:     "empty digest",
+			checksum:  "sha256:",
+			expectErr: ErrInvalidChecksum,
+		},
+		{
+			title:     "empty checksum",
+			checksum:  "",
+			expectErr: ErrInvalidChecksum,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			algorithm, digest, err := ParseChecksum(tc.checksum)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if algorithm != tc.expectAlgorithm || digest != tc.expectDigest {
+				t.Fatalf("expected %s:%s got %s:%s", tc.expectAlgorithm, tc.expectDigest, algorithm, digest)
+			}
+		})
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("content")
+
+	testCases := []struct {
+		title     string
+		algorithm string
+		expect    string
+		expectErr error
+	}{
+		{
+			title:     "default algorithm",
+			algorithm: "",
+			expect:    fmt.Sprintf("sha256:%x", sha256.Sum256(content)),
+		},
+		{
+			title:     "sha512",
+			algorithm: ChecksumSHA512,
+			expect:    fmt.Sprintf("sha512:%x", sha512.Sum512(content)),
+		},
+		{
+			title:     "unsupported algorithm",
+			algorithm: "md5",
+			expectErr: ErrInvalidChecksum,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			checksum, err := Checksum(tc.algorithm, content)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if checksum != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, checksum)
+			}
+		})
+	}
+}
+
+func TestCompactChecksum(t *testing.T) {
+	t.Parallel()
+
+	digest := "7f06720503c80153816b4ef9f58571c2fce620e0447fba1bb092188ff87e322d"
+
+	testCases := []struct {
+		title    string
+		checksum string
+		expect   string
+	}{
+		{
+			title:    "sha256",
+			checksum: "sha256:" + digest,
+			expect:   digest,
+		},
+		{
+			title:    "sha256 without algorithm",
+			checksum: digest,
+			expect:   digest,
+		},
+		{
+			title:    "sha512",
+			checksum: "sha512:" + digest,
+			expect:   "sha512:" + digest,
+		},
+		{
+			title:    "invalid checksum",
+			checksum: "md5:" + digest,
+			expect:   "md5:" + digest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			if checksum := CompactChecksum(tc.checksum); checksum != tc.expect {
+				t.Fatalf("expected %q got %q", tc.expect, checksum)
+			}
+		})
+	}
+}
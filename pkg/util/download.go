@@ -2,51 +2,269 @@ package util
 
 import (
 	"context"
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"os"
-	"syscall"
+	"path/filepath"
 )
 
 var (
-	ErrDownloadFailed = fmt.Errorf("downloading file failed")    //nolint:revive
-	ErrWritingFile    = fmt.Errorf("opening output file failed") //nolint:revive
+	ErrChecksumMismatch  = fmt.Errorf("checksum mismatch")          //nolint:revive
+	ErrDownloadFailed    = fmt.Errorf("downloading file failed")    //nolint:revive
+	ErrSignatureMismatch = fmt.Errorf("signature mismatch")         //nolint:revive
+	ErrWritingFile       = fmt.Errorf("opening output file failed") //nolint:revive
 )
 
+// Verifier checks the content of a file before it is written, given the checksum of the content
+// in the form <algorithm>:<hex digest>
+type Verifier func(checksum string) error
+
+// SignatureVerifier returns a Verifier that checks the base64 encoded ed25519 signature is over
+// the checksum of the content
+func SignatureVerifier(key ed25519.PublicKey, signature string) Verifier {
+	return func(checksum string) error {
+		decoded, err := base64.StdEncoding.DecodeString(signature)
+		if err != nil {
+			return fmt.Errorf("%w: decoding signature %w", ErrSignatureMismatch, err)
+		}
+
+		if !ed25519.Verify(key, []byte(checksum), decoded) {
+			return ErrSignatureMismatch
+		}
+
+		return nil
+	}
+}
+
 // Download downloads a file from a URL and saves it to the output file.
 func Download(ctx context.Context, url string, output string) error {
+	return DownloadWithProgress(ctx, url, output, "", nil)
+}
+
+// DownloadWithProgress downloads a file from a URL and saves it to the output file,
+// reporting the progress of the download to the progress function, if not nil.
+// If checksum is not empty, the output file is only written if the content matches it.
+// The checksum is in the form <algorithm>:<hex digest>. A digest without algorithm is assumed to be sha256.
+func DownloadWithProgress(
+	ctx context.Context,
+	url string,
+	output string,
+	checksum string,
+	progress ProgressFunc,
+) error {
+	return DownloadVerified(ctx, url, output, checksum, progress, nil)
+}
+
+// DownloadVerified downloads a file from a URL and saves it to the output file as DownloadWithProgress,
+// but the output file is only written if the verifier, if not nil, accepts the downloaded content.
+// The verifier receives the checksum of the content, using the algorithm of the expected checksum.
+func DownloadVerified(
+	ctx context.Context,
+	url string,
+	output string,
+	checksum string,
+	progress ProgressFunc,
+	verifier Verifier,
+) error {
+	return DownloadWithOptions(ctx, url, output, DownloadOptions{
+		Checksum: checksum,
+		Progress: progress,
+		Verifier: verifier,
+	})
+}
+
+// DownloadOptions defines the optional settings of a download
+type DownloadOptions struct {
+	// Expected checksum of the content in the form <algorithm>:<hex digest>
+	Checksum string
+	// Function that receives the progress of the download
+	Progress ProgressFunc
+	// Function that must accept the checksum of the content
+	Verifier Verifier
+	// Size of the content. Required for downloading in parallel
+	Size int64
+	// Number of byte ranges downloaded in parallel. The content is downloaded in a single stream if its size
+	// is not known or the server doesn't support ranges. Defaults to 1 (a single stream)
+	Parallel int
+}
+
+// DownloadWithOptions downloads a file from a URL and saves it to the output file as DownloadVerified.
+// If the options allow it, the content is downloaded in byte ranges in parallel, writing each range
+// at its offset in the output.
+func DownloadWithOptions(ctx context.Context, url string, output string, opts DownloadOptions) error {
+	chunkSize := ChunkSize(opts.Size, opts.Parallel)
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return fmt.Errorf("%w %w", ErrDownloadFailed, err)
 	}
+	if chunkSize > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=0-%d", chunkSize-1))
+	}
+
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("%w %w", ErrDownloadFailed, err)
 	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	// if the server doesn't support ranges, it returns the whole content
+	if chunkSize > 0 && resp.StatusCode == http.StatusPartialContent {
+		return writeExecutable(output, opts.Checksum, opts.Verifier, func(file *os.File, hash hash.Hash) error {
+			var out io.WriterAt = file
+			if opts.Progress != nil {
+				out = NewProgressWriterAt(file, opts.Size, opts.Progress)
+			}
+
+			size, err := WriteRange(resp, 0, chunkSize-1, out)
+			if err != nil {
+				return err
+			}
+			if size != opts.Size {
+				return fmt.Errorf("%w expected %d bytes got %d", ErrDownloadFailed, opts.Size, size)
+			}
+
+			err = DownloadRanges(ctx, http.DefaultClient, url, chunkSize, size, chunkSize, out)
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(hash, io.NewSectionReader(file, 0, size))
+			if err != nil {
+				return fmt.Errorf("%w %w", ErrWritingFile, err)
+			}
+
+			return nil
+		})
+	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("%w %w", ErrDownloadFailed, err)
+		return fmt.Errorf("%w status %s", ErrDownloadFailed, resp.Status)
+	}
+
+	var content io.Reader = resp.Body
+	if opts.Progress != nil {
+		content = NewProgressReader(resp.Body, resp.ContentLength, opts.Progress)
+	}
+
+	return writeExecutable(output, opts.Checksum, opts.Verifier, copyContent(content))
+}
+
+// WriteExecutable writes the content to an executable file.
+// The content is written to a temporary file in the same directory that replaces the output file
+// only if the content was completely written and, if checksum is not empty, matches the checksum.
+// This way, a failed write never leaves a partially written executable.
+func WriteExecutable(output string, content io.Reader, checksum string) error {
+	return writeExecutable(output, checksum, nil, copyContent(content))
+}
+
+// copyContent returns a function that writes the content to the file, computing its hash
+func copyContent(content io.Reader) func(*os.File, hash.Hash) error {
+	return func(file *os.File, hash hash.Hash) error {
+		_, err := io.Copy(io.MultiWriter(file, hash), content)
+		if err != nil {
+			return fmt.Errorf("%w %w", ErrWritingFile, err)
+		}
+
+		return nil
+	}
+}
+
+// writeExecutable writes the content to an executable file using the write function, that must also
+// write the content to the hash
+func writeExecutable(output string, checksum string, verifier Verifier, write func(*os.File, hash.Hash) error) error {
+	algorithm, expected := DefaultChecksumAlgorithm, ""
+	if checksum != "" {
+		var err error
+		algorithm, expected, err = ParseChecksum(checksum)
+		if err != nil {
+			return err
+		}
+	}
+
+	hash, err := NewChecksumHash(algorithm)
+	if err != nil {
+		return err
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(output), "."+filepath.Base(output)+"-*")
+	if err != nil {
+		return fmt.Errorf("%w %w", ErrWritingFile, err)
 	}
 	defer func() {
-		_ = resp.Body.Close()
+		// after the rename, the temporary file no longer exists
+		_ = tmpFile.Close()
+		_ = os.Remove(tmpFile.Name())
 	}()
 
-	outFile, err := os.OpenFile( //nolint:gosec
-		output,
-		os.O_TRUNC|os.O_WRONLY|os.O_CREATE,
-		syscall.S_IRUSR|syscall.S_IXUSR|syscall.S_IWUSR,
-	)
+	if err = write(tmpFile, hash); err != nil {
+		return err
+	}
+
+	actual := FormatChecksum(algorithm, hash.Sum(nil))
+	if expected != "" && actual != algorithm+":"+expected {
+		return fmt.Errorf("%w: expected %s got %s", ErrChecksumMismatch, checksum, actual)
+	}
+
+	if verifier != nil {
+		if err = verifier(actual); err != nil {
+			return err
+		}
+	}
+
+	err = tmpFile.Chmod(0o755) //nolint:gosec
+	if err != nil {
+		return fmt.Errorf("%w %w", ErrWritingFile, err)
+	}
+
+	err = tmpFile.Close()
 	if err != nil {
 		return fmt.Errorf("%w %w", ErrWritingFile, err)
 	}
+
+	err = os.Rename(tmpFile.Name(), output)
+	if err != nil {
+		return fmt.Errorf("%w %w", ErrWritingFile, err)
+	}
+
+	return nil
+}
+
+// VerifyChecksum checks the checksum of a file matches the expected checksum.
+// The checksum is in the form <algorithm>:<hex digest>. A digest without algorithm is assumed to be sha256.
+func VerifyChecksum(path string, checksum string) error {
+	algorithm, expected, err := ParseChecksum(checksum)
+	if err != nil {
+		return err
+	}
+
+	hash, err := NewChecksumHash(algorithm)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path) //nolint:gosec
+	if err != nil {
+		return err
+	}
 	defer func() {
-		_ = outFile.Close()
+		_ = file.Close()
 	}()
 
-	_, err = io.Copy(outFile, resp.Body)
+	_, err = io.Copy(hash, file)
 	if err != nil {
-		return fmt.Errorf("%w %w", ErrWritingFile, err)
+		return err
+	}
+
+	actual := FormatChecksum(algorithm, hash.Sum(nil))
+	if actual != algorithm+":"+expected {
+		return fmt.Errorf("%w: expected %s got %s", ErrChecksumMismatch, checksum, actual)
 	}
 
 	return nil
@@ -1,13 +1,22 @@
 package util
 
 import (
+	"bytes"
 	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"sync/atomic"
 	"testing"
 	"testing/fstest"
+	"time"
 )
 
 func TestDownload(t *testing.T) {
@@ -56,3 +65,333 @@ func TestDownload(t *testing.T) {
 		})
 	}
 }
+
+func TestDownloadVerified(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("hello, world\n")
+	files := fstest.MapFS{
+		"file": &fstest.MapFile{Data: content},
+	}
+
+	fileSrv := httptest.NewServer(http.FileServerFS(files))
+	t.Cleanup(fileSrv.Close)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generating key %v", err)
+	}
+	// the signature is over the checksum of the content
+	checksum, err := Checksum(ChecksumSHA256, content)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte(checksum)))
+	otherSignature := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, []byte("other")))
+
+	testCases := []struct {
+		title     string
+		verifier  Verifier
+		expectErr error
+	}{
+		{
+			title:    "valid signature",
+			verifier: SignatureVerifier(publicKey, signature),
+		},
+		{
+			title:     "signature mismatch",
+			verifier:  SignatureVerifier(publicKey, otherSignature),
+			expectErr: ErrSignatureMismatch,
+		},
+		{
+			title:     "invalid signature",
+			verifier:  SignatureVerifier(publicKey, "not base64"),
+			expectErr: ErrSignatureMismatch,
+		},
+		{
+			title: "no verifier",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			output := filepath.Join(t.TempDir(), "file")
+			err := DownloadVerified(context.TODO(), fileSrv.URL+"/file", output, "", nil, tc.verifier)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v, got %v", tc.expectErr, err)
+			}
+
+			// the output is only written if verified
+			_, err = os.Stat(output)
+			if written := err == nil; written != (tc.expectErr == nil) {
+				t.Fatalf("expected written %t got %t", tc.expectErr == nil, written)
+			}
+		})
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("hello, world\n")
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, content, 0o600); err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	testCases := []struct {
+		title     string
+		path      string
+		checksum  string
+		expectErr error
+	}{
+		{
+			title:    "matching checksum",
+			path:     path,
+			checksum: fmt.Sprintf("%x", sha256.Sum256(content)),
+		},
+		{
+			title:    "matching sha256 checksum with algorithm",
+			path:     path,
+			checksum: fmt.Sprintf("sha256:%x", sha256.Sum256(content)),
+		},
+		{
+			title:    "matching sha512 checksum",
+			path:     path,
+			checksum: fmt.Sprintf("sha512:%x", sha512.Sum512(content)),
+		},
+		{
+			title:     "unsupported algorithm",
+			path:      path,
+			checksum:  fmt.Sprintf("md5:%x", sha256.Sum256(content)),
+			expectErr: ErrInvalidChecksum,
+		},
+		{
+			title:     "corrupted file",
+			path:      path,
+			checksum:  fmt.Sprintf("%x", sha256.Sum256([]byte("hello"))),
+			expectErr: ErrChecksumMismatch,
+		},
+		{
+			title:     "missing file",
+			path:      filepath.Join(t.TempDir(), "missing"),
+			checksum:  fmt.Sprintf("%x", sha256.Sum256(content)),
+			expectErr: os.ErrNotExist,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			err := VerifyChecksum(tc.path, tc.checksum)
+			if !errors.Is(err, tc.expectErr) {
+				t.Errorf("expected %v, got %v", tc.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestWriteExecutable(t *testing.T) {
+	t.Parallel()
+
+	content := []byte("hello, world\n")
+	checksum := fmt.Sprintf("%x", sha256.Sum256(content))
+	previous := []byte("previous content that is longer than the new content\n")
+
+	testCases := []struct {
+		title     string
+		checksum  string
+		expect    []byte
+		expectErr error
+	}{
+		{
+			title:    "matching checksum",
+			checksum: checksum,
+			expect:   content,
+		},
+		{
+			title:    "matching sha512 checksum",
+			checksum: fmt.Sprintf("sha512:%x", sha512.Sum512(content)),
+			expect:   content,
+		},
+		{
+			title:  "no checksum",
+			expect: content,
+		},
+		{
+			title:     "checksum mismatch",
+			checksum:  fmt.Sprintf("%x", sha256.Sum256([]byte("hello"))),
+			expect:    previous,
+			expectErr: ErrChecksumMismatch,
+		},
+		{
+			title:     "sha512 checksum mismatch",
+			checksum:  fmt.Sprintf("sha512:%x", sha512.Sum512([]byte("hello"))),
+			expect:    previous,
+			expectErr: ErrChecksumMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, "k6")
+			if err := os.WriteFile(path, previous, 0o600); err != nil {
+				t.Fatalf("test setup %v", err)
+			}
+
+			err := WriteExecutable(path, bytes.NewReader(content), tc.checksum)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v, got %v", tc.expectErr, err)
+			}
+
+			written, err := os.ReadFile(path) //nolint:gosec
+			if err != nil {
+				t.Fatalf("reading file %v", err)
+			}
+			if !bytes.Equal(written, tc.expect) {
+				t.Fatalf("expected %q, got %q", tc.expect, written)
+			}
+
+			if tc.expectErr == nil {
+				info, _ := os.Stat(path)
+				if info.Mode().Perm() != 0o755 {
+					t.Fatalf("expected mode 0755 got %o", info.Mode().Perm())
+				}
+			}
+
+			// temporary files must be removed
+			entries, _ := os.ReadDir(dir)
+			if len(entries) != 1 {
+				t.Fatalf("expected only the output file, found %d files", len(entries))
+			}
+		})
+	}
+}
+
+func TestDownloadParallel(t *testing.T) {
+	t.Parallel()
+
+	content := bytes.Repeat([]byte("0123456789"), (3*MinChunkSize+5)/10)
+	checksum, err := Checksum(DefaultChecksumAlgorithm, content)
+	if err != nil {
+		t.Fatalf("test setup %v", err)
+	}
+
+	// serves the content supporting ranges
+	ranges := func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}
+
+	// serves the ranges without their last byte
+	shortRanges := func(w http.ResponseWriter, r *http.Request) {
+		var start, end int64
+		_, _ = fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end)
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(content)))
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write(content[start:end])
+	}
+
+	// ignores the ranges
+	whole := func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(content)
+	}
+
+	testCases := []struct {
+		title          string
+		handler        http.HandlerFunc
+		parallel       int
+		checksum       string
+		expectRequests int64
+		expectErr      error
+	}{
+		{
+			title:          "parallel download",
+			handler:        ranges,
+			parallel:       3,
+			checksum:       checksum,
+			expectRequests: 3,
+		},
+		{
+			title:          "single stream",
+			handler:        ranges,
+			parallel:       1,
+			checksum:       checksum,
+			expectRequests: 1,
+		},
+		{
+			title:          "ranges not supported",
+			handler:        whole,
+			parallel:       3,
+			checksum:       checksum,
+			expectRequests: 1,
+		},
+		{
+			title:          "incomplete ranges",
+			handler:        shortRanges,
+			parallel:       3,
+			checksum:       checksum,
+			expectRequests: 1,
+			expectErr:      ErrDownloadFailed,
+		},
+		{
+			title:          "checksum mismatch",
+			handler:        ranges,
+			parallel:       3,
+			checksum:       "sha256:0000",
+			expectRequests: 3,
+			expectErr:      ErrChecksumMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			requests := atomic.Int64{}
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				tc.handler(w, r)
+			}))
+			t.Cleanup(srv.Close)
+
+			transferred := atomic.Int64{}
+			output := filepath.Join(t.TempDir(), "file")
+			err := DownloadWithOptions(context.TODO(), srv.URL, output, DownloadOptions{
+				Checksum: tc.checksum,
+				Size:     int64(len(content)),
+				Parallel: tc.parallel,
+				Progress: func(n int64, _ int64) { transferred.Store(n) },
+			})
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if requests.Load() != tc.expectRequests {
+				t.Fatalf("expected %d requests got %d", tc.expectRequests, requests.Load())
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			downloaded, err := os.ReadFile(output) //nolint:gosec
+			if err != nil {
+				t.Fatalf("reading output %v", err)
+			}
+
+			if !bytes.Equal(downloaded, content) {
+				t.Fatalf("downloaded content doesn't match")
+			}
+
+			if transferred.Load() != int64(len(content)) {
+				t.Fatalf("expected %d bytes transferred got %d", len(content), transferred.Load())
+			}
+		})
+	}
+}
@@ -1,17 +1,71 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"strconv"
+	"strings"
 )
 
-// ParseLogLevel parses the level from a string
+// ErrInvalidLogFormat is returned when the log format is not supported
+var ErrInvalidLogFormat = errors.New("invalid log format")
+
+// ErrInvalidLogLevel is returned when the log level is not valid
+var ErrInvalidLogLevel = errors.New("invalid log level")
+
+// log level names and aliases. slog doesn't define a trace level, so it is mapped below debug.
+var logLevels = map[string]slog.Level{ //nolint:gochecknoglobals
+	"trace":   slog.LevelDebug - 4,
+	"debug":   slog.LevelDebug,
+	"info":    slog.LevelInfo,
+	"warn":    slog.LevelWarn,
+	"warning": slog.LevelWarn,
+	"err":     slog.LevelError,
+	"error":   slog.LevelError,
+}
+
+// ParseLogLevel parses the level from a string. The level can be given by its name, case insensitive,
+// by its name with an offset (e.g. debug+2) or as a numeric slog level (e.g. -4 for debug)
 func ParseLogLevel(levelString string) (slog.Level, error) {
+	name := strings.ToLower(strings.TrimSpace(levelString))
+	if level, found := logLevels[name]; found {
+		return level, nil
+	}
+
+	if number, err := strconv.Atoi(name); err == nil {
+		return slog.Level(number), nil
+	}
+
 	var level slog.Level
-	err := level.UnmarshalText([]byte(levelString))
-	if err != nil {
-		return level, fmt.Errorf("parsing log level from string: %w", err)
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf(
+			"%w %q: valid levels are trace, debug, info, warn (warning), error (err) or a number",
+			ErrInvalidLogLevel,
+			levelString,
+		)
 	}
 
 	return level, nil
 }
+
+// NewLogger returns a logger that writes to the output the records of the given level or above
+// using the given format, either "text" or "json"
+func NewLogger(out io.Writer, levelString string, format string) (*slog.Logger, error) {
+	level, err := ParseLogLevel(levelString)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch format {
+	case "text", "":
+		return slog.New(slog.NewTextHandler(out, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(out, opts)), nil
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrInvalidLogFormat, format)
+	}
+}
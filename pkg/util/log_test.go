@@ -0,0 +1,107 @@
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		level     string
+		expect    slog.Level
+		expectErr error
+	}{
+		{level: "debug", expect: slog.LevelDebug},
+		{level: "DEBUG", expect: slog.LevelDebug},
+		{level: "Info", expect: slog.LevelInfo},
+		{level: "trace", expect: slog.LevelDebug - 4},
+		{level: "warn", expect: slog.LevelWarn},
+		{level: "WARNING", expect: slog.LevelWarn},
+		{level: "err", expect: slog.LevelError},
+		{level: "error", expect: slog.LevelError},
+		{level: " info ", expect: slog.LevelInfo},
+		{level: "debug+2", expect: slog.LevelDebug + 2},
+		{level: "-4", expect: slog.LevelDebug},
+		{level: "8", expect: slog.LevelError},
+		{level: "verbose", expectErr: ErrInvalidLogLevel},
+		{level: "", expectErr: ErrInvalidLogLevel},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.level, func(t *testing.T) {
+			t.Parallel()
+
+			level, err := ParseLogLevel(tc.level)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr == nil && level != tc.expect {
+				t.Fatalf("expected %v got %v", tc.expect, level)
+			}
+		})
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title     string
+		level     string
+		format    string
+		expectErr error
+	}{
+		{
+			title:  "text format",
+			level:  "INFO",
+			format: "text",
+		},
+		{
+			title:  "json format",
+			level:  "INFO",
+			format: "json",
+		},
+		{
+			title:     "invalid format",
+			level:     "INFO",
+			format:    "xml",
+			expectErr: ErrInvalidLogFormat,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			out := &bytes.Buffer{}
+			log, err := NewLogger(out, tc.level, tc.format)
+			if !errors.Is(err, tc.expectErr) {
+				t.Fatalf("expected %v got %v", tc.expectErr, err)
+			}
+
+			if tc.expectErr != nil {
+				return
+			}
+
+			log.Debug("filtered")
+			log.Info("message", "key", "value")
+
+			if strings.Contains(out.String(), "filtered") {
+				t.Fatalf("expected records below the level to be filtered: %s", out.String())
+			}
+
+			record := map[string]any{}
+			isJSON := json.Unmarshal(out.Bytes(), &record) == nil
+			if isJSON != (tc.format == "json") {
+				t.Fatalf("unexpected %s record: %s", tc.format, out.String())
+			}
+		})
+	}
+}
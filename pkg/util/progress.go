@@ -0,0 +1,136 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressInterval is the minimum time between the progress updates printed by a ProgressPrinter
+const progressInterval = 100 * time.Millisecond
+
+// ProgressFunc is called as content is transferred with the bytes transferred so far and the total
+// bytes to transfer, or -1 if the total is unknown. Once the transfer completes, the total is set.
+type ProgressFunc func(transferred int64, total int64)
+
+type progressReader struct {
+	reader      io.Reader
+	transferred int64
+	total       int64
+	progress    ProgressFunc
+}
+
+// NewProgressReader returns a reader that reports the progress of reading the total bytes
+// from the reader to the progress function. If the total is unknown, it must be -1.
+func NewProgressReader(reader io.Reader, total int64, progress ProgressFunc) io.Reader {
+	return &progressReader{
+		reader:   reader,
+		total:    total,
+		progress: progress,
+	}
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.reader.Read(b)
+	p.transferred += int64(n)
+
+	// the total is known once the content is completely read
+	if errors.Is(err, io.EOF) && p.total < 0 {
+		p.total = p.transferred
+		p.progress(p.transferred, p.total)
+		return n, err
+	}
+
+	if n > 0 {
+		p.progress(p.transferred, p.total)
+	}
+
+	return n, err
+}
+
+type progressWriterAt struct {
+	writer      io.WriterAt
+	mutex       sync.Mutex
+	transferred int64
+	total       int64
+	progress    ProgressFunc
+}
+
+// NewProgressWriterAt returns a WriterAt that reports the progress of writing, possibly concurrently,
+// the total bytes to the writer to the progress function.
+func NewProgressWriterAt(writer io.WriterAt, total int64, progress ProgressFunc) io.WriterAt {
+	return &progressWriterAt{
+		writer:   writer,
+		total:    total,
+		progress: progress,
+	}
+}
+
+func (p *progressWriterAt) WriteAt(b []byte, off int64) (int, error) {
+	n, err := p.writer.WriteAt(b, off)
+	if n == 0 {
+		return n, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.transferred += int64(n)
+	p.progress(p.transferred, p.total)
+
+	return n, err
+}
+
+// ProgressPrinter returns a ProgressFunc that prints the percentage transferred and the throughput
+// to the writer, overwriting the previous update. If the total is unknown, only the bytes transferred
+// are printed.
+func ProgressPrinter(w io.Writer) ProgressFunc {
+	start := time.Now()
+	last := time.Time{}
+
+	return func(transferred int64, total int64) {
+		now := time.Now()
+		done := total >= 0 && transferred >= total
+		if !done && now.Sub(last) < progressInterval {
+			return
+		}
+		last = now
+
+		throughput := int64(float64(transferred) / max(now.Sub(start).Seconds(), 0.001))
+
+		if total > 0 {
+			_, _ = fmt.Fprintf(
+				w,
+				"\rdownloading %3d%% %s of %s (%s/s)",
+				transferred*100/total,
+				formatBytes(transferred),
+				formatBytes(total),
+				formatBytes(throughput),
+			)
+		} else {
+			_, _ = fmt.Fprintf(w, "\rdownloading %s (%s/s)", formatBytes(transferred), formatBytes(throughput))
+		}
+
+		if done {
+			_, _ = fmt.Fprintln(w)
+		}
+	}
+}
+
+// formatBytes returns a human readable representation of a number of bytes (e.g. 1.5 MB)
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGT"[exp])
+}
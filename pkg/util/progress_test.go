@@ -0,0 +1,112 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestProgressReader(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Repeat("x", 1000)
+
+	testCases := []struct {
+		title string
+		total int64
+	}{
+		{
+			title: "known total",
+			total: int64(len(content)),
+		},
+		{
+			title: "unknown total",
+			total: -1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			var lastTransferred, lastTotal int64
+			updates := 0
+			progress := func(transferred int64, total int64) {
+				if transferred < lastTransferred {
+					t.Fatalf("transferred decreased from %d to %d", lastTransferred, transferred)
+				}
+				lastTransferred, lastTotal = transferred, total
+				updates++
+			}
+
+			// copy in small chunks to receive multiple updates
+			reader := NewProgressReader(strings.NewReader(content), tc.total, progress)
+			out := &bytes.Buffer{}
+			_, err := io.CopyBuffer(struct{ io.Writer }{out}, reader, make([]byte, 100))
+			if err != nil {
+				t.Fatalf("reading %v", err)
+			}
+
+			if out.String() != content {
+				t.Fatalf("content modified")
+			}
+
+			if updates < 10 {
+				t.Fatalf("expected at least 10 updates got %d", updates)
+			}
+
+			if lastTransferred != int64(len(content)) || lastTotal != int64(len(content)) {
+				t.Fatalf("expected final update %d/%d got %d/%d", len(content), len(content), lastTransferred, lastTotal)
+			}
+		})
+	}
+}
+
+func TestProgressPrinter(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		title       string
+		transferred int64
+		total       int64
+		expect      string
+	}{
+		{
+			title:       "known total",
+			transferred: 512 * 1024,
+			total:       1024 * 1024,
+			expect:      "downloading  50% 512.0 KB of 1.0 MB",
+		},
+		{
+			title:       "unknown total",
+			transferred: 2048,
+			total:       -1,
+			expect:      "downloading 2.0 KB",
+		},
+		{
+			title:       "completed",
+			transferred: 100,
+			total:       100,
+			expect:      "downloading 100% 100 B of 100 B",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.title, func(t *testing.T) {
+			t.Parallel()
+
+			out := &bytes.Buffer{}
+			ProgressPrinter(out)(tc.transferred, tc.total)
+
+			if !strings.Contains(out.String(), tc.expect) {
+				t.Fatalf("expected %q in %q", tc.expect, out.String())
+			}
+
+			completed := tc.total >= 0 && tc.transferred >= tc.total
+			if strings.HasSuffix(out.String(), "\n") != completed {
+				t.Fatalf("expected line to end only when completed: %q", out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// MinChunkSize is the minimum size of the byte ranges of a parallel download.
+// Content smaller than two chunks is downloaded in a single stream.
+const MinChunkSize = 1 << 20
+
+// ChunkSize returns the size of the byte ranges for downloading content of the given size
+// using the given number of parallel downloads.
+// Returns 0 if the content must be downloaded in a single stream.
+func ChunkSize(size int64, parallel int) int64 {
+	if parallel <= 1 || size < 2*MinChunkSize {
+		return 0
+	}
+
+	return max((size+int64(parallel)-1)/int64(parallel), MinChunkSize)
+}
+
+// WriteRange writes the content of the response to a request for the byte range [start, end]
+// at its offset in the output. Returns the total size of the content reported in the response.
+// Fails if the response is not the requested range or its content is incomplete.
+func WriteRange(resp *http.Response, start int64, end int64, out io.WriterAt) (int64, error) {
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("%w status %s", ErrDownloadFailed, resp.Status)
+	}
+
+	var rangeStart, rangeEnd, size int64
+	contentRange := resp.Header.Get("Content-Range")
+	_, err := fmt.Sscanf(contentRange, "bytes %d-%d/%d", &rangeStart, &rangeEnd, &size)
+	if err != nil || rangeStart != start || rangeEnd != end {
+		return 0, fmt.Errorf("%w invalid content range %q", ErrDownloadFailed, contentRange)
+	}
+
+	n, err := io.Copy(io.NewOffsetWriter(out, start), resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("%w %w", ErrDownloadFailed, err)
+	}
+
+	if n != end-start+1 {
+		return 0, fmt.Errorf("%w expected %d bytes at offset %d got %d", ErrDownloadFailed, end-start+1, start, n)
+	}
+
+	return size, nil
+}
+
+// DownloadRanges downloads in parallel the byte ranges of the url from the offset to the size, each one
+// of chunkSize bytes, writing them at their offset in the output. Stops at the first range that fails.
+func DownloadRanges(
+	ctx context.Context,
+	client *http.Client,
+	url string,
+	offset int64,
+	size int64,
+	chunkSize int64,
+	out io.WriterAt,
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for start := offset; start < size; start += chunkSize {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := downloadRange(ctx, client, url, start, min(start+chunkSize, size)-1, out)
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// downloadRange downloads the byte range [start, end] of the url and writes it at its offset in the output
+func downloadRange(ctx context.Context, client *http.Client, url string, start int64, end int64, out io.WriterAt) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w %w", ErrDownloadFailed, err)
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w %w", ErrDownloadFailed, err)
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	_, err = WriteRange(resp, start, end, out)
+
+	return err
+}
@@ -0,0 +1,25 @@
+package util
+
+import (
+	"context"
+	"io"
+)
+
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+// NewContextReader returns a reader that fails with the context's error once the context is done,
+// so copying from it stops on cancellation even if the underlying reader doesn't support it.
+func NewContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+func (c *contextReader) Read(b []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.reader.Read(b)
+}
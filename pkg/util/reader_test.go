@@ -0,0 +1,28 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestContextReader(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := NewContextReader(ctx, strings.NewReader("content"))
+
+	b := make([]byte, 3)
+	if _, err := reader.Read(b); err != nil {
+		t.Fatalf("unexpected %v", err)
+	}
+
+	cancel()
+
+	_, err := io.ReadAll(reader)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v got %v", context.Canceled, err)
+	}
+}